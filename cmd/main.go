@@ -3,11 +3,15 @@ package main
 import (
 	"github.com/choff5507/vehicle-image-comparison/pkg/vehiclecompare"
 	"github.com/choff5507/vehicle-image-comparison/internal/models"
+	"bytes"
+	"encoding/csv"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"runtime"
 )
 
 func main() {
@@ -17,10 +21,27 @@ func main() {
 		image1Base64 = flag.String("image1-base64", "", "Base64 encoded first vehicle image")
 		image2Base64 = flag.String("image2-base64", "", "Base64 encoded second vehicle image")
 		outputPath   = flag.String("output", "", "Path to output JSON file (optional)")
+		csvOutput    = flag.Bool("csv", false, "Output results as CSV instead of JSON")
 		verbose      = flag.Bool("verbose", false, "Enable verbose output")
+		manifestPath = flag.String("manifest", "", "CSV manifest of image1,image2[,expected] pairs to compare in bulk")
+		workers      = flag.Int("workers", runtime.NumCPU(), "Number of concurrent comparisons in manifest mode")
+		jsonLines    = flag.Bool("jsonl", false, "In manifest mode, stream one JSON result per line as pairs complete instead of CSV")
+		unordered    = flag.Bool("unordered", false, "With -jsonl, write lines in completion order rather than manifest order")
 	)
 	flag.Parse()
 	
+	if *manifestPath != "" {
+		if *workers < 1 {
+			log.Fatalf("-workers must be at least 1, got %d", *workers)
+		}
+		if *jsonLines {
+			runManifestJSONLinesMode(*manifestPath, *outputPath, *workers, !*unordered, *verbose)
+			return
+		}
+		runManifestMode(*manifestPath, *outputPath, *workers, *verbose)
+		return
+	}
+	
 	// Validate input parameters
 	hasFilePaths := *image1Path != "" && *image2Path != ""
 	hasBase64 := *image1Base64 != "" && *image2Base64 != ""
@@ -31,6 +52,10 @@ func main() {
 		fmt.Println("    ./vehicle-compare -image1 <path> -image2 <path> [-output <path>] [-verbose]")
 		fmt.Println("  For base64 inputs:")
 		fmt.Println("    ./vehicle-compare -image1-base64 <base64> -image2-base64 <base64> [-output <path>] [-verbose]")
+		fmt.Println("  Add -csv to emit a CSV header and row instead of JSON")
+		fmt.Println("  For bulk comparison:")
+		fmt.Println("    ./vehicle-compare -manifest <pairs.csv> [-workers <n>] [-output <results.csv>]")
+		fmt.Println("    ./vehicle-compare -manifest <pairs.csv> -jsonl [-unordered] [-output <results.jsonl>]")
 		os.Exit(1)
 	}
 	
@@ -64,6 +89,11 @@ func main() {
 	// Validate and sanitize result to prevent NaN/Inf JSON marshaling errors
 	result.ValidateAndSanitize()
 	
+	if *csvOutput {
+		writeCSVResult(result, *outputPath)
+		return
+	}
+	
 	// Output results
 	resultJSON, err := json.MarshalIndent(result, "", "  ")
 	if err != nil {
@@ -82,6 +112,7 @@ func main() {
 	fmt.Printf("Vehicle Comparison Results:\n")
 	fmt.Printf("==========================\n")
 	fmt.Printf("Same Vehicle: %v\n", result.IsSameVehicle)
+	fmt.Printf("Decision: %v\n", result.Decision)
 	fmt.Printf("Similarity Score: %.3f\n", result.SimilarityScore)
 	fmt.Printf("Confidence: %v\n", getConfidenceString(result.ConfidenceLevel))
 	fmt.Printf("Processing Time: %dms\n", result.ProcessingInfo.ProcessingTimeMs)
@@ -107,11 +138,107 @@ func main() {
 		fmt.Printf("  Lighting Consistency: %v\n", result.ProcessingInfo.LightingConsistency)
 	}
 	
+	if len(result.Warnings) > 0 {
+		fmt.Printf("\nWarnings:\n")
+		for _, warning := range result.Warnings {
+			fmt.Printf("  - %s\n", warning)
+		}
+	}
+	
 	if *outputPath == "" && *verbose {
 		fmt.Printf("\nFull JSON Result:\n%s\n", string(resultJSON))
 	}
 }
 
+func runManifestMode(manifestPath, outputPath string, workers int, verbose bool) {
+	entries := loadManifest(manifestPath)
+	
+	if verbose {
+		fmt.Fprintf(os.Stderr, "Comparing %d pairs with %d workers\n", len(entries), workers)
+	}
+	
+	// One service is shared by all workers so initialization is paid once
+	service := vehiclecompare.NewVehicleComparisonService()
+	results := runManifest(service, entries, workers)
+	
+	var buf bytes.Buffer
+	if err := writeManifestResults(&buf, results); err != nil {
+		log.Fatalf("Failed to write CSV: %v", err)
+	}
+	
+	if outputPath == "" {
+		fmt.Print(buf.String())
+		return
+	}
+	
+	if err := os.WriteFile(outputPath, buf.Bytes(), 0644); err != nil {
+		log.Fatalf("Failed to write output file: %v", err)
+	}
+	fmt.Printf("Results written to %s\n", outputPath)
+}
+
+func runManifestJSONLinesMode(manifestPath, outputPath string, workers int, ordered, verbose bool) {
+	entries := loadManifest(manifestPath)
+	
+	if verbose {
+		fmt.Fprintf(os.Stderr, "Streaming %d pairs with %d workers\n", len(entries), workers)
+	}
+	
+	output := os.Stdout
+	if outputPath != "" {
+		file, err := os.Create(outputPath)
+		if err != nil {
+			log.Fatalf("Failed to create output file: %v", err)
+		}
+		defer file.Close()
+		output = file
+	}
+	
+	service := vehiclecompare.NewVehicleComparisonService()
+	if err := streamManifestJSONLines(output, service, entries, workers, ordered); err != nil {
+		log.Fatalf("Failed to write results: %v", err)
+	}
+	
+	if outputPath != "" {
+		fmt.Printf("Results written to %s\n", outputPath)
+	}
+}
+
+func loadManifest(manifestPath string) []manifestEntry {
+	file, err := os.Open(manifestPath)
+	if err != nil {
+		log.Fatalf("Failed to open manifest: %v", err)
+	}
+	defer file.Close()
+	
+	entries, err := readManifest(file, filepath.Dir(manifestPath))
+	if err != nil {
+		log.Fatalf("Failed to read manifest: %v", err)
+	}
+	return entries
+}
+
+func writeCSVResult(result *models.ComparisonResult, outputPath string) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	writer.Write(models.CSVHeader())
+	writer.Write(result.CSVRecord())
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		log.Fatalf("Failed to write CSV: %v", err)
+	}
+	
+	if outputPath == "" {
+		fmt.Print(buf.String())
+		return
+	}
+	
+	if err := os.WriteFile(outputPath, buf.Bytes(), 0644); err != nil {
+		log.Fatalf("Failed to write output file: %v", err)
+	}
+	fmt.Printf("Results written to %s\n", outputPath)
+}
+
 func getConfidenceString(level models.ConfidenceLevel) string {
 	switch level {
 	case models.ConfidenceHigh:
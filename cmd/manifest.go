@@ -0,0 +1,265 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+)
+
+// pairComparer is the part of the comparison service the manifest runner needs
+type pairComparer interface {
+	CompareVehicleImages(image1Path, image2Path string) (*models.ComparisonResult, error)
+}
+
+// manifestEntry is one image pair from a manifest. Expected is nil when the manifest
+// row carries no label.
+type manifestEntry struct {
+	Image1   string
+	Image2   string
+	Expected *bool
+}
+
+type manifestResult struct {
+	Entry  manifestEntry
+	Result *models.ComparisonResult
+	Err    error
+}
+
+// readManifest parses a CSV manifest of "image1,image2[,expected]" rows. Expected
+// accepts true/false, 1/0 or same/different. An optional header row starting with
+// "image1" is skipped, and relative paths are resolved against baseDir.
+func readManifest(r io.Reader, baseDir string) ([]manifestEntry, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+
+	var entries []manifestEntry
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("manifest line %d: %v", line, err)
+		}
+
+		if line == 1 && strings.EqualFold(strings.TrimSpace(record[0]), "image1") {
+			continue
+		}
+		if len(record) < 2 || len(record) > 3 {
+			return nil, fmt.Errorf("manifest line %d: expected image1,image2[,expected], got %d fields", line, len(record))
+		}
+
+		entry := manifestEntry{
+			Image1: resolveManifestPath(baseDir, record[0]),
+			Image2: resolveManifestPath(baseDir, record[1]),
+		}
+
+		if len(record) == 3 && strings.TrimSpace(record[2]) != "" {
+			expected, err := parseExpectedLabel(record[2])
+			if err != nil {
+				return nil, fmt.Errorf("manifest line %d: %v", line, err)
+			}
+			entry.Expected = &expected
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+func resolveManifestPath(baseDir, path string) string {
+	path = strings.TrimSpace(path)
+	if filepath.IsAbs(path) || baseDir == "" {
+		return path
+	}
+	return filepath.Join(baseDir, path)
+}
+
+func parseExpectedLabel(label string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(label)) {
+	case "same":
+		return true, nil
+	case "different":
+		return false, nil
+	}
+
+	expected, err := strconv.ParseBool(strings.TrimSpace(label))
+	if err != nil {
+		return false, fmt.Errorf("invalid expected label %q", label)
+	}
+	return expected, nil
+}
+
+// runManifest compares every entry using a pool of workers sharing one service.
+// Results are returned in manifest order; a failed pair records its error rather
+// than stopping the batch.
+func runManifest(comparer pairComparer, entries []manifestEntry, workers int) []manifestResult {
+	results := make([]manifestResult, len(entries))
+	streamManifest(comparer, entries, workers, false, func(i int, result manifestResult) error {
+		results[i] = result
+		return nil
+	})
+	return results
+}
+
+// streamManifest compares every entry using a pool of workers sharing one service
+// and passes each result to emit with its manifest index as soon as it is ready. With
+// ordered set, results that finish early are held back until those before them have
+// been emitted. An error from emit stops the batch and is returned.
+func streamManifest(comparer pairComparer, entries []manifestEntry, workers int, ordered bool, emit func(int, manifestResult) error) error {
+	if workers < 1 {
+		workers = 1
+	}
+
+	type indexedResult struct {
+		index  int
+		result manifestResult
+	}
+
+	jobs := make(chan int)
+	completed := make(chan indexedResult)
+	stop := make(chan struct{})
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				result, err := comparer.CompareVehicleImages(entries[i].Image1, entries[i].Image2)
+				if result != nil {
+					result.ValidateAndSanitize()
+				}
+				completed <- indexedResult{i, manifestResult{Entry: entries[i], Result: result, Err: err}}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(completed)
+		defer wg.Wait()
+		defer close(jobs)
+		for i := range entries {
+			select {
+			case jobs <- i:
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	var emitErr error
+	pending := make(map[int]manifestResult)
+	next := 0
+	for c := range completed {
+		// Keep draining after a failure so the workers can exit
+		if emitErr != nil {
+			continue
+		}
+
+		if !ordered {
+			emitErr = emit(c.index, c.result)
+		} else {
+			pending[c.index] = c.result
+			for result, ok := pending[next]; ok && emitErr == nil; result, ok = pending[next] {
+				delete(pending, next)
+				emitErr = emit(next, result)
+				next++
+			}
+		}
+
+		if emitErr != nil {
+			close(stop)
+		}
+	}
+
+	return emitErr
+}
+
+// manifestLine is one pair's outcome in JSON-lines output. Index is the pair's
+// position in the manifest, since lines may be written as pairs complete.
+type manifestLine struct {
+	Index    int                      `json:"index"`
+	Image1   string                   `json:"image1"`
+	Image2   string                   `json:"image2"`
+	Expected *bool                    `json:"expected,omitempty"`
+	Correct  *bool                    `json:"correct,omitempty"`
+	Result   *models.ComparisonResult `json:"result,omitempty"`
+	Error    string                   `json:"error,omitempty"`
+}
+
+// streamManifestJSONLines compares every entry and writes each sanitized result to w
+// as one JSON object per line as soon as it is available, so large batches are never
+// buffered. Lines are in manifest order when ordered is set, else in completion order.
+func streamManifestJSONLines(w io.Writer, comparer pairComparer, entries []manifestEntry, workers int, ordered bool) error {
+	encoder := json.NewEncoder(w)
+	return streamManifest(comparer, entries, workers, ordered, func(i int, r manifestResult) error {
+		line := manifestLine{
+			Index:    i,
+			Image1:   r.Entry.Image1,
+			Image2:   r.Entry.Image2,
+			Expected: r.Entry.Expected,
+			Result:   r.Result,
+		}
+		if r.Err != nil {
+			line.Error = r.Err.Error()
+		} else if r.Entry.Expected != nil {
+			correct := r.Result.IsSameVehicle == *r.Entry.Expected
+			line.Correct = &correct
+		}
+		return encoder.Encode(line)
+	})
+}
+
+// manifestHeader returns the column names written by writeManifestResults
+func manifestHeader() []string {
+	header := []string{"image1", "image2", "expected"}
+	header = append(header, models.CSVHeader()...)
+	return append(header, "correct", "error")
+}
+
+// writeManifestResults writes one CSV row per pair. Result columns are left empty for
+// failed pairs, and "correct" is only filled when the pair had an expected label.
+func writeManifestResults(w io.Writer, results []manifestResult) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(manifestHeader()); err != nil {
+		return err
+	}
+
+	for _, r := range results {
+		row := []string{r.Entry.Image1, r.Entry.Image2, ""}
+		if r.Entry.Expected != nil {
+			row[2] = strconv.FormatBool(*r.Entry.Expected)
+		}
+
+		correct := ""
+		errMsg := ""
+		if r.Err != nil {
+			row = append(row, make([]string, len(models.CSVHeader()))...)
+			errMsg = r.Err.Error()
+		} else {
+			row = append(row, r.Result.CSVRecord()...)
+			if r.Entry.Expected != nil {
+				correct = strconv.FormatBool(r.Result.IsSameVehicle == *r.Entry.Expected)
+			}
+		}
+
+		if err := writer.Write(append(row, correct, errMsg)); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
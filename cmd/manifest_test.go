@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+	"github.com/choff5507/vehicle-image-comparison/internal/testutil"
+	"github.com/choff5507/vehicle-image-comparison/pkg/vehiclecompare"
+)
+
+func TestManifestComparesAllPairs(t *testing.T) {
+	dir := t.TempDir()
+
+	spec := testutil.DefaultSpec()
+	vehicle := testutil.FrontVehicle(spec)
+	defer vehicle.Close()
+	for _, name := range []string{"a.png", "b.png"} {
+		if _, err := testutil.WriteImage(dir, name, vehicle); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	manifest := "image1,image2,expected\n" +
+		"a.png,b.png,same\n" +
+		"a.png,missing.png,\n"
+	manifestPath := filepath.Join(dir, "pairs.csv")
+	if err := os.WriteFile(manifestPath, []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Open(manifestPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	entries, err := readManifest(file, dir)
+	if err != nil {
+		t.Fatalf("Failed to read manifest: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].Expected == nil || !*entries[0].Expected || entries[1].Expected != nil {
+		t.Errorf("Expected labels not parsed correctly: %+v", entries)
+	}
+
+	results := runManifest(vehiclecompare.NewVehicleComparisonService(), entries, 2)
+
+	var buf bytes.Buffer
+	if err := writeManifestResults(&buf, results); err != nil {
+		t.Fatalf("Failed to write results: %v", err)
+	}
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("Results are not valid CSV: %v", err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("Expected header and 2 rows, got %d rows", len(rows))
+	}
+
+	header := rows[0]
+	column := func(name string) int {
+		for i, h := range header {
+			if h == name {
+				return i
+			}
+		}
+		t.Fatalf("Missing column %q", name)
+		return -1
+	}
+
+	matched := rows[1]
+	if matched[column("error")] != "" {
+		t.Errorf("Unexpected error for identical pair: %s", matched[column("error")])
+	}
+	if matched[column("correct")] != "true" {
+		t.Errorf("Expected identical pair to be judged correctly, got row %v", matched)
+	}
+
+	failed := rows[2]
+	if failed[column("error")] == "" || failed[column("similarity_score")] != "" {
+		t.Errorf("Expected missing image to be reported as an error, got row %v", failed)
+	}
+	if failed[column("correct")] != "" {
+		t.Errorf("Expected no correctness verdict without a label, got %q", failed[column("correct")])
+	}
+}
+
+// delayedComparer finishes pairs out of order: earlier pairs take longer
+type delayedComparer struct {
+	total int
+}
+
+func (dc delayedComparer) CompareVehicleImages(image1Path, image2Path string) (*models.ComparisonResult, error) {
+	if image2Path == "missing.png" {
+		return nil, errors.New("failed to load one or both images")
+	}
+
+	index, _ := strconv.Atoi(strings.TrimSuffix(image1Path, ".png"))
+	time.Sleep(time.Duration(dc.total-index) * 5 * time.Millisecond)
+	return &models.ComparisonResult{IsSameVehicle: true, SimilarityScore: math.NaN()}, nil
+}
+
+func TestManifestStreamsOneJSONLinePerPair(t *testing.T) {
+	same := true
+	entries := []manifestEntry{
+		{Image1: "0.png", Image2: "b.png", Expected: &same},
+		{Image1: "1.png", Image2: "b.png"},
+		{Image1: "2.png", Image2: "missing.png"},
+		{Image1: "3.png", Image2: "b.png"},
+	}
+	comparer := delayedComparer{total: len(entries)}
+
+	for _, ordered := range []bool{true, false} {
+		var buf bytes.Buffer
+		if err := streamManifestJSONLines(&buf, comparer, entries, len(entries), ordered); err != nil {
+			t.Fatalf("ordered=%v: failed to stream results: %v", ordered, err)
+		}
+
+		lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+		if len(lines) != len(entries) {
+			t.Fatalf("ordered=%v: expected %d lines, got %d:\n%s", ordered, len(entries), len(lines), buf.String())
+		}
+
+		seen := make(map[int]bool)
+		var indices []int
+		for _, text := range lines {
+			var line manifestLine
+			if err := json.Unmarshal([]byte(text), &line); err != nil {
+				t.Fatalf("ordered=%v: line is not a JSON object: %v\n%s", ordered, err, text)
+			}
+			seen[line.Index] = true
+			indices = append(indices, line.Index)
+
+			if entries[line.Index].Image2 == "missing.png" {
+				if line.Error == "" || line.Result != nil {
+					t.Errorf("ordered=%v: expected pair %d to report its error, got %s", ordered, line.Index, text)
+				}
+			} else if line.Result == nil || line.Result.SimilarityScore != 0 {
+				t.Errorf("ordered=%v: expected a sanitized result for pair %d, got %s", ordered, line.Index, text)
+			}
+		}
+		if len(seen) != len(entries) {
+			t.Errorf("ordered=%v: expected one line per pair, got indices %v", ordered, indices)
+		}
+
+		if ordered {
+			for i, index := range indices {
+				if index != i {
+					t.Errorf("Expected lines in manifest order, got indices %v", indices)
+					break
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package comparator
+
+import (
+	"math"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+)
+
+// Share of the min-biased aggregation taken by the lowest weighted score
+const minBiasedShare = 0.5
+
+type weightedScore struct {
+	score  float64
+	weight float64
+}
+
+// aggregate combines the weighted feature scores into the overall similarity.
+// The arithmetic mean is the plain weighted sum, as the weights sum to 1; the other
+// aggregations normalize by the total weight and ignore unweighted features.
+func aggregate(aggregation models.Aggregation, scores models.DetailedScores, weights models.FeatureWeights) float64 {
+	terms := []weightedScore{
+		{scores.GeometricSimilarity, weights.Geometric},
+		{scores.LightPatternSimilarity, weights.LightPattern},
+		{scores.BumperSimilarity, weights.Bumper},
+		{scores.ColorSimilarity, weights.Color},
+		{scores.ThermalSimilarity, weights.Thermal},
+	}
+
+	switch aggregation {
+	case models.AggregationGeometric:
+		return geometricMean(terms)
+	case models.AggregationHarmonic:
+		return harmonicMean(terms)
+	case models.AggregationMinBiased:
+		return (1.0-minBiasedShare)*arithmeticMean(terms) + minBiasedShare*lowestScore(terms)
+	default:
+		return arithmeticMean(terms)
+	}
+}
+
+func arithmeticMean(terms []weightedScore) float64 {
+	sum := 0.0
+	for _, term := range terms {
+		sum += term.score * term.weight
+	}
+	return sum
+}
+
+// geometricMean is 0 when any weighted score is 0
+func geometricMean(terms []weightedScore) float64 {
+	logSum, totalWeight := 0.0, 0.0
+	for _, term := range terms {
+		if term.weight <= 0 {
+			continue
+		}
+		if term.score <= 0 {
+			return 0.0
+		}
+		logSum += term.weight * math.Log(term.score)
+		totalWeight += term.weight
+	}
+	if totalWeight == 0 {
+		return 0.0
+	}
+	return math.Exp(logSum / totalWeight)
+}
+
+// harmonicMean is 0 when any weighted score is 0
+func harmonicMean(terms []weightedScore) float64 {
+	inverseSum, totalWeight := 0.0, 0.0
+	for _, term := range terms {
+		if term.weight <= 0 {
+			continue
+		}
+		if term.score <= 0 {
+			return 0.0
+		}
+		inverseSum += term.weight / term.score
+		totalWeight += term.weight
+	}
+	if totalWeight == 0 {
+		return 0.0
+	}
+	return totalWeight / inverseSum
+}
+
+// lowestScore is the lowest score among the weighted features
+func lowestScore(terms []weightedScore) float64 {
+	lowest := math.Inf(1)
+	for _, term := range terms {
+		if term.weight > 0 {
+			lowest = math.Min(lowest, term.score)
+		}
+	}
+	if math.IsInf(lowest, 1) {
+		return 0.0
+	}
+	return lowest
+}
@@ -0,0 +1,62 @@
+package comparator
+
+import (
+	"math"
+	"testing"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+)
+
+func TestConservativeAggregationsPenalizeOneLowScore(t *testing.T) {
+	scores := models.DetailedScores{
+		GeometricSimilarity:    0.9,
+		LightPatternSimilarity: 0.9,
+		BumperSimilarity:       0.2,
+	}
+	weights := models.FeatureWeights{Geometric: 1.0 / 3.0, LightPattern: 1.0 / 3.0, Bumper: 1.0 / 3.0}
+
+	engine := NewComparisonEngine()
+	arithmetic := engine.calculateWeightedSimilarity(scores, weights)
+	if math.Abs(arithmetic-2.0/3.0) > 1e-9 {
+		t.Fatalf("Expected the arithmetic mean 0.667, got %.3f", arithmetic)
+	}
+
+	for _, tc := range []struct {
+		aggregation models.Aggregation
+		want        float64
+	}{
+		{models.AggregationGeometric, math.Cbrt(0.9 * 0.9 * 0.2)},
+		{models.AggregationHarmonic, 3.0 / (1/0.9 + 1/0.9 + 1/0.2)},
+		{models.AggregationMinBiased, (2.0/3.0 + 0.2) / 2.0},
+	} {
+		engine.SetAggregation(tc.aggregation)
+		got := engine.calculateWeightedSimilarity(scores, weights)
+		if math.Abs(got-tc.want) > 1e-9 {
+			t.Errorf("%s: expected %.3f, got %.3f", tc.aggregation, tc.want, got)
+		}
+		if arithmetic-got < 0.1 {
+			t.Errorf("%s: expected notably less than the arithmetic mean %.3f, got %.3f", tc.aggregation, arithmetic, got)
+		}
+	}
+}
+
+func TestAggregationsAgreeOnUniformScores(t *testing.T) {
+	scores := models.DetailedScores{
+		GeometricSimilarity:    0.8,
+		LightPatternSimilarity: 0.8,
+		BumperSimilarity:       0.8,
+		ColorSimilarity:        0.8,
+	}
+	weights := DefaultConfig().DaylightWeights
+
+	engine := NewComparisonEngine()
+	for _, aggregation := range []models.Aggregation{
+		models.AggregationArithmetic, models.AggregationGeometric,
+		models.AggregationHarmonic, models.AggregationMinBiased,
+	} {
+		engine.SetAggregation(aggregation)
+		if got := engine.calculateWeightedSimilarity(scores, weights); math.Abs(got-0.8) > 1e-9 {
+			t.Errorf("%s: expected 0.8 for uniform scores, got %.3f", aggregation, got)
+		}
+	}
+}
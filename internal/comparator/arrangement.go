@@ -0,0 +1,147 @@
+package comparator
+
+import (
+	"math"
+	"sort"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+)
+
+// Shape context bins: log-spaced distances relative to the mean pairwise distance,
+// and angles relative to the direction towards the constellation's centroid
+const (
+	arrangementAngleBins = 8
+	minArrangementLights = 3 // Any two points are alike up to translation, rotation and scale
+
+	// Share of the light pattern score given to the arrangement when enabled
+	arrangementWeight = 0.3
+)
+
+// Upper edges of the distance bins, as multiples of the mean pairwise distance
+var arrangementDistanceEdges = []float64{0.5, 1.0, 2.0}
+
+// compareLightArrangements compares how the light elements are arranged, ignoring
+// what each element looks like. Each element is described by a shape context: a
+// histogram of the distances and directions to every other element, with distances
+// relative to the mean pairwise distance and directions relative to the centroid, so
+// the descriptor is unchanged by translation, rotation and scale but not by
+// mirroring. Elements are paired one-to-one by cheapest descriptor cost. ok is false
+// when either set has too few elements for an arrangement to mean anything.
+func (ce *ComparisonEngine) compareLightArrangements(elements1, elements2 []models.LightElement) (float64, bool) {
+	contexts1, ok1 := shapeContexts(elements1)
+	contexts2, ok2 := shapeContexts(elements2)
+	if !ok1 || !ok2 {
+		return 0.0, false
+	}
+
+	type pair struct {
+		i, j int
+		cost float64
+	}
+	pairs := make([]pair, 0, len(contexts1)*len(contexts2))
+	for i, c1 := range contexts1 {
+		for j, c2 := range contexts2 {
+			pairs = append(pairs, pair{i, j, chiSquareCost(c1, c2)})
+		}
+	}
+	sort.SliceStable(pairs, func(a, b int) bool { return pairs[a].cost < pairs[b].cost })
+
+	// Greedy one-to-one assignment; elements left unpaired cost the maximum
+	used1 := make([]bool, len(contexts1))
+	used2 := make([]bool, len(contexts2))
+	total, matched := 0.0, 0
+	for _, p := range pairs {
+		if used1[p.i] || used2[p.j] {
+			continue
+		}
+		used1[p.i], used2[p.j] = true, true
+		total += p.cost
+		matched++
+	}
+
+	count := max(len(contexts1), len(contexts2))
+	total += float64(count - matched)
+	return safeFloat64(1.0-total/float64(count), 0.5), true
+}
+
+// shapeContexts returns the normalized shape context histogram of each element
+func shapeContexts(elements []models.LightElement) ([][]float64, bool) {
+	n := len(elements)
+	if n < minArrangementLights {
+		return nil, false
+	}
+
+	var centroid models.Point2D
+	for _, e := range elements {
+		centroid.X += e.Position.X / float64(n)
+		centroid.Y += e.Position.Y / float64(n)
+	}
+
+	meanDistance := 0.0
+	for i := range elements {
+		for j := i + 1; j < n; j++ {
+			meanDistance += pointDistance(elements[i].Position, elements[j].Position)
+		}
+	}
+	meanDistance /= float64(n * (n - 1) / 2)
+	if meanDistance <= 0 {
+		return nil, false
+	}
+
+	bins := (len(arrangementDistanceEdges) + 1) * arrangementAngleBins
+	contexts := make([][]float64, n)
+	for i, e := range elements {
+		reference := referenceAngle(elements, i, centroid, meanDistance)
+
+		histogram := make([]float64, bins)
+		for j, other := range elements {
+			if j == i {
+				continue
+			}
+			distance := pointDistance(e.Position, other.Position) / meanDistance
+			distanceBin := sort.SearchFloat64s(arrangementDistanceEdges, distance)
+
+			angle := math.Atan2(other.Position.Y-e.Position.Y, other.Position.X-e.Position.X) - reference
+			angle = math.Mod(angle+4*math.Pi, 2*math.Pi)
+			angleBin := min(int(angle/(2*math.Pi)*arrangementAngleBins), arrangementAngleBins-1)
+
+			histogram[distanceBin*arrangementAngleBins+angleBin] += 1.0 / float64(n-1)
+		}
+		contexts[i] = histogram
+	}
+	return contexts, true
+}
+
+// referenceAngle is the direction from element i towards the centroid, or towards
+// the farthest other element when i sits on the centroid
+func referenceAngle(elements []models.LightElement, i int, centroid models.Point2D, meanDistance float64) float64 {
+	from := elements[i].Position
+	if pointDistance(from, centroid) > meanDistance*1e-6 {
+		return math.Atan2(centroid.Y-from.Y, centroid.X-from.X)
+	}
+
+	farthest, farthestDistance := from, -1.0
+	for j, other := range elements {
+		if d := pointDistance(from, other.Position); j != i && d > farthestDistance {
+			farthest, farthestDistance = other.Position, d
+		}
+	}
+	return math.Atan2(farthest.Y-from.Y, farthest.X-from.X)
+}
+
+// chiSquareCost is the chi-square distance between two normalized histograms,
+// from 0 (identical) to 1 (disjoint)
+func chiSquareCost(h1, h2 []float64) float64 {
+	cost := 0.0
+	for k := range h1 {
+		if sum := h1[k] + h2[k]; sum > 0 {
+			diff := h1[k] - h2[k]
+			cost += diff * diff / sum
+		}
+	}
+	return cost / 2.0
+}
+
+func pointDistance(a, b models.Point2D) float64 {
+	return math.Hypot(a.X-b.X, a.Y-b.Y)
+}
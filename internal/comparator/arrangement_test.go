@@ -0,0 +1,66 @@
+package comparator
+
+import (
+	"math"
+	"testing"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+)
+
+// constellation returns identical taillights at the given positions
+func constellation(positions ...models.Point2D) []models.LightElement {
+	elements := make([]models.LightElement, len(positions))
+	for i, p := range positions {
+		elements[i] = models.LightElement{Position: p, Shape: models.ShapeRectangular, Size: 2400, Intensity: 0.8, Type: models.TypeTaillight}
+	}
+	return elements
+}
+
+// transformed rotates elements by angle about the origin, scales and translates them
+func transformed(elements []models.LightElement, angle, scale, dx, dy float64) []models.LightElement {
+	sin, cos := math.Sincos(angle)
+	moved := make([]models.LightElement, len(elements))
+	for i, e := range elements {
+		e.Position = models.Point2D{
+			X: scale*(e.Position.X*cos-e.Position.Y*sin) + dx,
+			Y: scale*(e.Position.X*sin+e.Position.Y*cos) + dy,
+		}
+		moved[i] = e
+	}
+	return moved
+}
+
+func TestLightArrangementDistinguishesMirroredLayouts(t *testing.T) {
+	ce := NewComparisonEngine()
+
+	// Two wide taillights, a high brake light off to the left and a low reverse
+	// light to the right
+	layout := constellation(
+		models.Point2D{X: 300, Y: 350},
+		models.Point2D{X: 980, Y: 360},
+		models.Point2D{X: 520, Y: 220},
+		models.Point2D{X: 760, Y: 600},
+	)
+	mirrored := make([]models.LightElement, len(layout))
+	for i, e := range layout {
+		e.Position.X = 1280 - e.Position.X
+		mirrored[i] = e
+	}
+
+	same, ok := ce.compareLightArrangements(layout, transformed(layout, 0.3, 0.5, 120, -40))
+	if !ok {
+		t.Fatal("Expected four lights to have an arrangement")
+	}
+	if same < 0.99 {
+		t.Errorf("Expected a rotated, scaled and shifted copy to match, got %.3f", same)
+	}
+
+	different, _ := ce.compareLightArrangements(layout, mirrored)
+	if same-different < 0.2 {
+		t.Errorf("Expected the mirrored layout to score well below the same layout, got %.3f vs %.3f", different, same)
+	}
+
+	if _, ok := ce.compareLightArrangements(layout[:2], layout[:2]); ok {
+		t.Error("Expected two lights to have no arrangement")
+	}
+}
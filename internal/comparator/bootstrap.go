@@ -0,0 +1,80 @@
+package comparator
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+)
+
+// The interval is the central bootstrapConfidence share of the similarity scores
+// of bootstrapSamples resampled comparisons. The seed is fixed so a pair always
+// gets the same interval.
+const (
+	bootstrapSamples    = 200
+	bootstrapConfidence = 0.95
+	bootstrapSeed       = 1
+)
+
+// bootstrapInterval estimates how stable the similarity score is under the choice
+// of features: image 1's matchable features (structural elements, reference points,
+// light elements, bumper contour and mounting points) are resampled with
+// replacement, each resample is compared against image 2 and the percentile
+// interval of the scores is returned. Each image 1 feature is matched on its own, so
+// this resamples the matches. The interval is widened to contain the point estimate
+// when needed.
+func (ce *ComparisonEngine) bootstrapInterval(features1, features2 models.VehicleFeatures, similarity float64) *models.SimilarityInterval {
+	inner := *ce
+	inner.intervals = false
+	inner.subScores = false
+
+	rng := rand.New(rand.NewSource(bootstrapSeed))
+	scores := make([]float64, 0, bootstrapSamples)
+	for i := 0; i < bootstrapSamples; i++ {
+		result, err := inner.CompareVehicles(resampleFeatures(features1, rng), features2)
+		if err != nil || result.Decision == models.DecisionUndetermined {
+			continue
+		}
+		scores = append(scores, result.SimilarityScore)
+	}
+
+	if len(scores) == 0 {
+		return &models.SimilarityInterval{Lower: similarity, Upper: similarity, Confidence: bootstrapConfidence}
+	}
+
+	sort.Float64s(scores)
+	tail := (1.0 - bootstrapConfidence) / 2.0
+	lower := scores[int(math.Floor(tail*float64(len(scores)-1)))]
+	upper := scores[int(math.Ceil((1.0-tail)*float64(len(scores)-1)))]
+
+	return &models.SimilarityInterval{
+		Lower:      math.Min(lower, similarity),
+		Upper:      math.Max(upper, similarity),
+		Confidence: bootstrapConfidence,
+	}
+}
+
+// resampleFeatures returns a copy of features whose matchable feature lists are
+// drawn with replacement from the originals, keeping their sizes
+func resampleFeatures(features models.VehicleFeatures, rng *rand.Rand) models.VehicleFeatures {
+	resampled := features
+	resampled.GeometricFeatures.StructuralElements = resample(features.GeometricFeatures.StructuralElements, rng)
+	resampled.GeometricFeatures.ReferencePoints = resample(features.GeometricFeatures.ReferencePoints, rng)
+	resampled.LightPatterns.LightElements = resample(features.LightPatterns.LightElements, rng)
+	resampled.BumperFeatures.ContourSignature = resample(features.BumperFeatures.ContourSignature, rng)
+	resampled.BumperFeatures.MountingPoints = resample(features.BumperFeatures.MountingPoints, rng)
+	return resampled
+}
+
+func resample[T any](items []T, rng *rand.Rand) []T {
+	if len(items) == 0 {
+		return items
+	}
+
+	drawn := make([]T, len(items))
+	for i := range drawn {
+		drawn[i] = items[rng.Intn(len(items))]
+	}
+	return drawn
+}
@@ -0,0 +1,74 @@
+package comparator
+
+import (
+	"testing"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+)
+
+// badgedFeatures returns the rear fixture pair with n extra structural elements
+// spread across the vehicle. Every other element is half the size in image 2, so
+// the individual matches disagree and the score depends on which are sampled.
+func badgedFeatures(n int) (models.VehicleFeatures, models.VehicleFeatures) {
+	features1 := rearFeatures(0, 0)
+	features2 := rearFeatures(0, 0)
+
+	for i := 0; i < n; i++ {
+		position := models.Point2D{X: 200 + float64(i)*880/float64(n), Y: 500}
+		size2 := 60.0
+		if i%2 == 1 {
+			size2 = 30.0
+		}
+		features1.GeometricFeatures.StructuralElements = append(features1.GeometricFeatures.StructuralElements,
+			models.StructuralElement{Type: "badge", Position: position, Size: 60})
+		features2.GeometricFeatures.StructuralElements = append(features2.GeometricFeatures.StructuralElements,
+			models.StructuralElement{Type: "badge", Position: position, Size: size2})
+	}
+
+	return features1, features2
+}
+
+func TestSimilarityIntervalContainsScoreAndNarrowsWithMoreFeatures(t *testing.T) {
+	ce := NewComparisonEngine()
+	ce.SetSimilarityInterval(true)
+
+	width := func(n int) float64 {
+		features1, features2 := badgedFeatures(n)
+		result, err := ce.CompareVehicles(features1, features2)
+		if err != nil {
+			t.Fatalf("CompareVehicles with %d badges: %v", n, err)
+		}
+
+		interval := result.ProcessingInfo.SimilarityInterval
+		if interval == nil {
+			t.Fatalf("%d badges: no similarity interval", n)
+		}
+		if interval.Lower > result.SimilarityScore || interval.Upper < result.SimilarityScore {
+			t.Errorf("%d badges: interval [%.4f, %.4f] does not contain score %.4f",
+				n, interval.Lower, interval.Upper, result.SimilarityScore)
+		}
+		if interval.Confidence != bootstrapConfidence {
+			t.Errorf("%d badges: confidence = %v, want %v", n, interval.Confidence, bootstrapConfidence)
+		}
+		return interval.Upper - interval.Lower
+	}
+
+	few, many := width(2), width(16)
+	if few <= 0 {
+		t.Fatalf("interval with 2 badges has no width; the fixture does not vary")
+	}
+	if many >= few {
+		t.Errorf("interval width with 16 badges = %.4f, want narrower than %.4f with 2", many, few)
+	}
+}
+
+func TestSimilarityIntervalDisabledByDefault(t *testing.T) {
+	features1, features2 := badgedFeatures(4)
+	result, err := NewComparisonEngine().CompareVehicles(features1, features2)
+	if err != nil {
+		t.Fatalf("CompareVehicles: %v", err)
+	}
+	if result.ProcessingInfo.SimilarityInterval != nil {
+		t.Errorf("SimilarityInterval = %+v, want nil when not enabled", result.ProcessingInfo.SimilarityInterval)
+	}
+}
@@ -0,0 +1,133 @@
+package comparator
+
+import (
+	"math"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+)
+
+// Largest RGB distance, between black and white
+const maxRGBDistance = 441.67 // sqrt(255^2 + 255^2 + 255^2)
+
+// Brightness changes with shading across a panel, so in the perceptual spaces it
+// counts for less than hue and saturation for saturated colors. Grays have nothing
+// but brightness to tell them apart, so the down-weighting fades out with
+// saturation. The full Lab weighting is CIE94's for textiles (kL = 2).
+const (
+	hsvValueWeight = 0.25 // Value weight between fully saturated colors
+	labLightnessK  = 2.0  // Lightness divisor between chromatic colors
+	labChromaticC  = 40.0 // Chroma from which a color counts as fully chromatic
+	labChromaK1    = 0.048
+	labHueK2       = 0.014
+	maxLabDistance = 100.0 // CIE94 difference treated as entirely different colors, black against white
+)
+
+// colorSimilarity compares two dominant colors in the given space, 1 for identical
+// colors falling to 0
+func colorSimilarity(c1, c2 models.Color, space models.ColorSpace) float64 {
+	var distance float64
+	switch space {
+	case models.ColorSpaceHSV:
+		distance = hsvDistance(c1, c2)
+	case models.ColorSpaceLab:
+		distance = math.Min(1.0, cie94Distance(toLab(c1), toLab(c2))/maxLabDistance)
+	default:
+		rDiff := float64(c1.R) - float64(c2.R)
+		gDiff := float64(c1.G) - float64(c2.G)
+		bDiff := float64(c1.B) - float64(c2.B)
+		distance = math.Sqrt(rDiff*rDiff+gDiff*gDiff+bDiff*bDiff) / maxRGBDistance
+	}
+
+	return 1.0 - distance
+}
+
+// hsvDistance measures the distance between colors on the hue/saturation disc, where
+// hue is the angle and saturation the radius, combined with the value difference,
+// down-weighted for saturated colors. Each part is normalized to 0-1.
+func hsvDistance(c1, c2 models.Color) float64 {
+	h1, s1, v1 := toHSV(c1)
+	h2, s2, v2 := toHSV(c2)
+
+	dx := (s1*math.Cos(h1) - s2*math.Cos(h2)) / 2.0
+	dy := (s1*math.Sin(h1) - s2*math.Sin(h2)) / 2.0
+	valueWeight := 1.0 - (1.0-hsvValueWeight)*math.Min(s1, s2)
+	dv := (v1 - v2) * valueWeight
+
+	return math.Min(1.0, math.Sqrt(dx*dx+dy*dy+dv*dv))
+}
+
+// toHSV returns hue in radians and saturation and value in 0-1
+func toHSV(c models.Color) (float64, float64, float64) {
+	r, g, b := float64(c.R)/255.0, float64(c.G)/255.0, float64(c.B)/255.0
+	maxC := math.Max(r, math.Max(g, b))
+	minC := math.Min(r, math.Min(g, b))
+	delta := maxC - minC
+
+	hue := 0.0
+	switch {
+	case delta == 0:
+	case maxC == r:
+		hue = math.Mod((g-b)/delta, 6.0)
+	case maxC == g:
+		hue = (b-r)/delta + 2.0
+	default:
+		hue = (r-g)/delta + 4.0
+	}
+
+	saturation := 0.0
+	if maxC > 0 {
+		saturation = delta / maxC
+	}
+
+	return hue * math.Pi / 3.0, saturation, maxC
+}
+
+type labColor struct {
+	l, a, b float64
+}
+
+// toLab converts an sRGB color to CIELab under the D65 white point
+func toLab(c models.Color) labColor {
+	linear := func(v uint8) float64 {
+		x := float64(v) / 255.0
+		if x <= 0.04045 {
+			return x / 12.92
+		}
+		return math.Pow((x+0.055)/1.055, 2.4)
+	}
+	r, g, b := linear(c.R), linear(c.G), linear(c.B)
+
+	x := (0.4124*r + 0.3576*g + 0.1805*b) / 0.95047
+	y := 0.2126*r + 0.7152*g + 0.0722*b
+	z := (0.0193*r + 0.1192*g + 0.9505*b) / 1.08883
+
+	f := func(t float64) float64 {
+		if t > 216.0/24389.0 {
+			return math.Cbrt(t)
+		}
+		return (24389.0/27.0*t + 16.0) / 116.0
+	}
+	fx, fy, fz := f(x), f(y), f(z)
+
+	return labColor{l: 116.0*fy - 16.0, a: 500.0 * (fx - fy), b: 200.0 * (fy - fz)}
+}
+
+// cie94Distance is the CIE94 color difference, which scales chroma and hue
+// differences down for saturated colors as perception does. Lightness is divided by
+// up to labLightnessK as both colors become chromatic.
+func cie94Distance(lab1, lab2 labColor) float64 {
+	c1 := math.Hypot(lab1.a, lab1.b)
+	c2 := math.Hypot(lab2.a, lab2.b)
+	kL := 1.0 + (labLightnessK-1.0)*math.Min(1.0, math.Min(c1, c2)/labChromaticC)
+
+	dL := lab1.l - lab2.l
+	dC := c1 - c2
+	da := lab1.a - lab2.a
+	db := lab1.b - lab2.b
+	dH2 := math.Max(0, da*da+db*db-dC*dC)
+
+	sC := 1.0 + labChromaK1*c1
+	sH := 1.0 + labHueK2*c1
+
+	return math.Sqrt(math.Pow(dL/kL, 2) + math.Pow(dC/sC, 2) + dH2/(sH*sH))
+}
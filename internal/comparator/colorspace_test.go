@@ -0,0 +1,39 @@
+package comparator
+
+import (
+	"testing"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+)
+
+func TestPerceptualColorSpacesTolerateShading(t *testing.T) {
+	// The same red paint in light and in shadow
+	lit := models.ColorProfile{DominantColors: []models.Color{{R: 200, G: 30, B: 30, Weight: 1}}}
+	shaded := models.ColorProfile{DominantColors: []models.Color{{R: 120, G: 18, B: 18, Weight: 1}}}
+	blue := models.ColorProfile{DominantColors: []models.Color{{R: 30, G: 30, B: 200, Weight: 1}}}
+
+	score := func(space models.ColorSpace, c1, c2 models.ColorProfile) float64 {
+		ce := NewComparisonEngine()
+		ce.SetColorSpace(space)
+		return ce.compareColorProfiles(c1, c2)
+	}
+
+	rgb := score(models.ColorSpaceRGB, lit, shaded)
+	for _, space := range []models.ColorSpace{models.ColorSpaceHSV, models.ColorSpaceLab} {
+		if shade := score(space, lit, shaded); shade <= rgb {
+			t.Errorf("%v: expected two shades of the same hue to score higher than in RGB (%.3f vs %.3f)", space, shade, rgb)
+		}
+		if different := score(space, lit, blue); different >= score(space, lit, shaded) {
+			t.Errorf("%v: expected a different hue to score below a shade, got %.3f", space, different)
+		}
+	}
+
+	// Brightness is all that separates grays, so it is not discounted for them
+	black := models.Color{R: 0, G: 0, B: 0}
+	white := models.Color{R: 255, G: 255, B: 255}
+	for _, space := range []models.ColorSpace{models.ColorSpaceRGB, models.ColorSpaceHSV, models.ColorSpaceLab} {
+		if similarity := colorSimilarity(black, white, space); similarity > 0.01 {
+			t.Errorf("%v: expected black and white to be entirely different, got %.3f", space, similarity)
+		}
+	}
+}
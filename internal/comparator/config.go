@@ -0,0 +1,234 @@
+package comparator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+)
+
+// How far a weight set may sum from 1, allowing for hand-entered decimals
+const weightSumTolerance = 0.01
+
+// engineVersion is raised whenever a change to extraction or scoring can give the
+// same inputs a different result
+const engineVersion = "1"
+
+// Version identifies the engine's extraction and scoring behavior. Results cached
+// under another version are stale.
+func Version() string {
+	return engineVersion
+}
+
+// Config is the complete configuration of a ComparisonEngine. Start from
+// DefaultConfig and change the fields of interest.
+type Config struct {
+	// Per-feature weights of the overall similarity. Layout-only, mounting-only and
+	// IR-signature-only comparisons use their own fixed weights.
+	DaylightWeights models.FeatureWeights
+	InfraredWeights models.FeatureWeights
+
+	// Similarity above which a pair is the same vehicle, by lighting of image 1
+	DaylightThreshold float64
+	InfraredThreshold float64
+
+	UncertaintyBand    float64 // See SetUncertaintyBand
+	DecisionMode       models.DecisionMode
+	Aggregation        models.Aggregation
+	FeatureFloors      models.FeatureFloors // Used by DecisionAllMustExceed
+	MinPlateConfidence float64              // IR signatures built around weaker plate detections are ignored
+	MinMatchedLights   int                  // See SetMinMatchedLights
+	HistogramMetric    models.HistogramMetric
+	ColorSpace         models.ColorSpace
+
+	// Comparison modes, as set by the corresponding Set methods
+	LayoutOnly          bool
+	MountingOnly        bool
+	IRSignatureOnly     bool
+	PartialCrops        bool
+	AlignmentHypotheses bool
+	SimilarityInterval  bool
+	SubScores           bool
+	KeypointMatching    bool
+	LightArrangement    bool
+	CrossCamera         bool
+	PlateRelativeFrame  bool
+}
+
+// DefaultConfig returns the configuration NewComparisonEngine uses
+func DefaultConfig() Config {
+	return Config{
+		DaylightWeights: models.FeatureWeights{
+			Geometric:    0.30,
+			LightPattern: 0.30,
+			Bumper:       0.20,
+			Color:        0.20,
+		},
+		InfraredWeights: models.FeatureWeights{
+			Geometric:    0.35,
+			LightPattern: 0.35,
+			Bumper:       0.20,
+			Thermal:      0.10,
+		},
+		DaylightThreshold:  0.75, // Higher for daylight, which has more features available
+		InfraredThreshold:  0.70,
+		UncertaintyBand:    0.04,
+		FeatureFloors:      defaultFeatureFloors,
+		MinPlateConfidence: 0.3,
+		MinMatchedLights:   2,
+	}
+}
+
+// Validate reports the first field of the configuration outside its valid range:
+// each weight set must lie in [0, 1] and sum to 1, thresholds, the uncertainty band,
+// the plate confidence and the feature floors must lie in [0, 1], and at least one
+// matched light is required
+func (c Config) Validate() error {
+	if err := validateWeights("daylight", c.DaylightWeights); err != nil {
+		return err
+	}
+	if err := validateWeights("infrared", c.InfraredWeights); err != nil {
+		return err
+	}
+
+	for _, field := range []struct {
+		name  string
+		value float64
+	}{
+		{"daylight threshold", c.DaylightThreshold},
+		{"infrared threshold", c.InfraredThreshold},
+		{"uncertainty band", c.UncertaintyBand},
+		{"minimum plate confidence", c.MinPlateConfidence},
+	} {
+		if !inUnitInterval(field.value) {
+			return fmt.Errorf("%s %v is outside [0, 1]", field.name, field.value)
+		}
+	}
+
+	for _, floor := range []struct {
+		name  string
+		value float64
+	}{
+		{"geometric", c.FeatureFloors.Geometric},
+		{"light pattern", c.FeatureFloors.LightPattern},
+		{"bumper", c.FeatureFloors.Bumper},
+		{"color", c.FeatureFloors.Color},
+		{"thermal", c.FeatureFloors.Thermal},
+	} {
+		if !inUnitInterval(floor.value) {
+			return fmt.Errorf("%s floor %v is outside [0, 1]", floor.name, floor.value)
+		}
+	}
+
+	if c.MinMatchedLights < 1 {
+		return fmt.Errorf("minimum matched lights %d must be at least 1", c.MinMatchedLights)
+	}
+
+	return nil
+}
+
+func validateWeights(lighting string, weights models.FeatureWeights) error {
+	for _, weight := range []struct {
+		name  string
+		value float64
+	}{
+		{"geometric", weights.Geometric},
+		{"light pattern", weights.LightPattern},
+		{"bumper", weights.Bumper},
+		{"color", weights.Color},
+		{"thermal", weights.Thermal},
+	} {
+		if !inUnitInterval(weight.value) {
+			return fmt.Errorf("%s %s weight %v is outside [0, 1]", lighting, weight.name, weight.value)
+		}
+	}
+
+	sum := weights.Geometric + weights.LightPattern + weights.Bumper + weights.Color + weights.Thermal
+	if sum < 1.0-weightSumTolerance || sum > 1.0+weightSumTolerance {
+		return fmt.Errorf("%s weights sum to %.3f, want 1", lighting, sum)
+	}
+
+	return nil
+}
+
+// Fingerprint identifies the configuration together with the engine Version, for
+// keying cached results: changing any weight, threshold or mode, or upgrading to an
+// engine of another version, changes the fingerprint.
+func (c Config) Fingerprint() string {
+	sum := sha256.Sum256([]byte(engineVersion + "\n" + fmt.Sprintf("%#v", c)))
+	return hex.EncodeToString(sum[:8])
+}
+
+// inUnitInterval also rejects NaN
+func inUnitInterval(value float64) bool {
+	return value >= 0.0 && value <= 1.0
+}
+
+// NewComparisonEngineWithConfig creates an engine from a complete configuration,
+// for callers comparing their own feature sets with CompareVehicles. It returns an
+// error naming the offending field when config fails Validate.
+func NewComparisonEngineWithConfig(config Config) (*ComparisonEngine, error) {
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid engine config: %w", err)
+	}
+	return newComparisonEngine(config), nil
+}
+
+func newComparisonEngine(config Config) *ComparisonEngine {
+	return &ComparisonEngine{
+		daylightWeights:    config.DaylightWeights,
+		infraredWeights:    config.InfraredWeights,
+		daylightThreshold:  config.DaylightThreshold,
+		infraredThreshold:  config.InfraredThreshold,
+		minPlateConfidence: config.MinPlateConfidence,
+		minMatchedLights:   config.MinMatchedLights,
+		layoutOnly:         config.LayoutOnly,
+		mountingOnly:       config.MountingOnly,
+		irSignatureOnly:    config.IRSignatureOnly,
+		partialCrops:       config.PartialCrops,
+		alignmentSearch:    config.AlignmentHypotheses,
+		intervals:          config.SimilarityInterval,
+		subScores:          config.SubScores,
+		keypointMatching:   config.KeypointMatching,
+		lightArrangement:   config.LightArrangement,
+		crossCamera:        config.CrossCamera,
+		plateRelative:      config.PlateRelativeFrame,
+		uncertaintyBand:    config.UncertaintyBand,
+		decisionMode:       config.DecisionMode,
+		featureFloors:      config.FeatureFloors,
+		aggregation:        config.Aggregation,
+		histogramMetric:    config.HistogramMetric,
+		colorSpace:         config.ColorSpace,
+	}
+}
+
+// Config returns the engine's current configuration, including changes made with
+// its Set methods since it was created
+func (ce *ComparisonEngine) Config() Config {
+	return Config{
+		DaylightWeights:     ce.daylightWeights,
+		InfraredWeights:     ce.infraredWeights,
+		DaylightThreshold:   ce.daylightThreshold,
+		InfraredThreshold:   ce.infraredThreshold,
+		UncertaintyBand:     ce.uncertaintyBand,
+		DecisionMode:        ce.decisionMode,
+		Aggregation:         ce.aggregation,
+		FeatureFloors:       ce.featureFloors,
+		MinPlateConfidence:  ce.minPlateConfidence,
+		MinMatchedLights:    ce.minMatchedLights,
+		HistogramMetric:     ce.histogramMetric,
+		ColorSpace:          ce.colorSpace,
+		LayoutOnly:          ce.layoutOnly,
+		MountingOnly:        ce.mountingOnly,
+		IRSignatureOnly:     ce.irSignatureOnly,
+		PartialCrops:        ce.partialCrops,
+		AlignmentHypotheses: ce.alignmentSearch,
+		SimilarityInterval:  ce.intervals,
+		SubScores:           ce.subScores,
+		KeypointMatching:    ce.keypointMatching,
+		LightArrangement:    ce.lightArrangement,
+		CrossCamera:         ce.crossCamera,
+		PlateRelativeFrame:  ce.plateRelative,
+	}
+}
@@ -0,0 +1,223 @@
+package comparator
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+)
+
+func TestDefaultConfigMatchesDefaultEngine(t *testing.T) {
+	features1, features2 := rearFeatures(0, 0), rearFeatures(4, 0.05)
+
+	want, err := NewComparisonEngine().CompareVehicles(features1, features2)
+	if err != nil {
+		t.Fatalf("CompareVehicles: %v", err)
+	}
+	ce, err := NewComparisonEngineWithConfig(DefaultConfig())
+	if err != nil {
+		t.Fatalf("NewComparisonEngineWithConfig: %v", err)
+	}
+	got, err := ce.CompareVehicles(features1, features2)
+	if err != nil {
+		t.Fatalf("CompareVehicles: %v", err)
+	}
+
+	if got.SimilarityScore != want.SimilarityScore || got.Decision != want.Decision {
+		t.Errorf("DefaultConfig engine scored %.4f (%v), default engine %.4f (%v)",
+			got.SimilarityScore, got.Decision, want.SimilarityScore, want.Decision)
+	}
+}
+
+func TestCustomConfigWeightsAndThreshold(t *testing.T) {
+	features1, features2 := rearFeatures(0, 0), rearFeatures(4, 0.05)
+
+	config := DefaultConfig()
+	config.DaylightWeights = models.FeatureWeights{Geometric: 1.0}
+	config.DaylightThreshold = 0.99
+	config.UncertaintyBand = 0
+	ce, err := NewComparisonEngineWithConfig(config)
+	if err != nil {
+		t.Fatalf("NewComparisonEngineWithConfig: %v", err)
+	}
+
+	result, err := ce.CompareVehicles(features1, features2)
+	if err != nil {
+		t.Fatalf("CompareVehicles: %v", err)
+	}
+
+	if result.EffectiveWeights != config.DaylightWeights {
+		t.Errorf("EffectiveWeights = %+v, want %+v", result.EffectiveWeights, config.DaylightWeights)
+	}
+	if result.SimilarityScore != result.DetailedScores.GeometricSimilarity {
+		t.Errorf("SimilarityScore = %.4f, want the geometric score %.4f with all weight on geometry",
+			result.SimilarityScore, result.DetailedScores.GeometricSimilarity)
+	}
+	if result.SimilarityScore >= config.DaylightThreshold {
+		t.Fatalf("fixture scored %.4f, not below the %.2f threshold", result.SimilarityScore, config.DaylightThreshold)
+	}
+	if result.IsSameVehicle || result.Decision != models.DecisionDifferent {
+		t.Errorf("Decision = %v (same %v), want different under a %.2f threshold",
+			result.Decision, result.IsSameVehicle, config.DaylightThreshold)
+	}
+}
+
+func TestInvalidConfigRejected(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*Config)
+		want   string // Expected in the error message
+	}{
+		{"negative weight", func(c *Config) {
+			c.DaylightWeights = models.FeatureWeights{Geometric: -0.2, LightPattern: 0.6, Bumper: 0.3, Color: 0.3}
+		}, "daylight geometric weight"},
+		{"weight above one", func(c *Config) {
+			c.InfraredWeights = models.FeatureWeights{Geometric: 1.5}
+		}, "infrared geometric weight"},
+		{"weights not summing to one", func(c *Config) {
+			c.DaylightWeights.Color = 0.5
+		}, "daylight weights sum to 1.300"},
+		{"NaN weight", func(c *Config) {
+			c.InfraredWeights.Thermal = math.NaN()
+		}, "infrared thermal weight"},
+		{"threshold above one", func(c *Config) {
+			c.DaylightThreshold = 1.2
+		}, "daylight threshold"},
+		{"negative threshold", func(c *Config) {
+			c.InfraredThreshold = -0.1
+		}, "infrared threshold"},
+		{"uncertainty band above one", func(c *Config) {
+			c.UncertaintyBand = 2
+		}, "uncertainty band"},
+		{"plate confidence above one", func(c *Config) {
+			c.MinPlateConfidence = 1.5
+		}, "minimum plate confidence"},
+		{"floor above one", func(c *Config) {
+			c.FeatureFloors.LightPattern = 1.5
+		}, "light pattern floor"},
+		{"no matched lights", func(c *Config) {
+			c.MinMatchedLights = 0
+		}, "minimum matched lights"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			tt.modify(&config)
+
+			ce, err := NewComparisonEngineWithConfig(config)
+			if err == nil {
+				t.Fatalf("expected an error, got engine %p", ce)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error %q does not mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultConfigIsValid(t *testing.T) {
+	if err := DefaultConfig().Validate(); err != nil {
+		t.Errorf("DefaultConfig().Validate() = %v", err)
+	}
+}
+
+func TestThresholdMarginJustAboveThreshold(t *testing.T) {
+	features1, features2 := rearFeatures(0, 0), rearFeatures(4, 0.05)
+
+	score, err := NewComparisonEngine().CompareVehicles(features1, features2)
+	if err != nil {
+		t.Fatalf("CompareVehicles: %v", err)
+	}
+
+	// A threshold just below the fixture's score
+	config := DefaultConfig()
+	config.DaylightThreshold = score.SimilarityScore - 0.01
+	ce, err := NewComparisonEngineWithConfig(config)
+	if err != nil {
+		t.Fatalf("NewComparisonEngineWithConfig: %v", err)
+	}
+
+	result, err := ce.CompareVehicles(features1, features2)
+	if err != nil {
+		t.Fatalf("CompareVehicles: %v", err)
+	}
+
+	info := result.ProcessingInfo
+	if info.DecisionThreshold != config.DaylightThreshold {
+		t.Errorf("DecisionThreshold = %.4f, want the daylight threshold %.4f", info.DecisionThreshold, config.DaylightThreshold)
+	}
+	if math.Abs(info.ThresholdMargin-0.01) > 1e-9 {
+		t.Errorf("ThresholdMargin = %.6f, want 0.01", info.ThresholdMargin)
+	}
+	if !result.IsSameVehicle {
+		t.Errorf("Expected a positive margin to decide the same vehicle")
+	}
+}
+
+func TestConfigReflectsSetMethods(t *testing.T) {
+	config := DefaultConfig()
+	config.DaylightThreshold = 0.8
+	config.Aggregation = models.AggregationGeometric
+	ce, err := NewComparisonEngineWithConfig(config)
+	if err != nil {
+		t.Fatalf("NewComparisonEngineWithConfig: %v", err)
+	}
+	if got := ce.Config(); got != config {
+		t.Errorf("Expected the engine to report the config it was created with\ngot  %+v\nwant %+v", got, config)
+	}
+
+	ce.SetLayoutOnly(true)
+	if !ce.Config().LayoutOnly {
+		t.Error("Expected Config to reflect SetLayoutOnly")
+	}
+	if ce.Config().Fingerprint() == config.Fingerprint() {
+		t.Error("Expected a mode change to change the fingerprint")
+	}
+}
+
+func TestWeightChangeRecomputesCachedPair(t *testing.T) {
+	features1, features2 := rearFeatures(0, 0), rearFeatures(4, 0.05)
+
+	// A result cache keyed on the pair and the engine's configuration fingerprint
+	cache := map[string]*models.ComparisonResult{}
+	computed := 0
+	compare := func(ce *ComparisonEngine) *models.ComparisonResult {
+		key := "rear/rear-shifted/" + ce.Config().Fingerprint()
+		if result, ok := cache[key]; ok {
+			return result
+		}
+		computed++
+		result, err := ce.CompareVehicles(features1, features2)
+		if err != nil {
+			t.Fatalf("CompareVehicles: %v", err)
+		}
+		cache[key] = result
+		return result
+	}
+
+	original := compare(NewComparisonEngine())
+	compare(NewComparisonEngine())
+	if computed != 1 {
+		t.Fatalf("Expected an unchanged configuration to be served from the cache, computed %d times", computed)
+	}
+
+	config := DefaultConfig()
+	config.DaylightWeights = models.FeatureWeights{Geometric: 0.40, LightPattern: 0.20, Bumper: 0.20, Color: 0.20}
+	ce, err := NewComparisonEngineWithConfig(config)
+	if err != nil {
+		t.Fatalf("NewComparisonEngineWithConfig: %v", err)
+	}
+	reweighted := compare(ce)
+	if computed != 2 {
+		t.Errorf("Expected a weight change to recompute the cached pair, computed %d times", computed)
+	}
+	if reweighted.ProcessingInfo.ConfigFingerprint == original.ProcessingInfo.ConfigFingerprint {
+		t.Error("Expected results scored under different weights to carry different fingerprints")
+	}
+	if reweighted.ProcessingInfo.ConfigFingerprint != ce.Config().Fingerprint() {
+		t.Errorf("Expected the result to carry the engine's fingerprint %s, got %s",
+			ce.Config().Fingerprint(), reweighted.ProcessingInfo.ConfigFingerprint)
+	}
+}
@@ -6,6 +6,15 @@ import (
 	"math"
 )
 
+// Share of the geometric score given to the grille pattern when both images have one
+const grilleWeight = 0.15
+
+// Score of an element comparison when either side has none. An empty set usually
+// means detection failed rather than that the vehicle lacks the feature, so it is
+// neither evidence for a match (as a perfect score for two empty sets would be) nor
+// against one (as a zero for one empty set would be).
+const missingDataScore = 0.5
+
 // safeFloat64 ensures a float64 value is valid (not NaN or Inf) and within bounds
 func safeFloat64(value float64, defaultValue float64) float64 {
 	if math.IsNaN(value) || math.IsInf(value, 0) {
@@ -15,82 +24,693 @@ func safeFloat64(value float64, defaultValue float64) float64 {
 }
 
 type ComparisonEngine struct {
-	geometricWeight    float64
-	lightPatternWeight float64
-	bumperWeight       float64
-	colorWeight        float64
-	thermalWeight      float64
+	daylightWeights    models.FeatureWeights
+	infraredWeights    models.FeatureWeights
+	daylightThreshold  float64
+	infraredThreshold  float64
+	
+	minPlateConfidence float64 // IR signatures built around weaker plate detections are ignored
+	minMatchedLights   int     // Fewer matched light elements pull the element score towards neutral
+	layoutOnly         bool    // Compare structural layout only, ignoring appearance
+	mountingOnly       bool    // Compare plate mounting point geometry only
+	irSignatureOnly    bool    // Compare the IR signature around the plate only
+	partialCrops       bool    // Register the images and compare only the region both show
+	alignmentSearch    bool    // Keep the best-scoring of several alignments of image 2 onto image 1
+	intervals          bool    // Bootstrap a confidence interval around the similarity score
+	subScores          bool    // Report the unweighted components behind each score
+	keypointMatching   bool    // Score geometry by ORB keypoint matches when available
+	lightArrangement   bool    // Also compare the constellation the light elements form
+	crossCamera        bool    // Images come from different cameras; ignore absolute brightness
+	plateRelative      bool    // Measure layout from the license plate when both images have one
+	uncertaintyBand    float64 // Width of the band around the threshold reported as uncertain
+	decisionMode       models.DecisionMode
+	featureFloors      models.FeatureFloors // Scores each feature must exceed under DecisionAllMustExceed
+	aggregation        models.Aggregation   // How weighted feature scores combine into the overall similarity
+	histogramMetric    models.HistogramMetric
+	colorSpace         models.ColorSpace
 }
 
 func NewComparisonEngine() *ComparisonEngine {
-	return &ComparisonEngine{
-		geometricWeight:    0.35,
-		lightPatternWeight: 0.30,
-		bumperWeight:       0.20,
-		colorWeight:        0.10,
-		thermalWeight:      0.05,
+	return newComparisonEngine(DefaultConfig())
+}
+
+// SetHistogramMetric selects how color histograms are compared (default
+// intersection)
+func (ce *ComparisonEngine) SetHistogramMetric(metric models.HistogramMetric) {
+	ce.histogramMetric = metric
+}
+
+// SetColorSpace selects the space dominant colors are compared in (default RGB). The
+// HSV and Lab comparisons follow perceived color and tolerate shading.
+func (ce *ComparisonEngine) SetColorSpace(space models.ColorSpace) {
+	ce.colorSpace = space
+}
+
+// SetUncertaintyBand sets the width of the band centred on the similarity threshold
+// within which the decision is reported as uncertain (default 0.04, i.e. ±0.02).
+// A width of zero always yields a definite decision.
+func (ce *ComparisonEngine) SetUncertaintyBand(width float64) {
+	if width >= 0 {
+		ce.uncertaintyBand = width
+	}
+}
+
+// Below neutral, a feature score counts as disagreement
+var defaultFeatureFloors = models.FeatureFloors{
+	Geometric:    0.5,
+	LightPattern: 0.5,
+	Bumper:       0.5,
+	Color:        0.5,
+	Thermal:      0.5,
+}
+
+// SetDecisionMode selects how IsSameVehicle is decided (default
+// DecisionWeightedMean). Under DecisionAllMustExceed the weighted mean must still
+// exceed the threshold, and every computed, weighted feature score must also exceed
+// its floor, so one catastrophic mismatch cannot hide behind several good scores.
+func (ce *ComparisonEngine) SetDecisionMode(mode models.DecisionMode) {
+	ce.decisionMode = mode
+}
+
+// SetAggregation selects how the weighted feature scores combine into the overall
+// similarity (default AggregationArithmetic). The geometric and harmonic means and
+// the min-biased mean let one low feature score drag the result down further;
+// thresholds tuned for the arithmetic mean may need lowering with them.
+func (ce *ComparisonEngine) SetAggregation(aggregation models.Aggregation) {
+	ce.aggregation = aggregation
+}
+
+// SetFeatureFloors sets the score each feature must exceed under
+// DecisionAllMustExceed (default 0.5 for every feature)
+func (ce *ComparisonEngine) SetFeatureFloors(floors models.FeatureFloors) {
+	ce.featureFloors = floors
+}
+
+// SetMinMatchedLights sets how many light elements must match before their mean
+// similarity is trusted (default 2). With fewer matches the element score is pulled
+// towards a neutral 0.5 in proportion, so a single matched light cannot carry the
+// light pattern term on its own.
+func (ce *ComparisonEngine) SetMinMatchedLights(count int) {
+	if count >= 1 {
+		ce.minMatchedLights = count
 	}
 }
 
-// CompareVehicles performs comprehensive vehicle comparison
+// SetCrossCamera marks the images as captured by different cameras. Sensors and IR
+// illuminators differ in brightness baseline, so light element intensities are not
+// compared and reflectivity maps are compared after removing each map's mean level.
+func (ce *ComparisonEngine) SetCrossCamera(enabled bool) {
+	ce.crossCamera = enabled
+}
+
+// SetPlateRelativeFrame measures positions and sizes from the detected license plate
+// rather than the vehicle bounds, whenever both images have a detected plate. The
+// plate is fixed to the vehicle, so differently cropped or zoomed captures of one
+// vehicle agree in this frame even when their vehicle bounds do not. Pairs without a
+// plate in both images are compared in the vehicle frame as before, as reported by
+// ProcessingInfo.PlateRelativeFrame.
+func (ce *ComparisonEngine) SetPlateRelativeFrame(enabled bool) {
+	ce.plateRelative = enabled
+}
+
+// SetLayoutOnly restricts comparison to structural layout: geometric proportions,
+// structural element positions and light count/symmetry/spacing. Color, texture,
+// light appearance and IR material signatures are ignored.
+func (ce *ComparisonEngine) SetLayoutOnly(enabled bool) {
+	ce.layoutOnly = enabled
+}
+
+// SetMountingOnly restricts comparison to the geometry of the plate mounting points
+// (screws, brackets) relative to the plate, which survives a plate swap. It takes
+// precedence over layout-only mode.
+func (ce *ComparisonEngine) SetMountingOnly(enabled bool) {
+	ce.mountingOnly = enabled
+}
+
+// SetIRSignatureOnly bases the decision entirely on the IR signature around the
+// plate, for plate-swap forensics on ANPR crops where whole-vehicle geometry is
+// unreliable. It takes precedence over the mounting-only and layout-only modes.
+func (ce *ComparisonEngine) SetIRSignatureOnly(enabled bool) {
+	ce.irSignatureOnly = enabled
+}
+
+// SetPartialCrops compares images that may show only part of the vehicle, such as
+// a rear-left corner against a full rear. The images are registered on shared
+// structural elements and the plate, and only features in the region both show are
+// compared. Crops are assumed to share a pixel scale. Pairs that cannot be
+// registered are compared as full views.
+func (ce *ComparisonEngine) SetPartialCrops(enabled bool) {
+	ce.partialCrops = enabled
+}
+
+// SetAlignmentHypotheses makes full-view comparisons robust to a wrong vehicle
+// anchor, such as a misplaced vehicle detection on a sparse image. Several
+// translations of image 2 onto image 1 are hypothesized from single same-kind
+// anchor pairs, the full comparison is scored under each (and under the plain
+// vehicle-frame alignment) and the best-scoring one is kept. Results report how many
+// anchors it aligns as ProcessingInfo.AlignmentQuality. Costs up to
+// maxAlignmentHypotheses full comparisons.
+func (ce *ComparisonEngine) SetAlignmentHypotheses(enabled bool) {
+	ce.alignmentSearch = enabled
+}
+
+// SetSimilarityInterval adds a bootstrap confidence interval around the similarity
+// score to results (ProcessingInfo.SimilarityInterval). It costs bootstrapSamples
+// extra comparisons.
+func (ce *ComparisonEngine) SetSimilarityInterval(enabled bool) {
+	ce.intervals = enabled
+}
+
+// SetSubScores adds the unweighted components behind each feature score to results
+func (ce *ComparisonEngine) SetSubScores(enabled bool) {
+	ce.subScores = enabled
+}
+
+// SetKeypointMatching scores geometric similarity by keypoint descriptor matching
+// instead of structural element and reference point positions, whenever both
+// feature sets carry keypoints
+func (ce *ComparisonEngine) SetKeypointMatching(enabled bool) {
+	ce.keypointMatching = enabled
+}
+
+// SetLightArrangement adds the arrangement of the light elements to the light
+// pattern score: the relative distances and angles between all elements, compared
+// independently of position, rotation and scale. Vehicles whose lights look alike
+// one by one but are laid out differently score lower. Pairs with fewer than three
+// lights in either image have no arrangement and are scored as before.
+func (ce *ComparisonEngine) SetLightArrangement(enabled bool) {
+	ce.lightArrangement = enabled
+}
+
+// CompareFeatures compares two feature sets. It is a pure function of its inputs
+// and the engine configuration: neither feature set, nor anything they point to, is
+// modified, no state is kept between calls, and equal inputs always give equal
+// results, so an engine can be shared between goroutines and calls repeated. Sets
+// extracted at different CanonicalWidths are compared after rescaling features2 to
+// the width of features1 (a rescaled copy; features2 itself is untouched). It is the
+// same comparison as CompareVehicles.
+func (ce *ComparisonEngine) CompareFeatures(features1, features2 models.VehicleFeatures) (*models.ComparisonResult, error) {
+	return ce.CompareVehicles(features1, features2)
+}
+
+// CompareVehicles performs comprehensive vehicle comparison (see CompareFeatures
+// for the purity guarantee)
 func (ce *ComparisonEngine) CompareVehicles(features1, features2 models.VehicleFeatures) (*models.ComparisonResult, error) {
+	features1, features2 = toCommonScale(features1, features2)
+	
 	// Validate that views and lighting are consistent
 	if features1.View != features2.View {
 		return nil, fmt.Errorf("cannot compare different vehicle views")
 	}
+	if !sharedEndView(features1, features2) {
+		return nil, fmt.Errorf("cannot compare views with no shared end view")
+	}
 	
 	if features1.Lighting != features2.Lighting {
 		return nil, fmt.Errorf("cannot compare different lighting conditions")
 	}
 	
-	// Calculate individual similarity scores
-	detailedScores := models.DetailedScores{
-		GeometricSimilarity:    ce.compareGeometricFeatures(features1.GeometricFeatures, features2.GeometricFeatures),
-		LightPatternSimilarity: ce.compareLightPatterns(features1.LightPatterns, features2.LightPatterns),
-		BumperSimilarity:       ce.compareBumperFeatures(features1.BumperFeatures, features2.BumperFeatures),
+	// Every term would fall back to its neutral default, fabricating a midpoint score.
+	// The IR-signature-only mode extracts none of these features by design.
+	if !ce.irSignatureOnly && isFeatureless(features1) && isFeatureless(features2) {
+		result := ce.undetermined(features1)
+		result.ProcessingInfo.FeaturePath = ce.FeaturePath(features1.Lighting)
+		result.ProcessingInfo.ConfigFingerprint = ce.Config().Fingerprint()
+		return result, nil
 	}
 	
-	// Add lighting-specific comparisons
-	if features1.DaylightFeatures != nil && features2.DaylightFeatures != nil {
-		detailedScores.ColorSimilarity = ce.compareDaylightFeatures(*features1.DaylightFeatures, *features2.DaylightFeatures)
+	// Positions and sizes are compared relative to each vehicle's own frame
+	frame1, frame2, plateRelative := ce.comparisonFrames(features1, features2)
+	
+	partialOverlap := partialViewOverlap(features1, features2)
+	
+	var registration cropRegistration
+	registered := false
+	alignmentQuality := 0.0
+	if ce.partialCrops && !ce.mountingOnly && !ce.irSignatureOnly {
+		registration, registered = registerCrops(features1, features2)
 	}
 	
-	if features1.InfraredFeatures != nil && features2.InfraredFeatures != nil {
-		detailedScores.ThermalSimilarity = ce.compareInfraredFeatures(*features1.InfraredFeatures, *features2.InfraredFeatures)
+	// Calculate individual similarity scores
+	var detailedScores models.DetailedScores
+	if ce.irSignatureOnly {
+		// Reported as the thermal score, the feature set the signature belongs to.
+		// Neutral when either signature is missing or unreliable; collectWarnings
+		// reports which.
+		signature, ok := ce.compareReliableIRSignatures(features1, features2, frame1, frame2)
+		if !ok {
+			signature = 0.5
+		}
+		detailedScores.ThermalSimilarity = signature
+	} else if ce.mountingOnly {
+		// Reported as the bumper score, the feature set mounting points belong to.
+		// Neutral when either layout is missing; collectWarnings reports which.
+		mounting, ok := ce.compareMountingGeometry(features1.BumperFeatures, features2.BumperFeatures)
+		if !ok {
+			mounting = 0.5
+		}
+		detailedScores.BumperSimilarity = mounting
+	} else if registered {
+		detailedScores = ce.comparePartialCrops(features1, features2, registration)
+	} else {
+		if ce.alignmentSearch {
+			// Image 2's frame is re-anchored for every later comparison, sub-scores included
+			frame2, alignmentQuality = ce.bestAlignment(features1, features2, frame1, frame2, partialOverlap)
+		}
+		detailedScores = ce.compareViews(features1, features2, frame1, frame2, partialOverlap)
 	}
 	
+	// Sanitize scores so the reported breakdown matches what was weighted
+	detailedScores.GeometricSimilarity = safeFloat64(detailedScores.GeometricSimilarity, 0.5)
+	detailedScores.LightPatternSimilarity = safeFloat64(detailedScores.LightPatternSimilarity, 0.5)
+	detailedScores.BumperSimilarity = safeFloat64(detailedScores.BumperSimilarity, 0.5)
+	detailedScores.ColorSimilarity = safeFloat64(detailedScores.ColorSimilarity, 0.5)
+	detailedScores.ThermalSimilarity = safeFloat64(detailedScores.ThermalSimilarity, 0.5)
+	
 	// Calculate weighted overall similarity
-	overallSimilarity := ce.calculateWeightedSimilarity(detailedScores, features1.Lighting)
+	weights := ce.EffectiveWeights(features1.Lighting)
+	overallSimilarity := ce.calculateWeightedSimilarity(detailedScores, weights)
 	
 	// Determine if same vehicle
-	isSameVehicle := overallSimilarity > ce.getSimilarityThreshold(features1.Lighting)
+	threshold := ce.getSimilarityThreshold(features1.Lighting)
+	isSameVehicle := overallSimilarity > threshold
 	
 	// Calculate confidence level
 	confidenceLevel := ce.calculateConfidenceLevel(overallSimilarity, features1, features2)
 	
+	decision := ce.decide(overallSimilarity, threshold)
+	availability := ce.featureAvailability(features1, features2)
+	warnings := ce.collectWarnings(features1, features2)
+	
+	// One feature far below its floor vetoes the match, however good the mean
+	if ce.decisionMode == models.DecisionAllMustExceed {
+		if failures := ce.floorFailures(detailedScores, weights, availability); len(failures) > 0 {
+			isSameVehicle = false
+			decision = models.DecisionDifferent
+			warnings = append(warnings, failures...)
+		}
+	}
+	
+	result := &models.ComparisonResult{
+		IsSameVehicle:    isSameVehicle,
+		Decision:         decision,
+		SimilarityScore:  overallSimilarity,
+		ConfidenceLevel:  confidenceLevel,
+		DetailedScores:   detailedScores,
+		EffectiveWeights: weights,
+		Availability:     availability,
+		Warnings:         warnings,
+	}
+	result.ProcessingInfo.AlignmentQuality = alignmentQuality
+	result.ProcessingInfo.PlateRelativeFrame = plateRelative
+	result.ProcessingInfo.FeaturePath = ce.FeaturePath(features1.Lighting)
+	result.ProcessingInfo.DecisionThreshold = threshold
+	result.ProcessingInfo.ThresholdMargin = overallSimilarity - threshold
+	result.ProcessingInfo.ConfigFingerprint = ce.Config().Fingerprint()
+	
+	if ce.intervals {
+		result.ProcessingInfo.SimilarityInterval = ce.bootstrapInterval(features1, features2, overallSimilarity)
+	}
+	
+	if ce.subScores {
+		result.SubScores = ce.computeSubScores(features1, features2, frame1, frame2)
+	}
+	
+	return result, nil
+}
+
+// compareViews scores two whole views of the same end of a vehicle, with positions
+// measured in the given frames
+func (ce *ComparisonEngine) compareViews(features1, features2 models.VehicleFeatures, frame1, frame2 frame, partialOverlap bool) models.DetailedScores {
+	var detailedScores models.DetailedScores
+	if ce.layoutOnly {
+		detailedScores = models.DetailedScores{
+			GeometricSimilarity:    ce.compareGeometricFeatures(features1.GeometricFeatures, features2.GeometricFeatures, frame1, frame2),
+			LightPatternSimilarity: ce.compareLightConfiguration(features1.LightPatterns.LightConfiguration, features2.LightPatterns.LightConfiguration, frame1, frame2),
+		}
+		if partialOverlap {
+			detailedScores.GeometricSimilarity = ce.compareSharedViewGeometry(features1.GeometricFeatures, features2.GeometricFeatures, frame1, frame2)
+		}
+		return detailedScores
+	}
+	
+	detailedScores = models.DetailedScores{
+		GeometricSimilarity:    ce.compareGeometry(features1.GeometricFeatures, features2.GeometricFeatures, frame1, frame2),
+		LightPatternSimilarity: ce.compareLightPatterns(features1.LightPatterns, features2.LightPatterns, frame1, frame2),
+		BumperSimilarity:       ce.compareBumperFeatures(features1.BumperFeatures, features2.BumperFeatures, frame1, frame2),
+	}
+	if partialOverlap {
+		detailedScores.GeometricSimilarity = ce.compareSharedViewGeometry(features1.GeometricFeatures, features2.GeometricFeatures, frame1, frame2)
+	}
+	
+	// Add lighting-specific comparisons
+	if features1.DaylightFeatures != nil && features2.DaylightFeatures != nil {
+		detailedScores.ColorSimilarity = ce.compareDaylightFeatures(*features1.DaylightFeatures, *features2.DaylightFeatures, textureTrust(features1, features2))
+	}
+	
+	if features1.InfraredFeatures != nil && features2.InfraredFeatures != nil {
+		detailedScores.ThermalSimilarity = ce.compareInfraredFeatures(*features1.InfraredFeatures, *features2.InfraredFeatures, frame1, frame2, textureTrust(features1, features2))
+	}
+	
+	return detailedScores
+}
+
+// SubScores returns the unweighted components behind the full-view scores of a
+// pair, whether or not sub-scores are enabled for CompareVehicles
+func (ce *ComparisonEngine) SubScores(features1, features2 models.VehicleFeatures) *models.SubScores {
+	features1, features2 = toCommonScale(features1, features2)
+	frame1, frame2, _ := ce.comparisonFrames(features1, features2)
+	return ce.computeSubScores(features1, features2, frame1, frame2)
+}
+
+// computeSubScores scores each component of the full-view comparison separately
+func (ce *ComparisonEngine) computeSubScores(features1, features2 models.VehicleFeatures, frame1, frame2 frame) *models.SubScores {
+	geo1, geo2 := features1.GeometricFeatures, features2.GeometricFeatures
+	lights1, lights2 := features1.LightPatterns, features2.LightPatterns
+	bumper1, bumper2 := features1.BumperFeatures, features2.BumperFeatures
+	
+	sub := &models.SubScores{
+		Geometry: models.GeometrySubScores{
+			Proportions: ce.compareVehicleProportions(geo1.VehicleProportions, geo2.VehicleProportions),
+			Structural:  ce.compareStructuralElements(geo1.StructuralElements, geo2.StructuralElements, frame1, frame2),
+			Alignment:   ce.compareReferencePoints(geo1.ReferencePoints, geo2.ReferencePoints, frame1, frame2),
+		},
+		Lights: models.LightSubScores{
+			Signature:     ce.compareSignatures(lights1.PatternSignature, lights2.PatternSignature),
+			Elements:      ce.compareLightElements(lights1.LightElements, lights2.LightElements, frame1, frame2),
+			Configuration: ce.compareLightConfiguration(lights1.LightConfiguration, lights2.LightConfiguration, frame1, frame2),
+			Arrangement:   0.5,
+		},
+		Bumper: models.BumperSubScores{
+			Contour:   ce.compareContours(bumper1.ContourSignature, bumper2.ContourSignature, frame1, frame2),
+			Texture:   ce.compareSignatures(bumper1.TextureFeatures, bumper2.TextureFeatures),
+			Mounting:  ce.compareReferencePoints(bumper1.MountingPoints, bumper2.MountingPoints, frame1, frame2),
+			PlateArea: ce.plateAreaSimilarity(bumper1.LicensePlateArea, bumper2.LicensePlateArea, frame1, frame2),
+		},
+	}
+	
+	if arrangement, ok := ce.compareLightArrangements(lights1.LightElements, lights2.LightElements); ok {
+		sub.Lights.Arrangement = arrangement
+	}
+	if features1.DaylightFeatures != nil && features2.DaylightFeatures != nil {
+		sub.Color = ce.compareDaylightFeatures(*features1.DaylightFeatures, *features2.DaylightFeatures, textureTrust(features1, features2))
+	}
+	if features1.InfraredFeatures != nil && features2.InfraredFeatures != nil {
+		sub.Thermal = ce.compareInfraredFeatures(*features1.InfraredFeatures, *features2.InfraredFeatures, frame1, frame2, textureTrust(features1, features2))
+	}
+	
+	return sub
+}
+
+// EarlyMismatch scores only the cheap terms available before light, bumper and IR
+// extraction: geometry, plus color when both feature sets carry daylight features.
+// If the similarity could not reach the threshold even with every remaining term a
+// perfect match, it returns a non-match result whose score is that upper bound.
+func (ce *ComparisonEngine) EarlyMismatch(features1, features2 models.VehicleFeatures) (*models.ComparisonResult, bool) {
+	if features1.View != features2.View || features1.Lighting != features2.Lighting || !sharedEndView(features1, features2) {
+		return nil, false
+	}
+	features1, features2 = toCommonScale(features1, features2)
+	
+	// Full-view geometry cannot bound the score of a partial crop, and plays no part
+	// in an IR-signature-only comparison
+	if ce.partialCrops || ce.irSignatureOnly {
+		return nil, false
+	}
+	
+	// Nor can geometry in the vehicle frame bound a comparison the plate-relative
+	// frame may take over once the plates are located
+	if ce.plateRelative {
+		return nil, false
+	}
+	
+	frame1, frame2 := frameOf(features1), frameOf(features2)
+	weights := ce.EffectiveWeights(features1.Lighting)
+	
+	var detailedScores models.DetailedScores
+	if partialViewOverlap(features1, features2) {
+		detailedScores.GeometricSimilarity = ce.compareSharedViewGeometry(features1.GeometricFeatures, features2.GeometricFeatures, frame1, frame2)
+	} else if ce.layoutOnly {
+		detailedScores.GeometricSimilarity = ce.compareGeometricFeatures(features1.GeometricFeatures, features2.GeometricFeatures, frame1, frame2)
+	} else {
+		detailedScores.GeometricSimilarity = ce.compareGeometry(features1.GeometricFeatures, features2.GeometricFeatures, frame1, frame2)
+	}
+	detailedScores.GeometricSimilarity = safeFloat64(detailedScores.GeometricSimilarity, 0.5)
+	
+	// Every term not yet scored is assumed to be a perfect match. Every aggregation
+	// only rises with each score, so this bounds the final similarity.
+	bound := models.DetailedScores{
+		GeometricSimilarity:    detailedScores.GeometricSimilarity,
+		LightPatternSimilarity: 1.0,
+		BumperSimilarity:       1.0,
+		ColorSimilarity:        1.0,
+		ThermalSimilarity:      1.0,
+	}
+	
+	if !ce.layoutOnly && features1.DaylightFeatures != nil && features2.DaylightFeatures != nil {
+		detailedScores.ColorSimilarity = safeFloat64(ce.compareDaylightFeatures(*features1.DaylightFeatures, *features2.DaylightFeatures, textureTrust(features1, features2)), 0.5)
+		bound.ColorSimilarity = detailedScores.ColorSimilarity
+	}
+	
+	upperBound := ce.calculateWeightedSimilarity(bound, weights)
+	threshold := ce.getSimilarityThreshold(features1.Lighting)
+	
+	// Stay clear of the uncertainty band so an early exit is always a definite decision
+	if upperBound >= threshold-ce.uncertaintyBand/2.0 {
+		return nil, false
+	}
+	
+	// The expensive features were never extracted
+	availability := ce.featureAvailability(features1, features2)
+	availability.LightPattern = skipped("early exit")
+	availability.Bumper = skipped("early exit")
+	availability.Thermal = skipped("early exit")
+	
 	return &models.ComparisonResult{
-		IsSameVehicle:   isSameVehicle,
-		SimilarityScore: overallSimilarity,
-		ConfidenceLevel: confidenceLevel,
-		DetailedScores:  detailedScores,
-	}, nil
+		IsSameVehicle:    false,
+		Decision:         models.DecisionDifferent,
+		SimilarityScore:  upperBound,
+		ConfidenceLevel:  ce.calculateConfidenceLevel(upperBound, features1, features2),
+		DetailedScores:   detailedScores,
+		EffectiveWeights: weights,
+		Availability:     availability,
+		ProcessingInfo: models.ProcessingInfo{
+			FeaturePath:       ce.FeaturePath(features1.Lighting),
+			DecisionThreshold: threshold,
+			ThresholdMargin:   upperBound - threshold,
+			ConfigFingerprint: ce.Config().Fingerprint(),
+		},
+	}, true
 }
 
-func (ce *ComparisonEngine) compareGeometricFeatures(geo1, geo2 models.GeometricFeatures) float64 {
+// featureAvailability records which feature scores CompareVehicles computes for the
+// pair. Features disabled by a comparison mode are reported as skipped even when the
+// images lack them.
+func (ce *ComparisonEngine) featureAvailability(features1, features2 models.VehicleFeatures) models.FeatureAvailability {
+	availability := models.FeatureAvailability{}
+	
+	if len(features1.LightPatterns.LightElements) == 0 || len(features2.LightPatterns.LightElements) == 0 {
+		availability.LightPattern = unavailable("no light elements detected")
+	}
+	if features1.DaylightFeatures == nil || features2.DaylightFeatures == nil {
+		availability.Color = unavailable("no color data in infrared or colorless images")
+	}
+	if features1.InfraredFeatures == nil || features2.InfraredFeatures == nil {
+		availability.Thermal = unavailable("no infrared data in daylight images")
+	}
+	
+	if ce.irSignatureOnly {
+		if _, ok := ce.compareReliableIRSignatures(features1, features2, frameOf(features1), frameOf(features2)); !ok {
+			availability.Thermal = unavailable("no reliable IR signature")
+		}
+	} else if ce.mountingOnly {
+		if _, ok := ce.compareMountingGeometry(features1.BumperFeatures, features2.BumperFeatures); !ok {
+			availability.Bumper = unavailable("no plate mounting points detected")
+		}
+	}
+	ce.MarkModeSkipped(&availability)
+	
+	return availability
+}
+
+// MarkModeSkipped marks the features the comparison mode leaves out as skipped,
+// naming the mode, and leaves the others untouched
+func (ce *ComparisonEngine) MarkModeSkipped(availability *models.FeatureAvailability) {
+	if ce.irSignatureOnly {
+		skipped := skipped("IR-signature-only mode")
+		availability.Geometric = skipped
+		availability.LightPattern = skipped
+		availability.Bumper = skipped
+		availability.Color = skipped
+	} else if ce.mountingOnly {
+		skipped := skipped("mounting-geometry-only mode")
+		availability.Geometric = skipped
+		availability.LightPattern = skipped
+		availability.Color = skipped
+		availability.Thermal = skipped
+	} else if ce.layoutOnly {
+		skipped := skipped("layout-only mode")
+		availability.Bumper = skipped
+		availability.Color = skipped
+		availability.Thermal = skipped
+	}
+}
+
+// isFeatureless reports whether no geometric, light or bumper features were
+// extracted. Proportions are not counted, as they are measured from the image size.
+func isFeatureless(features models.VehicleFeatures) bool {
+	geo := features.GeometricFeatures
+	bumper := features.BumperFeatures
+	return len(geo.StructuralElements) == 0 && len(geo.ReferencePoints) == 0 && len(geo.Keypoints) == 0 && geo.Grille == nil &&
+		len(features.LightPatterns.LightElements) == 0 &&
+		len(bumper.ContourSignature) == 0 && len(bumper.MountingPoints) == 0 && !plateAreaKnown(bumper.LicensePlateArea)
+}
+
+// undetermined is the result for a pair with no features to compare: no score, Low
+// confidence and a warning
+func (ce *ComparisonEngine) undetermined(features models.VehicleFeatures) *models.ComparisonResult {
+	none := unavailable("no geometric, light or bumper features extracted from either image")
+	return &models.ComparisonResult{
+		IsSameVehicle:    false,
+		Decision:         models.DecisionUndetermined,
+		ConfidenceLevel:  models.ConfidenceLow,
+		EffectiveWeights: ce.EffectiveWeights(features.Lighting),
+		Availability: models.FeatureAvailability{
+			Geometric:    none,
+			LightPattern: none,
+			Bumper:       none,
+			Color:        none,
+			Thermal:      none,
+		},
+		Warnings: []string{"no geometric, light or bumper features extracted from either image: similarity cannot be determined"},
+	}
+}
+
+func unavailable(reason string) models.FeatureState {
+	return models.FeatureState{Status: models.FeatureUnavailable, Reason: reason}
+}
+
+func skipped(reason string) models.FeatureState {
+	return models.FeatureState{Status: models.FeatureSkipped, Reason: reason}
+}
+
+// collectWarnings reports conditions that let the comparison complete but make
+// its result less reliable
+func (ce *ComparisonEngine) collectWarnings(features1, features2 models.VehicleFeatures) []string {
+	var warnings []string
+	
+	if ce.partialCrops && !ce.mountingOnly && !ce.irSignatureOnly {
+		if registration, ok := registerCrops(features1, features2); ok {
+			coverage1, coverage2 := registration.coverage(features1, features2)
+			if coverage1 < 0.99 || coverage2 < 0.99 {
+				warnings = append(warnings, fmt.Sprintf("compared over the region both images show (%.0f%% of image 1, %.0f%% of image 2)",
+					coverage1*100, coverage2*100))
+			}
+		} else {
+			warnings = append(warnings, "partial crops could not be registered; compared as full views")
+		}
+	}
+	
+	if partialViewOverlap(features1, features2) {
+		warnings = append(warnings, fmt.Sprintf("views only partly overlap (%v vs %v); geometry compared on the %v view without overall proportions",
+			features1.AllViews(), features2.AllViews(), features1.View))
+	}
+	
+	for i, features := range []models.VehicleFeatures{features1, features2} {
+		image := i + 1
+		
+		// Nothing but the IR signature is extracted
+		if ce.irSignatureOnly {
+			if ir := features.InfraredFeatures; ir == nil || ir.IRSignature == nil {
+				warnings = append(warnings, fmt.Sprintf("no IR signature in image %d", image))
+			} else if !ce.isReliableIRSignature(ir.IRSignature) {
+				warnings = append(warnings, fmt.Sprintf("IR signature in image %d is not anchored on a confident plate detection (%.2f)",
+					image, ir.IRSignature.PlateRegion.Confidence))
+			}
+			continue
+		}
+		
+		if len(features.GeometricFeatures.StructuralElements) == 0 {
+			warnings = append(warnings, fmt.Sprintf("no structural elements detected in image %d", image))
+		}
+		
+		if features.GeometricFeatures.VehicleProportions.Roofless {
+			warnings = append(warnings, fmt.Sprintf("no roofline detected in image %d: upper/lower proportions not compared", image))
+		}
+		
+		if len(features.LightPatterns.LightElements) == 0 {
+			warnings = append(warnings, fmt.Sprintf("no light elements detected in image %d", image))
+		}
+		
+		if ce.mountingOnly {
+			if _, ok := plateRelative(features.BumperFeatures.MountingPoints, features.BumperFeatures.LicensePlateArea); !ok {
+				warnings = append(warnings, fmt.Sprintf("no plate mounting points detected in image %d", image))
+			}
+		}
+		
+		if ir := features.InfraredFeatures; ir != nil && ir.IRSignature != nil && !ce.isReliableIRSignature(ir.IRSignature) {
+			if !ir.IRSignature.PlateRegion.Detected {
+				warnings = append(warnings, fmt.Sprintf("no plate detected in image %d: IR signature ignored in favor of basic thermal comparison", image))
+			} else {
+				warnings = append(warnings, fmt.Sprintf("low plate confidence in image %d (%.2f): IR signature ignored in favor of basic thermal comparison",
+					image, ir.IRSignature.PlateRegion.Confidence))
+			}
+		}
+	}
+	
+	return warnings
+}
+
+// compareGeometry uses keypoint matching when enabled and possible, falling back to
+// the structural comparison
+func (ce *ComparisonEngine) compareGeometry(geo1, geo2 models.GeometricFeatures, frame1, frame2 frame) float64 {
+	if ce.keypointMatching && len(geo1.Keypoints) > 0 && len(geo2.Keypoints) > 0 {
+		return ce.compareKeypoints(geo1.Keypoints, geo2.Keypoints)
+	}
+	
+	return ce.compareGeometricFeatures(geo1, geo2, frame1, frame2)
+}
+
+func (ce *ComparisonEngine) compareGeometricFeatures(geo1, geo2 models.GeometricFeatures, frame1, frame2 frame) float64 {
 	// Compare vehicle proportions
 	proportionSimilarity := ce.compareVehicleProportions(geo1.VehicleProportions, geo2.VehicleProportions)
 	
 	// Compare structural elements
-	structuralSimilarity := ce.compareStructuralElements(geo1.StructuralElements, geo2.StructuralElements)
+	structuralSimilarity := ce.compareStructuralElements(geo1.StructuralElements, geo2.StructuralElements, frame1, frame2)
 	
 	// Compare reference points alignment
-	alignmentSimilarity := ce.compareReferencePoints(geo1.ReferencePoints, geo2.ReferencePoints)
+	alignmentSimilarity := ce.compareReferencePoints(geo1.ReferencePoints, geo2.ReferencePoints, frame1, frame2)
 	
 	// Weighted combination
 	result := (proportionSimilarity*0.4 + structuralSimilarity*0.4 + alignmentSimilarity*0.2)
+	
+	// Grille patterns are only compared when both images have one
+	if geo1.Grille != nil && geo2.Grille != nil {
+		grilleSimilarity := ce.compareGrilles(*geo1.Grille, *geo2.Grille, frame1, frame2)
+		result = result*(1.0-grilleWeight) + grilleSimilarity*grilleWeight
+	}
+	
+	return safeFloat64(result, 0.5)
+}
+
+// compareGrilles compares line spacing (relative to vehicle width) and edge density
+func (ce *ComparisonEngine) compareGrilles(grille1, grille2 models.GrilleFeatures, frame1, frame2 frame) float64 {
+	spacingSim := ratioSimilarity(frame1.length(grille1.LineSpacing), frame2.length(grille2.LineSpacing))
+	densitySim := ratioSimilarity(grille1.EdgeDensity, grille2.EdgeDensity)
+	
+	result := spacingSim*0.6 + densitySim*0.4
 	return safeFloat64(result, 0.5)
 }
 
+// ratioSimilarity is 1 for equal non-negative values, falling towards 0 as they diverge
+func ratioSimilarity(a, b float64) float64 {
+	maxValue := math.Max(a, b)
+	if maxValue <= 0 {
+		return 1.0
+	}
+	return 1.0 - math.Abs(a-b)/maxValue
+}
+
 func (ce *ComparisonEngine) compareVehicleProportions(prop1, prop2 models.VehicleProportions) float64 {
 	// Compare width/height ratio
 	widthHeightSim := 0.5 // Default similarity
@@ -114,6 +734,12 @@ func (ce *ComparisonEngine) compareVehicleProportions(prop1, prop2 models.Vehicl
 	
 	result := (widthHeightSim*0.4 + upperLowerSim*0.4 + licensePlateSim*0.2)
 	
+	// Without a roofline the upper/lower split of an open-top vehicle depends on
+	// whether the top is up, so only the other ratios are compared
+	if prop1.Roofless || prop2.Roofless {
+		result = (widthHeightSim*0.4 + licensePlateSim*0.2) / 0.6
+	}
+	
 	// Ensure result is valid
 	if math.IsNaN(result) || math.IsInf(result, 0) {
 		return 0.5
@@ -121,109 +747,135 @@ func (ce *ComparisonEngine) compareVehicleProportions(prop1, prop2 models.Vehicl
 	return math.Max(0.0, math.Min(1.0, result))
 }
 
-func (ce *ComparisonEngine) compareStructuralElements(elements1, elements2 []models.StructuralElement) float64 {
-	if len(elements1) == 0 && len(elements2) == 0 {
-		return 1.0
-	}
-	
+func (ce *ComparisonEngine) compareStructuralElements(elements1, elements2 []models.StructuralElement, frame1, frame2 frame) float64 {
 	if len(elements1) == 0 || len(elements2) == 0 {
-		return 0.0
+		return missingDataScore
 	}
 	
 	// Find best matching between structural elements
-	totalSimilarity := 0.0
-	matchCount := 0
+	matches := []pointMatch{}
 	
 	for _, e1 := range elements1 {
 		bestSimilarity := 0.0
+		var bestPosition models.Point2D
 		for _, e2 := range elements2 {
 			if e1.Type == e2.Type {
 				// Calculate position similarity
-				dx := e1.Position.X - e2.Position.X
-				dy := e1.Position.Y - e2.Position.Y
-				distance := math.Sqrt(dx*dx + dy*dy)
-				positionSim := math.Exp(-distance / 50.0)
+				distance := fractionalDistance(e1.Position, frame1, e2.Position, frame2)
+				positionSim := math.Exp(-distance / structuralDecay)
 				
 				// Calculate size similarity
+				size1 := frame1.length(e1.Size)
+				size2 := frame2.length(e2.Size)
 				sizeSim := 0.5 // Default
-				if maxSize := math.Max(e1.Size, e2.Size); maxSize > 0 {
-					sizeSim = 1.0 - math.Abs(e1.Size-e2.Size)/maxSize
+				if maxSize := math.Max(size1, size2); maxSize > 0 {
+					sizeSim = 1.0 - math.Abs(size1-size2)/maxSize
 				}
 				
 				// Combine similarities
 				similarity := safeFloat64(positionSim*0.7 + sizeSim*0.3, 0.0)
 				if similarity > bestSimilarity {
 					bestSimilarity = similarity
+					bestPosition = e2.Position
 				}
 			}
 		}
 		
 		if bestSimilarity > 0.3 {
-			totalSimilarity += bestSimilarity
-			matchCount++
+			matches = append(matches, pointMatch{
+				from:  frame1.point(e1.Position),
+				to:    frame2.point(bestPosition),
+				value: bestSimilarity,
+			})
 		}
 	}
 	
-	if matchCount == 0 {
+	// Score on matches consistent with the dominant displacement only
+	matches, inlierShare := rejectOutliers(matches)
+	if len(matches) == 0 {
 		return 0.0
 	}
 	
-	result := totalSimilarity / float64(matchCount)
+	totalSimilarity := 0.0
+	for _, m := range matches {
+		totalSimilarity += m.value
+	}
+	
+	result := totalSimilarity / float64(len(matches)) * inlierShare
 	return safeFloat64(result, 0.5)
 }
 
-func (ce *ComparisonEngine) compareReferencePoints(points1, points2 []models.Point2D) float64 {
+func (ce *ComparisonEngine) compareReferencePoints(points1, points2 []models.Point2D, frame1, frame2 frame) float64 {
 	if len(points1) == 0 || len(points2) == 0 {
-		return 0.5 // Neutral score if no reference points
+		return missingDataScore
 	}
 	
 	// Find best matching between point sets
-	totalDistance := 0.0
-	matchCount := 0
+	matches := []pointMatch{}
 	
 	for _, p1 := range points1 {
 		minDistance := math.Inf(1)
+		var nearest models.Point2D
 		for _, p2 := range points2 {
-			distance := math.Sqrt(math.Pow(p1.X-p2.X, 2) + math.Pow(p1.Y-p2.Y, 2))
+			distance := fractionalDistance(p1, frame1, p2, frame2)
 			if distance < minDistance {
 				minDistance = distance
+				nearest = p2
 			}
 		}
 		
-		if minDistance < 50.0 && !math.IsInf(minDistance, 0) { // Threshold for matching
-			totalDistance += minDistance
-			matchCount++
+		if minDistance < referenceMatchRadius && !math.IsInf(minDistance, 0) { // Threshold for matching
+			matches = append(matches, pointMatch{
+				from:  frame1.point(p1),
+				to:    frame2.point(nearest),
+				value: minDistance,
+			})
 		}
 	}
 	
-	if matchCount == 0 {
+	// Average distance over matches consistent with the dominant displacement only
+	matches, inlierShare := rejectOutliers(matches)
+	if len(matches) == 0 {
 		return 0.0
 	}
 	
-	avgDistance := totalDistance / float64(matchCount)
+	totalDistance := 0.0
+	for _, m := range matches {
+		totalDistance += m.value
+	}
+	
+	avgDistance := totalDistance / float64(len(matches))
 	
 	// Convert distance to similarity (closer = more similar)
-	result := math.Exp(-avgDistance / 20.0)
+	result := math.Exp(-avgDistance / referenceDecay) * inlierShare
 	return safeFloat64(result, 0.5)
 }
 
-func (ce *ComparisonEngine) compareLightPatterns(pattern1, pattern2 models.LightPatternFeatures) float64 {
+func (ce *ComparisonEngine) compareLightPatterns(pattern1, pattern2 models.LightPatternFeatures, frame1, frame2 frame) float64 {
 	// Compare pattern signatures
 	signatureSimilarity := ce.compareSignatures(pattern1.PatternSignature, pattern2.PatternSignature)
 	
 	// Compare individual light elements
-	elementSimilarity := ce.compareLightElements(pattern1.LightElements, pattern2.LightElements)
+	elementSimilarity := ce.compareLightElements(pattern1.LightElements, pattern2.LightElements, frame1, frame2)
 	
 	// Compare light configuration
-	configSimilarity := ce.compareLightConfiguration(pattern1.LightConfiguration, pattern2.LightConfiguration)
+	configSimilarity := ce.compareLightConfiguration(pattern1.LightConfiguration, pattern2.LightConfiguration, frame1, frame2)
 	
 	result := (signatureSimilarity*0.4 + elementSimilarity*0.4 + configSimilarity*0.2)
+	
+	if ce.lightArrangement {
+		if arrangementSimilarity, ok := ce.compareLightArrangements(pattern1.LightElements, pattern2.LightElements); ok {
+			result = result*(1.0-arrangementWeight) + arrangementSimilarity*arrangementWeight
+		}
+	}
 	return safeFloat64(result, 0.5)
 }
 
 func (ce *ComparisonEngine) compareSignatures(sig1, sig2 []float64) float64 {
+	// Zero-pad the shorter signature so signatures generated with different
+	// lengths (e.g. a different max light count) remain comparable
 	if len(sig1) != len(sig2) {
-		return 0.0
+		sig1, sig2 = padToCommonLength(sig1, sig2)
 	}
 	
 	if len(sig1) == 0 {
@@ -254,13 +906,23 @@ func (ce *ComparisonEngine) compareSignatures(sig1, sig2 []float64) float64 {
 	return safeFloat64(result, 0.0)
 }
 
-func (ce *ComparisonEngine) compareLightElements(elements1, elements2 []models.LightElement) float64 {
-	if len(elements1) == 0 && len(elements2) == 0 {
-		return 1.0
+// padToCommonLength returns copies of both signatures zero-padded to the longer length
+func padToCommonLength(sig1, sig2 []float64) ([]float64, []float64) {
+	length := len(sig1)
+	if len(sig2) > length {
+		length = len(sig2)
 	}
 	
+	padded1 := make([]float64, length)
+	padded2 := make([]float64, length)
+	copy(padded1, sig1)
+	copy(padded2, sig2)
+	return padded1, padded2
+}
+
+func (ce *ComparisonEngine) compareLightElements(elements1, elements2 []models.LightElement, frame1, frame2 frame) float64 {
 	if len(elements1) == 0 || len(elements2) == 0 {
-		return 0.0
+		return missingDataScore
 	}
 	
 	// Find best matching between light elements
@@ -270,7 +932,7 @@ func (ce *ComparisonEngine) compareLightElements(elements1, elements2 []models.L
 	for _, e1 := range elements1 {
 		bestSimilarity := 0.0
 		for _, e2 := range elements2 {
-			similarity := ce.compareSingleLightElement(e1, e2)
+			similarity := ce.compareSingleLightElement(e1, e2, frame1, frame2)
 			if similarity > bestSimilarity {
 				bestSimilarity = similarity
 			}
@@ -286,18 +948,26 @@ func (ce *ComparisonEngine) compareLightElements(elements1, elements2 []models.L
 		return 0.0
 	}
 	
-	return totalSimilarity / float64(matchCount)
+	similarity := totalSimilarity / float64(matchCount)
+	
+	// Too few matches to trust the mean
+	if matchCount < ce.minMatchedLights {
+		trust := float64(matchCount) / float64(ce.minMatchedLights)
+		similarity = similarity*trust + 0.5*(1.0-trust)
+	}
+	
+	return similarity
 }
 
-func (ce *ComparisonEngine) compareSingleLightElement(e1, e2 models.LightElement) float64 {
+func (ce *ComparisonEngine) compareSingleLightElement(e1, e2 models.LightElement, frame1, frame2 frame) float64 {
 	// Elements must be same type
 	if e1.Type != e2.Type {
 		return 0.0
 	}
 	
 	// Compare position (normalized)
-	positionDistance := math.Sqrt(math.Pow(e1.Position.X-e2.Position.X, 2) + math.Pow(e1.Position.Y-e2.Position.Y, 2))
-	positionSim := math.Exp(-positionDistance / 30.0)
+	positionDistance := fractionalDistance(e1.Position, frame1, e2.Position, frame2)
+	positionSim := math.Exp(-positionDistance / lightDecay)
 	
 	// Compare shape
 	shapeSim := 0.0
@@ -305,20 +975,26 @@ func (ce *ComparisonEngine) compareSingleLightElement(e1, e2 models.LightElement
 		shapeSim = 1.0
 	}
 	
-	// Compare size
+	// Compare size (light sizes are areas)
+	size1 := frame1.area(e1.Size)
+	size2 := frame2.area(e2.Size)
 	sizeSim := 0.5 // Default
-	if maxSize := math.Max(e1.Size, e2.Size); maxSize > 0 {
-		sizeSim = 1.0 - math.Abs(e1.Size-e2.Size)/maxSize
+	if maxSize := math.Max(size1, size2); maxSize > 0 {
+		sizeSim = 1.0 - math.Abs(size1-size2)/maxSize
 	}
 	
-	// Compare intensity
+	// Compare intensity, which depends on the camera's brightness baseline
 	intensitySim := 1.0 - math.Abs(e1.Intensity-e2.Intensity)
+	intensityWeight := 0.2
+	if ce.crossCamera {
+		intensityWeight = 0.0
+	}
 	
-	result := (positionSim*0.4 + shapeSim*0.2 + sizeSim*0.2 + intensitySim*0.2)
+	result := (positionSim*0.4 + shapeSim*0.2 + sizeSim*0.2 + intensitySim*intensityWeight) / (0.8 + intensityWeight)
 	return safeFloat64(result, 0.0)
 }
 
-func (ce *ComparisonEngine) compareLightConfiguration(config1, config2 models.LightConfiguration) float64 {
+func (ce *ComparisonEngine) compareLightConfiguration(config1, config2 models.LightConfiguration, frame1, frame2 frame) float64 {
 	// Compare number of elements
 	numElementsSim := 0.5 // Default
 	if maxElements := math.Max(float64(config1.NumElements), float64(config2.NumElements)); maxElements > 0 {
@@ -330,9 +1006,11 @@ func (ce *ComparisonEngine) compareLightConfiguration(config1, config2 models.Li
 	
 	// Compare spacing
 	spacingSim := 1.0
-	if config1.Spacing > 0 && config2.Spacing > 0 {
-		if maxSpacing := math.Max(config1.Spacing, config2.Spacing); maxSpacing > 0 {
-			spacingSim = 1.0 - math.Abs(config1.Spacing-config2.Spacing)/maxSpacing
+	spacing1 := frame1.length(config1.Spacing)
+	spacing2 := frame2.length(config2.Spacing)
+	if spacing1 > 0 && spacing2 > 0 {
+		if maxSpacing := math.Max(spacing1, spacing2); maxSpacing > 0 {
+			spacingSim = 1.0 - math.Abs(spacing1-spacing2)/maxSpacing
 		}
 	}
 	
@@ -340,30 +1018,36 @@ func (ce *ComparisonEngine) compareLightConfiguration(config1, config2 models.Li
 	return safeFloat64(result, 0.5)
 }
 
-func (ce *ComparisonEngine) compareBumperFeatures(bumper1, bumper2 models.BumperFeatures) float64 {
+func (ce *ComparisonEngine) compareBumperFeatures(bumper1, bumper2 models.BumperFeatures, frame1, frame2 frame) float64 {
 	// Compare contour signatures
-	contourSimilarity := ce.compareContours(bumper1.ContourSignature, bumper2.ContourSignature)
+	contourSimilarity := ce.compareContours(bumper1.ContourSignature, bumper2.ContourSignature, frame1, frame2)
 	
 	// Compare texture features
 	textureSimilarity := ce.compareSignatures(bumper1.TextureFeatures, bumper2.TextureFeatures)
 	
 	// Compare mounting points
-	mountingSimilarity := ce.compareReferencePoints(bumper1.MountingPoints, bumper2.MountingPoints)
+	mountingSimilarity := ce.compareReferencePoints(bumper1.MountingPoints, bumper2.MountingPoints, frame1, frame2)
 	
 	// Compare license plate area
-	plateAreaSimilarity := ce.comparePlateAreas(bumper1.LicensePlateArea, bumper2.LicensePlateArea)
+	plateAreaSimilarity := ce.plateAreaSimilarity(bumper1.LicensePlateArea, bumper2.LicensePlateArea, frame1, frame2)
 	
 	result := (contourSimilarity*0.3 + textureSimilarity*0.3 + mountingSimilarity*0.2 + plateAreaSimilarity*0.2)
 	return safeFloat64(result, 0.5)
 }
 
-func (ce *ComparisonEngine) compareContours(contour1, contour2 []models.Point2D) float64 {
-	if len(contour1) == 0 && len(contour2) == 0 {
-		return 1.0
+// plateAreaSimilarity compares plate areas, neutral when a plate was found in only
+// one image: that says nothing about whether the plates sit in the same place.
+// Plate-relative comparisons measure the plates in the vehicle frame.
+func (ce *ComparisonEngine) plateAreaSimilarity(area1, area2 models.Bounds, frame1, frame2 frame) float64 {
+	if plateAreaKnown(area1) != plateAreaKnown(area2) {
+		return missingDataScore
 	}
-	
+	return ce.comparePlateAreas(area1, area2, frame1.plateAreaFrame(), frame2.plateAreaFrame())
+}
+
+func (ce *ComparisonEngine) compareContours(contour1, contour2 []models.Point2D, frame1, frame2 frame) float64 {
 	if len(contour1) == 0 || len(contour2) == 0 {
-		return 0.0
+		return missingDataScore
 	}
 	
 	// Simplified contour comparison using point matching
@@ -373,13 +1057,13 @@ func (ce *ComparisonEngine) compareContours(contour1, contour2 []models.Point2D)
 	for _, p1 := range contour1 {
 		minDistance := math.Inf(1)
 		for _, p2 := range contour2 {
-			distance := math.Sqrt(math.Pow(p1.X-p2.X, 2) + math.Pow(p1.Y-p2.Y, 2))
+			distance := fractionalDistance(p1, frame1, p2, frame2)
 			if distance < minDistance {
 				minDistance = distance
 			}
 		}
 		
-		if minDistance < 30.0 {
+		if minDistance < contourMatchRadius {
 			totalDistance += minDistance
 			matchCount++
 		}
@@ -390,23 +1074,26 @@ func (ce *ComparisonEngine) compareContours(contour1, contour2 []models.Point2D)
 	}
 	
 	avgDistance := totalDistance / float64(matchCount)
-	result := math.Exp(-avgDistance / 15.0)
+	result := math.Exp(-avgDistance / contourDecay)
 	return safeFloat64(result, 0.5)
 }
 
-func (ce *ComparisonEngine) comparePlateAreas(area1, area2 models.Bounds) float64 {
+// plateAreaKnown reports whether a bumper carries a detected plate area
+func plateAreaKnown(area models.Bounds) bool {
+	return area.Width > 0 && area.Height > 0
+}
+
+func (ce *ComparisonEngine) comparePlateAreas(area1, area2 models.Bounds, frame1, frame2 frame) float64 {
 	// Compare position
-	centerX1 := float64(area1.X + area1.Width/2)
-	centerY1 := float64(area1.Y + area1.Height/2)
-	centerX2 := float64(area2.X + area2.Width/2)
-	centerY2 := float64(area2.Y + area2.Height/2)
+	center1 := models.Point2D{X: float64(area1.X) + float64(area1.Width)/2, Y: float64(area1.Y) + float64(area1.Height)/2}
+	center2 := models.Point2D{X: float64(area2.X) + float64(area2.Width)/2, Y: float64(area2.Y) + float64(area2.Height)/2}
 	
-	centerDistance := math.Sqrt(math.Pow(centerX1-centerX2, 2) + math.Pow(centerY1-centerY2, 2))
-	positionSim := math.Exp(-centerDistance / 20.0)
+	centerDistance := fractionalDistance(center1, frame1, center2, frame2)
+	positionSim := math.Exp(-centerDistance / plateDecay)
 	
 	// Compare size
-	area1Size := float64(area1.Width * area1.Height)
-	area2Size := float64(area2.Width * area2.Height)
+	area1Size := frame1.area(float64(area1.Width * area1.Height))
+	area2Size := frame2.area(float64(area2.Width * area2.Height))
 	sizeSim := 0.5 // Default
 	if maxArea := math.Max(area1Size, area2Size); maxArea > 0 {
 		sizeSim = 1.0 - math.Abs(area1Size-area2Size)/maxArea
@@ -416,10 +1103,17 @@ func (ce *ComparisonEngine) comparePlateAreas(area1, area2 models.Bounds) float6
 	return safeFloat64(result, 0.5)
 }
 
-func (ce *ComparisonEngine) compareDaylightFeatures(day1, day2 models.DaylightFeatures) float64 {
+// compareDaylightFeatures scores color, badges, trim and surface texture. The
+// texture term's weight is scaled by textureTrust (see textureTrust).
+func (ce *ComparisonEngine) compareDaylightFeatures(day1, day2 models.DaylightFeatures, textureTrust float64) float64 {
 	// Compare color profiles
 	colorSimilarity := ce.compareColorProfiles(day1.ColorProfile, day2.ColorProfile)
 	
+	// Histograms capture secondary paint and trim colors that dominant colors smear
+	if histogramSimilarity, ok := ce.compareColorHistograms(day1.ColorProfile, day2.ColorProfile); ok {
+		colorSimilarity = colorSimilarity*0.5 + histogramSimilarity*0.5
+	}
+	
 	// Compare badge locations
 	badgeSimilarity := ce.compareBadgeFeatures(day1.BadgeLocations, day2.BadgeLocations)
 	
@@ -429,14 +1123,15 @@ func (ce *ComparisonEngine) compareDaylightFeatures(day1, day2 models.DaylightFe
 	// Compare surface texture
 	textureSimilarity := ce.compareTextureSignatures(day1.SurfaceTexture, day2.SurfaceTexture)
 	
-	result := (colorSimilarity*0.4 + badgeSimilarity*0.2 + trimSimilarity*0.2 + textureSimilarity*0.2)
+	textureWeight := 0.2 * textureTrust
+	result := (colorSimilarity*0.4 + badgeSimilarity*0.2 + trimSimilarity*0.2 + textureSimilarity*textureWeight) / (0.8 + textureWeight)
 	return safeFloat64(result, 0.5)
 }
 
-func (ce *ComparisonEngine) compareInfraredFeatures(ir1, ir2 models.InfraredFeatures) float64 {
-	// If both have IR signatures, use them for comparison
-	if ir1.IRSignature != nil && ir2.IRSignature != nil {
-		return ce.compareIRSignatures(*ir1.IRSignature, *ir2.IRSignature)
+func (ce *ComparisonEngine) compareInfraredFeatures(ir1, ir2 models.InfraredFeatures, frame1, frame2 frame, textureTrust float64) float64 {
+	// If both have IR signatures anchored on a confident plate detection, use them for comparison
+	if ce.isReliableIRSignature(ir1.IRSignature) && ce.isReliableIRSignature(ir2.IRSignature) {
+		return ce.compareIRSignatures(*ir1.IRSignature, *ir2.IRSignature, frame1, frame2, textureTrust)
 	}
 	
 	// Fallback to basic thermal comparison
@@ -456,15 +1151,86 @@ func (ce *ComparisonEngine) compareInfraredFeatures(ir1, ir2 models.InfraredFeat
 	return safeFloat64(result, 0.5)
 }
 
-// Placeholder methods for missing feature comparisons
-func (ce *ComparisonEngine) compareColorProfiles(color1, color2 models.ColorProfile) float64 {
-	// Compare dominant colors
-	if len(color1.DominantColors) == 0 && len(color2.DominantColors) == 0 {
+// compareReliableIRSignatures compares the IR signatures of two feature sets. ok is
+// false unless both carry a reliable signature.
+func (ce *ComparisonEngine) compareReliableIRSignatures(features1, features2 models.VehicleFeatures, frame1, frame2 frame) (float64, bool) {
+	ir1, ir2 := features1.InfraredFeatures, features2.InfraredFeatures
+	if ir1 == nil || ir2 == nil || !ce.isReliableIRSignature(ir1.IRSignature) || !ce.isReliableIRSignature(ir2.IRSignature) {
+		return 0, false
+	}
+	return ce.compareIRSignatures(*ir1.IRSignature, *ir2.IRSignature, frame1, frame2, textureTrust(features1, features2)), true
+}
+
+// isReliableIRSignature reports whether a signature exists and was built around a
+// detected plate confident enough for its surroundings to be meaningful
+func (ce *ComparisonEngine) isReliableIRSignature(signature *models.IRSignature) bool {
+	return signature != nil && signature.PlateRegion.Detected && signature.PlateRegion.Confidence >= ce.minPlateConfidence
+}
+
+// compareColorHistograms compares the hue and saturation histograms with the
+// configured metric. ok is false when either profile lacks histograms.
+func (ce *ComparisonEngine) compareColorHistograms(color1, color2 models.ColorProfile) (float64, bool) {
+	if len(color1.HueHistogram) == 0 || len(color2.HueHistogram) == 0 ||
+		len(color1.SaturationHistogram) == 0 || len(color2.SaturationHistogram) == 0 {
+		return 0, false
+	}
+	
+	hueSim := ce.compareHistograms(color1.HueHistogram, color2.HueHistogram)
+	saturationSim := ce.compareHistograms(color1.SaturationHistogram, color2.SaturationHistogram)
+	
+	result := hueSim*0.6 + saturationSim*0.4
+	return safeFloat64(result, 0.5), true
+}
+
+// compareHistograms returns the similarity of two normalized histograms in [0, 1].
+// Two empty (all-zero) histograms, e.g. hue of two gray cars, are identical.
+func (ce *ComparisonEngine) compareHistograms(h1, h2 []float64) float64 {
+	if len(h1) != len(h2) {
+		h1, h2 = padToCommonLength(h1, h2)
+	}
+	
+	sum1, sum2 := 0.0, 0.0
+	for i := range h1 {
+		sum1 += h1[i]
+		sum2 += h2[i]
+	}
+	if sum1 == 0 && sum2 == 0 {
 		return 1.0
 	}
+	if sum1 == 0 || sum2 == 0 {
+		return 0.0
+	}
 	
+	result := 0.0
+	switch ce.histogramMetric {
+	case models.HistogramBhattacharyya:
+		// Bhattacharyya coefficient
+		for i := range h1 {
+			result += math.Sqrt(h1[i] * h2[i])
+		}
+	case models.HistogramChiSquare:
+		// Symmetric chi-square distance lies in [0, 2]
+		distance := 0.0
+		for i := range h1 {
+			if total := h1[i] + h2[i]; total > 0 {
+				distance += (h1[i] - h2[i]) * (h1[i] - h2[i]) / total
+			}
+		}
+		result = 1.0 - distance/2.0
+	default:
+		for i := range h1 {
+			result += math.Min(h1[i], h2[i])
+		}
+	}
+	
+	return safeFloat64(result, 0.5)
+}
+
+// Placeholder methods for missing feature comparisons
+func (ce *ComparisonEngine) compareColorProfiles(color1, color2 models.ColorProfile) float64 {
+	// Compare dominant colors
 	if len(color1.DominantColors) == 0 || len(color2.DominantColors) == 0 {
-		return 0.0
+		return missingDataScore
 	}
 	
 	// Simple color comparison
@@ -474,13 +1240,7 @@ func (ce *ComparisonEngine) compareColorProfiles(color1, color2 models.ColorProf
 	for _, c1 := range color1.DominantColors {
 		bestSimilarity := 0.0
 		for _, c2 := range color2.DominantColors {
-			// Calculate color distance in RGB space
-			rDiff := float64(c1.R) - float64(c2.R)
-			gDiff := float64(c1.G) - float64(c2.G)
-			bDiff := float64(c1.B) - float64(c2.B)
-			colorDistance := math.Sqrt(rDiff*rDiff + gDiff*gDiff + bDiff*bDiff)
-			
-			similarity := 1.0 - colorDistance/441.67 // 441.67 = sqrt(255^2 + 255^2 + 255^2)
+			similarity := colorSimilarity(c1, c2, ce.colorSpace)
 			if similarity > bestSimilarity {
 				bestSimilarity = similarity
 			}
@@ -507,6 +1267,13 @@ func (ce *ComparisonEngine) compareTrimFeatures(trim1, trim2 []models.TrimFeatur
 	return 0.5 // Placeholder implementation
 }
 
+// textureTrust is how far texture terms can be trusted for a pair: 1 for clean
+// images, falling to 0 as the more heavily JPEG-blocked image approaches severe
+// blocking, whose 8x8 block edges pass for texture
+func textureTrust(features1, features2 models.VehicleFeatures) float64 {
+	return 1.0 - math.Max(0.0, math.Min(1.0, math.Max(features1.BlockArtifacts, features2.BlockArtifacts)))
+}
+
 func (ce *ComparisonEngine) compareTextureSignatures(texture1, texture2 models.TextureSignature) float64 {
 	return ce.compareSignatures(texture1.Features, texture2.Features)
 }
@@ -519,24 +1286,27 @@ func (ce *ComparisonEngine) compareHeatPatterns(patterns1, patterns2 []models.He
 	return 0.5 // Placeholder implementation
 }
 
-// compareIRSignatures compares IR signatures around license plates
-func (ce *ComparisonEngine) compareIRSignatures(sig1, sig2 models.IRSignature) float64 {
+// compareIRSignatures compares IR signatures around license plates, scaling the
+// texture term's weight by textureTrust
+func (ce *ComparisonEngine) compareIRSignatures(sig1, sig2 models.IRSignature, frame1, frame2 frame, textureTrust float64) float64 {
 	// Compare different components of the IR signature
 	reflectivitySimilarity := ce.compareReflectivityMaps(sig1.ReflectivityMap, sig2.ReflectivityMap)
 	materialSimilarity := ce.compareSignatures(sig1.MaterialSignature, sig2.MaterialSignature)
 	illuminationSimilarity := ce.compareSignatures(sig1.IlluminationGradient, sig2.IlluminationGradient)
-	shadowSimilarity := ce.compareShadowPatterns(sig1.ShadowPatterns, sig2.ShadowPatterns)
+	shadowSimilarity := ce.compareShadowPatterns(sig1.ShadowPatterns, sig2.ShadowPatterns, frame1, frame2)
 	textureSimilarity := ce.compareSignatures(sig1.TextureFeatures, sig2.TextureFeatures)
 	
 	// Weight the different components
 	// Reflectivity map and material signature are most important for distinguishing vehicles
 	// Shadow patterns and illumination gradients help with 3D structure
 	// Texture provides additional discriminative power
+	// Compression block edges pass for texture, so its weight shrinks with textureTrust
+	textureWeight := 0.10 * textureTrust
 	result := (reflectivitySimilarity*0.35 + 
 			  materialSimilarity*0.30 + 
 			  shadowSimilarity*0.15 + 
 			  illuminationSimilarity*0.10 + 
-			  textureSimilarity*0.10)
+			  textureSimilarity*textureWeight) / (0.90 + textureWeight)
 	
 	return safeFloat64(result, 0.5)
 }
@@ -555,6 +1325,12 @@ func (ce *ComparisonEngine) compareReflectivityMaps(map1, map2 [][]float64) floa
 		return 0.0
 	}
 	
+	// Across cameras only the pattern of reflectivity is comparable, not its level
+	offset1, offset2 := 0.0, 0.0
+	if ce.crossCamera {
+		offset1, offset2 = meanReflectivity(map1, map2), meanReflectivity(map2, map1)
+	}
+	
 	var totalDifference float64
 	var cellCount int
 	
@@ -565,7 +1341,7 @@ func (ce *ComparisonEngine) compareReflectivityMaps(map1, map2 [][]float64) floa
 		
 		for j := 0; j < len(map1[i]); j++ {
 			// Calculate absolute difference between reflectivity values
-			diff := math.Abs(map1[i][j] - map2[i][j])
+			diff := math.Abs((map1[i][j] - offset1) - (map2[i][j] - offset2))
 			totalDifference += diff
 			cellCount++
 		}
@@ -582,14 +1358,29 @@ func (ce *ComparisonEngine) compareReflectivityMaps(map1, map2 [][]float64) floa
 	return safeFloat64(similarity, 0.5)
 }
 
-func (ce *ComparisonEngine) compareShadowPatterns(shadows1, shadows2 []models.Point2D) float64 {
-	if len(shadows1) == 0 && len(shadows2) == 0 {
-		return 1.0
+// meanReflectivity is the mean of m over the rows whose length matches other's, the
+// cells compareReflectivityMaps compares
+func meanReflectivity(m, other [][]float64) float64 {
+	sum, count := 0.0, 0
+	for i := range m {
+		if len(m[i]) != len(other[i]) {
+			continue
+		}
+		for _, value := range m[i] {
+			sum += value
+			count++
+		}
 	}
-	
-	if len(shadows1) == 0 || len(shadows2) == 0 {
+	if count == 0 {
 		return 0.0
 	}
+	return sum / float64(count)
+}
+
+func (ce *ComparisonEngine) compareShadowPatterns(shadows1, shadows2 []models.Point2D, frame1, frame2 frame) float64 {
+	if len(shadows1) == 0 || len(shadows2) == 0 {
+		return missingDataScore
+	}
 	
 	// Find best matching between shadow points
 	totalSimilarity := 0.0
@@ -599,10 +1390,10 @@ func (ce *ComparisonEngine) compareShadowPatterns(shadows1, shadows2 []models.Po
 		bestSimilarity := 0.0
 		for _, s2 := range shadows2 {
 			// Calculate distance between shadow points
-			distance := math.Sqrt(math.Pow(s1.X-s2.X, 2) + math.Pow(s1.Y-s2.Y, 2))
+			distance := fractionalDistance(s1, frame1, s2, frame2)
 			
 			// Convert distance to similarity (closer = more similar)
-			similarity := math.Exp(-distance / 50.0) // 50 pixel tolerance
+			similarity := math.Exp(-distance / shadowDecay)
 			if similarity > bestSimilarity {
 				bestSimilarity = similarity
 			}
@@ -625,39 +1416,96 @@ func (ce *ComparisonEngine) compareShadowPatterns(shadows1, shadows2 []models.Po
 	return safeFloat64(normalizedSimilarity, 0.5)
 }
 
-func (ce *ComparisonEngine) calculateWeightedSimilarity(scores models.DetailedScores, lighting models.LightingType) float64 {
-	// Adjust weights based on lighting conditions
-	var weights struct {
-		geometric, lightPattern, bumper, color, thermal float64
+// FeaturePath returns the lighting-specific path a pair under the given lighting is
+// compared on. Like the weights and thresholds, anything other than daylight takes
+// the infrared path.
+func (ce *ComparisonEngine) FeaturePath(lighting models.LightingType) models.FeaturePath {
+	if lighting == models.LightingDaylight {
+		return models.FeaturePathDaylight
+	}
+	return models.FeaturePathInfrared
+}
+
+// EffectiveWeights returns the per-feature weights used for the given lighting
+func (ce *ComparisonEngine) EffectiveWeights(lighting models.LightingType) models.FeatureWeights {
+	// IR-signature-only and mounting-only comparisons have a single score
+	if ce.irSignatureOnly {
+		return models.FeatureWeights{Thermal: 1.0}
+	}
+	if ce.mountingOnly {
+		return models.FeatureWeights{Bumper: 1.0}
+	}
+	
+	// Layout-only comparison has no appearance scores to weight
+	if ce.layoutOnly {
+		return models.FeatureWeights{
+			Geometric:    0.70,
+			LightPattern: 0.30,
+		}
 	}
 	
+	// Adjust weights based on lighting conditions
 	if lighting == models.LightingDaylight {
-		weights.geometric = 0.30
-		weights.lightPattern = 0.30
-		weights.bumper = 0.20
-		weights.color = 0.20
-		weights.thermal = 0.0
-	} else { // Infrared
-		weights.geometric = 0.35
-		weights.lightPattern = 0.35
-		weights.bumper = 0.20
-		weights.color = 0.0
-		weights.thermal = 0.10
-	}
-	
-	result := (safeFloat64(scores.GeometricSimilarity, 0.5)*weights.geometric +
-			safeFloat64(scores.LightPatternSimilarity, 0.5)*weights.lightPattern +
-			safeFloat64(scores.BumperSimilarity, 0.5)*weights.bumper +
-			safeFloat64(scores.ColorSimilarity, 0.5)*weights.color +
-			safeFloat64(scores.ThermalSimilarity, 0.5)*weights.thermal)
-	return safeFloat64(result, 0.5)
+		return ce.daylightWeights
+	}
+	return ce.infraredWeights
+}
+
+func (ce *ComparisonEngine) calculateWeightedSimilarity(scores models.DetailedScores, weights models.FeatureWeights) float64 {
+	return safeFloat64(aggregate(ce.aggregation, scores, weights), 0.5)
+}
+
+// SimilarityThreshold returns the similarity a pair under the given lighting must
+// exceed to be the same vehicle
+func (ce *ComparisonEngine) SimilarityThreshold(lighting models.LightingType) float64 {
+	return ce.getSimilarityThreshold(lighting)
 }
 
 func (ce *ComparisonEngine) getSimilarityThreshold(lighting models.LightingType) float64 {
 	if lighting == models.LightingDaylight {
-		return 0.75 // Higher threshold for daylight (more features available)
+		return ce.daylightThreshold
+	}
+	return ce.infraredThreshold
+}
+
+// floorFailures describes each computed, weighted feature score that does not exceed
+// its floor
+func (ce *ComparisonEngine) floorFailures(scores models.DetailedScores, weights models.FeatureWeights, availability models.FeatureAvailability) []string {
+	var failures []string
+	for _, feature := range []struct {
+		name   string
+		score  float64
+		weight float64
+		floor  float64
+		state  models.FeatureState
+	}{
+		{"geometric", scores.GeometricSimilarity, weights.Geometric, ce.featureFloors.Geometric, availability.Geometric},
+		{"light pattern", scores.LightPatternSimilarity, weights.LightPattern, ce.featureFloors.LightPattern, availability.LightPattern},
+		{"bumper", scores.BumperSimilarity, weights.Bumper, ce.featureFloors.Bumper, availability.Bumper},
+		{"color", scores.ColorSimilarity, weights.Color, ce.featureFloors.Color, availability.Color},
+		{"thermal", scores.ThermalSimilarity, weights.Thermal, ce.featureFloors.Thermal, availability.Thermal},
+	} {
+		if feature.state.Status != models.FeatureExtracted || feature.weight == 0 {
+			continue
+		}
+		if feature.score <= feature.floor {
+			failures = append(failures, fmt.Sprintf("%s similarity %.2f does not exceed its floor %.2f: not the same vehicle",
+				feature.name, feature.score, feature.floor))
+		}
+	}
+	return failures
+}
+
+// decide returns the three-way decision for a similarity score, reporting scores
+// within half the uncertainty band of the threshold as uncertain
+func (ce *ComparisonEngine) decide(similarity, threshold float64) models.Decision {
+	if math.Abs(similarity-threshold) < ce.uncertaintyBand/2.0 {
+		return models.DecisionUncertain
+	}
+	if similarity > threshold {
+		return models.DecisionSame
 	}
-	return 0.70 // Slightly lower threshold for infrared
+	return models.DecisionDifferent
 }
 
 func (ce *ComparisonEngine) calculateConfidenceLevel(similarity float64, features1, features2 models.VehicleFeatures) models.ConfidenceLevel {
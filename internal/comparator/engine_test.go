@@ -0,0 +1,1204 @@
+package comparator
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+)
+
+// rearFeatures builds a rear-view daylight feature set. offset shifts every
+// position and perturb scales sizes, intensities and signatures so fixtures can
+// represent identical, similar and different vehicles.
+func rearFeatures(offset, perturb float64) models.VehicleFeatures {
+	scale := 1.0 + perturb
+
+	lights := []models.LightElement{
+		{Position: models.Point2D{X: 300 + offset, Y: 350 + offset}, Shape: models.ShapeRectangular, Size: 2400 * scale, Intensity: 0.8 / scale, Type: models.TypeTaillight},
+		{Position: models.Point2D{X: 980 + offset, Y: 350 + offset}, Shape: models.ShapeRectangular, Size: 2400 * scale, Intensity: 0.8 / scale, Type: models.TypeTaillight},
+	}
+
+	return models.VehicleFeatures{
+		View:     models.ViewRear,
+		Lighting: models.LightingDaylight,
+		GeometricFeatures: models.GeometricFeatures{
+			VehicleProportions: models.VehicleProportions{
+				WidthHeightRatio:  1.33 * scale,
+				UpperLowerRatio:   1.2 * scale,
+				LicensePlateRatio: 0.15 * scale,
+			},
+			StructuralElements: []models.StructuralElement{
+				{Type: "taillight", Position: models.Point2D{X: 300 + offset, Y: 350 + offset}, Size: 80 * scale},
+				{Type: "taillight", Position: models.Point2D{X: 980 + offset, Y: 350 + offset}, Size: 80 * scale},
+				{Type: "bumper_line", Position: models.Point2D{X: 640 + offset, Y: 700 + offset}, Size: 1280},
+			},
+			ReferencePoints: []models.Point2D{
+				{X: 300 + offset, Y: 350 + offset},
+				{X: 980 + offset, Y: 350 + offset},
+				{X: 640 + offset, Y: 700 + offset},
+			},
+		},
+		LightPatterns: models.LightPatternFeatures{
+			LightElements:    lights,
+			PatternSignature: []float64{2400.8 * scale, 2400.8 / scale, 0.8 * scale, 1.0, 0, 0, 0, 0, 0, 0},
+			LightConfiguration: models.LightConfiguration{
+				NumElements: 2,
+				Symmetry:    1.0 / scale,
+				Spacing:     680 * scale,
+			},
+		},
+		BumperFeatures: models.BumperFeatures{
+			ContourSignature: []models.Point2D{{X: 300 + offset, Y: 700 + offset}, {X: 980 + offset, Y: 700 + offset}},
+			TextureFeatures:  []float64{0.5 * scale, 0.3, 0.7 / scale},
+			MountingPoints:   []models.Point2D{{X: 560 + offset, Y: 610 + offset}, {X: 720 + offset, Y: 610 + offset}},
+			LicensePlateArea: models.Bounds{X: int(560 + offset), Y: int(600 + offset), Width: int(160 * scale), Height: 50},
+		},
+		DaylightFeatures: &models.DaylightFeatures{
+			ColorProfile: models.ColorProfile{
+				DominantColors: []models.Color{
+					{R: uint8(120 * scale), G: 30, B: 30, Weight: 0.6},
+					{R: 40, G: 40, B: uint8(40 * scale), Weight: 0.4},
+				},
+			},
+			SurfaceTexture: models.TextureSignature{Features: []float64{0.4 * scale, 0.6, 0.2 / scale}, Type: "basic"},
+		},
+		ExtractionQuality: 0.8,
+	}
+}
+
+func TestCompareVehiclesFixtures(t *testing.T) {
+	ce := NewComparisonEngine()
+	reference := rearFeatures(0, 0)
+
+	tests := []struct {
+		name          string
+		candidate     models.VehicleFeatures
+		minSimilarity float64
+		maxSimilarity float64
+		sameVehicle   bool
+	}{
+		{"identical", rearFeatures(0, 0), 0.9, 1.0, true},
+		{"similar", rearFeatures(3, 0.05), 0.75, 0.99, true},
+		{"different", rearFeatures(60, 1.5), 0.0, 0.6, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ce.CompareVehicles(reference, tt.candidate)
+			if err != nil {
+				t.Fatalf("CompareVehicles failed: %v", err)
+			}
+
+			if result.SimilarityScore < tt.minSimilarity || result.SimilarityScore > tt.maxSimilarity {
+				t.Errorf("Similarity %.3f outside expected range [%.2f, %.2f]", result.SimilarityScore, tt.minSimilarity, tt.maxSimilarity)
+			}
+
+			if result.IsSameVehicle != tt.sameVehicle {
+				t.Errorf("Expected IsSameVehicle=%v, got %v (similarity %.3f)", tt.sameVehicle, result.IsSameVehicle, result.SimilarityScore)
+			}
+		})
+	}
+}
+
+func TestCompareVehiclesRejectsMismatchedConditions(t *testing.T) {
+	ce := NewComparisonEngine()
+	reference := rearFeatures(0, 0)
+
+	frontView := rearFeatures(0, 0)
+	frontView.View = models.ViewFront
+	if _, err := ce.CompareVehicles(reference, frontView); err == nil {
+		t.Error("Expected error comparing different views")
+	}
+
+	infrared := rearFeatures(0, 0)
+	infrared.Lighting = models.LightingInfrared
+	if _, err := ce.CompareVehicles(reference, infrared); err == nil {
+		t.Error("Expected error comparing different lighting")
+	}
+}
+
+// assertNonIncreasing checks that a similarity function never improves as the
+// perturbation applied to the second input grows
+func assertNonIncreasing(t *testing.T, name string, similarity func(perturb float64) float64) {
+	t.Helper()
+
+	previous := similarity(0)
+	if previous < 0.99 {
+		t.Errorf("%s: expected ~1.0 for identical input, got %.3f", name, previous)
+	}
+
+	for _, perturb := range []float64{0.05, 0.1, 0.25, 0.5, 1.0, 2.0} {
+		current := similarity(perturb)
+		if current < 0 || current > 1 {
+			t.Errorf("%s: similarity %.3f out of range at perturbation %.2f", name, current, perturb)
+		}
+		if current > previous+1e-9 {
+			t.Errorf("%s: similarity increased from %.3f to %.3f at perturbation %.2f", name, previous, current, perturb)
+		}
+		previous = current
+	}
+}
+
+func TestSubComparatorsAreMonotonic(t *testing.T) {
+	ce := NewComparisonEngine()
+	reference := rearFeatures(0, 0)
+
+	assertNonIncreasing(t, "compareSignatures", func(perturb float64) float64 {
+		sig1 := []float64{1, 2, 3, 4}
+		sig2 := []float64{1, 2 + perturb*4, 3, 4 - perturb*2}
+		return ce.compareSignatures(sig1, sig2)
+	})
+
+	assertNonIncreasing(t, "compareStructuralElements", func(perturb float64) float64 {
+		return ce.compareStructuralElements(reference.GeometricFeatures.StructuralElements,
+			rearFeatures(perturb*40, 0).GeometricFeatures.StructuralElements, canonicalFrame, canonicalFrame)
+	})
+
+	assertNonIncreasing(t, "compareReferencePoints", func(perturb float64) float64 {
+		return ce.compareReferencePoints(reference.GeometricFeatures.ReferencePoints,
+			rearFeatures(perturb*20, 0).GeometricFeatures.ReferencePoints, canonicalFrame, canonicalFrame)
+	})
+
+	assertNonIncreasing(t, "compareVehicleProportions", func(perturb float64) float64 {
+		return ce.compareVehicleProportions(reference.GeometricFeatures.VehicleProportions,
+			rearFeatures(0, perturb).GeometricFeatures.VehicleProportions)
+	})
+
+	assertNonIncreasing(t, "compareLightElements", func(perturb float64) float64 {
+		return ce.compareLightElements(reference.LightPatterns.LightElements,
+			rearFeatures(perturb*30, perturb).LightPatterns.LightElements, canonicalFrame, canonicalFrame)
+	})
+
+	assertNonIncreasing(t, "compareLightConfiguration", func(perturb float64) float64 {
+		return ce.compareLightConfiguration(reference.LightPatterns.LightConfiguration,
+			rearFeatures(0, perturb).LightPatterns.LightConfiguration, canonicalFrame, canonicalFrame)
+	})
+
+	assertNonIncreasing(t, "comparePlateAreas", func(perturb float64) float64 {
+		return ce.comparePlateAreas(reference.BumperFeatures.LicensePlateArea,
+			rearFeatures(perturb*20, perturb).BumperFeatures.LicensePlateArea, canonicalFrame, canonicalFrame)
+	})
+
+	assertNonIncreasing(t, "compareReflectivityMaps", func(perturb float64) float64 {
+		map1 := [][]float64{{0.2, 0.4}, {0.6, 0.8}}
+		map2 := [][]float64{{0.2, 0.4 - perturb*0.2}, {0.6, 0.8 - perturb*0.3}}
+		return ce.compareReflectivityMaps(map1, map2)
+	})
+
+	assertNonIncreasing(t, "compareShadowPatterns", func(perturb float64) float64 {
+		shadows1 := []models.Point2D{{X: 100, Y: 100}, {X: 200, Y: 120}}
+		shadows2 := []models.Point2D{{X: 100 + perturb*20, Y: 100}, {X: 200, Y: 120 + perturb*20}}
+		return ce.compareShadowPatterns(shadows1, shadows2, canonicalFrame, canonicalFrame)
+	})
+}
+
+func TestCompareSignaturesDifferingLengths(t *testing.T) {
+	ce := NewComparisonEngine()
+
+	sig10 := []float64{2400.8, 2400.8, 0.8, 1.0, 0, 0, 0, 0, 0, 0}
+	sig12 := append(append([]float64{}, sig10...), 0, 0)
+
+	if similarity := ce.compareSignatures(sig10, sig12); similarity < 0.99 {
+		t.Errorf("Expected zero-padded signatures to match, got %.3f", similarity)
+	}
+
+	// Extra non-zero slots lower the score without zeroing it
+	sig12[10] = 500
+	similarity := ce.compareSignatures(sig10, sig12)
+	if similarity <= 0.5 || similarity >= 0.99 {
+		t.Errorf("Expected a sensible partial similarity, got %.3f", similarity)
+	}
+
+	if reversed := ce.compareSignatures(sig12, sig10); reversed != similarity {
+		t.Errorf("Expected symmetric comparison, got %.3f vs %.3f", similarity, reversed)
+	}
+}
+
+func TestCompareInfraredFeaturesIgnoresLowConfidencePlates(t *testing.T) {
+	ce := NewComparisonEngine()
+
+	// Basic thermal features are identical, while the IR signatures disagree
+	basic := models.InfraredFeatures{
+		ThermalSignature:  []float64{0.2, 0.4, 0.6},
+		MaterialSignature: []float64{0.1, 0.3, 0.5},
+	}
+	signature := func(confidence, reflectivity float64) *models.IRSignature {
+		return &models.IRSignature{
+			PlateRegion:          models.LicensePlateRegion{Detected: true, Confidence: confidence},
+			ReflectivityMap:      [][]float64{{reflectivity, reflectivity}, {reflectivity, reflectivity}},
+			MaterialSignature:    []float64{reflectivity, 1 - reflectivity},
+			IlluminationGradient: []float64{reflectivity, reflectivity, reflectivity, reflectivity},
+			TextureFeatures:      []float64{reflectivity, 0.5},
+		}
+	}
+
+	confident := basic
+	confident.IRSignature = signature(0.9, 0.9)
+	lowConfidence := basic
+	lowConfidence.IRSignature = signature(0.1, 0.1)
+
+	fallback := ce.compareInfraredFeatures(basic, basic, canonicalFrame, canonicalFrame, 1.0)
+	if similarity := ce.compareInfraredFeatures(confident, lowConfidence, canonicalFrame, canonicalFrame, 1.0); similarity != fallback {
+		t.Errorf("Expected low-confidence plate to fall back to basic thermal comparison (%.3f), got %.3f", fallback, similarity)
+	}
+
+	bothConfident := basic
+	bothConfident.IRSignature = signature(0.9, 0.1)
+	if similarity := ce.compareInfraredFeatures(confident, bothConfident, canonicalFrame, canonicalFrame, 1.0); similarity == fallback {
+		t.Errorf("Expected confident signatures to be compared directly, got fallback score %.3f", similarity)
+	}
+}
+
+func TestContributionsSumToSimilarity(t *testing.T) {
+	ce := NewComparisonEngine()
+
+	for _, candidate := range []models.VehicleFeatures{rearFeatures(0, 0), rearFeatures(3, 0.05), rearFeatures(60, 1.5)} {
+		result, err := ce.CompareVehicles(rearFeatures(0, 0), candidate)
+		if err != nil {
+			t.Fatalf("CompareVehicles failed: %v", err)
+		}
+
+		contributions := result.Contributions()
+		if total := contributions.Total(); math.Abs(total-result.SimilarityScore) > 1e-9 {
+			t.Errorf("Contributions sum to %.6f, expected similarity %.6f", total, result.SimilarityScore)
+		}
+
+		if contributions.Geometric <= 0 || contributions.Geometric > result.EffectiveWeights.Geometric {
+			t.Errorf("Geometric contribution %.3f outside (0, %.2f]", contributions.Geometric, result.EffectiveWeights.Geometric)
+		}
+	}
+}
+
+func TestContributionsSumToSimilarityUnderEveryAggregation(t *testing.T) {
+	for _, aggregation := range []models.Aggregation{models.AggregationGeometric, models.AggregationHarmonic, models.AggregationMinBiased} {
+		ce := NewComparisonEngine()
+		ce.SetAggregation(aggregation)
+
+		result, err := ce.CompareVehicles(rearFeatures(0, 0), rearFeatures(60, 1.5))
+		if err != nil {
+			t.Fatalf("CompareVehicles failed: %v", err)
+		}
+		if total := result.Contributions().Total(); math.Abs(total-result.SimilarityScore) > 1e-9 {
+			t.Errorf("%v: contributions sum to %.6f, expected similarity %.6f", aggregation, total, result.SimilarityScore)
+		}
+	}
+}
+
+// halfResolution rescales a 1280x960 feature set as if it had been extracted from
+// the same vehicle at 640x480
+func halfResolution(features models.VehicleFeatures) models.VehicleFeatures {
+	half := func(p models.Point2D) models.Point2D { return models.Point2D{X: p.X / 2, Y: p.Y / 2} }
+	halfPoints := func(points []models.Point2D) []models.Point2D {
+		scaled := make([]models.Point2D, len(points))
+		for i, p := range points {
+			scaled[i] = half(p)
+		}
+		return scaled
+	}
+
+	scaled := features
+	scaled.VehicleBounds = models.Bounds{Width: 640, Height: 480}
+
+	scaled.GeometricFeatures.StructuralElements = make([]models.StructuralElement, len(features.GeometricFeatures.StructuralElements))
+	for i, e := range features.GeometricFeatures.StructuralElements {
+		scaled.GeometricFeatures.StructuralElements[i] = models.StructuralElement{Type: e.Type, Position: half(e.Position), Size: e.Size / 2}
+	}
+	scaled.GeometricFeatures.ReferencePoints = halfPoints(features.GeometricFeatures.ReferencePoints)
+
+	scaled.LightPatterns.LightElements = make([]models.LightElement, len(features.LightPatterns.LightElements))
+	for i, e := range features.LightPatterns.LightElements {
+		e.Position = half(e.Position)
+		e.Size /= 4 // Sizes are areas
+		scaled.LightPatterns.LightElements[i] = e
+	}
+	scaled.LightPatterns.PatternSignature = append([]float64{}, features.LightPatterns.PatternSignature...)
+	for i, e := range scaled.LightPatterns.LightElements {
+		scaled.LightPatterns.PatternSignature[i] = e.Size + e.Intensity + float64(e.Shape)
+	}
+	scaled.LightPatterns.LightConfiguration.Spacing /= 2
+
+	plate := features.BumperFeatures.LicensePlateArea
+	scaled.BumperFeatures.LicensePlateArea = models.Bounds{X: plate.X / 2, Y: plate.Y / 2, Width: plate.Width / 2, Height: plate.Height / 2}
+	scaled.BumperFeatures.ContourSignature = halfPoints(features.BumperFeatures.ContourSignature)
+	scaled.BumperFeatures.MountingPoints = halfPoints(features.BumperFeatures.MountingPoints)
+
+	return scaled
+}
+
+func TestCompareVehiclesAcrossResolutions(t *testing.T) {
+	ce := NewComparisonEngine()
+
+	full := rearFeatures(0, 0)
+	full.VehicleBounds = models.Bounds{Width: 1280, Height: 960}
+	half := halfResolution(full)
+
+	sameResolution, err := ce.CompareVehicles(full, full)
+	if err != nil {
+		t.Fatalf("CompareVehicles failed: %v", err)
+	}
+	crossResolution, err := ce.CompareVehicles(full, half)
+	if err != nil {
+		t.Fatalf("CompareVehicles failed: %v", err)
+	}
+
+	if diff := math.Abs(sameResolution.SimilarityScore - crossResolution.SimilarityScore); diff > 0.01 {
+		t.Errorf("Expected near-identical similarity across resolutions, got %.3f vs %.3f",
+			sameResolution.SimilarityScore, crossResolution.SimilarityScore)
+	}
+
+	if !crossResolution.IsSameVehicle {
+		t.Errorf("Expected 640px features to match 1280px features, got similarity %.3f", crossResolution.SimilarityScore)
+	}
+}
+
+func TestPlateRelativeFrameToleratesCropping(t *testing.T) {
+	// The second capture is cropped 100px tighter on the left and top, so the
+	// vehicle bounds, which end at the image edge, no longer match the vehicle
+	reference := rearFeatures(0, 0)
+	reference.VehicleBounds = models.Bounds{Width: 1280, Height: 960}
+	cropped := rearFeatures(-100, 0)
+	cropped.VehicleBounds = models.Bounds{Width: 1180, Height: 860}
+
+	absolute, err := NewComparisonEngine().CompareVehicles(reference, cropped)
+	if err != nil {
+		t.Fatalf("CompareVehicles failed: %v", err)
+	}
+
+	ce := NewComparisonEngine()
+	ce.SetPlateRelativeFrame(true)
+	plateRelative, err := ce.CompareVehicles(reference, cropped)
+	if err != nil {
+		t.Fatalf("CompareVehicles failed: %v", err)
+	}
+
+	if !plateRelative.ProcessingInfo.PlateRelativeFrame || absolute.ProcessingInfo.PlateRelativeFrame {
+		t.Errorf("Expected only the plate-relative comparison to report the plate frame")
+	}
+	if plateRelative.SimilarityScore < 0.9 {
+		t.Errorf("Expected differently cropped captures to match in the plate frame, got %.3f", plateRelative.SimilarityScore)
+	}
+	if plateRelative.SimilarityScore-absolute.SimilarityScore < 0.1 {
+		t.Errorf("Expected the plate frame to score well above the vehicle frame, got %.3f vs %.3f",
+			plateRelative.SimilarityScore, absolute.SimilarityScore)
+	}
+
+	// Without a plate in both images the vehicle frame is kept
+	cropped.BumperFeatures.LicensePlateArea = models.Bounds{}
+	fallback, err := ce.CompareVehicles(reference, cropped)
+	if err != nil {
+		t.Fatalf("CompareVehicles failed: %v", err)
+	}
+	if fallback.ProcessingInfo.PlateRelativeFrame {
+		t.Errorf("Expected the vehicle frame when image 2 has no plate")
+	}
+}
+
+func TestPlateRelativeFrameStillComparesPlatePlacement(t *testing.T) {
+	ce := NewComparisonEngine()
+	ce.SetPlateRelativeFrame(true)
+
+	// The same vehicle layout with the plate mounted 200px further right
+	reference := rearFeatures(0, 0)
+	reference.VehicleBounds = models.Bounds{Width: 1280, Height: 960}
+	moved := reference
+	moved.BumperFeatures.LicensePlateArea.X += 200
+
+	same := ce.SubScores(reference, reference).Bumper.PlateArea
+	shifted := ce.SubScores(reference, moved).Bumper.PlateArea
+	if same-shifted < 0.3 {
+		t.Errorf("Expected a moved plate to lower the plate-area score in the plate frame, got %.3f vs %.3f", shifted, same)
+	}
+}
+
+// repainted returns the same vehicle layout with a different paint color, light
+// lenses and surface finish
+func repainted(features models.VehicleFeatures) models.VehicleFeatures {
+	features.DaylightFeatures = &models.DaylightFeatures{
+		ColorProfile: models.ColorProfile{
+			DominantColors: []models.Color{
+				{R: 20, G: 40, B: 200, Weight: 0.6},
+				{R: 220, G: 220, B: 230, Weight: 0.4},
+			},
+		},
+		SurfaceTexture: models.TextureSignature{Features: []float64{0.9, 0.1, 0.8}, Type: "basic"},
+	}
+
+	features.LightPatterns.LightElements = append([]models.LightElement{}, features.LightPatterns.LightElements...)
+	for i := range features.LightPatterns.LightElements {
+		features.LightPatterns.LightElements[i].Intensity = 0.2
+		features.LightPatterns.LightElements[i].Shape = models.ShapeRound
+	}
+	features.LightPatterns.PatternSignature = []float64{2401.2, 2401.2, 0.2, 1.0, 0, 0, 0, 0, 0, 0}
+	features.BumperFeatures.TextureFeatures = []float64{0.1, 0.9, 0.2}
+
+	return features
+}
+
+func TestLayoutOnlyIgnoresAppearance(t *testing.T) {
+	reference := rearFeatures(0, 0)
+	candidate := repainted(rearFeatures(0, 0))
+
+	full, err := NewComparisonEngine().CompareVehicles(reference, candidate)
+	if err != nil {
+		t.Fatalf("CompareVehicles failed: %v", err)
+	}
+
+	layoutEngine := NewComparisonEngine()
+	layoutEngine.SetLayoutOnly(true)
+	layout, err := layoutEngine.CompareVehicles(reference, candidate)
+	if err != nil {
+		t.Fatalf("CompareVehicles failed: %v", err)
+	}
+
+	if layout.SimilarityScore < 0.9 || !layout.IsSameVehicle {
+		t.Errorf("Expected same-layout vehicles to score high in layout-only mode, got %.3f", layout.SimilarityScore)
+	}
+	if full.SimilarityScore >= layout.SimilarityScore-0.05 {
+		t.Errorf("Expected full comparison (%.3f) to score lower than layout-only (%.3f)", full.SimilarityScore, layout.SimilarityScore)
+	}
+	if layout.DetailedScores.ColorSimilarity != 0 || layout.EffectiveWeights.Color != 0 {
+		t.Errorf("Expected color to be ignored in layout-only mode, got score %.3f weight %.2f",
+			layout.DetailedScores.ColorSimilarity, layout.EffectiveWeights.Color)
+	}
+}
+
+func TestLowPlateConfidenceEmitsWarning(t *testing.T) {
+	ce := NewComparisonEngine()
+
+	infrared := func(plateConfidence float64) models.VehicleFeatures {
+		features := rearFeatures(0, 0)
+		features.Lighting = models.LightingInfrared
+		features.DaylightFeatures = nil
+		features.InfraredFeatures = &models.InfraredFeatures{
+			ThermalSignature: []float64{0.2, 0.4, 0.6},
+			IRSignature: &models.IRSignature{
+				PlateRegion: models.LicensePlateRegion{Detected: true, Confidence: plateConfidence},
+			},
+		}
+		return features
+	}
+
+	result, err := ce.CompareVehicles(infrared(0.9), infrared(0.1))
+	if err != nil {
+		t.Fatalf("CompareVehicles failed: %v", err)
+	}
+
+	found := false
+	for _, warning := range result.Warnings {
+		if strings.Contains(warning, "low plate confidence") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected a low plate confidence warning, got %v", result.Warnings)
+	}
+
+	confident, err := ce.CompareVehicles(infrared(0.9), infrared(0.9))
+	if err != nil {
+		t.Fatalf("CompareVehicles failed: %v", err)
+	}
+	if len(confident.Warnings) != 0 {
+		t.Errorf("Expected no warnings for well-detected features, got %v", confident.Warnings)
+	}
+}
+
+func TestDecisionUncertaintyBand(t *testing.T) {
+	ce := NewComparisonEngine()
+	ce.SetUncertaintyBand(0.1)
+
+	tests := []struct {
+		similarity float64
+		expected   models.Decision
+	}{
+		{0.76, models.DecisionUncertain},
+		{0.74, models.DecisionUncertain},
+		{0.82, models.DecisionSame},
+		{0.60, models.DecisionDifferent},
+	}
+
+	for _, tt := range tests {
+		if decision := ce.decide(tt.similarity, 0.75); decision != tt.expected {
+			t.Errorf("Similarity %.2f: expected %v, got %v", tt.similarity, tt.expected, decision)
+		}
+	}
+
+	ce.SetUncertaintyBand(0)
+	if decision := ce.decide(0.76, 0.75); decision != models.DecisionSame {
+		t.Errorf("Expected a definite decision without a band, got %v", decision)
+	}
+}
+
+func TestCompareGrillesDistinguishesPatterns(t *testing.T) {
+	ce := NewComparisonEngine()
+
+	mesh := models.GrilleFeatures{Region: models.Bounds{Width: 300, Height: 120}, LineSpacing: 8, EdgeDensity: 0.45}
+	slats := models.GrilleFeatures{Region: models.Bounds{Width: 300, Height: 120}, LineSpacing: 30, EdgeDensity: 0.08}
+
+	same := ce.compareGrilles(mesh, mesh, canonicalFrame, canonicalFrame)
+	different := ce.compareGrilles(mesh, slats, canonicalFrame, canonicalFrame)
+
+	if same < 0.99 {
+		t.Errorf("Expected identical grilles to match, got %.3f", same)
+	}
+	if different > 0.5 {
+		t.Errorf("Expected mesh and slat grilles to differ, got %.3f", different)
+	}
+
+	geometry := func(grille *models.GrilleFeatures) models.GeometricFeatures {
+		return models.GeometricFeatures{
+			VehicleProportions: models.VehicleProportions{WidthHeightRatio: 1.33, UpperLowerRatio: 0.8},
+			Grille:             grille,
+		}
+	}
+
+	withGrilles := ce.compareGeometricFeatures(geometry(&mesh), geometry(&slats), canonicalFrame, canonicalFrame)
+	oneMissing := ce.compareGeometricFeatures(geometry(&mesh), geometry(nil), canonicalFrame, canonicalFrame)
+	if withGrilles >= oneMissing {
+		t.Errorf("Expected a grille mismatch to lower geometric similarity (%.3f vs %.3f without grille)", withGrilles, oneMissing)
+	}
+}
+
+func TestEarlyMismatchOnlyForHopelessPairs(t *testing.T) {
+	ce := NewComparisonEngine()
+
+	vehicle := func(ratio float64, elementType string, x float64, c models.Color) models.VehicleFeatures {
+		return models.VehicleFeatures{
+			View:     models.ViewFront,
+			Lighting: models.LightingDaylight,
+			GeometricFeatures: models.GeometricFeatures{
+				VehicleProportions: models.VehicleProportions{WidthHeightRatio: ratio, UpperLowerRatio: ratio / 2},
+				StructuralElements: []models.StructuralElement{{Type: elementType, Position: models.Point2D{X: x, Y: 400}, Size: 100}},
+				ReferencePoints:    []models.Point2D{{X: x, Y: 400}},
+			},
+			DaylightFeatures: &models.DaylightFeatures{
+				ColorProfile: models.ColorProfile{DominantColors: []models.Color{c}},
+			},
+		}
+	}
+
+	red := vehicle(1.33, "headlight", 300, models.Color{R: 220, G: 20, B: 20, Weight: 1})
+	blue := vehicle(4.0, "grille", 900, models.Color{R: 20, G: 20, B: 220, Weight: 1})
+
+	result, ok := ce.EarlyMismatch(red, blue)
+	if !ok {
+		t.Fatal("Expected an early exit for an obvious mismatch")
+	}
+	if result.IsSameVehicle || result.Decision != models.DecisionDifferent {
+		t.Errorf("Expected a definite non-match, got %+v", result)
+	}
+	if result.DetailedScores.LightPatternSimilarity != 0 || result.DetailedScores.BumperSimilarity != 0 {
+		t.Errorf("Expected the expensive terms to be left unscored, got %+v", result.DetailedScores)
+	}
+
+	// The full comparison can never score above the early-exit upper bound
+	full, err := ce.CompareVehicles(red, blue)
+	if err != nil {
+		t.Fatalf("CompareVehicles failed: %v", err)
+	}
+	if full.SimilarityScore > result.SimilarityScore+1e-9 {
+		t.Errorf("Full score %.3f exceeds early-exit bound %.3f", full.SimilarityScore, result.SimilarityScore)
+	}
+
+	if _, ok := ce.EarlyMismatch(red, red); ok {
+		t.Error("Expected no early exit for identical features")
+	}
+}
+
+func TestColorHistogramsSeparateSecondaryColors(t *testing.T) {
+	// Hue bins: red at 0, blue at 20, green at 10
+	hues := func(secondaryBin int) []float64 {
+		h := make([]float64, 30)
+		h[0] = 0.7
+		h[secondaryBin] = 0.3
+		return h
+	}
+	saturation := make([]float64, 32)
+	saturation[28] = 1.0
+
+	profile := func(secondaryBin int) models.ColorProfile {
+		return models.ColorProfile{
+			DominantColors:      []models.Color{{R: 200, G: 30, B: 30, Weight: 0.7}},
+			HueHistogram:        hues(secondaryBin),
+			SaturationHistogram: saturation,
+		}
+	}
+
+	redBlue := profile(20)
+	redGreen := profile(10)
+
+	for _, metric := range []models.HistogramMetric{models.HistogramIntersection, models.HistogramBhattacharyya, models.HistogramChiSquare} {
+		ce := NewComparisonEngine()
+		ce.SetHistogramMetric(metric)
+
+		if dominant := ce.compareColorProfiles(redBlue, redGreen); dominant < 0.99 {
+			t.Fatalf("Expected identical dominant colors, got %.3f", dominant)
+		}
+
+		same, _ := ce.compareColorHistograms(redBlue, redBlue)
+		different, ok := ce.compareColorHistograms(redBlue, redGreen)
+		if !ok {
+			t.Fatalf("%v: expected histograms to be compared", metric)
+		}
+		if same < 0.99 || different > 0.9 {
+			t.Errorf("%v: expected histograms to separate secondary colors, same %.3f, different %.3f", metric, same, different)
+		}
+
+		sameDay := ce.compareDaylightFeatures(models.DaylightFeatures{ColorProfile: redBlue}, models.DaylightFeatures{ColorProfile: redBlue}, 1.0)
+		differentDay := ce.compareDaylightFeatures(models.DaylightFeatures{ColorProfile: redBlue}, models.DaylightFeatures{ColorProfile: redGreen}, 1.0)
+		if differentDay >= sameDay {
+			t.Errorf("%v: expected daylight similarity to drop, same %.3f, different %.3f", metric, sameDay, differentDay)
+		}
+	}
+}
+
+func TestUndetectedPlateIsNotReliable(t *testing.T) {
+	ce := NewComparisonEngine()
+
+	fallback := &models.IRSignature{PlateRegion: models.LicensePlateRegion{Confidence: 0.8}}
+	if ce.isReliableIRSignature(fallback) {
+		t.Error("Expected a fallback plate region to be unreliable regardless of confidence")
+	}
+
+	features := rearFeatures(0, 0)
+	features.Lighting = models.LightingInfrared
+	features.DaylightFeatures = nil
+	features.InfraredFeatures = &models.InfraredFeatures{IRSignature: fallback}
+
+	warnings := ce.collectWarnings(features, features)
+	if len(warnings) == 0 || !strings.Contains(warnings[0], "no plate detected") {
+		t.Errorf("Expected a no plate detected warning, got %v", warnings)
+	}
+
+	// A plate missing from one image is neutral, unlike a plate found elsewhere
+	detected := models.BumperFeatures{LicensePlateArea: models.Bounds{X: 560, Y: 600, Width: 160, Height: 50}}
+	misplaced := models.BumperFeatures{LicensePlateArea: models.Bounds{X: 100, Y: 300, Width: 80, Height: 25}}
+	undetected := models.BumperFeatures{}
+	oneMissing := ce.compareBumperFeatures(detected, undetected, canonicalFrame, canonicalFrame)
+	mismatched := ce.compareBumperFeatures(detected, misplaced, canonicalFrame, canonicalFrame)
+	if oneMissing <= mismatched {
+		t.Errorf("Expected an undetected plate (%.3f) to score above a misplaced one (%.3f)", oneMissing, mismatched)
+	}
+}
+
+func TestFeatureAvailability(t *testing.T) {
+	ce := NewComparisonEngine()
+
+	daylight := rearFeatures(0, 0)
+	result, err := ce.CompareVehicles(daylight, daylight)
+	if err != nil {
+		t.Fatalf("CompareVehicles failed: %v", err)
+	}
+	if result.Availability.Color.Status != models.FeatureExtracted {
+		t.Errorf("Expected color to be extracted for a daylight pair, got %+v", result.Availability.Color)
+	}
+	if result.Availability.Thermal.Status != models.FeatureUnavailable {
+		t.Errorf("Expected thermal to be unavailable for a daylight pair, got %+v", result.Availability.Thermal)
+	}
+
+	infrared := rearFeatures(0, 0)
+	infrared.Lighting = models.LightingInfrared
+	infrared.DaylightFeatures = nil
+	infrared.InfraredFeatures = &models.InfraredFeatures{}
+
+	result, err = ce.CompareVehicles(infrared, infrared)
+	if err != nil {
+		t.Fatalf("CompareVehicles failed: %v", err)
+	}
+	if result.Availability.Color.Status != models.FeatureUnavailable || result.Availability.Color.Reason == "" {
+		t.Errorf("Expected color to be unavailable with a reason for an IR pair, got %+v", result.Availability.Color)
+	}
+	if result.Availability.Thermal.Status != models.FeatureExtracted {
+		t.Errorf("Expected thermal to be extracted for an IR pair, got %+v", result.Availability.Thermal)
+	}
+
+	ce.SetLayoutOnly(true)
+	result, err = ce.CompareVehicles(infrared, infrared)
+	if err != nil {
+		t.Fatalf("CompareVehicles failed: %v", err)
+	}
+	if result.Availability.Thermal.Status != models.FeatureSkipped {
+		t.Errorf("Expected thermal to be skipped in layout-only mode, got %+v", result.Availability.Thermal)
+	}
+}
+
+func TestFeaturePathFollowsLighting(t *testing.T) {
+	ce := NewComparisonEngine()
+
+	daylight := rearFeatures(0, 0)
+	result, err := ce.CompareVehicles(daylight, daylight)
+	if err != nil {
+		t.Fatalf("CompareVehicles failed: %v", err)
+	}
+	if result.ProcessingInfo.FeaturePath != models.FeaturePathDaylight {
+		t.Errorf("Expected the Daylight path for a daylight pair, got %v", result.ProcessingInfo.FeaturePath)
+	}
+
+	infrared := rearFeatures(0, 0)
+	infrared.Lighting = models.LightingInfrared
+	infrared.DaylightFeatures = nil
+	infrared.InfraredFeatures = &models.InfraredFeatures{}
+
+	result, err = ce.CompareVehicles(infrared, infrared)
+	if err != nil {
+		t.Fatalf("CompareVehicles failed: %v", err)
+	}
+	if result.ProcessingInfo.FeaturePath != models.FeaturePathInfrared {
+		t.Errorf("Expected the Infrared path for an IR pair, got %v", result.ProcessingInfo.FeaturePath)
+	}
+	var unset models.ProcessingInfo
+	if unset.FeaturePath != models.FeaturePathUnknown {
+		t.Errorf("Expected an unrecorded path to read as Unknown, got %v", unset.FeaturePath)
+	}
+}
+
+func TestCompareVehiclesWithPartiallyOverlappingViews(t *testing.T) {
+	ce := NewComparisonEngine()
+
+	front := rearFeatures(0, 0)
+	front.View = models.ViewFront
+
+	// A 3/4 front of the same vehicle: the visible side stretches the overall
+	// proportions while the front structure is unchanged
+	threeQuarter := rearFeatures(0, 0)
+	threeQuarter.View = models.ViewFront
+	threeQuarter.Views = models.ViewSetOf(models.ViewFront, models.ViewSide)
+	threeQuarter.GeometricFeatures.VehicleProportions.WidthHeightRatio = 2.1
+	threeQuarter.GeometricFeatures.VehicleProportions.LicensePlateRatio = 0.1
+
+	result, err := ce.CompareVehicles(front, threeQuarter)
+	if err != nil {
+		t.Fatalf("CompareVehicles failed: %v", err)
+	}
+	if result.DetailedScores.GeometricSimilarity < 0.95 {
+		t.Errorf("Expected the shared front geometry to match, got %.3f", result.DetailedScores.GeometricSimilarity)
+	}
+	if len(result.Warnings) == 0 || !strings.Contains(result.Warnings[0], "partly overlap") {
+		t.Errorf("Expected a partial view overlap warning, got %v", result.Warnings)
+	}
+
+	// Labelled as a straight front, the stretched proportions count against it
+	threeQuarter.Views = 0
+	mislabelled, err := ce.CompareVehicles(front, threeQuarter)
+	if err != nil {
+		t.Fatalf("CompareVehicles failed: %v", err)
+	}
+	if mislabelled.DetailedScores.GeometricSimilarity >= result.DetailedScores.GeometricSimilarity {
+		t.Errorf("Expected the full geometric comparison (%.3f) to score below the shared-view one (%.3f)",
+			mislabelled.DetailedScores.GeometricSimilarity, result.DetailedScores.GeometricSimilarity)
+	}
+
+	// Both labelled front, but the views of the second show the rear and side: the
+	// pair shares no end view
+	front.Views = models.ViewSetOf(models.ViewFront)
+	rearQuarter := rearFeatures(0, 0)
+	rearQuarter.View = models.ViewFront
+	rearQuarter.Views = models.ViewSetOf(models.ViewRear, models.ViewSide)
+	if _, err := ce.CompareVehicles(front, rearQuarter); err == nil || !strings.Contains(err.Error(), "no shared end view") {
+		t.Errorf("Expected error comparing views with no shared end view, got %v", err)
+	}
+}
+
+func TestSubScoresPopulatedAndBounded(t *testing.T) {
+	ce := NewComparisonEngine()
+
+	result, err := ce.CompareVehicles(rearFeatures(0, 0), rearFeatures(10, 1))
+	if err != nil {
+		t.Fatalf("CompareVehicles failed: %v", err)
+	}
+	if result.SubScores != nil {
+		t.Fatalf("Expected no sub-scores unless requested, got %+v", result.SubScores)
+	}
+
+	ce.SetSubScores(true)
+	result, err = ce.CompareVehicles(rearFeatures(0, 0), rearFeatures(10, 1))
+	if err != nil {
+		t.Fatalf("CompareVehicles failed: %v", err)
+	}
+	sub := result.SubScores
+	if sub == nil {
+		t.Fatal("Expected sub-scores when requested")
+	}
+
+	scores := map[string]float64{
+		"geometry.proportions": sub.Geometry.Proportions,
+		"geometry.structural":  sub.Geometry.Structural,
+		"geometry.alignment":   sub.Geometry.Alignment,
+		"lights.signature":     sub.Lights.Signature,
+		"lights.elements":      sub.Lights.Elements,
+		"lights.configuration": sub.Lights.Configuration,
+		"bumper.contour":       sub.Bumper.Contour,
+		"bumper.texture":       sub.Bumper.Texture,
+		"bumper.mounting":      sub.Bumper.Mounting,
+		"bumper.plate_area":    sub.Bumper.PlateArea,
+		"color":                sub.Color,
+	}
+	for name, score := range scores {
+		if score <= 0 || score > 1 {
+			t.Errorf("Expected %s in (0, 1], got %.3f", name, score)
+		}
+	}
+	if sub.Thermal != 0 {
+		t.Errorf("Expected no thermal sub-score for a daylight pair, got %.3f", sub.Thermal)
+	}
+}
+
+func TestIRSignatureOnlyFollowsSignature(t *testing.T) {
+	ce := NewComparisonEngine()
+	ce.SetIRSignatureOnly(true)
+
+	// Tight plate crops: no vehicle geometry, only the signature around the plate
+	plateCrop := func(reflectivity float64) models.VehicleFeatures {
+		return models.VehicleFeatures{
+			View:          models.ViewRear,
+			Lighting:      models.LightingInfrared,
+			VehicleBounds: models.Bounds{Width: 320, Height: 120},
+			InfraredFeatures: &models.InfraredFeatures{
+				IRSignature: &models.IRSignature{
+					PlateRegion:          models.LicensePlateRegion{Detected: true, Confidence: 0.9},
+					ReflectivityMap:      [][]float64{{reflectivity, 0.5}, {0.5, reflectivity}},
+					MaterialSignature:    []float64{reflectivity, 1 - reflectivity},
+					IlluminationGradient: []float64{reflectivity, 0.5, reflectivity},
+					TextureFeatures:      []float64{reflectivity, 0.5},
+				},
+			},
+		}
+	}
+
+	for _, tc := range []struct {
+		name         string
+		reflectivity float64
+		expectedSame bool
+	}{
+		{"same signature", 0.8, true},
+		{"different signature", 0.1, false},
+	} {
+		features1, features2 := plateCrop(0.8), plateCrop(tc.reflectivity)
+
+		// Geometry that would otherwise dominate the decision the other way
+		if tc.expectedSame {
+			features2.GeometricFeatures = rearFeatures(0, 3).GeometricFeatures
+		} else {
+			features1.GeometricFeatures = rearFeatures(0, 0).GeometricFeatures
+			features2.GeometricFeatures = rearFeatures(0, 0).GeometricFeatures
+		}
+
+		result, err := ce.CompareVehicles(features1, features2)
+		if err != nil {
+			t.Fatalf("%s: CompareVehicles failed: %v", tc.name, err)
+		}
+
+		signature := ce.compareIRSignatures(*features1.InfraredFeatures.IRSignature, *features2.InfraredFeatures.IRSignature,
+			frameOf(features1), frameOf(features2), 1.0)
+		if math.Abs(result.SimilarityScore-signature) > 1e-9 {
+			t.Errorf("%s: expected similarity to be the IR signature similarity %.3f, got %.3f", tc.name, signature, result.SimilarityScore)
+		}
+		if result.IsSameVehicle != tc.expectedSame {
+			t.Errorf("%s: expected same vehicle %v, got %v (similarity %.3f)", tc.name, tc.expectedSame, result.IsSameVehicle, result.SimilarityScore)
+		}
+		if result.Availability.Geometric.Status != models.FeatureSkipped {
+			t.Errorf("%s: expected geometry to be skipped, got %+v", tc.name, result.Availability.Geometric)
+		}
+	}
+}
+
+func TestCrossCameraIgnoresBrightnessOffset(t *testing.T) {
+	// The second camera's sensor and illuminator read every surface brighter
+	const offset = 0.25
+	capture := func(brightness float64) models.VehicleFeatures {
+		features := rearFeatures(0, 0)
+		features.Lighting = models.LightingInfrared
+		features.DaylightFeatures = nil
+		for i := range features.LightPatterns.LightElements {
+			features.LightPatterns.LightElements[i].Intensity = 0.6 + brightness
+		}
+		features.InfraredFeatures = &models.InfraredFeatures{
+			IRSignature: &models.IRSignature{
+				PlateRegion:          models.LicensePlateRegion{Detected: true, Confidence: 0.9},
+				ReflectivityMap:      [][]float64{{0.2 + brightness, 0.6 + brightness}, {0.5 + brightness, 0.1 + brightness}},
+				MaterialSignature:    []float64{0.7, 0.3},
+				IlluminationGradient: []float64{0.4, 0.5, 0.4},
+				TextureFeatures:      []float64{0.6, 0.5},
+			},
+		}
+		return features
+	}
+
+	sameCamera := NewComparisonEngine()
+	crossCamera := NewComparisonEngine()
+	crossCamera.SetCrossCamera(true)
+
+	reference, err := crossCamera.CompareVehicles(capture(0), capture(0))
+	if err != nil {
+		t.Fatalf("CompareVehicles failed: %v", err)
+	}
+	same, err := sameCamera.CompareVehicles(capture(0), capture(offset))
+	if err != nil {
+		t.Fatalf("CompareVehicles failed: %v", err)
+	}
+	cross, err := crossCamera.CompareVehicles(capture(0), capture(offset))
+	if err != nil {
+		t.Fatalf("CompareVehicles failed: %v", err)
+	}
+
+	if cross.SimilarityScore <= same.SimilarityScore {
+		t.Errorf("Expected cross-camera mode to score the offset pair above %.3f, got %.3f", same.SimilarityScore, cross.SimilarityScore)
+	}
+	if math.Abs(cross.SimilarityScore-reference.SimilarityScore) > 1e-9 {
+		t.Errorf("Expected cross-camera mode to ignore the offset entirely, got %.4f against %.4f without it",
+			cross.SimilarityScore, reference.SimilarityScore)
+	}
+}
+
+func TestAllMustExceedVetoesOneBadFeature(t *testing.T) {
+	// Same body, color and bumper, but lights of another shape in another place
+	features1, features2 := rearFeatures(0, 0), rearFeatures(0, 0)
+	for i := range features2.LightPatterns.LightElements {
+		features2.LightPatterns.LightElements[i].Position.Y += 400
+		features2.LightPatterns.LightElements[i].Shape = models.ShapeRound
+	}
+	features2.LightPatterns.PatternSignature = []float64{0, 0, 0, 0, 0, 0, 0, 0, 1, 1}
+
+	ce := NewComparisonEngine()
+	result, err := ce.CompareVehicles(features1, features2)
+	if err != nil {
+		t.Fatalf("CompareVehicles failed: %v", err)
+	}
+	if !result.IsSameVehicle {
+		t.Fatalf("fixture: expected the weighted mean %.3f to pass on its own", result.SimilarityScore)
+	}
+	if result.DetailedScores.LightPatternSimilarity >= defaultFeatureFloors.LightPattern {
+		t.Fatalf("fixture: light pattern similarity %.3f is not below its floor", result.DetailedScores.LightPatternSimilarity)
+	}
+
+	ce.SetDecisionMode(models.DecisionAllMustExceed)
+	result, err = ce.CompareVehicles(features1, features2)
+	if err != nil {
+		t.Fatalf("CompareVehicles failed: %v", err)
+	}
+	if result.IsSameVehicle || result.Decision != models.DecisionDifferent {
+		t.Errorf("Expected AllMustExceed to reject the pair, got same %v, decision %v", result.IsSameVehicle, result.Decision)
+	}
+
+	reported := false
+	for _, warning := range result.Warnings {
+		reported = reported || strings.Contains(warning, "light pattern")
+	}
+	if !reported {
+		t.Errorf("Expected a warning naming the light pattern score, got %v", result.Warnings)
+	}
+
+	// The well-matched pair still passes
+	result, err = ce.CompareVehicles(features1, features1)
+	if err != nil {
+		t.Fatalf("CompareVehicles failed: %v", err)
+	}
+	if !result.IsSameVehicle {
+		t.Errorf("Expected AllMustExceed to accept identical features, got warnings %v", result.Warnings)
+	}
+}
+
+func TestSingleMatchedLightIsTempered(t *testing.T) {
+	ce := NewComparisonEngine()
+
+	// Four taillights expected; the other image has only one, the rest of its lights
+	// filtered out by the extractor
+	light := func(x float64, lightType models.LightType) models.LightElement {
+		return models.LightElement{Position: models.Point2D{X: x, Y: 350}, Shape: models.ShapeRectangular, Size: 2400, Intensity: 0.8, Type: lightType}
+	}
+	expected := []models.LightElement{
+		light(200, models.TypeTaillight),
+		light(400, models.TypeBrakeLight),
+		light(880, models.TypeBrakeLight),
+		light(1080, models.TypeFogLight),
+	}
+	single := []models.LightElement{light(200, models.TypeTaillight)}
+
+	ce.SetMinMatchedLights(1)
+	untempered := ce.compareLightElements(expected, single, canonicalFrame, canonicalFrame)
+	if untempered < 0.99 {
+		t.Fatalf("Expected the lone match to be perfect without a minimum, got %.3f", untempered)
+	}
+
+	ce.SetMinMatchedLights(4)
+	tempered := ce.compareLightElements(expected, single, canonicalFrame, canonicalFrame)
+	if math.Abs(tempered-(0.25*untempered+0.75*0.5)) > 1e-9 {
+		t.Errorf("Expected one of four required matches to be pulled most of the way to neutral, got %.3f", tempered)
+	}
+
+	// Enough matches are trusted as they are
+	if similarity := ce.compareLightElements(expected, expected, canonicalFrame, canonicalFrame); similarity < 0.99 {
+		t.Errorf("Expected four matched lights to be untempered, got %.3f", similarity)
+	}
+}
+
+func TestRooflessVehicleIgnoresUpperLowerRatio(t *testing.T) {
+	ce := NewComparisonEngine()
+
+	// The same convertible with the top up and down: only the upper/lower split changes
+	topUp := models.VehicleProportions{WidthHeightRatio: 1.6, UpperLowerRatio: 0.9, LicensePlateRatio: 0.12}
+	topDown := models.VehicleProportions{WidthHeightRatio: 1.6, UpperLowerRatio: 0.3, LicensePlateRatio: 0.12}
+
+	withRoof := ce.compareVehicleProportions(topUp, topDown)
+	topDown.Roofless = true
+	roofless := ce.compareVehicleProportions(topUp, topDown)
+
+	if roofless <= withRoof {
+		t.Errorf("Expected a roofless image to downweight the upper/lower term (%.3f roofless vs %.3f)", roofless, withRoof)
+	}
+	if roofless < 0.99 {
+		t.Errorf("Expected otherwise identical proportions to match when the upper/lower ratio is skipped, got %.3f", roofless)
+	}
+
+	features1, features2 := rearFeatures(0, 0), rearFeatures(0, 0)
+	features2.GeometricFeatures.VehicleProportions = topDown
+	warned := false
+	for _, warning := range ce.collectWarnings(features1, features2) {
+		if strings.Contains(warning, "no roofline detected in image 2") {
+			warned = true
+		}
+	}
+	if !warned {
+		t.Error("Expected a warning that the upper/lower proportions were not compared")
+	}
+}
+
+func TestFeaturelessPairIsUndetermined(t *testing.T) {
+	ce := NewComparisonEngine()
+
+	// Two blank walls: nothing but the proportions of the image itself
+	blank := models.VehicleFeatures{
+		View:     models.ViewRear,
+		Lighting: models.LightingDaylight,
+		GeometricFeatures: models.GeometricFeatures{
+			VehicleProportions: models.VehicleProportions{WidthHeightRatio: 1.33, UpperLowerRatio: 1.0},
+		},
+	}
+
+	result, err := ce.CompareVehicles(blank, blank)
+	if err != nil {
+		t.Fatalf("Comparison failed: %v", err)
+	}
+
+	if result.Decision != models.DecisionUndetermined {
+		t.Errorf("Expected an undetermined decision, got %v", result.Decision)
+	}
+	if result.IsSameVehicle {
+		t.Error("Expected no match to be claimed for featureless images")
+	}
+	if result.ConfidenceLevel != models.ConfidenceLow {
+		t.Errorf("Expected Low confidence, got %v", result.ConfidenceLevel)
+	}
+	if len(result.Warnings) == 0 {
+		t.Error("Expected a warning explaining the undetermined result")
+	}
+
+	// A pair with features on one side is still compared
+	result, err = ce.CompareVehicles(rearFeatures(0, 0), blank)
+	if err != nil {
+		t.Fatalf("Comparison failed: %v", err)
+	}
+	if result.Decision == models.DecisionUndetermined {
+		t.Error("Expected a pair with features on one side to be compared")
+	}
+}
+
+func TestBlockArtifactsDownweightTexture(t *testing.T) {
+	ce := NewComparisonEngine()
+
+	features1 := rearFeatures(0, 0)
+	features2 := rearFeatures(0, 0)
+	// Identical paint; only the surface texture differs, as block edges make it
+	features2.DaylightFeatures.SurfaceTexture.Features = []float64{0.9, 0.1, 0.8}
+
+	clean, err := ce.CompareVehicles(features1, features2)
+	if err != nil {
+		t.Fatalf("Comparison failed: %v", err)
+	}
+
+	features2.BlockArtifacts = 0.9
+	blocked, err := ce.CompareVehicles(features1, features2)
+	if err != nil {
+		t.Fatalf("Comparison failed: %v", err)
+	}
+
+	if blocked.DetailedScores.ColorSimilarity <= clean.DetailedScores.ColorSimilarity {
+		t.Errorf("Expected the texture mismatch to count less for a blocked image: color %.3f blocked, %.3f clean",
+			blocked.DetailedScores.ColorSimilarity, clean.DetailedScores.ColorSimilarity)
+	}
+
+	// Blocking in either image lowers trust in the pair's texture
+	if trust := textureTrust(features2, features1); trust > 0.1+1e-9 {
+		t.Errorf("Expected texture trust 0.1 with one image at 0.9 blocking, got %.3f", trust)
+	}
+	if trust := textureTrust(features1, features1); trust != 1.0 {
+		t.Errorf("Expected full texture trust for clean images, got %.3f", trust)
+	}
+}
+
+func TestMissingDataIsNeutral(t *testing.T) {
+	ce := NewComparisonEngine()
+	features := rearFeatures(0, 1.0)
+	shadows := []models.Point2D{{X: 600, Y: 660}, {X: 680, Y: 660}}
+
+	// Each comparator takes whether each side is empty
+	comparators := map[string]func(empty1, empty2 bool) float64{
+		"structural elements": func(empty1, empty2 bool) float64 {
+			elements := features.GeometricFeatures.StructuralElements
+			return ce.compareStructuralElements(emptyIf(empty1, elements), emptyIf(empty2, elements), canonicalFrame, canonicalFrame)
+		},
+		"reference points": func(empty1, empty2 bool) float64 {
+			points := features.GeometricFeatures.ReferencePoints
+			return ce.compareReferencePoints(emptyIf(empty1, points), emptyIf(empty2, points), canonicalFrame, canonicalFrame)
+		},
+		"light elements": func(empty1, empty2 bool) float64 {
+			elements := features.LightPatterns.LightElements
+			return ce.compareLightElements(emptyIf(empty1, elements), emptyIf(empty2, elements), canonicalFrame, canonicalFrame)
+		},
+		"color profiles": func(empty1, empty2 bool) float64 {
+			colors := features.DaylightFeatures.ColorProfile.DominantColors
+			return ce.compareColorProfiles(
+				models.ColorProfile{DominantColors: emptyIf(empty1, colors)},
+				models.ColorProfile{DominantColors: emptyIf(empty2, colors)})
+		},
+		"contours": func(empty1, empty2 bool) float64 {
+			contour := features.BumperFeatures.ContourSignature
+			return ce.compareContours(emptyIf(empty1, contour), emptyIf(empty2, contour), canonicalFrame, canonicalFrame)
+		},
+		"shadow patterns": func(empty1, empty2 bool) float64 {
+			return ce.compareShadowPatterns(emptyIf(empty1, shadows), emptyIf(empty2, shadows), canonicalFrame, canonicalFrame)
+		},
+	}
+
+	cases := []struct {
+		name           string
+		empty1, empty2 bool
+	}{
+		{"both empty", true, true},
+		{"first empty", true, false},
+		{"second empty", false, true},
+		{"neither empty", false, false},
+	}
+
+	for name, compare := range comparators {
+		for _, c := range cases {
+			score := compare(c.empty1, c.empty2)
+			switch {
+			case c.empty1 || c.empty2:
+				if score != missingDataScore {
+					t.Errorf("%s, %s: expected neutral %.1f, got %.3f", name, c.name, missingDataScore, score)
+				}
+			case score != 1.0:
+				t.Errorf("%s, %s: expected identical sets to score 1.0, got %.3f", name, c.name, score)
+			}
+		}
+	}
+}
+
+// emptyIf returns an empty slice of the same type when empty is set, and s otherwise
+func emptyIf[T any](empty bool, s []T) []T {
+	if empty {
+		return []T{}
+	}
+	return s
+}
@@ -0,0 +1,128 @@
+package comparator
+
+import (
+	"math"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+)
+
+// Width of the canonical frame (see preprocessor.CanonicalWidth). Features that
+// carry neither vehicle bounds nor an extraction width are assumed to have been
+// measured in this frame.
+const canonicalWidth = 1280.0
+
+// Distance tolerances as fractions of the vehicle width. Each is equivalent to
+// the pixel tolerance noted at the canonical width.
+const (
+	structuralDecay      = 50.0 / canonicalWidth // 50px
+	referenceMatchRadius = 50.0 / canonicalWidth // 50px
+	referenceDecay       = 20.0 / canonicalWidth // 20px
+	lightDecay           = 30.0 / canonicalWidth // 30px
+	contourMatchRadius   = 30.0 / canonicalWidth // 30px
+	contourDecay         = 15.0 / canonicalWidth // 15px
+	plateDecay           = 20.0 / canonicalWidth // 20px
+	shadowDecay          = 50.0 / canonicalWidth // 50px
+)
+
+// frame is the vehicle region a feature set was measured in. Positions and sizes
+// are compared as fractions of its width so images of different resolutions match.
+// Both axes use the width so distances stay isotropic.
+type frame struct {
+	originX, originY float64
+	width            float64
+
+	// Vehicle frame a plate frame was derived from; nil for vehicle frames
+	vehicle *frame
+}
+
+// plateAreaFrame is the frame plate areas are compared in. The plate sits at the
+// origin of its own plate frame, so comparing it there would always find the plates
+// in the same place; plate frames defer to their vehicle frame.
+func (f frame) plateAreaFrame() frame {
+	if f.vehicle != nil {
+		return *f.vehicle
+	}
+	return f
+}
+
+var canonicalFrame = frame{width: canonicalWidth}
+
+func frameOf(features models.VehicleFeatures) frame {
+	bounds := features.VehicleBounds
+	if bounds.Width <= 0 || bounds.Height <= 0 {
+		if features.CanonicalWidth > 0 {
+			return frame{width: float64(features.CanonicalWidth)}
+		}
+		return canonicalFrame
+	}
+
+	return frame{
+		originX: float64(bounds.X),
+		originY: float64(bounds.Y),
+		width:   float64(bounds.Width),
+	}
+}
+
+// point converts an image position into vehicle-relative fractions
+func (f frame) point(p models.Point2D) models.Point2D {
+	return models.Point2D{
+		X: (p.X - f.originX) / f.width,
+		Y: (p.Y - f.originY) / f.width,
+	}
+}
+
+// length converts a pixel length into a fraction of the vehicle width
+func (f frame) length(pixels float64) float64 {
+	return pixels / f.width
+}
+
+// area converts a pixel area into a fraction of the squared vehicle width
+func (f frame) area(pixels float64) float64 {
+	return pixels / (f.width * f.width)
+}
+
+// Typical vehicle width in plate widths: a 520mm plate on a 2m wide vehicle.
+// Plate-relative frames are scaled by it so the tolerances above, fractions of the
+// vehicle width, keep their meaning.
+const vehicleWidthInPlates = 4.0
+
+// plateFrame is the frame anchored on the detected license plate: positions are
+// measured from the plate center, and both axes in units of the vehicle width the
+// plate width implies. Cropping and rescaling move and resize the plate with the
+// rest of the vehicle, so positions in this frame are unaffected by either. ok is
+// false when no plate was detected.
+func plateFrame(features models.VehicleFeatures) (frame, bool) {
+	plate := features.BumperFeatures.LicensePlateArea
+	if plate.Width <= 0 || plate.Height <= 0 {
+		return frame{}, false
+	}
+
+	vehicle := frameOf(features)
+	return frame{
+		originX: float64(plate.X) + float64(plate.Width)/2.0,
+		originY: float64(plate.Y) + float64(plate.Height)/2.0,
+		width:   float64(plate.Width) * vehicleWidthInPlates,
+		vehicle: &vehicle,
+	}, true
+}
+
+// comparisonFrames returns the frames features1 and features2 are compared in:
+// plate-relative when that mode is on and both images have a detected plate, and
+// vehicle-relative otherwise. plateRelative reports which.
+func (ce *ComparisonEngine) comparisonFrames(features1, features2 models.VehicleFeatures) (frame1, frame2 frame, plateRelative bool) {
+	if ce.plateRelative {
+		plate1, ok1 := plateFrame(features1)
+		plate2, ok2 := plateFrame(features2)
+		if ok1 && ok2 {
+			return plate1, plate2, true
+		}
+	}
+	return frameOf(features1), frameOf(features2), false
+}
+
+// fractionalDistance is the distance between two points measured in their own frames
+func fractionalDistance(p1 models.Point2D, f1 frame, p2 models.Point2D, f2 frame) float64 {
+	a := f1.point(p1)
+	b := f2.point(p2)
+	return math.Sqrt(math.Pow(a.X-b.X, 2) + math.Pow(a.Y-b.Y, 2))
+}
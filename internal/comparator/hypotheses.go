@@ -0,0 +1,129 @@
+package comparator
+
+import (
+	"math"
+	"sort"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+)
+
+// Most alignment hypotheses scored with a full comparison, besides the plain
+// vehicle-frame alignment
+const maxAlignmentHypotheses = 8
+
+// alignmentHypothesis is a translation of image 2's positions onto image 1's, in
+// vehicle-relative fractions, with the number of anchor pairs consistent with it
+type alignmentHypothesis struct {
+	offset  models.Point2D
+	support int
+}
+
+// bestAlignment returns image 2's frame shifted by the alignment hypothesis under
+// which the comparison scores highest, and the share of anchors aligned under it.
+// The unshifted frame is always a candidate, so the search never scores below the
+// plain alignment.
+func (ce *ComparisonEngine) bestAlignment(features1, features2 models.VehicleFeatures, frame1, frame2 frame, partialOverlap bool) (frame, float64) {
+	pairs := anchorPairs(features1, features2, frame1, frame2)
+	weights := ce.EffectiveWeights(features1.Lighting)
+
+	best := frame2
+	bestOffset := models.Point2D{}
+	bestScore := math.Inf(-1)
+	for _, hypothesis := range alignmentHypotheses(pairs) {
+		shifted := frame2
+		shifted.originX += hypothesis.offset.X * frame2.width
+		shifted.originY += hypothesis.offset.Y * frame2.width
+
+		scores := ce.compareViews(features1, features2, frame1, shifted, partialOverlap)
+		if score := ce.calculateWeightedSimilarity(scores, weights); score > bestScore {
+			best, bestOffset, bestScore = shifted, hypothesis.offset, score
+		}
+	}
+
+	return best, alignmentQuality(features1, features2, frame1, frame2, bestOffset)
+}
+
+// alignmentHypotheses proposes translations from minimal subsets of the anchor
+// pairs: a single same-kind pair fixes a translation. Near-duplicate proposals are
+// merged and the best-supported are kept, after the zero (plain) alignment.
+func alignmentHypotheses(pairs []pointMatch) []alignmentHypothesis {
+	candidates := make([]alignmentHypothesis, 0, len(pairs))
+	for _, pair := range pairs {
+		offset := pair.displacement()
+		support := 0
+		for _, m := range pairs {
+			if displacementResidual(m, offset) <= outlierTolerance {
+				support++
+			}
+		}
+		candidates = append(candidates, alignmentHypothesis{offset: offset, support: support})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].support > candidates[j].support
+	})
+
+	hypotheses := []alignmentHypothesis{{}}
+	for _, candidate := range candidates {
+		if len(hypotheses) > maxAlignmentHypotheses {
+			break
+		}
+		duplicate := false
+		for _, h := range hypotheses {
+			if math.Hypot(candidate.offset.X-h.offset.X, candidate.offset.Y-h.offset.Y) <= outlierTolerance {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			hypotheses = append(hypotheses, candidate)
+		}
+	}
+
+	return hypotheses
+}
+
+// anchorPairs pairs every anchor of image 1 with every same-kind anchor of image 2,
+// as positions in their frames
+func anchorPairs(features1, features2 models.VehicleFeatures, frame1, frame2 frame) []pointMatch {
+	var pairs []pointMatch
+	for _, a1 := range anchorsOf(features1) {
+		for _, a2 := range anchorsOf(features2) {
+			if a1.kind == a2.kind {
+				pairs = append(pairs, pointMatch{from: frame1.point(a1.position), to: frame2.point(a2.position)})
+			}
+		}
+	}
+	return pairs
+}
+
+// anchorsOf returns the crop registration anchors plus the reference points
+func anchorsOf(features models.VehicleFeatures) []cropAnchor {
+	anchors := cropAnchors(features)
+	for _, p := range features.GeometricFeatures.ReferencePoints {
+		anchors = append(anchors, cropAnchor{kind: "reference", position: p})
+	}
+	return anchors
+}
+
+// alignmentQuality is the share of image 1's anchors with a same-kind image 2
+// anchor within outlierTolerance once image 2 is shifted by offset
+func alignmentQuality(features1, features2 models.VehicleFeatures, frame1, frame2 frame, offset models.Point2D) float64 {
+	anchors1, anchors2 := anchorsOf(features1), anchorsOf(features2)
+	if len(anchors1) == 0 {
+		return 0.0
+	}
+
+	aligned := 0
+	for _, a1 := range anchors1 {
+		for _, a2 := range anchors2 {
+			pair := pointMatch{from: frame1.point(a1.position), to: frame2.point(a2.position)}
+			if a1.kind == a2.kind && displacementResidual(pair, offset) <= outlierTolerance {
+				aligned++
+				break
+			}
+		}
+	}
+
+	return float64(aligned) / float64(len(anchors1))
+}
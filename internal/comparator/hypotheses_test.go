@@ -0,0 +1,43 @@
+package comparator
+
+import "testing"
+
+func TestAlignmentHypothesesRecoverMisplacedVehicle(t *testing.T) {
+	reference := rearFeatures(0, 0)
+
+	// The same vehicle, but its detection was misplaced so every position sits 150px
+	// off in the vehicle frame
+	misplaced := rearFeatures(150, 0)
+
+	naive, err := NewComparisonEngine().CompareVehicles(reference, misplaced)
+	if err != nil {
+		t.Fatalf("Comparison failed: %v", err)
+	}
+
+	ce := NewComparisonEngine()
+	ce.SetAlignmentHypotheses(true)
+	searched, err := ce.CompareVehicles(reference, misplaced)
+	if err != nil {
+		t.Fatalf("Comparison failed: %v", err)
+	}
+
+	if naive.IsSameVehicle {
+		t.Fatalf("Expected the naive alignment to miss the match, got %.3f", naive.SimilarityScore)
+	}
+	if !searched.IsSameVehicle || searched.SimilarityScore <= naive.SimilarityScore {
+		t.Errorf("Expected the hypothesis search to find the match (%.3f searched vs %.3f naive)",
+			searched.SimilarityScore, naive.SimilarityScore)
+	}
+	if searched.ProcessingInfo.AlignmentQuality < 0.99 {
+		t.Errorf("Expected every anchor to be aligned under the best hypothesis, got %.3f", searched.ProcessingInfo.AlignmentQuality)
+	}
+
+	// Correctly aligned pairs keep the plain alignment
+	aligned, err := ce.CompareVehicles(reference, rearFeatures(0, 0))
+	if err != nil {
+		t.Fatalf("Comparison failed: %v", err)
+	}
+	if aligned.SimilarityScore < searched.SimilarityScore-1e-9 {
+		t.Errorf("Expected an aligned pair to score at least as high, got %.3f", aligned.SimilarityScore)
+	}
+}
@@ -0,0 +1,65 @@
+package comparator
+
+import (
+	"math"
+	"math/bits"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+)
+
+// A match is kept only when its best descriptor distance is clearly smaller than
+// the second best (Lowe's ratio test), which rejects ambiguous repeated structure
+const loweRatio = 0.75
+
+// compareKeypoints scores two keypoint sets by the fraction of keypoints that find
+// an unambiguous match, averaged over both matching directions
+func (ce *ComparisonEngine) compareKeypoints(keypoints1, keypoints2 []models.Keypoint) float64 {
+	if len(keypoints1) == 0 || len(keypoints2) == 0 {
+		return 0.0
+	}
+
+	forward := float64(countGoodMatches(keypoints1, keypoints2)) / float64(len(keypoints1))
+	backward := float64(countGoodMatches(keypoints2, keypoints1)) / float64(len(keypoints2))
+
+	return safeFloat64((forward+backward)/2, 0.0)
+}
+
+// countGoodMatches counts keypoints in from whose nearest neighbour in to passes
+// the ratio test
+func countGoodMatches(from, to []models.Keypoint) int {
+	if len(to) < 2 {
+		return 0
+	}
+
+	good := 0
+	for _, k1 := range from {
+		best, second := math.MaxInt, math.MaxInt
+		for _, k2 := range to {
+			distance := hammingDistance(k1.Descriptor, k2.Descriptor)
+			if distance < best {
+				best, second = distance, best
+			} else if distance < second {
+				second = distance
+			}
+		}
+
+		if float64(best) < loweRatio*float64(second) {
+			good++
+		}
+	}
+
+	return good
+}
+
+func hammingDistance(a, b []byte) int {
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+
+	distance := 0
+	for i := 0; i < n; i++ {
+		distance += bits.OnesCount8(a[i] ^ b[i])
+	}
+	return distance
+}
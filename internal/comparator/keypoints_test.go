@@ -0,0 +1,48 @@
+package comparator
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+)
+
+func randomKeypoints(rng *rand.Rand, n int) []models.Keypoint {
+	keypoints := make([]models.Keypoint, n)
+	for i := range keypoints {
+		descriptor := make([]byte, 32)
+		rng.Read(descriptor)
+		keypoints[i] = models.Keypoint{
+			Position:   models.Point2D{X: rng.Float64() * 1280, Y: rng.Float64() * 960},
+			Descriptor: descriptor,
+		}
+	}
+	return keypoints
+}
+
+func TestCompareKeypoints(t *testing.T) {
+	ce := NewComparisonEngine()
+	rng := rand.New(rand.NewSource(1))
+
+	reference := randomKeypoints(rng, 200)
+
+	// The same keypoints re-detected with a few flipped descriptor bits
+	noisy := make([]models.Keypoint, len(reference))
+	for i, k := range reference {
+		descriptor := append([]byte{}, k.Descriptor...)
+		descriptor[rng.Intn(len(descriptor))] ^= 1 << uint(rng.Intn(8))
+		noisy[i] = models.Keypoint{Position: k.Position, Descriptor: descriptor}
+	}
+
+	if similarity := ce.compareKeypoints(reference, noisy); similarity < 0.9 {
+		t.Errorf("Expected most keypoints to match a re-detected copy, got %.3f", similarity)
+	}
+
+	if similarity := ce.compareKeypoints(reference, randomKeypoints(rng, 200)); similarity > 0.2 {
+		t.Errorf("Expected few matches between unrelated keypoints, got %.3f", similarity)
+	}
+
+	if similarity := ce.compareKeypoints(reference, nil); similarity != 0 {
+		t.Errorf("Expected 0 with no keypoints, got %.3f", similarity)
+	}
+}
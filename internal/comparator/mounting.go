@@ -0,0 +1,56 @@
+package comparator
+
+import (
+	"math"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+)
+
+// Mounting point distances are measured in plate widths. A screw 5% of a plate width
+// out of place (about 15mm on a US plate) weakens its match by a factor of e.
+const mountingDecay = 0.05
+
+// compareMountingGeometry compares mounting point layouts in plate-relative
+// coordinates (offset from the plate center, in plate widths), so the comparison
+// survives a plate swap as well as different framing and resolution. It returns
+// false when either bumper lacks a plate area or mounting points.
+func (ce *ComparisonEngine) compareMountingGeometry(bumper1, bumper2 models.BumperFeatures) (float64, bool) {
+	points1, ok1 := plateRelative(bumper1.MountingPoints, bumper1.LicensePlateArea)
+	points2, ok2 := plateRelative(bumper2.MountingPoints, bumper2.LicensePlateArea)
+	if !ok1 || !ok2 {
+		return 0, false
+	}
+
+	// Match each point to its nearest counterpart in both directions so extra or
+	// missing screws count against the layout
+	similarity := (nearestMatchSimilarity(points1, points2) + nearestMatchSimilarity(points2, points1)) / 2.0
+	return safeFloat64(similarity, 0.5), true
+}
+
+func plateRelative(points []models.Point2D, plate models.Bounds) ([]models.Point2D, bool) {
+	if len(points) == 0 || !plateAreaKnown(plate) {
+		return nil, false
+	}
+
+	centerX := float64(plate.X) + float64(plate.Width)/2.0
+	centerY := float64(plate.Y) + float64(plate.Height)/2.0
+	width := float64(plate.Width)
+
+	relative := make([]models.Point2D, len(points))
+	for i, p := range points {
+		relative[i] = models.Point2D{X: (p.X - centerX) / width, Y: (p.Y - centerY) / width}
+	}
+	return relative, true
+}
+
+func nearestMatchSimilarity(from, to []models.Point2D) float64 {
+	total := 0.0
+	for _, p := range from {
+		nearest := math.Inf(1)
+		for _, q := range to {
+			nearest = math.Min(nearest, math.Hypot(p.X-q.X, p.Y-q.Y))
+		}
+		total += math.Exp(-nearest / mountingDecay)
+	}
+	return total / float64(len(from))
+}
@@ -0,0 +1,81 @@
+package comparator
+
+import (
+	"testing"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+)
+
+// mountedBumper places mounting points at plate-relative offsets (in plate widths
+// from the plate center) on a plate with the given bounds
+func mountedBumper(plate models.Bounds, offsets ...models.Point2D) models.BumperFeatures {
+	centerX := float64(plate.X) + float64(plate.Width)/2.0
+	centerY := float64(plate.Y) + float64(plate.Height)/2.0
+
+	points := make([]models.Point2D, len(offsets))
+	for i, o := range offsets {
+		points[i] = models.Point2D{
+			X: centerX + o.X*float64(plate.Width),
+			Y: centerY + o.Y*float64(plate.Width),
+		}
+	}
+	return models.BumperFeatures{LicensePlateArea: plate, MountingPoints: points}
+}
+
+func TestCompareMountingGeometry(t *testing.T) {
+	ce := NewComparisonEngine()
+
+	// Screws either side of the plate center, the common US layout
+	sideScrews := []models.Point2D{{X: -0.4, Y: 0}, {X: 0.4, Y: 0}}
+	// Four corner screws of a European bracket
+	cornerScrews := []models.Point2D{{X: -0.45, Y: -0.08}, {X: 0.45, Y: -0.08}, {X: -0.45, Y: 0.08}, {X: 0.45, Y: 0.08}}
+
+	reference := mountedBumper(models.Bounds{X: 540, Y: 600, Width: 200, Height: 100}, sideScrews...)
+	// Same layout on a plate framed elsewhere and at a different resolution
+	reframed := mountedBumper(models.Bounds{X: 210, Y: 330, Width: 120, Height: 60}, sideScrews...)
+	different := mountedBumper(models.Bounds{X: 540, Y: 600, Width: 200, Height: 100}, cornerScrews...)
+
+	matched, ok := ce.compareMountingGeometry(reference, reframed)
+	if !ok {
+		t.Fatal("Expected mounting geometry to be comparable")
+	}
+	mismatched, ok := ce.compareMountingGeometry(reference, different)
+	if !ok {
+		t.Fatal("Expected mounting geometry to be comparable")
+	}
+
+	if matched < 0.95 {
+		t.Errorf("Expected matching layouts to score near 1, got %.3f", matched)
+	}
+	if mismatched > 0.5 {
+		t.Errorf("Expected mismatched layouts to score low, got %.3f", mismatched)
+	}
+
+	if _, ok := ce.compareMountingGeometry(reference, models.BumperFeatures{}); ok {
+		t.Error("Expected a bumper without a plate to be incomparable")
+	}
+
+	// Mounting-only mode scores the pair on the layout alone
+	ce.SetMountingOnly(true)
+	features1 := rearFeatures(0, 0)
+	features2 := rearFeatures(0, 0)
+	features1.BumperFeatures = reference
+	features2.BumperFeatures = different
+
+	result, err := ce.CompareVehicles(features1, features2)
+	if err != nil {
+		t.Fatalf("CompareVehicles failed: %v", err)
+	}
+	if result.IsSameVehicle {
+		t.Errorf("Expected different mounting layouts to be different vehicles, got %.3f", result.SimilarityScore)
+	}
+
+	features2.BumperFeatures = reframed
+	result, err = ce.CompareVehicles(features1, features2)
+	if err != nil {
+		t.Fatalf("CompareVehicles failed: %v", err)
+	}
+	if !result.IsSameVehicle {
+		t.Errorf("Expected matching mounting layouts to be the same vehicle, got %.3f", result.SimilarityScore)
+	}
+}
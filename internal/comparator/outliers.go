@@ -0,0 +1,88 @@
+package comparator
+
+import (
+	"math"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+)
+
+// Matches whose displacement differs from the dominant displacement by more than
+// this (as a fraction of vehicle width) are treated as outliers. 15px at the
+// canonical width.
+const outlierTolerance = 15.0 / canonicalWidth
+
+// Below this many matches a dominant displacement cannot be told apart from an
+// outlier, so no matches are rejected
+const minMatchesForRejection = 3
+
+// pointMatch is a matched pair of positions in vehicle-relative fractions, with the
+// per-match value (similarity or distance) averaged over the inliers
+type pointMatch struct {
+	from, to models.Point2D
+	value    float64
+}
+
+func (pm pointMatch) displacement() models.Point2D {
+	return models.Point2D{X: pm.to.X - pm.from.X, Y: pm.to.Y - pm.from.Y}
+}
+
+// rejectOutliers keeps the matches consistent with the dominant translation between
+// the two point sets (see consistentMatches), unless there are too few matches to
+// tell an outlier apart. It also returns the share of matches kept: callers scale
+// their score by it, so a pair that is mostly outliers cannot score as well as a
+// consistent one on the few matches that happen to agree.
+func rejectOutliers(matches []pointMatch) ([]pointMatch, float64) {
+	if len(matches) < minMatchesForRejection {
+		return matches, 1.0
+	}
+
+	inliers := consistentMatches(matches)
+	return inliers, float64(len(inliers)) / float64(len(matches))
+}
+
+// consistentMatches keeps the matches consistent with the dominant translation.
+// Every match is tried as the translation hypothesis (the sets are small, so this
+// exhaustive form of RANSAC is cheap and deterministic) and the hypothesis with the
+// most inliers wins, ties going to the smallest total residual.
+func consistentMatches(matches []pointMatch) []pointMatch {
+	if len(matches) == 0 {
+		return matches
+	}
+
+	bestInliers := -1
+	bestResidual := math.Inf(1)
+	var bestHypothesis models.Point2D
+
+	for _, candidate := range matches {
+		hypothesis := candidate.displacement()
+
+		inliers := 0
+		residual := 0.0
+		for _, m := range matches {
+			if r := displacementResidual(m, hypothesis); r <= outlierTolerance {
+				inliers++
+				residual += r
+			}
+		}
+
+		if inliers > bestInliers || (inliers == bestInliers && residual < bestResidual) {
+			bestInliers = inliers
+			bestResidual = residual
+			bestHypothesis = hypothesis
+		}
+	}
+
+	inliers := make([]pointMatch, 0, bestInliers)
+	for _, m := range matches {
+		if displacementResidual(m, bestHypothesis) <= outlierTolerance {
+			inliers = append(inliers, m)
+		}
+	}
+
+	return inliers
+}
+
+func displacementResidual(m pointMatch, hypothesis models.Point2D) float64 {
+	d := m.displacement()
+	return math.Sqrt(math.Pow(d.X-hypothesis.X, 2) + math.Pow(d.Y-hypothesis.Y, 2))
+}
@@ -0,0 +1,88 @@
+package comparator
+
+import (
+	"math"
+	"testing"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+)
+
+func TestOutlierRejectionRecoversAlignedScore(t *testing.T) {
+	ce := NewComparisonEngine()
+
+	aligned := []models.Point2D{
+		{X: 300, Y: 300}, {X: 640, Y: 300}, {X: 980, Y: 300},
+		{X: 300, Y: 600}, {X: 640, Y: 600}, {X: 980, Y: 600},
+	}
+
+	points1 := append([]models.Point2D{}, aligned...)
+	points2 := append([]models.Point2D{}, aligned...)
+
+	// Spurious detections with a nearby but wrong partner in the other image
+	points1 = append(points1, models.Point2D{X: 450, Y: 450}, models.Point2D{X: 800, Y: 200})
+	points2 = append(points2, models.Point2D{X: 490, Y: 430}, models.Point2D{X: 770, Y: 235})
+
+	// The six aligned points score perfectly and the two outliers count against them
+	if score := ce.compareReferencePoints(points1, points2, canonicalFrame, canonicalFrame); math.Abs(score-0.75) > 0.01 {
+		t.Errorf("Expected outliers to be rejected and weighted out for reference points, got %.3f", score)
+	}
+
+	var elements1, elements2 []models.StructuralElement
+	for _, p := range points1 {
+		elements1 = append(elements1, models.StructuralElement{Type: "corner", Position: p, Size: 40})
+	}
+	for _, p := range points2 {
+		elements2 = append(elements2, models.StructuralElement{Type: "corner", Position: p, Size: 40})
+	}
+
+	if score := ce.compareStructuralElements(elements1, elements2, canonicalFrame, canonicalFrame); math.Abs(score-0.75) > 0.01 {
+		t.Errorf("Expected outliers to be rejected and weighted out for structural elements, got %.3f", score)
+	}
+
+	// Too few matches to identify a dominant displacement: nothing is rejected
+	few, share := rejectOutliers([]pointMatch{
+		{from: models.Point2D{X: 0.1, Y: 0.1}, to: models.Point2D{X: 0.1, Y: 0.1}},
+		{from: models.Point2D{X: 0.5, Y: 0.5}, to: models.Point2D{X: 0.53, Y: 0.5}},
+	})
+	if len(few) != 2 || share != 1.0 {
+		t.Errorf("Expected both matches kept below the rejection minimum, got %d (share %.2f)", len(few), share)
+	}
+}
+
+func TestMostlyOutlierPairScoresLow(t *testing.T) {
+	ce := NewComparisonEngine()
+
+	aligned := []models.Point2D{{X: 300, Y: 300}, {X: 640, Y: 300}, {X: 980, Y: 300}}
+	if score := ce.compareReferencePoints(aligned, aligned, canonicalFrame, canonicalFrame); score < 0.95 {
+		t.Fatalf("Expected a consistent pair to score high, got %.3f", score)
+	}
+
+	// Five more points, each displaced differently: only the three aligned agree
+	points1 := append([]models.Point2D{}, aligned...)
+	points2 := append([]models.Point2D{}, aligned...)
+	for _, outlier := range []struct{ at, shift models.Point2D }{
+		{models.Point2D{X: 200, Y: 800}, models.Point2D{X: 30, Y: 0}},
+		{models.Point2D{X: 500, Y: 850}, models.Point2D{X: 0, Y: 30}},
+		{models.Point2D{X: 800, Y: 820}, models.Point2D{X: -30, Y: 0}},
+		{models.Point2D{X: 1100, Y: 780}, models.Point2D{X: 0, Y: -30}},
+		{models.Point2D{X: 1000, Y: 100}, models.Point2D{X: 25, Y: 25}},
+	} {
+		points1 = append(points1, outlier.at)
+		points2 = append(points2, models.Point2D{X: outlier.at.X + outlier.shift.X, Y: outlier.at.Y + outlier.shift.Y})
+	}
+
+	if score := ce.compareReferencePoints(points1, points2, canonicalFrame, canonicalFrame); score > 0.5 {
+		t.Errorf("Expected a mostly-outlier pair to score low, got %.3f", score)
+	}
+
+	var elements1, elements2 []models.StructuralElement
+	for _, p := range points1 {
+		elements1 = append(elements1, models.StructuralElement{Type: "corner", Position: p, Size: 40})
+	}
+	for _, p := range points2 {
+		elements2 = append(elements2, models.StructuralElement{Type: "corner", Position: p, Size: 40})
+	}
+	if score := ce.compareStructuralElements(elements1, elements2, canonicalFrame, canonicalFrame); score > 0.5 {
+		t.Errorf("Expected mostly-outlier structural elements to score low, got %.3f", score)
+	}
+}
@@ -0,0 +1,240 @@
+package comparator
+
+import (
+	"math"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+)
+
+// Height of the canonical frame, assumed for features that carry no vehicle bounds
+const canonicalHeight = 960.0
+
+// Structural elements at least this wide relative to their frame (bumper lines) are
+// clipped by a crop, so their position and size say nothing about the vehicle
+const extendedElementShare = 0.5
+
+// cropRegistration places two possibly partial views of a vehicle in one coordinate
+// system. frame2 is shifted so image 2 positions land on the image 1 positions of
+// the same features, and overlap is the region both images show, in the shared
+// fractions of frame1.
+type cropRegistration struct {
+	frame1, frame2 frame
+	overlap        region
+}
+
+type region struct {
+	minX, minY, maxX, maxY float64
+}
+
+func (r region) contains(p models.Point2D) bool {
+	return p.X >= r.minX && p.X <= r.maxX && p.Y >= r.minY && p.Y <= r.maxY
+}
+
+func (r region) area() float64 {
+	return math.Max(0, r.maxX-r.minX) * math.Max(0, r.maxY-r.minY)
+}
+
+// cropAnchor is a compact feature used to register crops: a structural element or
+// the license plate
+type cropAnchor struct {
+	kind     string
+	position models.Point2D
+	size     float64
+}
+
+// registerCrops finds the translation between two crops of the same view. Both are
+// assumed to share a pixel scale, as crops from captures of similar resolution do,
+// so positions are measured in fractions of the wider image. Same-kind anchor pairs
+// vote for a translation and the one most pairs agree on wins. ok is false when the
+// images share no anchors or do not overlap.
+func registerCrops(features1, features2 models.VehicleFeatures) (cropRegistration, bool) {
+	width1, height1 := extentOf(features1)
+	width2, height2 := extentOf(features2)
+	width := math.Max(width1, width2)
+
+	frame1, frame2 := frameOf(features1), frameOf(features2)
+	frame1.width, frame2.width = width, width
+
+	var candidates []pointMatch
+	for _, a1 := range cropAnchors(features1) {
+		for _, a2 := range cropAnchors(features2) {
+			if a1.kind != a2.kind {
+				continue
+			}
+			candidates = append(candidates, pointMatch{
+				from: frame1.point(a1.position),
+				to:   frame2.point(a2.position),
+			})
+		}
+	}
+
+	inliers := consistentMatches(candidates)
+	if len(inliers) == 0 {
+		return cropRegistration{}, false
+	}
+
+	var offset models.Point2D
+	for _, m := range inliers {
+		d := m.displacement()
+		offset.X += d.X / float64(len(inliers))
+		offset.Y += d.Y / float64(len(inliers))
+	}
+
+	// Shift image 2's frame by the offset so its positions map onto image 1's
+	frame2.originX += offset.X * width
+	frame2.originY += offset.Y * width
+
+	overlap := region{
+		minX: math.Max(0, -offset.X),
+		minY: math.Max(0, -offset.Y),
+		maxX: math.Min(width1/width, (width2/width)-offset.X),
+		maxY: math.Min(height1/width, (height2/width)-offset.Y),
+	}
+	if overlap.area() == 0 {
+		return cropRegistration{}, false
+	}
+
+	return cropRegistration{frame1: frame1, frame2: frame2, overlap: overlap}, true
+}
+
+// coverage returns the share of each image that lies in the overlap
+func (cr cropRegistration) coverage(features1, features2 models.VehicleFeatures) (float64, float64) {
+	width1, height1 := extentOf(features1)
+	width2, height2 := extentOf(features2)
+	area := cr.overlap.area() * cr.frame1.width * cr.frame1.width
+	return area / (width1 * height1), area / (width2 * height2)
+}
+
+// comparePartialCrops scores the features both images show, ignoring any that fall
+// outside the overlap. A full reference is not penalized for features a partial
+// query cannot show, and overall proportions, which a crop changes, are skipped.
+func (ce *ComparisonEngine) comparePartialCrops(features1, features2 models.VehicleFeatures, cr cropRegistration) models.DetailedScores {
+	scores := models.DetailedScores{
+		GeometricSimilarity: cr.compareOverlapElements(
+			cr.structuralInOverlap(features1, cr.frame1), cr.structuralInOverlap(features2, cr.frame2)),
+		LightPatternSimilarity: ce.compareLightElements(
+			cr.lightsInOverlap(features1.LightPatterns.LightElements, cr.frame1), cr.lightsInOverlap(features2.LightPatterns.LightElements, cr.frame2), cr.frame1, cr.frame2),
+		BumperSimilarity: ce.compareBumperFeatures(
+			cr.bumperInOverlap(features1.BumperFeatures, cr.frame1), cr.bumperInOverlap(features2.BumperFeatures, cr.frame2), cr.frame1, cr.frame2),
+	}
+
+	if !ce.layoutOnly && features1.DaylightFeatures != nil && features2.DaylightFeatures != nil {
+		scores.ColorSimilarity = ce.compareDaylightFeatures(*features1.DaylightFeatures, *features2.DaylightFeatures, textureTrust(features1, features2))
+	}
+
+	if !ce.layoutOnly && features1.InfraredFeatures != nil && features2.InfraredFeatures != nil {
+		scores.ThermalSimilarity = ce.compareInfraredFeatures(*features1.InfraredFeatures, *features2.InfraredFeatures, cr.frame1, cr.frame2, textureTrust(features1, features2))
+	}
+
+	return scores
+}
+
+// structuralInOverlap returns the registration anchors in the overlap as structural
+// elements. The plate is included: in a crop its offset from the lights is much of
+// the layout left to compare.
+func (cr cropRegistration) structuralInOverlap(features models.VehicleFeatures, f frame) []models.StructuralElement {
+	elements := []models.StructuralElement{}
+	for _, a := range cropAnchors(features) {
+		if cr.overlap.contains(f.point(a.position)) {
+			elements = append(elements, models.StructuralElement{Type: a.kind, Position: a.position, Size: a.size})
+		}
+	}
+	return elements
+}
+
+// compareOverlapElements scores every element in the overlap of either image by its
+// best same-type match in the other, so an element missing from one image counts
+// against the pair: both images show the region, so both should show the element
+func (cr cropRegistration) compareOverlapElements(elements1, elements2 []models.StructuralElement) float64 {
+	if len(elements1) == 0 && len(elements2) == 0 {
+		return 1.0
+	}
+
+	total := 0.0
+	for _, e1 := range elements1 {
+		total += bestElementMatch(e1, cr.frame1, elements2, cr.frame2)
+	}
+	for _, e2 := range elements2 {
+		total += bestElementMatch(e2, cr.frame2, elements1, cr.frame1)
+	}
+
+	return safeFloat64(total/float64(len(elements1)+len(elements2)), 0.5)
+}
+
+// bestElementMatch combines position and size similarity as compareStructuralElements
+// does, returning 0 when no element of the same type exists
+func bestElementMatch(e models.StructuralElement, f frame, candidates []models.StructuralElement, candidateFrame frame) float64 {
+	best := 0.0
+	for _, c := range candidates {
+		if c.Type != e.Type {
+			continue
+		}
+		positionSim := math.Exp(-fractionalDistance(e.Position, f, c.Position, candidateFrame) / structuralDecay)
+		sizeSim := ratioSimilarity(f.length(e.Size), candidateFrame.length(c.Size))
+		best = math.Max(best, positionSim*0.7+sizeSim*0.3)
+	}
+	return best
+}
+
+func (cr cropRegistration) lightsInOverlap(lights []models.LightElement, f frame) []models.LightElement {
+	inside := []models.LightElement{}
+	for _, l := range lights {
+		if cr.overlap.contains(f.point(l.Position)) {
+			inside = append(inside, l)
+		}
+	}
+	return inside
+}
+
+// bumperInOverlap keeps the bumper contour and mounting points in the overlap. The
+// plate needs no filtering: one outside the overlap is missing from the other image,
+// which scores neutral.
+func (cr cropRegistration) bumperInOverlap(bumper models.BumperFeatures, f frame) models.BumperFeatures {
+	inside := bumper
+	inside.ContourSignature = cr.pointsInOverlap(bumper.ContourSignature, f)
+	inside.MountingPoints = cr.pointsInOverlap(bumper.MountingPoints, f)
+	return inside
+}
+
+func (cr cropRegistration) pointsInOverlap(points []models.Point2D, f frame) []models.Point2D {
+	inside := []models.Point2D{}
+	for _, p := range points {
+		if cr.overlap.contains(f.point(p)) {
+			inside = append(inside, p)
+		}
+	}
+	return inside
+}
+
+func cropAnchors(features models.VehicleFeatures) []cropAnchor {
+	width, _ := extentOf(features)
+
+	anchors := []cropAnchor{}
+	for _, e := range features.GeometricFeatures.StructuralElements {
+		if e.Size < width*extendedElementShare {
+			anchors = append(anchors, cropAnchor{kind: e.Type, position: e.Position, size: e.Size})
+		}
+	}
+
+	if plate := features.BumperFeatures.LicensePlateArea; plateAreaKnown(plate) {
+		anchors = append(anchors, cropAnchor{
+			kind: "plate",
+			position: models.Point2D{
+				X: float64(plate.X) + float64(plate.Width)/2.0,
+				Y: float64(plate.Y) + float64(plate.Height)/2.0,
+			},
+			size: float64(plate.Width),
+		})
+	}
+
+	return anchors
+}
+
+// extentOf returns the size in pixels of the region the features were measured in
+func extentOf(features models.VehicleFeatures) (float64, float64) {
+	bounds := features.VehicleBounds
+	if bounds.Width <= 0 || bounds.Height <= 0 {
+		return canonicalWidth, canonicalHeight
+	}
+	return float64(bounds.Width), float64(bounds.Height)
+}
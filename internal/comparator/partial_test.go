@@ -0,0 +1,137 @@
+package comparator
+
+import (
+	"testing"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+)
+
+// cropRear cuts the rear fixture down to the rear-left corner: the left taillight
+// and the plate, as a camera seeing only part of the vehicle would capture it
+func cropRear(features models.VehicleFeatures) models.VehicleFeatures {
+	const x0, y0, width, height = 150, 250, 610, 510
+	shift := func(p models.Point2D) models.Point2D {
+		return models.Point2D{X: p.X - x0, Y: p.Y - y0}
+	}
+	inside := func(p models.Point2D) bool {
+		return p.X >= x0 && p.X < x0+width && p.Y >= y0 && p.Y < y0+height
+	}
+
+	cropped := features
+	cropped.VehicleBounds = models.Bounds{Width: width, Height: height}
+
+	cropped.GeometricFeatures.StructuralElements = nil
+	for _, e := range features.GeometricFeatures.StructuralElements {
+		if e.Type == "bumper_line" {
+			// The detector reports the visible segment of the line
+			e.Position = models.Point2D{X: x0 + width/2, Y: e.Position.Y}
+			e.Size = width
+		}
+		if inside(e.Position) {
+			e.Position = shift(e.Position)
+			cropped.GeometricFeatures.StructuralElements = append(cropped.GeometricFeatures.StructuralElements, e)
+		}
+	}
+
+	cropped.GeometricFeatures.ReferencePoints = nil
+	for _, p := range features.GeometricFeatures.ReferencePoints {
+		if inside(p) {
+			cropped.GeometricFeatures.ReferencePoints = append(cropped.GeometricFeatures.ReferencePoints, shift(p))
+		}
+	}
+
+	cropped.LightPatterns.LightElements = nil
+	for _, l := range features.LightPatterns.LightElements {
+		if inside(l.Position) {
+			l.Position = shift(l.Position)
+			cropped.LightPatterns.LightElements = append(cropped.LightPatterns.LightElements, l)
+		}
+	}
+	cropped.LightPatterns.LightConfiguration.NumElements = len(cropped.LightPatterns.LightElements)
+
+	bumper := features.BumperFeatures
+	cropped.BumperFeatures.ContourSignature = nil
+	for _, p := range bumper.ContourSignature {
+		if inside(p) {
+			cropped.BumperFeatures.ContourSignature = append(cropped.BumperFeatures.ContourSignature, shift(p))
+		}
+	}
+	cropped.BumperFeatures.MountingPoints = nil
+	for _, p := range bumper.MountingPoints {
+		cropped.BumperFeatures.MountingPoints = append(cropped.BumperFeatures.MountingPoints, shift(p))
+	}
+	cropped.BumperFeatures.LicensePlateArea.X -= x0
+	cropped.BumperFeatures.LicensePlateArea.Y -= y0
+
+	return cropped
+}
+
+func TestComparePartialCrops(t *testing.T) {
+	ce := NewComparisonEngine()
+	full := rearFeatures(0, 0)
+	crop := cropRear(rearFeatures(0, 0))
+
+	asFullViews, err := ce.CompareVehicles(full, crop)
+	if err != nil {
+		t.Fatalf("CompareVehicles failed: %v", err)
+	}
+
+	ce.SetPartialCrops(true)
+	partial, err := ce.CompareVehicles(full, crop)
+	if err != nil {
+		t.Fatalf("CompareVehicles failed: %v", err)
+	}
+
+	if partial.SimilarityScore < 0.8 || !partial.IsSameVehicle {
+		t.Errorf("Expected a crop of the same vehicle to match, got %.3f", partial.SimilarityScore)
+	}
+	if partial.SimilarityScore <= asFullViews.SimilarityScore {
+		t.Errorf("Expected partial comparison (%.3f) to score above full-view comparison (%.3f)",
+			partial.SimilarityScore, asFullViews.SimilarityScore)
+	}
+
+	registration, ok := registerCrops(full, crop)
+	if !ok {
+		t.Fatal("Expected the crop to register against the full view")
+	}
+	if coverage1, coverage2 := registration.coverage(full, crop); coverage2 < 0.99 || coverage1 > 0.5 {
+		t.Errorf("Expected the overlap to be the whole crop and part of the full view, got %.2f and %.2f", coverage1, coverage2)
+	}
+
+	// The same corner of a different vehicle, with larger lights set further out
+	other := rearFeatures(0, 1.5)
+	for i := range other.GeometricFeatures.StructuralElements {
+		if e := &other.GeometricFeatures.StructuralElements[i]; e.Type == "taillight" {
+			e.Position.X -= 60
+		}
+	}
+	for i := range other.LightPatterns.LightElements {
+		other.LightPatterns.LightElements[i].Position.X -= 60
+	}
+	different, err := ce.CompareVehicles(full, cropRear(other))
+	if err != nil {
+		t.Fatalf("CompareVehicles failed: %v", err)
+	}
+	if different.IsSameVehicle || different.SimilarityScore >= partial.SimilarityScore {
+		t.Errorf("Expected a crop of a different vehicle to score lower, got %.3f vs %.3f",
+			different.SimilarityScore, partial.SimilarityScore)
+	}
+}
+
+func TestPartialCropsIgnoreBumperPointsOutsideOverlap(t *testing.T) {
+	ce := NewComparisonEngine()
+	ce.SetPartialCrops(true)
+
+	// A mounting point just beyond the crop's right edge, close enough to be paired
+	// with the crop's rightmost point
+	full := rearFeatures(0, 0)
+	full.BumperFeatures.MountingPoints = append(full.BumperFeatures.MountingPoints, models.Point2D{X: 765, Y: 610})
+
+	result, err := ce.CompareVehicles(full, cropRear(rearFeatures(0, 0)))
+	if err != nil {
+		t.Fatalf("CompareVehicles failed: %v", err)
+	}
+	if result.DetailedScores.BumperSimilarity < 0.99 {
+		t.Errorf("Expected bumper points outside the overlap to be ignored, got %.3f", result.DetailedScores.BumperSimilarity)
+	}
+}
@@ -0,0 +1,99 @@
+package comparator
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+)
+
+// pureFixtures returns a pair exercising every comparison path that reads slices:
+// structural elements, lights, bumper contour, mounting points, color histograms,
+// keypoints and the IR signature
+func pureFixtures(lighting models.LightingType) (models.VehicleFeatures, models.VehicleFeatures) {
+	rng := rand.New(rand.NewSource(7))
+	features1, features2 := rearFeatures(0, 0), rearFeatures(12, 0.1)
+
+	for _, f := range []*models.VehicleFeatures{&features1, &features2} {
+		f.Lighting = lighting
+		f.GeometricFeatures.Keypoints = randomKeypoints(rng, 40)
+		f.VehicleBounds = models.Bounds{Width: 1280, Height: 960}
+		if lighting == models.LightingInfrared {
+			f.DaylightFeatures = nil
+			f.InfraredFeatures = &models.InfraredFeatures{
+				ThermalSignature:  []float64{0.3, 0.5, 0.2},
+				MaterialSignature: []float64{0.6, 0.4},
+				IRSignature: &models.IRSignature{
+					PlateRegion:          models.LicensePlateRegion{Detected: true, Confidence: 0.9},
+					ReflectivityMap:      [][]float64{{0.8, 0.5}, {0.5, 0.7}},
+					MaterialSignature:    []float64{0.7, 0.3},
+					IlluminationGradient: []float64{0.2, 0.5, 0.9},
+					ShadowPatterns:       []models.Point2D{{X: 500, Y: 650}, {X: 780, Y: 640}},
+					TextureFeatures:      []float64{0.4, 0.6},
+				},
+			}
+		}
+	}
+	// Unsorted and out of order relative to each other, so any sorting would show
+	features2.GeometricFeatures.StructuralElements[0], features2.GeometricFeatures.StructuralElements[2] =
+		features2.GeometricFeatures.StructuralElements[2], features2.GeometricFeatures.StructuralElements[0]
+	features2.LightPatterns.LightElements[0], features2.LightPatterns.LightElements[1] =
+		features2.LightPatterns.LightElements[1], features2.LightPatterns.LightElements[0]
+
+	return features1, features2
+}
+
+func snapshot(t *testing.T, features models.VehicleFeatures) []byte {
+	t.Helper()
+	data, err := json.Marshal(features)
+	if err != nil {
+		t.Fatalf("failed to snapshot features: %v", err)
+	}
+	return data
+}
+
+func TestCompareFeaturesIsPure(t *testing.T) {
+	configure := map[string]func(*ComparisonEngine){
+		"default":          func(*ComparisonEngine) {},
+		"layout only":      func(ce *ComparisonEngine) { ce.SetLayoutOnly(true) },
+		"mounting only":    func(ce *ComparisonEngine) { ce.SetMountingOnly(true) },
+		"ir signature":     func(ce *ComparisonEngine) { ce.SetIRSignatureOnly(true) },
+		"partial crops":    func(ce *ComparisonEngine) { ce.SetPartialCrops(true) },
+		"keypoints":        func(ce *ComparisonEngine) { ce.SetKeypointMatching(true) },
+		"hypotheses":       func(ce *ComparisonEngine) { ce.SetAlignmentHypotheses(true) },
+		"interval and sub": func(ce *ComparisonEngine) { ce.SetSimilarityInterval(true); ce.SetSubScores(true) },
+	}
+
+	for name, configureEngine := range configure {
+		for _, lighting := range []models.LightingType{models.LightingDaylight, models.LightingInfrared} {
+			ce := NewComparisonEngine()
+			configureEngine(ce)
+			engineBefore := *ce
+
+			features1, features2 := pureFixtures(lighting)
+			before1, before2 := snapshot(t, features1), snapshot(t, features2)
+
+			first, err := ce.CompareFeatures(features1, features2)
+			if err != nil {
+				t.Fatalf("%s/%v: comparison failed: %v", name, lighting, err)
+			}
+			second, err := ce.CompareFeatures(features1, features2)
+			if err != nil {
+				t.Fatalf("%s/%v: comparison failed: %v", name, lighting, err)
+			}
+
+			if !bytes.Equal(before1, snapshot(t, features1)) || !bytes.Equal(before2, snapshot(t, features2)) {
+				t.Errorf("%s/%v: comparison modified its input features", name, lighting)
+			}
+			if !reflect.DeepEqual(engineBefore, *ce) {
+				t.Errorf("%s/%v: comparison modified the engine", name, lighting)
+			}
+			if !reflect.DeepEqual(first, second) {
+				t.Errorf("%s/%v: repeated comparison differs: %+v vs %+v", name, lighting, first, second)
+			}
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package comparator
+
+import (
+	"math"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+)
+
+// toCommonScale rescales features2 to the extraction width of features1 when both
+// record one and they differ, so a reference set stored at one scale still matches
+// a set freshly extracted at another. Sets without a recorded width are returned
+// unchanged: their scale is unknown, and rescaling on a guess would do more harm
+// than the vehicle frame already undoes.
+func toCommonScale(features1, features2 models.VehicleFeatures) (models.VehicleFeatures, models.VehicleFeatures) {
+	width1, width2 := features1.CanonicalWidth, features2.CanonicalWidth
+	if width1 <= 0 || width2 <= 0 || width1 == width2 {
+		return features1, features2
+	}
+	return features1, rescaleFeatures(features2, float64(width1)/float64(width2), width1)
+}
+
+// rescaleFeatures returns a copy of features with every position, length and area
+// multiplied as for an image scaled by factor, extracted at width. Ratios,
+// intensities and histograms do not depend on scale and are kept. Slices are copied,
+// never modified, so the caller's feature set is left untouched.
+func rescaleFeatures(features models.VehicleFeatures, factor float64, width int) models.VehicleFeatures {
+	area := factor * factor
+
+	scaled := features
+	scaled.CanonicalWidth = width
+	scaled.VehicleBounds = scaleBounds(features.VehicleBounds, factor)
+
+	geometric := &scaled.GeometricFeatures
+	geometric.StructuralElements = make([]models.StructuralElement, len(features.GeometricFeatures.StructuralElements))
+	for i, e := range features.GeometricFeatures.StructuralElements {
+		geometric.StructuralElements[i] = models.StructuralElement{Type: e.Type, Position: scalePoint(e.Position, factor), Size: e.Size * factor}
+	}
+	geometric.ReferencePoints = scalePoints(features.GeometricFeatures.ReferencePoints, factor)
+	if keypoints := features.GeometricFeatures.Keypoints; keypoints != nil {
+		geometric.Keypoints = make([]models.Keypoint, len(keypoints))
+		for i, k := range keypoints {
+			geometric.Keypoints[i] = models.Keypoint{Position: scalePoint(k.Position, factor), Descriptor: k.Descriptor}
+		}
+	}
+	if grille := features.GeometricFeatures.Grille; grille != nil {
+		geometric.Grille = &models.GrilleFeatures{
+			Region:      scaleBounds(grille.Region, factor),
+			LineSpacing: grille.LineSpacing * factor,
+			EdgeDensity: grille.EdgeDensity,
+		}
+	}
+
+	// Each element's signature slot is its area plus scale-free terms; the summary
+	// slots after them are scale-free
+	lights := &scaled.LightPatterns
+	lights.LightElements = make([]models.LightElement, len(features.LightPatterns.LightElements))
+	lights.PatternSignature = append([]float64(nil), features.LightPatterns.PatternSignature...)
+	elementSlots := len(lights.PatternSignature) - models.PatternSummarySlots
+	for i, e := range features.LightPatterns.LightElements {
+		if i < elementSlots {
+			lights.PatternSignature[i] += e.Size * (area - 1.0)
+		}
+		e.Position = scalePoint(e.Position, factor)
+		e.Size *= area
+		lights.LightElements[i] = e
+	}
+	lights.LightConfiguration.Spacing *= factor
+
+	bumper := &scaled.BumperFeatures
+	bumper.ContourSignature = scalePoints(features.BumperFeatures.ContourSignature, factor)
+	bumper.MountingPoints = scalePoints(features.BumperFeatures.MountingPoints, factor)
+	bumper.LicensePlateArea = scaleBounds(features.BumperFeatures.LicensePlateArea, factor)
+
+	if daylight := features.DaylightFeatures; daylight != nil {
+		scaledDaylight := *daylight
+		scaledDaylight.BadgeLocations = make([]models.BadgeFeature, len(daylight.BadgeLocations))
+		for i, b := range daylight.BadgeLocations {
+			scaledDaylight.BadgeLocations[i] = models.BadgeFeature{Position: scalePoint(b.Position, factor), Size: b.Size * area, Shape: b.Shape}
+		}
+		scaledDaylight.TrimDetails = make([]models.TrimFeature, len(daylight.TrimDetails))
+		for i, trim := range daylight.TrimDetails {
+			trim.Position = scalePoint(trim.Position, factor)
+			scaledDaylight.TrimDetails[i] = trim
+		}
+		scaled.DaylightFeatures = &scaledDaylight
+	}
+
+	if infrared := features.InfraredFeatures; infrared != nil {
+		scaledInfrared := *infrared
+		scaledInfrared.ReflectiveElements = make([]models.ReflectiveElement, len(infrared.ReflectiveElements))
+		for i, r := range infrared.ReflectiveElements {
+			r.Position = scalePoint(r.Position, factor)
+			r.Size *= area
+			scaledInfrared.ReflectiveElements[i] = r
+		}
+		scaledInfrared.HeatPatterns = make([]models.HeatPattern, len(infrared.HeatPatterns))
+		for i, h := range infrared.HeatPatterns {
+			h.Region = scaleBounds(h.Region, factor)
+			scaledInfrared.HeatPatterns[i] = h
+		}
+		if signature := infrared.IRSignature; signature != nil {
+			scaledSignature := *signature
+			scaledSignature.PlateRegion.Bounds = scaleBounds(signature.PlateRegion.Bounds, factor)
+			scaledSignature.SurroundingRegion = scaleBounds(signature.SurroundingRegion, factor)
+			scaledSignature.ShadowPatterns = scalePoints(signature.ShadowPatterns, factor)
+			scaledInfrared.IRSignature = &scaledSignature
+		}
+		scaled.InfraredFeatures = &scaledInfrared
+	}
+
+	return scaled
+}
+
+func scalePoint(p models.Point2D, factor float64) models.Point2D {
+	return models.Point2D{X: p.X * factor, Y: p.Y * factor}
+}
+
+// scalePoints keeps nil as nil, so an absent list stays absent
+func scalePoints(points []models.Point2D, factor float64) []models.Point2D {
+	if points == nil {
+		return nil
+	}
+	scaled := make([]models.Point2D, len(points))
+	for i, p := range points {
+		scaled[i] = scalePoint(p, factor)
+	}
+	return scaled
+}
+
+func scaleBounds(b models.Bounds, factor float64) models.Bounds {
+	return models.Bounds{
+		X:      int(math.Round(float64(b.X) * factor)),
+		Y:      int(math.Round(float64(b.Y) * factor)),
+		Width:  int(math.Round(float64(b.Width) * factor)),
+		Height: int(math.Round(float64(b.Height) * factor)),
+	}
+}
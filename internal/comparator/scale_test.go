@@ -0,0 +1,92 @@
+package comparator
+
+import (
+	"math"
+	"testing"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+)
+
+func TestCompareFeaturesRescalesToCommonWidth(t *testing.T) {
+	ce := NewComparisonEngine()
+
+	// A reference stored from a 1280px extraction against the same vehicle freshly
+	// extracted at 640px. Neither carries vehicle bounds, so only the recorded
+	// widths tell the scales apart.
+	reference := rearFeatures(0, 0)
+	reference.CanonicalWidth = 1280
+	fresh := halfResolution(reference)
+	fresh.VehicleBounds = models.Bounds{}
+	fresh.CanonicalWidth = 640
+
+	same, err := ce.CompareFeatures(reference, reference)
+	if err != nil {
+		t.Fatalf("CompareFeatures failed: %v", err)
+	}
+	rescaled, err := ce.CompareFeatures(reference, fresh)
+	if err != nil {
+		t.Fatalf("CompareFeatures failed: %v", err)
+	}
+	if diff := math.Abs(same.SimilarityScore - rescaled.SimilarityScore); diff > 0.01 {
+		t.Errorf("Expected rescaling to preserve similarity, got %.3f vs %.3f", rescaled.SimilarityScore, same.SimilarityScore)
+	}
+
+	// Either order rescales to the same comparison
+	reversed, err := ce.CompareFeatures(fresh, reference)
+	if err != nil {
+		t.Fatalf("CompareFeatures failed: %v", err)
+	}
+	if diff := math.Abs(reversed.SimilarityScore - rescaled.SimilarityScore); diff > 0.01 {
+		t.Errorf("Expected the same similarity in both orders, got %.3f vs %.3f", reversed.SimilarityScore, rescaled.SimilarityScore)
+	}
+
+	// Without recorded widths the mismatch goes unnoticed and lowers the score
+	reference.CanonicalWidth, fresh.CanonicalWidth = 0, 0
+	unscaled, err := ce.CompareFeatures(reference, fresh)
+	if err != nil {
+		t.Fatalf("CompareFeatures failed: %v", err)
+	}
+	if rescaled.SimilarityScore-unscaled.SimilarityScore < 0.1 {
+		t.Errorf("Expected the unscaled comparison to score well below the rescaled one, got %.3f vs %.3f",
+			unscaled.SimilarityScore, rescaled.SimilarityScore)
+	}
+}
+
+func TestRescaleFeaturesLeavesInputUntouched(t *testing.T) {
+	features := rearFeatures(0, 0)
+	features.CanonicalWidth = 1600
+	before := snapshot(t, features)
+
+	scaled := rescaleFeatures(features, 0.5, 800)
+	if string(snapshot(t, features)) != string(before) {
+		t.Errorf("Expected rescaling to leave the input feature set unmodified")
+	}
+
+	if scaled.CanonicalWidth != 800 {
+		t.Errorf("Expected the rescaled set to record width 800, got %d", scaled.CanonicalWidth)
+	}
+	if got := scaled.LightPatterns.LightElements[0].Size; got != 600 {
+		t.Errorf("Expected light areas to scale by the square of the factor, got %.1f", got)
+	}
+	if got := scaled.BumperFeatures.LicensePlateArea; got != (models.Bounds{X: 280, Y: 300, Width: 80, Height: 25}) {
+		t.Errorf("Expected the plate area to halve, got %+v", got)
+	}
+}
+
+func TestRescaleFeaturesKeepsSignatureSummarySlots(t *testing.T) {
+	// More lights than element slots: the last lights have no slot of their own
+	features := rearFeatures(0, 0)
+	features.CanonicalWidth = 1600
+	elementSlots := len(features.LightPatterns.LightElements) - 1
+	features.LightPatterns.PatternSignature = make([]float64, elementSlots+models.PatternSummarySlots)
+	for i := range features.LightPatterns.PatternSignature {
+		features.LightPatterns.PatternSignature[i] = float64(i) + 0.5
+	}
+
+	scaled := rescaleFeatures(features, 0.5, 800)
+	for i := elementSlots; i < len(scaled.LightPatterns.PatternSignature); i++ {
+		if got, want := scaled.LightPatterns.PatternSignature[i], features.LightPatterns.PatternSignature[i]; got != want {
+			t.Errorf("Expected summary slot %d to be left unscaled at %.2f, got %.2f", i, want, got)
+		}
+	}
+}
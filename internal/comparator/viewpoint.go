@@ -0,0 +1,94 @@
+package comparator
+
+import (
+	"math"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+)
+
+// Lights count as a symmetric pair when their heights differ by at most this share
+// of the vehicle height and their sizes by at most this ratio
+const (
+	pairHeightTolerance = 0.05
+	pairMinSizeRatio    = 0.5
+)
+
+// EstimateViewpointDelta estimates how much the capture angle differs between two
+// images from how the vehicle face is foreshortened in each. Under weak perspective
+// turning away from a face compresses it by the cosine of the angle, so:
+//
+//   - yaw compresses horizontal extents: the span of the widest symmetric light pair
+//     relative to the vehicle height. It is not estimated when either image lacks a
+//     pair; the width/height ratio is the image's, not the vehicle's, so it changes
+//     with the crop as much as with the angle
+//   - pitch shows more of the roof: the upper/lower ratio grows by the tangent of
+//     the angle, taking the roof to be about as deep as the lower body is tall. It
+//     is not estimated for open-top vehicles, which have no roof to show
+//
+// Each angle is estimated as if the other were unchanged and the less oblique image
+// were square-on, so the estimates are first-order.
+func (ce *ComparisonEngine) EstimateViewpointDelta(features1, features2 models.VehicleFeatures) models.ViewpointDelta {
+	delta := models.ViewpointDelta{}
+
+	scale1, ok1 := horizontalScale(features1)
+	scale2, ok2 := horizontalScale(features2)
+	if ok1 && ok2 {
+		delta.YawDegrees = foreshorteningAngle(scale1, scale2)
+		delta.Estimated = true
+	}
+
+	upperLower1 := features1.GeometricFeatures.VehicleProportions.UpperLowerRatio
+	upperLower2 := features2.GeometricFeatures.VehicleProportions.UpperLowerRatio
+	roofless := features1.GeometricFeatures.VehicleProportions.Roofless || features2.GeometricFeatures.VehicleProportions.Roofless
+	if upperLower1 > 0 && upperLower2 > 0 && !roofless {
+		delta.PitchDegrees = math.Atan(upperLower2-upperLower1) * 180.0 / math.Pi
+		delta.Estimated = true
+	}
+
+	return delta
+}
+
+// foreshorteningAngle is the angle in degrees that compresses the larger of two
+// extents to the smaller, positive when the second is the compressed one
+func foreshorteningAngle(extent1, extent2 float64) float64 {
+	angle := math.Acos(math.Min(extent1, extent2)/math.Max(extent1, extent2)) * 180.0 / math.Pi
+	if extent2 > extent1 {
+		return -angle
+	}
+	return angle
+}
+
+// horizontalScale is the span of the widest symmetric light pair relative to the
+// vehicle height. ok is false when there is no such pair or no known height.
+func horizontalScale(features models.VehicleFeatures) (float64, bool) {
+	height := float64(features.VehicleBounds.Height)
+	if height <= 0 {
+		// Features without bounds were measured at the canonical width
+		if ratio := features.GeometricFeatures.VehicleProportions.WidthHeightRatio; ratio > 0 {
+			height = canonicalWidth / ratio
+		}
+	}
+	if height <= 0 {
+		return 0, false
+	}
+
+	lights := features.LightPatterns.LightElements
+	span := 0.0
+	for i := range lights {
+		for j := i + 1; j < len(lights); j++ {
+			a, b := lights[i], lights[j]
+			if math.Abs(a.Position.Y-b.Position.Y) > pairHeightTolerance*height {
+				continue
+			}
+			if a.Size <= 0 || b.Size <= 0 || math.Min(a.Size, b.Size)/math.Max(a.Size, b.Size) < pairMinSizeRatio {
+				continue
+			}
+			span = math.Max(span, math.Abs(a.Position.X-b.Position.X))
+		}
+	}
+
+	if span == 0 {
+		return 0, false
+	}
+	return span / height, true
+}
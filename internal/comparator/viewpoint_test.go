@@ -0,0 +1,61 @@
+package comparator
+
+import (
+	"math"
+	"testing"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+)
+
+// yawed foreshortens features as if the camera had turned by degrees about the
+// vehicle's vertical axis: horizontal positions and the width compress by the
+// cosine, heights are unchanged
+func yawed(features models.VehicleFeatures, degrees float64) models.VehicleFeatures {
+	c := math.Cos(degrees * math.Pi / 180.0)
+
+	skewed := features
+	skewed.VehicleBounds.Width = int(float64(features.VehicleBounds.Width) * c)
+	skewed.GeometricFeatures.VehicleProportions.WidthHeightRatio *= c
+
+	skewed.LightPatterns.LightElements = make([]models.LightElement, len(features.LightPatterns.LightElements))
+	for i, light := range features.LightPatterns.LightElements {
+		light.Position.X *= c
+		light.Size *= c
+		skewed.LightPatterns.LightElements[i] = light
+	}
+
+	return skewed
+}
+
+func TestEstimateViewpointDeltaFromSkew(t *testing.T) {
+	ce := NewComparisonEngine()
+
+	squareOn := rearFeatures(0, 0)
+	squareOn.VehicleBounds = models.Bounds{Width: 1280, Height: 962}
+
+	if delta := ce.EstimateViewpointDelta(squareOn, squareOn); !delta.Estimated || delta.YawDegrees != 0 || delta.PitchDegrees != 0 {
+		t.Errorf("Expected a zero delta for identical captures, got %+v", delta)
+	}
+
+	delta := ce.EstimateViewpointDelta(squareOn, yawed(squareOn, 30))
+	if math.Abs(delta.YawDegrees-30) > 2 {
+		t.Errorf("Expected about 30° of yaw, got %+v", delta)
+	}
+	if delta.PitchDegrees != 0 {
+		t.Errorf("Expected no pitch from a pure yaw, got %+v", delta)
+	}
+
+	// Swapping the images flips the sign
+	if reversed := ce.EstimateViewpointDelta(yawed(squareOn, 30), squareOn); math.Abs(reversed.YawDegrees+delta.YawDegrees) > 1e-9 {
+		t.Errorf("Expected swapped images to negate the yaw, got %+v vs %+v", reversed, delta)
+	}
+
+	// Without light pairs only the crop's aspect ratio differs, which is not yaw
+	noLights := squareOn
+	noLights.LightPatterns.LightElements = nil
+	recropped := noLights
+	recropped.GeometricFeatures.VehicleProportions.WidthHeightRatio = 16.0 / 9.0
+	if delta := ce.EstimateViewpointDelta(noLights, recropped); delta.YawDegrees != 0 {
+		t.Errorf("Expected no yaw estimate without light pairs, got %+v", delta)
+	}
+}
@@ -0,0 +1,47 @@
+package comparator
+
+import (
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+)
+
+// partialViewOverlap reports whether the pair shares its end view but only one image
+// also shows more of the vehicle, e.g. a straight front against a 3/4 front
+func partialViewOverlap(features1, features2 models.VehicleFeatures) bool {
+	return features1.View == features2.View && features1.AllViews() != features2.AllViews()
+}
+
+// sharedEndView reports whether the two images show a common end of the vehicle.
+// Views, when classified, lists what each image shows; a pair labelled with the same
+// primary View but whose Views share no end view has nothing to compare.
+func sharedEndView(features1, features2 models.VehicleFeatures) bool {
+	ends := models.ViewSetOf(models.ViewFront, models.ViewRear)
+	return labelledViews(features1)&labelledViews(features2)&ends != 0
+}
+
+// labelledViews is the classified view set, or the primary view for features
+// extracted without one
+func labelledViews(features models.VehicleFeatures) models.ViewSet {
+	if features.Views != 0 {
+		return features.Views
+	}
+	return models.ViewSetOf(features.View)
+}
+
+// compareSharedViewGeometry compares the geometry of a partially overlapping pair on
+// the shared end view alone. The side visible in one image stretches its overall
+// proportions, so only structural elements, reference points and the grille are
+// compared. Keypoint matching is not used either, as it assumes the same perspective.
+func (ce *ComparisonEngine) compareSharedViewGeometry(geo1, geo2 models.GeometricFeatures, frame1, frame2 frame) float64 {
+	structuralSimilarity := ce.compareStructuralElements(geo1.StructuralElements, geo2.StructuralElements, frame1, frame2)
+	alignmentSimilarity := ce.compareReferencePoints(geo1.ReferencePoints, geo2.ReferencePoints, frame1, frame2)
+
+	// Same 2:1 balance as compareGeometricFeatures
+	result := structuralSimilarity*2.0/3.0 + alignmentSimilarity/3.0
+
+	if geo1.Grille != nil && geo2.Grille != nil {
+		grilleSimilarity := ce.compareGrilles(*geo1.Grille, *geo2.Grille, frame1, frame2)
+		result = result*(1.0-grilleWeight) + grilleSimilarity*grilleWeight
+	}
+
+	return safeFloat64(result, 0.5)
+}
@@ -0,0 +1,102 @@
+package extractor
+
+import (
+	"image"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+	"gocv.io/x/gocv"
+)
+
+type BumperExtractor struct {
+	minWidthShare float64 // Narrowest outline accepted, as a share of the image width
+	samples       int     // Points in the contour signature
+	closeKernel   int     // Gap (px) bridged between edge fragments of one outline
+}
+
+func NewBumperExtractor() *BumperExtractor {
+	return &BumperExtractor{
+		minWidthShare: 0.25,
+		samples:       32,
+		closeKernel:   7,
+	}
+}
+
+// ExtractContourSignature traces the bumper outline: the widest connected edge
+// contour in the lower half of the image, reduced to its lower edge sampled at
+// evenly spaced columns from left to right. Points are in image coordinates, like
+// every other positional feature; the comparison engine normalizes them to the
+// vehicle frame. Only edges inside mask (see HasMask) are traced. Returns an empty
+// signature when no outline spans enough of the width to be a bumper.
+func (be *BumperExtractor) ExtractContourSignature(img, mask gocv.Mat) []models.Point2D {
+	signature := []models.Point2D{}
+
+	gray := gocv.NewMat()
+	defer gray.Close()
+
+	if img.Channels() > 1 {
+		gocv.CvtColor(img, &gray, gocv.ColorBGRToGray)
+	} else {
+		img.CopyTo(&gray)
+	}
+
+	// Same lower-half search area as detectRearBumperLine
+	lowerRect := image.Rect(0, gray.Rows()/2, gray.Cols(), gray.Rows())
+	if lowerRect.Empty() {
+		return signature
+	}
+	lowerHalf := gray.Region(lowerRect)
+	defer lowerHalf.Close()
+
+	edges := gocv.NewMat()
+	defer edges.Close()
+	gocv.Canny(lowerHalf, &edges, 50, 150)
+	maskBinary(&edges, mask, lowerRect)
+
+	// Join the fragments Canny leaves along one outline
+	kernel := gocv.GetStructuringElement(gocv.MorphRect, image.Pt(be.closeKernel, be.closeKernel))
+	defer kernel.Close()
+	closed := gocv.NewMat()
+	defer closed.Close()
+	gocv.MorphologyEx(edges, &closed, gocv.MorphClose, kernel)
+
+	contours := gocv.FindContours(closed, gocv.RetrievalExternal, gocv.ChainApproxNone)
+	defer contours.Close()
+
+	// The bumper spans the vehicle, so the dominant outline is the widest
+	best, bestWidth := -1, int(be.minWidthShare*float64(gray.Cols()))
+	for i := 0; i < contours.Size(); i++ {
+		if width := gocv.BoundingRect(contours.At(i)).Dx(); width >= bestWidth {
+			best, bestWidth = i, width
+		}
+	}
+	if best < 0 {
+		return signature
+	}
+
+	contour := contours.At(best)
+	rect := gocv.BoundingRect(contour)
+
+	// Lowest outline point in each column
+	lowest := make([]int, rect.Dx())
+	for i := range lowest {
+		lowest[i] = -1
+	}
+	for _, p := range contour.ToPoints() {
+		if column := p.X - rect.Min.X; column >= 0 && column < len(lowest) {
+			lowest[column] = max(lowest[column], p.Y)
+		}
+	}
+
+	for k := 0; k < be.samples; k++ {
+		column := k * (len(lowest) - 1) / max(be.samples-1, 1)
+		if lowest[column] < 0 {
+			continue
+		}
+		signature = append(signature, models.Point2D{
+			X: float64(rect.Min.X + column),
+			Y: float64(lowerRect.Min.Y + lowest[column]),
+		})
+	}
+
+	return signature
+}
@@ -0,0 +1,57 @@
+package extractor
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"gocv.io/x/gocv"
+)
+
+func TestExtractContourSignatureFollowsLowerOutline(t *testing.T) {
+	be := NewBumperExtractor()
+
+	img := gocv.NewMatWithSizeFromScalar(gocv.NewScalar(40, 40, 40, 0), 960, 1280, gocv.MatTypeCV8UC3)
+	defer img.Close()
+
+	// A body whose lower edge steps up on the right half
+	body := color.RGBA{R: 150, G: 150, B: 150}
+	gocv.Rectangle(&img, image.Rect(300, 250, 640, 750), body, -1)
+	gocv.Rectangle(&img, image.Rect(640, 250, 980, 700), body, -1)
+
+	signature := be.ExtractContourSignature(img, gocv.Mat{})
+	if len(signature) < be.samples/2 {
+		t.Fatalf("Expected a sampled outline, got %v", signature)
+	}
+
+	for i, p := range signature {
+		if i > 0 && p.X <= signature[i-1].X {
+			t.Fatalf("Expected points ordered left to right, got %v", signature)
+		}
+		// Away from the step, each point lies on the lower edge below it
+		expected := 750.0
+		if p.X > 650 {
+			expected = 700.0
+		} else if p.X > 630 {
+			continue
+		}
+		if math.Abs(p.Y-expected) > 3 {
+			t.Errorf("Expected the point at x=%.0f on the lower edge at y=%.0f, got y=%.0f", p.X, expected, p.Y)
+		}
+	}
+}
+
+func TestExtractContourSignatureNeedsWideOutline(t *testing.T) {
+	be := NewBumperExtractor()
+
+	img := gocv.NewMatWithSizeFromScalar(gocv.NewScalar(40, 40, 40, 0), 960, 1280, gocv.MatTypeCV8UC3)
+	defer img.Close()
+
+	// A small patch is no bumper
+	gocv.Rectangle(&img, image.Rect(600, 600, 700, 680), color.RGBA{R: 200, G: 200, B: 200}, -1)
+
+	if signature := be.ExtractContourSignature(img, gocv.Mat{}); len(signature) != 0 {
+		t.Errorf("Expected no outline from a narrow patch, got %v", signature)
+	}
+}
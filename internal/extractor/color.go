@@ -0,0 +1,229 @@
+package extractor
+
+import (
+	"image"
+	"sort"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/matstat"
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+	"gocv.io/x/gocv"
+)
+
+const (
+	colorSampleWidth  = 160 // Images are downsampled to this width before color analysis
+	colorLevels       = 4   // Quantization levels per channel (64 color bins)
+	maxDominantColors = 3
+
+	// grayscaleSaturation is the weighted mean HSV-style saturation of the dominant
+	// colors below which a profile is treated as colorless
+	grayscaleSaturation = 0.08
+
+	hueBins        = 30 // 6° per bin (OpenCV hue spans 0-179)
+	saturationBins = 32
+
+	// Pixels darker or less saturated than this (0-255) have no reliable hue
+	minChromaticValue      = 40
+	minChromaticSaturation = 40
+)
+
+type ColorExtractor struct{}
+
+func NewColorExtractor() *ColorExtractor {
+	return &ColorExtractor{}
+}
+
+// ExtractColorProfile quantizes a downsampled copy of the image into coarse color bins
+// and reports the mean color of the most populated bins, weighted by pixel share,
+// along with a 256-bin intensity histogram and normalized hue/saturation histograms.
+// Only pixels inside mask (see HasMask) are counted.
+func (ce *ColorExtractor) ExtractColorProfile(img, mask gocv.Mat) models.ColorProfile {
+	profile := models.ColorProfile{
+		DominantColors: []models.Color{},
+		Histogram:      make([]int, 256),
+	}
+
+	if img.Empty() || img.Channels() != 3 {
+		return profile
+	}
+
+	sample := gocv.NewMat()
+	defer sample.Close()
+
+	if img.Cols() > colorSampleWidth {
+		height := img.Rows() * colorSampleWidth / img.Cols()
+		if height < 1 {
+			height = 1
+		}
+		gocv.Resize(img, &sample, image.Pt(colorSampleWidth, height), 0, 0, gocv.InterpolationArea)
+	} else {
+		img.CopyTo(&sample)
+	}
+
+	// The mask at the sample's size; nil to count every pixel
+	var inMask func(y, x int) bool
+	if HasMask(mask) {
+		sampleMask := gocv.NewMat()
+		defer sampleMask.Close()
+		gocv.Resize(mask, &sampleMask, image.Pt(sample.Cols(), sample.Rows()), 0, 0, gocv.InterpolationNearestNeighbor)
+		inMask = func(y, x int) bool { return sampleMask.GetUCharAt(y, x) > 0 }
+	}
+
+	type colorBin struct {
+		count            int
+		sumB, sumG, sumR int
+	}
+	bins := make([]colorBin, colorLevels*colorLevels*colorLevels)
+	step := 256 / colorLevels
+	total := 0
+
+	for y := 0; y < sample.Rows(); y++ {
+		for x := 0; x < sample.Cols(); x++ {
+			if inMask != nil && !inMask(y, x) {
+				continue
+			}
+			total++
+
+			pixel := sample.GetVecbAt(y, x)
+			b, g, r := int(pixel[0]), int(pixel[1]), int(pixel[2])
+
+			index := (b/step)*colorLevels*colorLevels + (g/step)*colorLevels + r/step
+			bins[index].count++
+			bins[index].sumB += b
+			bins[index].sumG += g
+			bins[index].sumR += r
+
+			// ITU-R BT.601 luma, matching OpenCV's BGR to gray conversion
+			intensity := (299*r + 587*g + 114*b) / 1000
+			profile.Histogram[intensity]++
+		}
+	}
+
+	if total == 0 {
+		return profile
+	}
+
+	if matstat.IsMonochrome(sample) {
+		profile.HueHistogram, profile.SaturationHistogram = monochromeHistograms()
+	} else {
+		profile.HueHistogram, profile.SaturationHistogram = ce.hueSaturationHistograms(sample, inMask)
+	}
+
+	order := make([]int, len(bins))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return bins[order[i]].count > bins[order[j]].count
+	})
+
+	for _, index := range order {
+		bin := bins[index]
+		if bin.count == 0 || len(profile.DominantColors) >= maxDominantColors {
+			break
+		}
+
+		profile.DominantColors = append(profile.DominantColors, models.Color{
+			R:      uint8(bin.sumR / bin.count),
+			G:      uint8(bin.sumG / bin.count),
+			B:      uint8(bin.sumB / bin.count),
+			Weight: float64(bin.count) / float64(total),
+		})
+	}
+
+	return profile
+}
+
+// hueSaturationHistograms returns hue and saturation distributions normalized to sum
+// to 1. Hue is only counted for chromatic pixels, where it is meaningful, and only
+// pixels inMask reports are counted at all, unless it is nil.
+func (ce *ColorExtractor) hueSaturationHistograms(sample gocv.Mat, inMask func(y, x int) bool) ([]float64, []float64) {
+	hsv := gocv.NewMat()
+	defer hsv.Close()
+	toHSV(sample, &hsv)
+
+	hue := make([]float64, hueBins)
+	saturation := make([]float64, saturationBins)
+	hueCount, saturationCount := 0, 0
+
+	for y := 0; y < hsv.Rows(); y++ {
+		for x := 0; x < hsv.Cols(); x++ {
+			if inMask != nil && !inMask(y, x) {
+				continue
+			}
+
+			pixel := hsv.GetVecbAt(y, x)
+			h, s, v := int(pixel[0]), int(pixel[1]), int(pixel[2])
+
+			saturation[s*saturationBins/256]++
+			saturationCount++
+
+			if s >= minChromaticSaturation && v >= minChromaticValue {
+				hue[min(h*hueBins/180, hueBins-1)]++
+				hueCount++
+			}
+		}
+	}
+
+	normalizeHistogram(hue, hueCount)
+	normalizeHistogram(saturation, saturationCount)
+	return hue, saturation
+}
+
+// monochromeHistograms are the hue and saturation histograms of a gray image: no
+// chromatic pixels, and every pixel at zero saturation
+func monochromeHistograms() ([]float64, []float64) {
+	saturation := make([]float64, saturationBins)
+	saturation[0] = 1.0
+	return make([]float64, hueBins), saturation
+}
+
+func normalizeHistogram(histogram []float64, count int) {
+	if count == 0 {
+		return
+	}
+	for i := range histogram {
+		histogram[i] /= float64(count)
+	}
+}
+
+// IsGrayscaleProfile reports whether a color profile carries effectively no hue
+// information. Grayscale and IR captures stored as 3-channel images produce such
+// profiles, and color comparison on them is meaningless.
+func IsGrayscaleProfile(profile models.ColorProfile) bool {
+	if len(profile.DominantColors) == 0 {
+		return true
+	}
+
+	weightedSaturation := 0.0
+	totalWeight := 0.0
+	for _, c := range profile.DominantColors {
+		weightedSaturation += colorSaturation(c) * c.Weight
+		totalWeight += c.Weight
+	}
+
+	if totalWeight == 0 {
+		return true
+	}
+
+	return weightedSaturation/totalWeight < grayscaleSaturation
+}
+
+// colorSaturation returns the HSV saturation of a color in [0, 1]
+func colorSaturation(c models.Color) float64 {
+	maxChannel := c.R
+	minChannel := c.R
+	for _, channel := range []uint8{c.G, c.B} {
+		if channel > maxChannel {
+			maxChannel = channel
+		}
+		if channel < minChannel {
+			minChannel = channel
+		}
+	}
+
+	if maxChannel == 0 {
+		return 0
+	}
+
+	return float64(maxChannel-minChannel) / float64(maxChannel)
+}
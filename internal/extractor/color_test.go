@@ -0,0 +1,63 @@
+package extractor
+
+import (
+	"image"
+	"math"
+	"testing"
+
+	"gocv.io/x/gocv"
+)
+
+func TestGrayscaleProfileDetection(t *testing.T) {
+	ce := NewColorExtractor()
+
+	gray := gocv.NewMatWithSizeFromScalar(gocv.NewScalar(120, 120, 120, 0), 240, 320, gocv.MatTypeCV8UC3)
+	defer gray.Close()
+	if profile := ce.ExtractColorProfile(gray, gocv.Mat{}); !IsGrayscaleProfile(profile) {
+		t.Errorf("Expected gray image to produce a grayscale profile, got %+v", profile.DominantColors)
+	}
+
+	red := gocv.NewMatWithSizeFromScalar(gocv.NewScalar(40, 40, 200, 0), 240, 320, gocv.MatTypeCV8UC3)
+	defer red.Close()
+	profile := ce.ExtractColorProfile(red, gocv.Mat{})
+	if IsGrayscaleProfile(profile) {
+		t.Errorf("Expected red image to produce a color profile, got %+v", profile.DominantColors)
+	}
+	if len(profile.DominantColors) == 0 || profile.DominantColors[0].R < profile.DominantColors[0].B {
+		t.Errorf("Expected a red dominant color, got %+v", profile.DominantColors)
+	}
+}
+
+func TestHueHistogramIgnoresExposure(t *testing.T) {
+	ce := NewColorExtractor()
+
+	scene := func(scale float64) gocv.Mat {
+		img := gocv.NewMatWithSizeFromScalar(gocv.NewScalar(40*scale, 40*scale, 200*scale, 0), 240, 320, gocv.MatTypeCV8UC3)
+		trim := img.Region(image.Rect(0, 180, 320, 240))
+		trim.SetTo(gocv.NewScalar(200*scale, 60*scale, 40*scale, 0))
+		trim.Close()
+		return img
+	}
+
+	bright := scene(1.0)
+	defer bright.Close()
+	dark := scene(0.6)
+	defer dark.Close()
+
+	brightProfile := ce.ExtractColorProfile(bright, gocv.Mat{})
+	darkProfile := ce.ExtractColorProfile(dark, gocv.Mat{})
+
+	sum := 0.0
+	overlap := 0.0
+	for i := range brightProfile.HueHistogram {
+		sum += brightProfile.HueHistogram[i]
+		overlap += math.Min(brightProfile.HueHistogram[i], darkProfile.HueHistogram[i])
+	}
+
+	if math.Abs(sum-1.0) > 1e-6 {
+		t.Errorf("Expected a normalized hue histogram, sums to %.3f", sum)
+	}
+	if overlap < 0.95 {
+		t.Errorf("Expected exposure change to keep the hue distribution, overlap %.3f", overlap)
+	}
+}
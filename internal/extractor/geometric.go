@@ -1,10 +1,13 @@
 package extractor
 
 import (
+	"github.com/choff5507/vehicle-image-comparison/internal/matstat"
 	"github.com/choff5507/vehicle-image-comparison/internal/models"
 	"gocv.io/x/gocv"
 	"image"
+	"image/color"
 	"math"
+	"sort"
 )
 
 type GeometricExtractor struct{}
@@ -13,23 +16,29 @@ func NewGeometricExtractor() *GeometricExtractor {
 	return &GeometricExtractor{}
 }
 
-// ExtractGeometricFeatures extracts view-consistent geometric features
-func (ge *GeometricExtractor) ExtractGeometricFeatures(img gocv.Mat, view models.VehicleView) (models.GeometricFeatures, error) {
+// ExtractGeometricFeatures extracts view-consistent geometric features from the
+// edges and bright regions inside mask (see HasMask)
+func (ge *GeometricExtractor) ExtractGeometricFeatures(img, mask gocv.Mat, view models.VehicleView) (models.GeometricFeatures, error) {
 	features := models.GeometricFeatures{}
 	
 	// Extract vehicle proportions
-	features.VehicleProportions = ge.extractVehicleProportions(img)
+	features.VehicleProportions = ge.extractVehicleProportions(img, mask)
 	
 	// Extract structural elements based on view
-	features.StructuralElements = ge.extractStructuralElements(img, view)
+	features.StructuralElements = ge.extractStructuralElements(img, mask, view)
 	
 	// Extract reference points for alignment
-	features.ReferencePoints = ge.extractReferencePoints(img, view)
+	features.ReferencePoints = ge.extractReferencePoints(img, mask, view)
+	
+	// Describe the grille pattern
+	if view == models.ViewFront {
+		features.Grille = ge.extractGrille(img, mask)
+	}
 	
 	return features, nil
 }
 
-func (ge *GeometricExtractor) extractVehicleProportions(img gocv.Mat) models.VehicleProportions {
+func (ge *GeometricExtractor) extractVehicleProportions(img, mask gocv.Mat) models.VehicleProportions {
 	height := float64(img.Rows())
 	width := float64(img.Cols())
 	
@@ -37,10 +46,10 @@ func (ge *GeometricExtractor) extractVehicleProportions(img gocv.Mat) models.Veh
 	widthHeightRatio := width / height
 	
 	// Estimate upper/lower vehicle proportions
-	upperLowerRatio := ge.calculateUpperLowerRatio(img)
+	upperLowerRatio := ge.calculateUpperLowerRatio(img, mask)
 	
 	// License plate proportion (if detectable)
-	licensePlateRatio := ge.estimateLicensePlateRatio(img)
+	licensePlateRatio := ge.estimateLicensePlateRatio(img, mask)
 	
 	return models.VehicleProportions{
 		WidthHeightRatio:  widthHeightRatio,
@@ -49,7 +58,7 @@ func (ge *GeometricExtractor) extractVehicleProportions(img gocv.Mat) models.Veh
 	}
 }
 
-func (ge *GeometricExtractor) calculateUpperLowerRatio(img gocv.Mat) float64 {
+func (ge *GeometricExtractor) calculateUpperLowerRatio(img, mask gocv.Mat) float64 {
 	// Find horizontal edge that divides upper/lower vehicle
 	gray := gocv.NewMat()
 	defer gray.Close()
@@ -64,6 +73,7 @@ func (ge *GeometricExtractor) calculateUpperLowerRatio(img gocv.Mat) float64 {
 	edges := gocv.NewMat()
 	defer edges.Close()
 	gocv.Canny(gray, &edges, 50, 150)
+	maskBinary(&edges, mask, fullRect(img))
 	
 	// Find horizontal lines using Hough transform
 	lines := gocv.NewMat()
@@ -86,17 +96,19 @@ func (ge *GeometricExtractor) findVehicleDividingLine(lines gocv.Mat, imageHeigh
 	// Analyze detected lines to find the main horizontal division
 	// This would typically be around the bumper line
 	
-	horizontalLines := []int{}
+	horizontalLines := []horizontalSegment{}
 	
 	for i := 0; i < lines.Rows(); i++ {
-		x1 := int(lines.GetFloatAt(i, 0))
-		y1 := int(lines.GetFloatAt(i, 1))
-		x2 := int(lines.GetFloatAt(i, 2))
-		y2 := int(lines.GetFloatAt(i, 3))
+		// HoughLinesP returns CV_32SC4 rows of x1, y1, x2, y2
+		line := lines.GetVeciAt(i, 0)
+		x1, y1, x2, y2 := int(line[0]), int(line[1]), int(line[2]), int(line[3])
 		
 		// Check if line is approximately horizontal
 		if math.Abs(float64(y2-y1)) < 10 && math.Abs(float64(x2-x1)) > 50 {
-			horizontalLines = append(horizontalLines, (y1+y2)/2)
+			horizontalLines = append(horizontalLines, horizontalSegment{
+				y:      float64(y1+y2) / 2,
+				length: math.Abs(float64(x2 - x1)),
+			})
 		}
 	}
 	
@@ -104,11 +116,68 @@ func (ge *GeometricExtractor) findVehicleDividingLine(lines gocv.Mat, imageHeigh
 		return 0
 	}
 	
-	// Find the most prominent horizontal line (simple mode calculation)
-	return ge.findMostCommonValue(horizontalLines)
+	return int(math.Round(dominantLineY(horizontalLines, dividingLineBinSize)))
+}
+
+// Height in pixels of the bins used to cluster horizontal lines
+const dividingLineBinSize = 10.0
+
+type horizontalSegment struct {
+	y      float64
+	length float64
 }
 
-func (ge *GeometricExtractor) estimateLicensePlateRatio(img gocv.Mat) float64 {
+// dominantLineY finds the y position most supported by the segments. Segments are
+// binned by height with each weighted by its length; the heaviest window of three
+// adjacent bins wins, and the length-weighted median of the segments inside it is
+// returned. Pixel-level jitter between detections of the same edge is absorbed.
+func dominantLineY(segments []horizontalSegment, binSize float64) float64 {
+	if len(segments) == 0 {
+		return 0
+	}
+	
+	weights := make(map[int]float64)
+	for _, segment := range segments {
+		weights[int(math.Floor(segment.y/binSize))] += segment.length
+	}
+	
+	// Pick the heaviest window; ties go to the lower bin so the result is deterministic
+	bestBin := 0
+	bestWeight := -1.0
+	for bin := range weights {
+		windowWeight := weights[bin-1] + weights[bin] + weights[bin+1]
+		if windowWeight > bestWeight || (windowWeight == bestWeight && bin < bestBin) {
+			bestBin = bin
+			bestWeight = windowWeight
+		}
+	}
+	
+	// Length-weighted median of the segments within the winning window
+	cluster := []horizontalSegment{}
+	totalLength := 0.0
+	for _, segment := range segments {
+		if bin := int(math.Floor(segment.y / binSize)); bin >= bestBin-1 && bin <= bestBin+1 {
+			cluster = append(cluster, segment)
+			totalLength += segment.length
+		}
+	}
+	
+	sort.Slice(cluster, func(i, j int) bool {
+		return cluster[i].y < cluster[j].y
+	})
+	
+	cumulative := 0.0
+	for _, segment := range cluster {
+		cumulative += segment.length
+		if cumulative >= totalLength/2 {
+			return segment.y
+		}
+	}
+	
+	return cluster[len(cluster)-1].y
+}
+
+func (ge *GeometricExtractor) estimateLicensePlateRatio(img, mask gocv.Mat) float64 {
 	// Detect rectangular regions that could be license plates
 	gray := gocv.NewMat()
 	defer gray.Close()
@@ -123,6 +192,7 @@ func (ge *GeometricExtractor) estimateLicensePlateRatio(img gocv.Mat) float64 {
 	edges := gocv.NewMat()
 	defer edges.Close()
 	gocv.Canny(gray, &edges, 50, 150)
+	maskBinary(&edges, mask, fullRect(img))
 	
 	// Find contours
 	contours := gocv.FindContours(edges, gocv.RetrievalExternal, gocv.ChainApproxSimple)
@@ -151,62 +221,62 @@ func (ge *GeometricExtractor) estimateLicensePlateRatio(img gocv.Mat) float64 {
 	return 0.0 // No license plate detected
 }
 
-func (ge *GeometricExtractor) extractStructuralElements(img gocv.Mat, view models.VehicleView) []models.StructuralElement {
+func (ge *GeometricExtractor) extractStructuralElements(img, mask gocv.Mat, view models.VehicleView) []models.StructuralElement {
 	elements := []models.StructuralElement{}
 	
 	switch view {
 	case models.ViewFront:
-		elements = append(elements, ge.extractFrontStructuralElements(img)...)
+		elements = append(elements, ge.extractFrontStructuralElements(img, mask)...)
 	case models.ViewRear:
-		elements = append(elements, ge.extractRearStructuralElements(img)...)
+		elements = append(elements, ge.extractRearStructuralElements(img, mask)...)
 	}
 	
 	return elements
 }
 
-func (ge *GeometricExtractor) extractFrontStructuralElements(img gocv.Mat) []models.StructuralElement {
+func (ge *GeometricExtractor) extractFrontStructuralElements(img, mask gocv.Mat) []models.StructuralElement {
 	// Extract front-specific structural elements
 	elements := []models.StructuralElement{}
 	
 	// Detect headlight regions
-	headlights := ge.detectHeadlightRegions(img)
+	headlights := ge.detectHeadlightRegions(img, mask)
 	for _, hl := range headlights {
 		elements = append(elements, models.StructuralElement{
 			Type:     "headlight",
 			Position: hl,
-			Size:     100.0, // Placeholder
+			Size:     float64(img.Cols()) * 0.08, // Placeholder: ~100px at 1280px wide
 		})
 	}
 	
 	// Detect grille area
-	grilleCenter := ge.detectGrilleCenter(img)
+	grilleCenter := ge.detectGrilleCenter(img, mask)
 	if grilleCenter.X > 0 && grilleCenter.Y > 0 {
 		elements = append(elements, models.StructuralElement{
 			Type:     "grille",
 			Position: grilleCenter,
-			Size:     200.0, // Placeholder
+			Size:     float64(img.Cols()) * 0.16, // Placeholder: ~200px at 1280px wide
 		})
 	}
 	
 	return elements
 }
 
-func (ge *GeometricExtractor) extractRearStructuralElements(img gocv.Mat) []models.StructuralElement {
+func (ge *GeometricExtractor) extractRearStructuralElements(img, mask gocv.Mat) []models.StructuralElement {
 	// Extract rear-specific structural elements
 	elements := []models.StructuralElement{}
 	
 	// Detect taillight regions
-	taillights := ge.detectTaillightRegions(img)
+	taillights := ge.detectTaillightRegions(img, mask)
 	for _, tl := range taillights {
 		elements = append(elements, models.StructuralElement{
 			Type:     "taillight",
 			Position: tl,
-			Size:     80.0, // Placeholder
+			Size:     float64(img.Cols()) * 0.06, // Placeholder: ~80px at 1280px wide
 		})
 	}
 	
 	// Detect rear bumper line
-	bumperLine := ge.detectRearBumperLine(img)
+	bumperLine := ge.detectRearBumperLine(img, mask)
 	if bumperLine.X > 0 && bumperLine.Y > 0 {
 		elements = append(elements, models.StructuralElement{
 			Type:     "bumper_line",
@@ -218,7 +288,7 @@ func (ge *GeometricExtractor) extractRearStructuralElements(img gocv.Mat) []mode
 	return elements
 }
 
-func (ge *GeometricExtractor) detectHeadlightRegions(img gocv.Mat) []models.Point2D {
+func (ge *GeometricExtractor) detectHeadlightRegions(img, mask gocv.Mat) []models.Point2D {
 	points := []models.Point2D{}
 	
 	gray := gocv.NewMat()
@@ -234,6 +304,7 @@ func (ge *GeometricExtractor) detectHeadlightRegions(img gocv.Mat) []models.Poin
 	threshold := gocv.NewMat()
 	defer threshold.Close()
 	gocv.Threshold(gray, &threshold, 200.0, 255, gocv.ThresholdBinary)
+	maskBinary(&threshold, mask, fullRect(img))
 	
 	// Focus on upper half of image
 	upperRect := image.Rect(0, 0, threshold.Cols(), threshold.Rows()/2)
@@ -247,38 +318,36 @@ func (ge *GeometricExtractor) detectHeadlightRegions(img gocv.Mat) []models.Poin
 		contour := contours.At(i)
 		area := gocv.ContourArea(contour)
 		if area > 100 && area < 5000 {
-			rect := gocv.BoundingRect(contour)
-			centerX := float64(rect.Min.X + rect.Dx()/2)
-			centerY := float64(rect.Min.Y + rect.Dy()/2)
-			points = append(points, models.Point2D{X: centerX, Y: centerY})
+			points = append(points, contourCentroid(contour))
 		}
 	}
 	
 	return points
 }
 
-func (ge *GeometricExtractor) detectTaillightRegions(img gocv.Mat) []models.Point2D {
+func (ge *GeometricExtractor) detectTaillightRegions(img, mask gocv.Mat) []models.Point2D {
 	points := []models.Point2D{}
 	
-	// Try to detect red regions if color image
-	if img.Channels() > 1 {
-		points = ge.detectRedRegionCenters(img)
+	// Try to detect red regions if color image; grayscale images stored as color
+	// have none
+	if !matstat.IsMonochrome(img) {
+		points = ge.detectRedRegionCenters(img, mask)
 	}
 	
 	// Fallback to brightness detection
 	if len(points) == 0 {
-		points = ge.detectBrightRegionCenters(img)
+		points = ge.detectBrightRegionCenters(img, mask)
 	}
 	
 	return points
 }
 
-func (ge *GeometricExtractor) detectRedRegionCenters(img gocv.Mat) []models.Point2D {
+func (ge *GeometricExtractor) detectRedRegionCenters(img, mask gocv.Mat) []models.Point2D {
 	points := []models.Point2D{}
 	
 	hsv := gocv.NewMat()
 	defer hsv.Close()
-	gocv.CvtColor(img, &hsv, gocv.ColorBGRToHSV)
+	toHSV(img, &hsv)
 	
 	// Define red color ranges as scalars
 	lowerRed1 := gocv.NewScalar(0, 50, 50, 0)
@@ -296,6 +365,7 @@ func (ge *GeometricExtractor) detectRedRegionCenters(img gocv.Mat) []models.Poin
 	gocv.InRangeWithScalar(hsv, lowerRed1, upperRed1, &mask1)
 	gocv.InRangeWithScalar(hsv, lowerRed2, upperRed2, &mask2)
 	gocv.BitwiseOr(mask1, mask2, &redMask)
+	maskBinary(&redMask, mask, fullRect(img))
 	
 	contours := gocv.FindContours(redMask, gocv.RetrievalExternal, gocv.ChainApproxSimple)
 	defer contours.Close()
@@ -304,17 +374,14 @@ func (ge *GeometricExtractor) detectRedRegionCenters(img gocv.Mat) []models.Poin
 		contour := contours.At(i)
 		area := gocv.ContourArea(contour)
 		if area > 200 && area < 8000 {
-			rect := gocv.BoundingRect(contour)
-			centerX := float64(rect.Min.X + rect.Dx()/2)
-			centerY := float64(rect.Min.Y + rect.Dy()/2)
-			points = append(points, models.Point2D{X: centerX, Y: centerY})
+			points = append(points, contourCentroid(contour))
 		}
 	}
 	
 	return points
 }
 
-func (ge *GeometricExtractor) detectBrightRegionCenters(img gocv.Mat) []models.Point2D {
+func (ge *GeometricExtractor) detectBrightRegionCenters(img, mask gocv.Mat) []models.Point2D {
 	points := []models.Point2D{}
 	
 	gray := gocv.NewMat()
@@ -329,6 +396,7 @@ func (ge *GeometricExtractor) detectBrightRegionCenters(img gocv.Mat) []models.P
 	threshold := gocv.NewMat()
 	defer threshold.Close()
 	gocv.Threshold(gray, &threshold, 180.0, 255, gocv.ThresholdBinary)
+	maskBinary(&threshold, mask, fullRect(img))
 	
 	contours := gocv.FindContours(threshold, gocv.RetrievalExternal, gocv.ChainApproxSimple)
 	defer contours.Close()
@@ -337,17 +405,14 @@ func (ge *GeometricExtractor) detectBrightRegionCenters(img gocv.Mat) []models.P
 		contour := contours.At(i)
 		area := gocv.ContourArea(contour)
 		if area > 300 && area < 10000 {
-			rect := gocv.BoundingRect(contour)
-			centerX := float64(rect.Min.X + rect.Dx()/2)
-			centerY := float64(rect.Min.Y + rect.Dy()/2)
-			points = append(points, models.Point2D{X: centerX, Y: centerY})
+			points = append(points, contourCentroid(contour))
 		}
 	}
 	
 	return points
 }
 
-func (ge *GeometricExtractor) detectGrilleCenter(img gocv.Mat) models.Point2D {
+func (ge *GeometricExtractor) detectGrilleCenter(img, mask gocv.Mat) models.Point2D {
 	gray := gocv.NewMat()
 	defer gray.Close()
 	
@@ -365,6 +430,7 @@ func (ge *GeometricExtractor) detectGrilleCenter(img gocv.Mat) models.Point2D {
 	edges := gocv.NewMat()
 	defer edges.Close()
 	gocv.Canny(centerRegion, &edges, 50, 150)
+	maskBinary(&edges, mask, centerRect)
 	
 	// Find center of mass of edge points
 	var sumX, sumY, count float64
@@ -385,7 +451,7 @@ func (ge *GeometricExtractor) detectGrilleCenter(img gocv.Mat) models.Point2D {
 	return models.Point2D{X: 0, Y: 0}
 }
 
-func (ge *GeometricExtractor) detectRearBumperLine(img gocv.Mat) models.Point2D {
+func (ge *GeometricExtractor) detectRearBumperLine(img, mask gocv.Mat) models.Point2D {
 	gray := gocv.NewMat()
 	defer gray.Close()
 	
@@ -403,6 +469,7 @@ func (ge *GeometricExtractor) detectRearBumperLine(img gocv.Mat) models.Point2D
 	edges := gocv.NewMat()
 	defer edges.Close()
 	gocv.Canny(lowerHalf, &edges, 50, 150)
+	maskBinary(&edges, mask, lowerRect)
 	
 	lines := gocv.NewMat()
 	defer lines.Close()
@@ -433,31 +500,31 @@ func (ge *GeometricExtractor) detectRearBumperLine(img gocv.Mat) models.Point2D
 	return bestLine
 }
 
-func (ge *GeometricExtractor) extractReferencePoints(img gocv.Mat, view models.VehicleView) []models.Point2D {
+func (ge *GeometricExtractor) extractReferencePoints(img, mask gocv.Mat, view models.VehicleView) []models.Point2D {
 	points := []models.Point2D{}
 	
 	// Extract key reference points for image alignment
 	switch view {
 	case models.ViewFront:
 		// Front reference points: headlight centers, grille center, bumper corners
-		points = append(points, ge.findFrontReferencePoints(img)...)
+		points = append(points, ge.findFrontReferencePoints(img, mask)...)
 	case models.ViewRear:
 		// Rear reference points: taillight centers, license plate center, bumper corners
-		points = append(points, ge.findRearReferencePoints(img)...)
+		points = append(points, ge.findRearReferencePoints(img, mask)...)
 	}
 	
 	return points
 }
 
-func (ge *GeometricExtractor) findFrontReferencePoints(img gocv.Mat) []models.Point2D {
+func (ge *GeometricExtractor) findFrontReferencePoints(img, mask gocv.Mat) []models.Point2D {
 	points := []models.Point2D{}
 	
 	// Add headlight centers
-	headlights := ge.detectHeadlightRegions(img)
+	headlights := ge.detectHeadlightRegions(img, mask)
 	points = append(points, headlights...)
 	
 	// Add grille center
-	grilleCenter := ge.detectGrilleCenter(img)
+	grilleCenter := ge.detectGrilleCenter(img, mask)
 	if grilleCenter.X > 0 && grilleCenter.Y > 0 {
 		points = append(points, grilleCenter)
 	}
@@ -471,15 +538,15 @@ func (ge *GeometricExtractor) findFrontReferencePoints(img gocv.Mat) []models.Po
 	return points
 }
 
-func (ge *GeometricExtractor) findRearReferencePoints(img gocv.Mat) []models.Point2D {
+func (ge *GeometricExtractor) findRearReferencePoints(img, mask gocv.Mat) []models.Point2D {
 	points := []models.Point2D{}
 	
 	// Add taillight centers
-	taillights := ge.detectTaillightRegions(img)
+	taillights := ge.detectTaillightRegions(img, mask)
 	points = append(points, taillights...)
 	
 	// Add bumper line center
-	bumperLine := ge.detectRearBumperLine(img)
+	bumperLine := ge.detectRearBumperLine(img, mask)
 	if bumperLine.X > 0 && bumperLine.Y > 0 {
 		points = append(points, bumperLine)
 	}
@@ -493,25 +560,35 @@ func (ge *GeometricExtractor) findRearReferencePoints(img gocv.Mat) []models.Poi
 	return points
 }
 
-func (ge *GeometricExtractor) findMostCommonValue(values []int) int {
-	if len(values) == 0 {
-		return 0
-	}
-	
-	frequency := make(map[int]int)
-	for _, value := range values {
-		frequency[value]++
+// contourCentroid returns the centroid of the region a contour encloses, from its
+// image moments. Bounding-rect midpoints are off for non-rectangular blobs such as
+// L-shaped taillights or angled headlights.
+func contourCentroid(contour gocv.PointVector) models.Point2D {
+	rect := gocv.BoundingRect(contour)
+	
+	// Rasterize the contour into a mask of its bounding rect; moments of the
+	// outline points alone would weight dense stretches of the contour
+	points := contour.ToPoints()
+	for i := range points {
+		points[i] = points[i].Sub(rect.Min)
 	}
-	
-	maxCount := 0
-	mostCommon := values[0]
-	
-	for value, count := range frequency {
-		if count > maxCount {
-			maxCount = count
-			mostCommon = value
+	outline := gocv.NewPointsVectorFromPoints([][]image.Point{points})
+	defer outline.Close()
+	
+	mask := gocv.NewMatWithSizeFromScalar(gocv.NewScalar(0, 0, 0, 0), rect.Dy()+1, rect.Dx()+1, gocv.MatTypeCV8U)
+	defer mask.Close()
+	gocv.FillPoly(&mask, outline, color.RGBA{R: 255, G: 255, B: 255})
+	
+	moments := gocv.Moments(mask, true)
+	if moments["m00"] == 0 {
+		return models.Point2D{
+			X: float64(rect.Min.X) + float64(rect.Dx())/2.0,
+			Y: float64(rect.Min.Y) + float64(rect.Dy())/2.0,
 		}
 	}
 	
-	return mostCommon
+	return models.Point2D{
+		X: float64(rect.Min.X) + moments["m10"]/moments["m00"],
+		Y: float64(rect.Min.Y) + moments["m01"]/moments["m00"],
+	}
 }
\ No newline at end of file
@@ -0,0 +1,112 @@
+package extractor
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"gocv.io/x/gocv"
+)
+
+func TestDominantLineYFindsCluster(t *testing.T) {
+	segments := []horizontalSegment{
+		// Bumper edge detected several times with pixel-level jitter
+		{y: 698, length: 400},
+		{y: 701, length: 520},
+		{y: 703, length: 380},
+		{y: 699.5, length: 300},
+		// Scattered one-off lines elsewhere
+		{y: 250, length: 600},
+		{y: 410, length: 120},
+		{y: 455, length: 90},
+		{y: 880, length: 200},
+	}
+
+	if y := dominantLineY(segments, dividingLineBinSize); math.Abs(y-700) > 5 {
+		t.Errorf("Expected division near the clustered lines at y=700, got %.1f", y)
+	}
+
+	// Input order must not affect the result
+	reversed := make([]horizontalSegment, len(segments))
+	for i, segment := range segments {
+		reversed[len(segments)-1-i] = segment
+	}
+	if dominantLineY(reversed, dividingLineBinSize) != dominantLineY(segments, dividingLineBinSize) {
+		t.Error("Expected the same division regardless of segment order")
+	}
+}
+
+func TestExtractGrilleDistinguishesMeshFromSlats(t *testing.T) {
+	ge := NewGeometricExtractor()
+	white := color.RGBA{R: 255, G: 255, B: 255}
+
+	// Dense mesh: small openings on an 8px pitch
+	mesh := gocv.NewMatWithSizeFromScalar(gocv.NewScalar(200, 200, 200, 0), 480, 640, gocv.MatTypeCV8UC3)
+	defer mesh.Close()
+	gocv.Rectangle(&mesh, image.Rect(200, 160, 440, 320), color.RGBA{R: 20, G: 20, B: 20}, -1)
+	for y := 162; y < 318; y += 8 {
+		for x := 202; x < 438; x += 8 {
+			gocv.Rectangle(&mesh, image.Rect(x, y, x+4, y+4), white, -1)
+		}
+	}
+
+	// Wide slats: 6px bars on a 30px pitch
+	slats := gocv.NewMatWithSizeFromScalar(gocv.NewScalar(20, 20, 20, 0), 480, 640, gocv.MatTypeCV8UC3)
+	defer slats.Close()
+	for y := 160; y < 320; y += 30 {
+		gocv.Rectangle(&slats, image.Rect(200, y, 440, y+6), white, -1)
+	}
+
+	meshGrille := ge.extractGrille(mesh, gocv.Mat{})
+	slatGrille := ge.extractGrille(slats, gocv.Mat{})
+	if meshGrille == nil || slatGrille == nil {
+		t.Fatalf("Expected both grilles to be detected, got mesh %v, slats %v", meshGrille, slatGrille)
+	}
+
+	if meshGrille.LineSpacing == 0 || meshGrille.LineSpacing >= slatGrille.LineSpacing {
+		t.Errorf("Expected mesh line spacing below slat spacing, got %.1f vs %.1f", meshGrille.LineSpacing, slatGrille.LineSpacing)
+	}
+	if math.Abs(slatGrille.LineSpacing-30) > 2 {
+		t.Errorf("Expected slat spacing near 30px, got %.1f", slatGrille.LineSpacing)
+	}
+	if meshGrille.EdgeDensity <= slatGrille.EdgeDensity {
+		t.Errorf("Expected mesh edge density above slats, got %.3f vs %.3f", meshGrille.EdgeDensity, slatGrille.EdgeDensity)
+	}
+}
+
+func TestContourCentroidOfLShapedBlob(t *testing.T) {
+	mask := gocv.NewMatWithSizeFromScalar(gocv.NewScalar(0, 0, 0, 0), 200, 200, gocv.MatTypeCV8U)
+	defer mask.Close()
+
+	// An L-shaped taillight: a 100x20 bar with a 20x80 leg hanging from its left end
+	white := color.RGBA{R: 255, G: 255, B: 255}
+	gocv.Rectangle(&mask, image.Rect(50, 50, 150, 70), white, -1)
+	gocv.Rectangle(&mask, image.Rect(50, 70, 70, 150), white, -1)
+
+	contours := gocv.FindContours(mask, gocv.RetrievalExternal, gocv.ChainApproxSimple)
+	defer contours.Close()
+	if contours.Size() != 1 {
+		t.Fatalf("Expected one blob, got %d", contours.Size())
+	}
+	contour := contours.At(0)
+
+	// Area-weighted centroid of the two rectangles, in pixel-center coordinates
+	barArea, legArea := 100.0*20.0, 20.0*80.0
+	expectedX := (barArea*99.5 + legArea*59.5) / (barArea + legArea)
+	expectedY := (barArea*59.5 + legArea*109.5) / (barArea + legArea)
+
+	centroid := contourCentroid(contour)
+	if math.Abs(centroid.X-expectedX) > 1 || math.Abs(centroid.Y-expectedY) > 1 {
+		t.Errorf("Expected centroid near (%.1f, %.1f), got (%.1f, %.1f)", expectedX, expectedY, centroid.X, centroid.Y)
+	}
+
+	rect := gocv.BoundingRect(contour)
+	rectX := float64(rect.Min.X) + float64(rect.Dx())/2.0
+	rectY := float64(rect.Min.Y) + float64(rect.Dy())/2.0
+	rectError := math.Hypot(rectX-expectedX, rectY-expectedY)
+	momentError := math.Hypot(centroid.X-expectedX, centroid.Y-expectedY)
+	if rectError < 10 || momentError >= rectError {
+		t.Errorf("Expected the moment centroid (off by %.1fpx) to beat the bounding-rect center (off by %.1fpx)", momentError, rectError)
+	}
+}
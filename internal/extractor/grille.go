@@ -0,0 +1,149 @@
+package extractor
+
+import (
+	"image"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+	"gocv.io/x/gocv"
+)
+
+const (
+	minGrilleEdgePoints = 100 // Same minimum detectGrilleCenter uses for a valid grille
+	grilleLineRowShare  = 0.3 // Share of a row's width that must be edges for it to count as a slat edge
+	minGrilleLinePeriod = 3   // Shortest line spacing (px) considered, below Canny's resolution
+)
+
+// extractGrille measures the grille in the central region of a front view: its
+// extent, the dominant spacing between horizontal slat/mesh lines and its edge
+// density, from the edges inside mask. Returns nil when too few edges are found for
+// a grille.
+func (ge *GeometricExtractor) extractGrille(img, mask gocv.Mat) *models.GrilleFeatures {
+	gray := gocv.NewMat()
+	defer gray.Close()
+
+	if img.Channels() > 1 {
+		gocv.CvtColor(img, &gray, gocv.ColorBGRToGray)
+	} else {
+		img.CopyTo(&gray)
+	}
+
+	// Same central search area as detectGrilleCenter
+	centerRect := image.Rect(gray.Cols()/4, gray.Rows()/4, 3*gray.Cols()/4, 3*gray.Rows()/4)
+	centerRegion := gray.Region(centerRect)
+	defer centerRegion.Close()
+
+	edges := gocv.NewMat()
+	defer edges.Close()
+	gocv.Canny(centerRegion, &edges, 50, 150)
+	maskBinary(&edges, mask, centerRect)
+
+	// Bound the edge points to find the grille extent
+	minX, minY := edges.Cols(), edges.Rows()
+	maxX, maxY := -1, -1
+	count := 0
+	for y := 0; y < edges.Rows(); y++ {
+		for x := 0; x < edges.Cols(); x++ {
+			if edges.GetUCharAt(y, x) > 0 {
+				minX, maxX = min(minX, x), max(maxX, x)
+				minY, maxY = min(minY, y), max(maxY, y)
+				count++
+			}
+		}
+	}
+
+	if count < minGrilleEdgePoints {
+		return nil
+	}
+
+	width := maxX - minX + 1
+	height := maxY - minY + 1
+
+	// Fraction of each row covered by edges; horizontal slat and mesh edges show up
+	// as rows with high coverage
+	profile := make([]float64, height)
+	for y := 0; y < height; y++ {
+		rowCount := 0
+		for x := minX; x <= maxX; x++ {
+			if edges.GetUCharAt(minY+y, x) > 0 {
+				rowCount++
+			}
+		}
+		if share := float64(rowCount) / float64(width); share >= grilleLineRowShare {
+			profile[y] = 1.0
+		}
+	}
+
+	return &models.GrilleFeatures{
+		Region: models.Bounds{
+			X:      centerRect.Min.X + minX,
+			Y:      centerRect.Min.Y + minY,
+			Width:  width,
+			Height: height,
+		},
+		LineSpacing: float64(dominantPeriod(profile, minGrilleLinePeriod)),
+		EdgeDensity: float64(count) / float64(width*height),
+	}
+}
+
+// dominantPeriod returns the fundamental period of a row profile: the shortest lag
+// whose autocorrelation is within 90% of the strongest, so multiples of the period
+// are not picked. Returns 0 when the profile has no repeating structure.
+func dominantPeriod(profile []float64, minLag int) int {
+	n := len(profile)
+	if n < 2*minLag {
+		return 0
+	}
+
+	mean := 0.0
+	for _, v := range profile {
+		mean += v
+	}
+	mean /= float64(n)
+
+	centered := make([]float64, n)
+	variance := 0.0
+	for i, v := range profile {
+		centered[i] = v - mean
+		variance += centered[i] * centered[i]
+	}
+	if variance == 0 {
+		return 0
+	}
+
+	maxLag := n / 2
+	correlations := make([]float64, maxLag+1)
+	best := 0.0
+	for lag := minLag; lag <= maxLag; lag++ {
+		sum := 0.0
+		for i := 0; i+lag < n; i++ {
+			sum += centered[i] * centered[i+lag]
+		}
+		// Normalize by overlap so long lags are not penalized for fewer terms
+		correlations[lag] = sum / float64(n-lag) * float64(n) / variance
+		if correlations[lag] > best {
+			best = correlations[lag]
+		}
+	}
+
+	if best <= 0 {
+		return 0
+	}
+
+	for lag := minLag; lag <= maxLag; lag++ {
+		if correlations[lag] >= 0.9*best && isLocalPeak(correlations, lag, minLag, maxLag) {
+			return lag
+		}
+	}
+
+	return 0
+}
+
+func isLocalPeak(values []float64, i, lo, hi int) bool {
+	if i > lo && values[i-1] > values[i] {
+		return false
+	}
+	if i < hi && values[i+1] > values[i] {
+		return false
+	}
+	return true
+}
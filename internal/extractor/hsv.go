@@ -0,0 +1,18 @@
+package extractor
+
+import (
+	"sync/atomic"
+
+	"gocv.io/x/gocv"
+)
+
+// hsvConversions counts conversions to HSV, so tests can verify that monochrome
+// inputs skip the color paths
+var hsvConversions atomic.Int64
+
+// toHSV converts a BGR image to HSV. Callers skip monochrome images, which have no
+// hue and zero saturation everywhere.
+func toHSV(img gocv.Mat, hsv *gocv.Mat) {
+	hsvConversions.Add(1)
+	gocv.CvtColor(img, hsv, gocv.ColorBGRToHSV)
+}
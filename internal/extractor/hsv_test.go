@@ -0,0 +1,50 @@
+package extractor
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+	"gocv.io/x/gocv"
+)
+
+// rearCapture draws a rear view with two taillights in lightColor on a gray body
+func rearCapture(lightColor color.RGBA) gocv.Mat {
+	img := gocv.NewMatWithSizeFromScalar(gocv.NewScalar(40, 40, 40, 0), 480, 640, gocv.MatTypeCV8UC3)
+	gocv.Rectangle(&img, image.Rect(100, 150, 540, 400), color.RGBA{R: 150, G: 150, B: 150}, -1)
+	gocv.Rectangle(&img, image.Rect(130, 200, 180, 240), lightColor, -1)
+	gocv.Rectangle(&img, image.Rect(460, 200, 510, 240), lightColor, -1)
+	return img
+}
+
+// hsvConversionsDuring counts the HSV conversions made by every color-aware
+// extraction of img
+func hsvConversionsDuring(t *testing.T, img gocv.Mat) int64 {
+	before := hsvConversions.Load()
+
+	NewColorExtractor().ExtractColorProfile(img, gocv.Mat{})
+	if _, err := NewLightPatternExtractor().ExtractLightPatterns(img, gocv.Mat{}, models.ViewRear, models.LightingDaylight); err != nil {
+		t.Fatalf("ExtractLightPatterns failed: %v", err)
+	}
+	if _, err := NewGeometricExtractor().ExtractGeometricFeatures(img, gocv.Mat{}, models.ViewRear); err != nil {
+		t.Fatalf("ExtractGeometricFeatures failed: %v", err)
+	}
+
+	return hsvConversions.Load() - before
+}
+
+func TestMonochromeInputSkipsColorPaths(t *testing.T) {
+	// An IR capture loaded as color: three identical channels
+	monochrome := rearCapture(color.RGBA{R: 255, G: 255, B: 255})
+	defer monochrome.Close()
+	if conversions := hsvConversionsDuring(t, monochrome); conversions != 0 {
+		t.Errorf("Expected a monochrome image to skip every HSV conversion, got %d", conversions)
+	}
+
+	colored := rearCapture(color.RGBA{R: 220, G: 30, B: 30})
+	defer colored.Close()
+	if conversions := hsvConversionsDuring(t, colored); conversions == 0 {
+		t.Error("Expected a color image to take the HSV color paths")
+	}
+}
@@ -1,26 +1,69 @@
 package extractor
 
 import (
+	"github.com/choff5507/vehicle-image-comparison/internal/matstat"
 	"github.com/choff5507/vehicle-image-comparison/internal/models"
 	"gocv.io/x/gocv"
 	"image"
 	"math"
+	"sort"
 )
 
+// Default intensity boundaries of the material bands: low reflectivity (plastic,
+// rubber) below 80, medium (painted surfaces) up to 200, high (metal, chrome) above
+var defaultMaterialBands = []float64{80, 200}
+
 type IRSignatureExtractor struct {
-	plateExtractor *LicensePlateExtractor
+	plateExtractor  *LicensePlateExtractor
+	expansionFactor float64   // Context added on each side of the plate, as a fraction of plate size
+	materialBands   []float64 // Ascending intensity boundaries between material bands
 }
 
 func NewIRSignatureExtractor() *IRSignatureExtractor {
 	return &IRSignatureExtractor{
-		plateExtractor: NewLicensePlateExtractor(),
+		plateExtractor:  NewLicensePlateExtractor(),
+		expansionFactor: 0.75, // 1.5x plate size in each direction
+		materialBands:   defaultMaterialBands,
 	}
 }
 
-// ExtractIRSignature extracts IR reflectivity signature around license plate
-func (irse *IRSignatureExtractor) ExtractIRSignature(img gocv.Mat) (*models.IRSignature, error) {
+// SetExpansionFactor sets how far the surrounding region extends beyond the plate on
+// each side, as a fraction of the plate width/height. Negative values are treated as 0.
+func (irse *IRSignatureExtractor) SetExpansionFactor(factor float64) {
+	irse.expansionFactor = math.Max(0, factor)
+}
+
+// SetMaterialBands sets the intensity boundaries that divide the surroundings into
+// material bands. Each boundary starts a new band, so n boundaries give n+1 bands
+// that partition the 0-255 range and whose fractions sum to 1. Boundaries are
+// sorted; those outside (0, 255] and duplicates are dropped, and the defaults are
+// kept when none remain.
+func (irse *IRSignatureExtractor) SetMaterialBands(boundaries []float64) {
+	valid := []float64{}
+	for _, b := range boundaries {
+		if b > 0 && b <= 255 {
+			valid = append(valid, b)
+		}
+	}
+	sort.Float64s(valid)
+
+	bands := []float64{}
+	for i, b := range valid {
+		if i == 0 || b != valid[i-1] {
+			bands = append(bands, b)
+		}
+	}
+	if len(bands) == 0 {
+		bands = defaultMaterialBands
+	}
+	irse.materialBands = bands
+}
+
+// ExtractIRSignature extracts IR reflectivity signature around license plate. The
+// plate, edges and shadows are only searched inside mask (see HasMask).
+func (irse *IRSignatureExtractor) ExtractIRSignature(img, mask gocv.Mat, view models.VehicleView) (*models.IRSignature, error) {
 	// First detect the license plate
-	plateRegion, err := irse.plateExtractor.DetectLicensePlate(img)
+	plateRegion, err := irse.plateExtractor.DetectLicensePlate(img, mask, view)
 	if err != nil {
 		return nil, err
 	}
@@ -43,9 +86,9 @@ func (irse *IRSignatureExtractor) ExtractIRSignature(img gocv.Mat) (*models.IRSi
 		PlateRegion:        *plateRegion,
 		SurroundingRegion:  surroundingRegion,
 		ReflectivityMap:    irse.extractReflectivityMap(gray, surroundingRegion, plateRegion.Bounds),
-		MaterialSignature:  irse.extractMaterialSignature(gray, surroundingRegion, plateRegion.Bounds),
+		MaterialSignature:  irse.extractMaterialSignature(gray, mask, surroundingRegion, plateRegion.Bounds),
 		IlluminationGradient: irse.extractIlluminationGradient(gray, surroundingRegion, plateRegion.Bounds),
-		ShadowPatterns:     irse.extractShadowPatterns(gray, surroundingRegion, plateRegion.Bounds),
+		ShadowPatterns:     irse.extractShadowPatterns(gray, mask, surroundingRegion, plateRegion.Bounds),
 		TextureFeatures:    irse.extractTextureFeatures(gray, surroundingRegion, plateRegion.Bounds),
 	}
 	
@@ -53,14 +96,17 @@ func (irse *IRSignatureExtractor) ExtractIRSignature(img gocv.Mat) (*models.IRSi
 }
 
 func (irse *IRSignatureExtractor) calculateSurroundingRegion(plateBounds models.Bounds, imgHeight, imgWidth int) models.Bounds {
-	// Create region 1.5x the plate size in each direction
-	expandX := int(float64(plateBounds.Width) * 0.75)
-	expandY := int(float64(plateBounds.Height) * 0.75)
+	// Expand the plate region by the configured factor in each direction
+	expandX := int(float64(plateBounds.Width) * irse.expansionFactor)
+	expandY := int(float64(plateBounds.Height) * irse.expansionFactor)
 	
+	// Clamp to image bounds
 	x := math.Max(0, float64(plateBounds.X-expandX))
 	y := math.Max(0, float64(plateBounds.Y-expandY))
-	width := math.Min(float64(imgWidth-int(x)), float64(plateBounds.Width+2*expandX))
-	height := math.Min(float64(imgHeight-int(y)), float64(plateBounds.Height+2*expandY))
+	right := math.Min(float64(imgWidth), float64(plateBounds.X+plateBounds.Width+expandX))
+	bottom := math.Min(float64(imgHeight), float64(plateBounds.Y+plateBounds.Height+expandY))
+	width := math.Max(0, right-x)
+	height := math.Max(0, bottom-y)
 	
 	return models.Bounds{
 		X:      int(x),
@@ -122,11 +168,11 @@ func (irse *IRSignatureExtractor) extractReflectivityMap(gray gocv.Mat, surround
 				cellROI := roi.Region(cellRect)
 				cellMask := mask.Region(cellRect)
 				
-				// Calculate mean reflectivity for this cell (excluding plate area)
-				mean := gocv.NewMat()
-				defer mean.Close()
-				gocv.MeanStdDev(cellROI, &mean, nil)
-				reflectivityMap[i][j] = mean.GetDoubleAt(0, 0) / 255.0
+				// Calculate mean reflectivity for this cell (excluding plate area).
+				// Cells lying entirely on the plate have nothing to sample and stay 0.
+				if gocv.CountNonZero(cellMask) > 0 {
+					reflectivityMap[i][j] = cellROI.MeanWithMask(cellMask).Val1 / 255.0
+				}
 				
 				cellROI.Close()
 				cellMask.Close()
@@ -137,65 +183,69 @@ func (irse *IRSignatureExtractor) extractReflectivityMap(gray gocv.Mat, surround
 	return reflectivityMap
 }
 
-func (irse *IRSignatureExtractor) extractMaterialSignature(gray gocv.Mat, surroundingRegion models.Bounds, plateBounds models.Bounds) []float64 {
-	// Extract different material response signatures
-	signature := make([]float64, 6)
-	
+// extractMaterialSignature holds the fraction of the surroundings in each material
+// band, brightest first, followed by the edge density, texture variance and
+// brightness variation
+func (irse *IRSignatureExtractor) extractMaterialSignature(gray, mask gocv.Mat, surroundingRegion models.Bounds, plateBounds models.Bounds) []float64 {
 	surroundingRect := image.Rect(surroundingRegion.X, surroundingRegion.Y, 
 		surroundingRegion.X+surroundingRegion.Width, surroundingRegion.Y+surroundingRegion.Height)
 	roi := gray.Region(surroundingRect)
 	defer roi.Close()
 	
-	// Apply different filters to detect material properties
-	
-	// 1. High reflectivity areas (metal, chrome)
-	highReflThresh := gocv.NewMat()
-	defer highReflThresh.Close()
-	gocv.Threshold(roi, &highReflThresh, 200, 255, gocv.ThresholdBinary)
-	signature[0] = float64(gocv.CountNonZero(highReflThresh)) / float64(roi.Rows()*roi.Cols())
-	
-	// 2. Medium reflectivity areas (painted surfaces)
-	medReflThresh := gocv.NewMat()
-	defer medReflThresh.Close()
-	gocv.Threshold(roi, &medReflThresh, 100, 200, gocv.ThresholdBinaryInv)
-	gocv.Threshold(medReflThresh, &medReflThresh, 100, 255, gocv.ThresholdBinary)
-	signature[1] = float64(gocv.CountNonZero(medReflThresh)) / float64(roi.Rows()*roi.Cols())
-	
-	// 3. Low reflectivity areas (plastic, rubber)
-	lowReflThresh := gocv.NewMat()
-	defer lowReflThresh.Close()
-	gocv.Threshold(roi, &lowReflThresh, 80, 255, gocv.ThresholdBinaryInv)
-	signature[2] = float64(gocv.CountNonZero(lowReflThresh)) / float64(roi.Rows()*roi.Cols())
+	// Band fractions: high reflectivity (metal, chrome), medium (painted surfaces)
+	// and low (plastic, rubber) with the default bands
+	signature := irse.materialBandFractions(roi)
 	
-	// 4. Edge density (material transitions)
+	// Edge density (material transitions)
 	edges := gocv.NewMat()
 	defer edges.Close()
 	gocv.Canny(roi, &edges, 50, 150)
-	signature[3] = float64(gocv.CountNonZero(edges)) / float64(roi.Rows()*roi.Cols())
+	maskBinary(&edges, mask, surroundingRect)
+	signature = append(signature, float64(gocv.CountNonZero(edges))/float64(roi.Rows()*roi.Cols()))
 	
-	// 5. Texture variance (surface roughness)
+	// Texture variance (surface roughness)
 	laplacian := gocv.NewMat()
 	defer laplacian.Close()
 	gocv.Laplacian(roi, &laplacian, gocv.MatTypeCV64F, 1, 1, 0, gocv.BorderDefault)
 	
-	meanMat := gocv.NewMat()
-	stddevMat2 := gocv.NewMat()
-	defer meanMat.Close()
-	defer stddevMat2.Close()
-	gocv.MeanStdDev(laplacian, &meanMat, &stddevMat2)
-	signature[4] = stddevMat2.GetDoubleAt(0, 0) / 255.0
-	
-	// 6. Overall brightness variation
-	stddevMat3 := gocv.NewMat()
-	meanMat2 := gocv.NewMat()
-	defer stddevMat3.Close()
-	defer meanMat2.Close()
-	gocv.MeanStdDev(roi, &meanMat2, &stddevMat3)
-	signature[5] = stddevMat3.GetDoubleAt(0, 0) / 255.0
+	signature = append(signature, matstat.StddevOf(laplacian)/255.0)
+	
+	// Overall brightness variation
+	signature = append(signature, matstat.StddevOf(roi)/255.0)
 	
 	return signature
 }
 
+// materialBandFractions returns the fraction of roi's pixels in each material band,
+// brightest band first. Every pixel falls in exactly one band.
+func (irse *IRSignatureExtractor) materialBandFractions(roi gocv.Mat) []float64 {
+	bands := len(irse.materialBands) + 1
+	fractions := make([]float64, bands)
+	total := roi.Rows() * roi.Cols()
+	if total == 0 {
+		return fractions
+	}
+	
+	// Band of each intensity, counted from the darkest
+	var bandOf [256]int
+	for intensity := range bandOf {
+		bandOf[intensity] = sort.Search(len(irse.materialBands), func(i int) bool {
+			return irse.materialBands[i] > float64(intensity)
+		})
+	}
+	
+	counts := make([]int, bands)
+	for y := 0; y < roi.Rows(); y++ {
+		for x := 0; x < roi.Cols(); x++ {
+			counts[bandOf[roi.GetUCharAt(y, x)]]++
+		}
+	}
+	for band, count := range counts {
+		fractions[bands-1-band] = float64(count) / float64(total)
+	}
+	return fractions
+}
+
 func (irse *IRSignatureExtractor) extractIlluminationGradient(gray gocv.Mat, surroundingRegion models.Bounds, plateBounds models.Bounds) []float64 {
 	// Analyze how illumination falls off from IR source
 	surroundingRect := image.Rect(surroundingRegion.X, surroundingRegion.Y, 
@@ -244,7 +294,7 @@ func (irse *IRSignatureExtractor) extractIlluminationGradient(gray gocv.Mat, sur
 	return gradient
 }
 
-func (irse *IRSignatureExtractor) extractShadowPatterns(gray gocv.Mat, surroundingRegion models.Bounds, plateBounds models.Bounds) []models.Point2D {
+func (irse *IRSignatureExtractor) extractShadowPatterns(gray, mask gocv.Mat, surroundingRegion models.Bounds, plateBounds models.Bounds) []models.Point2D {
 	// Find shadow/dark regions that indicate 3D structure
 	surroundingRect := image.Rect(surroundingRegion.X, surroundingRegion.Y, 
 		surroundingRegion.X+surroundingRegion.Width, surroundingRegion.Y+surroundingRegion.Height)
@@ -255,6 +305,7 @@ func (irse *IRSignatureExtractor) extractShadowPatterns(gray gocv.Mat, surroundi
 	shadows := gocv.NewMat()
 	defer shadows.Close()
 	gocv.Threshold(roi, &shadows, 60, 255, gocv.ThresholdBinaryInv)
+	maskBinary(&shadows, mask, surroundingRect)
 	
 	// Find contours of shadow regions
 	contours := gocv.FindContours(shadows, gocv.RetrievalExternal, gocv.ChainApproxSimple)
@@ -266,15 +317,12 @@ func (irse *IRSignatureExtractor) extractShadowPatterns(gray gocv.Mat, surroundi
 		
 		// Only consider significant shadow regions
 		if area > 50 {
-			// Calculate centroid using bounding rect as approximation
-			rect := gocv.BoundingRect(contour)
-			cx := float64(rect.Min.X + rect.Dx()/2)
-			cy := float64(rect.Min.Y + rect.Dy()/2)
+			centroid := contourCentroid(contour)
 			
 			// Convert back to image coordinates
 			shadowCenters = append(shadowCenters, models.Point2D{
-				X: cx + float64(surroundingRegion.X),
-				Y: cy + float64(surroundingRegion.Y),
+				X: centroid.X + float64(surroundingRegion.X),
+				Y: centroid.Y + float64(surroundingRegion.Y),
 			})
 		}
 	}
@@ -294,16 +342,14 @@ func (irse *IRSignatureExtractor) extractTextureFeatures(gray gocv.Mat, surround
 	// 1. Local variance (texture roughness)
 	blurred := gocv.NewMat()
 	defer blurred.Close()
-	gocv.GaussianBlur(roi, &blurred, image.Pt(5, 5), 0, 0, gocv.BorderDefault)
+	blurSize := clampKernelSize(5, roi.Rows(), roi.Cols())
+	gocv.GaussianBlur(roi, &blurred, image.Pt(blurSize, blurSize), 0, 0, gocv.BorderDefault)
 	
 	diff := gocv.NewMat()
 	defer diff.Close()
 	gocv.AbsDiff(roi, blurred, &diff)
 	
-	meanMat := gocv.NewMat()
-	defer meanMat.Close()
-	gocv.MeanStdDev(diff, &meanMat, nil)
-	features[0] = meanMat.GetDoubleAt(0, 0) / 255.0
+	features[0] = matstat.MeanOf(diff) / 255.0
 	
 	// 2. Gradient magnitude (edge density)
 	gradX := gocv.NewMat()
@@ -318,20 +364,11 @@ func (irse *IRSignatureExtractor) extractTextureFeatures(gray gocv.Mat, surround
 	defer gradMag.Close()
 	gocv.Magnitude(gradX, gradY, &gradMag)
 	
-	meanMat2 := gocv.NewMat()
-	defer meanMat2.Close()
-	gocv.MeanStdDev(gradMag, &meanMat2, nil)
-	features[1] = meanMat2.GetDoubleAt(0, 0) / 255.0
+	features[1] = matstat.MeanOf(gradMag) / 255.0
 	
 	// 3. Directional texture (horizontal vs vertical patterns)
-	meanMatX := gocv.NewMat()
-	meanMatY := gocv.NewMat()
-	defer meanMatX.Close()
-	defer meanMatY.Close()
-	gocv.MeanStdDev(gradX, &meanMatX, nil)
-	horizontalGrad := math.Abs(meanMatX.GetDoubleAt(0, 0))
-	gocv.MeanStdDev(gradY, &meanMatY, nil)
-	verticalGrad := math.Abs(meanMatY.GetDoubleAt(0, 0))
+	horizontalGrad := math.Abs(matstat.MeanOf(gradX))
+	verticalGrad := math.Abs(matstat.MeanOf(gradY))
 	
 	if horizontalGrad+verticalGrad > 0 {
 		features[2] = horizontalGrad / (horizontalGrad + verticalGrad)
@@ -364,4 +401,17 @@ func (irse *IRSignatureExtractor) extractTextureFeatures(gray gocv.Mat, surround
 	features[3] = entropy / 8.0 // Normalize by max entropy
 	
 	return features
-}
\ No newline at end of file
+}
+
+// clampKernelSize shrinks an odd kernel size so it never exceeds the smaller
+// dimension of a small region
+func clampKernelSize(size, rows, cols int) int {
+	limit := min(rows, cols)
+	if limit%2 == 0 {
+		limit--
+	}
+	if size > limit {
+		size = limit
+	}
+	return max(size, 1)
+}
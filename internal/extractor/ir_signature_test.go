@@ -0,0 +1,106 @@
+package extractor
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+	"gocv.io/x/gocv"
+)
+
+func TestSurroundingRegionExpansionFactor(t *testing.T) {
+	plate := models.Bounds{X: 500, Y: 400, Width: 200, Height: 60}
+
+	irse := NewIRSignatureExtractor()
+	defaultRegion := irse.calculateSurroundingRegion(plate, 960, 1280)
+
+	irse.SetExpansionFactor(1.5)
+	largerRegion := irse.calculateSurroundingRegion(plate, 960, 1280)
+
+	if defaultRegion.Width != 500 || defaultRegion.Height != 150 {
+		t.Errorf("Expected default region 500x150, got %dx%d", defaultRegion.Width, defaultRegion.Height)
+	}
+	if largerRegion.Width != 800 || largerRegion.Height != 240 {
+		t.Errorf("Expected expanded region 800x240, got %dx%d", largerRegion.Width, largerRegion.Height)
+	}
+
+	// A plate near the corner is clamped to the image
+	cornerPlate := models.Bounds{X: 1150, Y: 880, Width: 100, Height: 50}
+	clamped := irse.calculateSurroundingRegion(cornerPlate, 960, 1280)
+	if clamped.X+clamped.Width > 1280 || clamped.Y+clamped.Height > 960 {
+		t.Errorf("Region exceeds image bounds: %+v", clamped)
+	}
+	if clamped.X != 1000 || clamped.Y != 805 {
+		t.Errorf("Expected region to start at (1000, 805), got (%d, %d)", clamped.X, clamped.Y)
+	}
+}
+
+func TestReflectivityMapExcludesPlate(t *testing.T) {
+	// Uniform dark surroundings with a bright plate that straddles cell boundaries
+	gray := gocv.NewMatWithSizeFromScalar(gocv.NewScalar(50, 0, 0, 0), 160, 400, gocv.MatTypeCV8UC1)
+	defer gray.Close()
+
+	plate := models.Bounds{X: 110, Y: 50, Width: 180, Height: 60}
+	gocv.Rectangle(&gray, image.Rect(plate.X, plate.Y, plate.X+plate.Width, plate.Y+plate.Height), color.RGBA{R: 255, G: 255, B: 255}, -1)
+
+	irse := NewIRSignatureExtractor()
+	surrounding := models.Bounds{X: 0, Y: 0, Width: 400, Height: 160}
+	reflectivityMap := irse.extractReflectivityMap(gray, surrounding, plate)
+
+	background := 50.0 / 255.0
+	for i, row := range reflectivityMap {
+		for j, value := range row {
+			if value > background+0.01 {
+				t.Errorf("Cell (%d, %d) skewed bright by plate pixels: %.3f", i, j, value)
+			}
+		}
+	}
+}
+
+func TestMaterialBandsPartitionSurroundings(t *testing.T) {
+	// Four equal stripes: dark, two mid-range (one in the former 80-100 gap) and bright
+	gray := gocv.NewMatWithSize(100, 400, gocv.MatTypeCV8UC1)
+	defer gray.Close()
+	for i, level := range []uint8{50, 90, 150, 230} {
+		stripe := gray.Region(image.Rect(i*100, 0, (i+1)*100, 100))
+		stripe.SetTo(gocv.NewScalar(float64(level), 0, 0, 0))
+		stripe.Close()
+	}
+	region := models.Bounds{Width: 400, Height: 100}
+
+	tests := []struct {
+		name      string
+		bands     []float64
+		fractions []float64 // Brightest band first
+	}{
+		{"default", nil, []float64{0.25, 0.5, 0.25}},
+		{"single boundary", []float64{100}, []float64{0.5, 0.5}},
+		{"unsorted with duplicates", []float64{200, 60, 200, 100, 300}, []float64{0.25, 0.25, 0.25, 0.25}},
+		{"boundary on a level", []float64{90}, []float64{0.75, 0.25}},
+	}
+
+	for _, tt := range tests {
+		irse := NewIRSignatureExtractor()
+		if tt.bands != nil {
+			irse.SetMaterialBands(tt.bands)
+		}
+
+		signature := irse.extractMaterialSignature(gray, gocv.Mat{}, region, models.Bounds{})
+		if len(signature) != len(tt.fractions)+3 {
+			t.Fatalf("%s: expected %d bands and 3 texture terms, got %v", tt.name, len(tt.fractions), signature)
+		}
+
+		sum := 0.0
+		for i, expected := range tt.fractions {
+			if math.Abs(signature[i]-expected) > 1e-9 {
+				t.Errorf("%s: expected band %d fraction %.2f, got %.3f", tt.name, i, expected, signature[i])
+			}
+			sum += signature[i]
+		}
+		if math.Abs(sum-1.0) > 1e-9 {
+			t.Errorf("%s: expected band fractions to sum to 1, got %.6f", tt.name, sum)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package extractor
+
+import (
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+	"gocv.io/x/gocv"
+)
+
+type KeypointExtractor struct{}
+
+func NewKeypointExtractor() *KeypointExtractor {
+	return &KeypointExtractor{}
+}
+
+// ExtractKeypoints detects ORB keypoints inside mask (see HasMask) and copies their
+// binary descriptors out of OpenCV so they can be stored and matched without the
+// source image
+func (ke *KeypointExtractor) ExtractKeypoints(img, mask gocv.Mat) []models.Keypoint {
+	gray := gocv.NewMat()
+	defer gray.Close()
+
+	if img.Channels() > 1 {
+		gocv.CvtColor(img, &gray, gocv.ColorBGRToGray)
+	} else {
+		img.CopyTo(&gray)
+	}
+
+	orb := gocv.NewORB()
+	defer orb.Close()
+
+	// ORB takes an empty Mat for the whole image
+	if !HasMask(mask) {
+		mask = gocv.NewMat()
+		defer mask.Close()
+	}
+
+	points, descriptors := orb.DetectAndCompute(gray, mask)
+	defer descriptors.Close()
+
+	if descriptors.Empty() || descriptors.Rows() != len(points) {
+		return []models.Keypoint{}
+	}
+
+	// Descriptors are one CV_8U row per keypoint
+	data := descriptors.ToBytes()
+	width := descriptors.Cols()
+
+	keypoints := make([]models.Keypoint, 0, len(points))
+	for i, point := range points {
+		descriptor := make([]byte, width)
+		copy(descriptor, data[i*width:(i+1)*width])
+
+		keypoints = append(keypoints, models.Keypoint{
+			Position:   models.Point2D{X: point.X, Y: point.Y},
+			Descriptor: descriptor,
+		})
+	}
+
+	return keypoints
+}
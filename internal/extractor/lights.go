@@ -1,43 +1,97 @@
 package extractor
 
 import (
+	"github.com/choff5507/vehicle-image-comparison/internal/matstat"
 	"github.com/choff5507/vehicle-image-comparison/internal/models"
 	"gocv.io/x/gocv"
+	"fmt"
 	"image"
 	"math"
 )
 
-type LightPatternExtractor struct{}
+type LightPatternExtractor struct {
+	minFillRatio float64 // Minimum contour area / bounding-rect area for a light candidate
+	maxElements  int     // Number of light elements encoded in the pattern signature
+	
+	// IR taillights are dimmer than the plate and reflectors, so they are found with
+	// their own threshold and only in the upper part of the frame, above the plate
+	irTaillightThreshold float64
+	irTaillightMaxY      float64 // Maximum candidate center Y as a fraction of image height
+	
+	relativeIntensity bool // Measure intensity against the surrounding ring rather than absolutely
+}
+
+// lightRegion is a light candidate cropped out of the image, with its location in
+// the image so its surroundings can be sampled
+type lightRegion struct {
+	gocv.Mat
+	rect image.Rectangle
+}
+
+// Width of the background ring sampled around a light for relative intensity, as a
+// fraction of the light's smaller side
+const lightRingWidth = 0.5
 
 func NewLightPatternExtractor() *LightPatternExtractor {
-	return &LightPatternExtractor{}
+	return &LightPatternExtractor{
+		minFillRatio:         0.4, // Rejects sparse glare and reflection smears
+		maxElements:          8,
+		irTaillightThreshold: 110.0,
+		irTaillightMaxY:      0.55,
+	}
+}
+
+// SetIRTaillightThreshold sets the gray level above which IR taillight candidates
+// are detected (default 110). It is independent of the plate-level threshold used
+// for IR headlights.
+func (lpe *LightPatternExtractor) SetIRTaillightThreshold(threshold float64) {
+	if threshold > 0 && threshold < 255 {
+		lpe.irTaillightThreshold = threshold
+	}
+}
+
+// SetMaxElements sets how many light elements are encoded in the pattern signature.
+// The signature length is maxElements plus two pattern-level slots.
+func (lpe *LightPatternExtractor) SetMaxElements(n int) {
+	if n > 0 {
+		lpe.maxElements = n
+	}
+}
+
+// SetRelativeIntensity selects how light element intensity is measured. Absolute
+// intensity is the region's mean brightness, which follows the exposure; relative
+// intensity is the light's contrast against a ring of its immediate surroundings,
+// which a change of exposure scales out.
+func (lpe *LightPatternExtractor) SetRelativeIntensity(enabled bool) {
+	lpe.relativeIntensity = enabled
 }
 
-// ExtractLightPatterns extracts headlight/taillight patterns
-func (lpe *LightPatternExtractor) ExtractLightPatterns(img gocv.Mat, view models.VehicleView, lighting models.LightingType) (models.LightPatternFeatures, error) {
+// ExtractLightPatterns extracts headlight/taillight patterns from the lights inside
+// mask (see HasMask)
+func (lpe *LightPatternExtractor) ExtractLightPatterns(img, mask gocv.Mat, view models.VehicleView, lighting models.LightingType) (models.LightPatternFeatures, error) {
 	features := models.LightPatternFeatures{}
 	
 	switch view {
 	case models.ViewFront:
-		features = lpe.extractHeadlightPatterns(img, lighting)
+		features = lpe.extractHeadlightPatterns(img, mask, lighting)
 	case models.ViewRear:
-		features = lpe.extractTaillightPatterns(img, lighting)
+		features = lpe.extractTaillightPatterns(img, mask, lighting)
 	}
 	
 	return features, nil
 }
 
-func (lpe *LightPatternExtractor) extractHeadlightPatterns(img gocv.Mat, lighting models.LightingType) models.LightPatternFeatures {
+func (lpe *LightPatternExtractor) extractHeadlightPatterns(img, mask gocv.Mat, lighting models.LightingType) models.LightPatternFeatures {
 	features := models.LightPatternFeatures{}
 	
 	// Find bright regions that could be headlights
-	lightRegions := lpe.findBrightRegions(img, lighting)
+	lightRegions := lpe.findBrightRegions(img, mask, lighting)
 	
 	// Filter for headlight-like characteristics
 	headlights := lpe.filterHeadlightCandidates(lightRegions, img)
 	
 	// Extract individual light elements
-	features.LightElements = lpe.analyzeLightElements(headlights, models.TypeHeadlight)
+	features.LightElements = lpe.analyzeLightElements(img, headlights, models.TypeHeadlight)
 	
 	// Generate pattern signature
 	features.PatternSignature = lpe.generatePatternSignature(features.LightElements)
@@ -56,17 +110,17 @@ func (lpe *LightPatternExtractor) extractHeadlightPatterns(img gocv.Mat, lightin
 	return features
 }
 
-func (lpe *LightPatternExtractor) extractTaillightPatterns(img gocv.Mat, lighting models.LightingType) models.LightPatternFeatures {
+func (lpe *LightPatternExtractor) extractTaillightPatterns(img, mask gocv.Mat, lighting models.LightingType) models.LightPatternFeatures {
 	features := models.LightPatternFeatures{}
 	
 	// Find light regions (different approach for taillights)
-	lightRegions := lpe.findTaillightRegions(img, lighting)
+	lightRegions := lpe.findTaillightRegions(img, mask, lighting)
 	
 	// Filter for taillight characteristics
 	taillights := lpe.filterTaillightCandidates(lightRegions, img)
 	
 	// Extract individual light elements
-	features.LightElements = lpe.analyzeLightElements(taillights, models.TypeTaillight)
+	features.LightElements = lpe.analyzeLightElements(img, taillights, models.TypeTaillight)
 	
 	// Generate pattern signature
 	features.PatternSignature = lpe.generatePatternSignature(features.LightElements)
@@ -85,7 +139,20 @@ func (lpe *LightPatternExtractor) extractTaillightPatterns(img gocv.Mat, lightin
 	return features
 }
 
-func (lpe *LightPatternExtractor) findBrightRegions(img gocv.Mat, lighting models.LightingType) []gocv.Mat {
+func (lpe *LightPatternExtractor) findBrightRegions(img, mask gocv.Mat, lighting models.LightingType) []lightRegion {
+	var thresholdValue float64
+	if lighting == models.LightingInfrared {
+		thresholdValue = 200.0 // Higher threshold for IR
+	} else {
+		thresholdValue = 180.0 // Lower threshold for daylight
+	}
+	
+	return lpe.findRegionsAboveThreshold(img, mask, thresholdValue, 1.0)
+}
+
+// findRegionsAboveThreshold returns bright blobs whose bounding-rect center lies in
+// the top maxCenterY fraction of the image, ignoring anything outside mask
+func (lpe *LightPatternExtractor) findRegionsAboveThreshold(img, mask gocv.Mat, thresholdValue float64, maxCenterY float64) []lightRegion {
 	gray := gocv.NewMat()
 	defer gray.Close()
 	
@@ -99,14 +166,8 @@ func (lpe *LightPatternExtractor) findBrightRegions(img gocv.Mat, lighting model
 	threshold := gocv.NewMat()
 	defer threshold.Close()
 	
-	var thresholdValue float64
-	if lighting == models.LightingInfrared {
-		thresholdValue = 200.0 // Higher threshold for IR
-	} else {
-		thresholdValue = 180.0 // Lower threshold for daylight
-	}
-	
 	gocv.Threshold(gray, &threshold, float32(thresholdValue), 255, gocv.ThresholdBinary)
+	maskBinary(&threshold, mask, fullRect(img))
 	
 	// Apply morphological operations to clean up
 	kernel := gocv.GetStructuringElement(gocv.MorphEllipse, image.Pt(5, 5))
@@ -120,16 +181,22 @@ func (lpe *LightPatternExtractor) findBrightRegions(img gocv.Mat, lighting model
 	contours := gocv.FindContours(cleaned, gocv.RetrievalExternal, gocv.ChainApproxSimple)
 	defer contours.Close()
 	
-	regions := []gocv.Mat{}
+	regions := []lightRegion{}
 	for i := 0; i < contours.Size(); i++ {
 		contour := contours.At(i)
 		rect := gocv.BoundingRect(contour)
 		
+		// Filter by position
+		centerY := float64(rect.Min.Y+rect.Max.Y) / 2.0
+		if centerY > maxCenterY*float64(img.Rows()) {
+			continue
+		}
+		
 		// Filter by size
 		area := rect.Dx() * rect.Dy()
 		if area > 100 && area < 10000 {
 			roi := img.Region(rect)
-			regions = append(regions, roi.Clone())
+			regions = append(regions, lightRegion{Mat: roi.Clone(), rect: rect})
 			roi.Close()
 		}
 	}
@@ -137,28 +204,29 @@ func (lpe *LightPatternExtractor) findBrightRegions(img gocv.Mat, lighting model
 	return regions
 }
 
-func (lpe *LightPatternExtractor) findTaillightRegions(img gocv.Mat, lighting models.LightingType) []gocv.Mat {
+func (lpe *LightPatternExtractor) findTaillightRegions(img, mask gocv.Mat, lighting models.LightingType) []lightRegion {
 	// For taillights, we might look for different characteristics
 	// - Red color in daylight images
 	// - Specific shapes (often vertical or L-shaped)
 	
 	if lighting == models.LightingDaylight {
-		return lpe.findRedRegions(img)
+		return lpe.findRedRegions(img, mask)
 	} else {
-		return lpe.findBrightRegions(img, lighting)
+		// In IR the plate and reflectors outshine the taillights
+		return lpe.findRegionsAboveThreshold(img, mask, lpe.irTaillightThreshold, lpe.irTaillightMaxY)
 	}
 }
 
-func (lpe *LightPatternExtractor) findRedRegions(img gocv.Mat) []gocv.Mat {
-	if img.Channels() == 1 {
-		// Grayscale image, fall back to brightness detection
-		return lpe.findBrightRegions(img, models.LightingDaylight)
+func (lpe *LightPatternExtractor) findRedRegions(img, mask gocv.Mat) []lightRegion {
+	if matstat.IsMonochrome(img) {
+		// Grayscale image, possibly stored as color: fall back to brightness detection
+		return lpe.findBrightRegions(img, mask, models.LightingDaylight)
 	}
 	
 	// Convert to HSV for better color detection
 	hsv := gocv.NewMat()
 	defer hsv.Close()
-	gocv.CvtColor(img, &hsv, gocv.ColorBGRToHSV)
+	toHSV(img, &hsv)
 	
 	// Define red color ranges in HSV as scalars
 	lowerRed1 := gocv.NewScalar(0, 50, 50, 0)
@@ -177,12 +245,13 @@ func (lpe *LightPatternExtractor) findRedRegions(img gocv.Mat) []gocv.Mat {
 	gocv.InRangeWithScalar(hsv, lowerRed1, upperRed1, &mask1)
 	gocv.InRangeWithScalar(hsv, lowerRed2, upperRed2, &mask2)
 	gocv.BitwiseOr(mask1, mask2, &redMask)
+	maskBinary(&redMask, mask, fullRect(img))
 	
 	// Find contours of red regions
 	contours := gocv.FindContours(redMask, gocv.RetrievalExternal, gocv.ChainApproxSimple)
 	defer contours.Close()
 	
-	regions := []gocv.Mat{}
+	regions := []lightRegion{}
 	for i := 0; i < contours.Size(); i++ {
 		contour := contours.At(i)
 		rect := gocv.BoundingRect(contour)
@@ -193,7 +262,7 @@ func (lpe *LightPatternExtractor) findRedRegions(img gocv.Mat) []gocv.Mat {
 		
 		if area > 200 && area < 8000 && aspectRatio > 0.3 && aspectRatio < 3.0 {
 			roi := img.Region(rect)
-			regions = append(regions, roi.Clone())
+			regions = append(regions, lightRegion{Mat: roi.Clone(), rect: rect})
 			roi.Close()
 		}
 	}
@@ -201,52 +270,97 @@ func (lpe *LightPatternExtractor) findRedRegions(img gocv.Mat) []gocv.Mat {
 	return regions
 }
 
-func (lpe *LightPatternExtractor) filterHeadlightCandidates(regions []gocv.Mat, fullImage gocv.Mat) []gocv.Mat {
+func (lpe *LightPatternExtractor) filterHeadlightCandidates(regions []lightRegion, fullImage gocv.Mat) []lightRegion {
 	// Filter regions based on headlight characteristics
 	// - Position (typically in upper portion of vehicle)
 	// - Size (reasonable for headlights)
 	// - Pair detection (headlights usually come in pairs)
 	
-	filtered := []gocv.Mat{}
+	filtered := []lightRegion{}
 	
 	for _, region := range regions {
 		// Check if region is reasonable size for headlights
 		if region.Cols() > 20 && region.Rows() > 15 && region.Cols() < 200 && region.Rows() < 150 {
-			filtered = append(filtered, region.Clone())
+			// Lights are solid blobs; reject irregular bright smears
+			if lpe.calculateFillRatio(region.Mat) >= lpe.minFillRatio {
+				filtered = append(filtered, lightRegion{Mat: region.Clone(), rect: region.rect})
+			}
 		}
 	}
 	
 	return filtered
 }
 
-func (lpe *LightPatternExtractor) filterTaillightCandidates(regions []gocv.Mat, fullImage gocv.Mat) []gocv.Mat {
+func (lpe *LightPatternExtractor) filterTaillightCandidates(regions []lightRegion, fullImage gocv.Mat) []lightRegion {
 	// Filter regions based on taillight characteristics
 	// - Position (various positions depending on vehicle type)
 	// - Shape (often vertical or complex shapes)
 	// - Pair detection
 	
-	filtered := []gocv.Mat{}
+	filtered := []lightRegion{}
 	
 	for _, region := range regions {
 		// Basic size filtering for taillights
 		if region.Cols() > 15 && region.Rows() > 20 && region.Cols() < 150 && region.Rows() < 200 {
-			filtered = append(filtered, region.Clone())
+			// Lights are solid blobs; reject irregular bright smears
+			if lpe.calculateFillRatio(region.Mat) >= lpe.minFillRatio {
+				filtered = append(filtered, lightRegion{Mat: region.Clone(), rect: region.rect})
+			}
 		}
 	}
 	
 	return filtered
 }
 
-func (lpe *LightPatternExtractor) analyzeLightElements(lightRegions []gocv.Mat, lightType models.LightType) []models.LightElement {
+// calculateFillRatio returns the area of the largest bright contour in the region
+// divided by the region (bounding-rect) area
+func (lpe *LightPatternExtractor) calculateFillRatio(region gocv.Mat) float64 {
+	regionArea := float64(region.Cols() * region.Rows())
+	if regionArea == 0 {
+		return 0.0
+	}
+	
+	gray := gocv.NewMat()
+	defer gray.Close()
+	
+	if region.Channels() > 1 {
+		gocv.CvtColor(region, &gray, gocv.ColorBGRToGray)
+	} else {
+		region.CopyTo(&gray)
+	}
+	
+	// Separate the light from its background within the region
+	threshold := gocv.NewMat()
+	defer threshold.Close()
+	gocv.Threshold(gray, &threshold, 0, 255, gocv.ThresholdBinary|gocv.ThresholdOtsu)
+	
+	contours := gocv.FindContours(threshold, gocv.RetrievalExternal, gocv.ChainApproxSimple)
+	defer contours.Close()
+	
+	largestArea := 0.0
+	for i := 0; i < contours.Size(); i++ {
+		area := gocv.ContourArea(contours.At(i))
+		if area > largestArea {
+			largestArea = area
+		}
+	}
+	
+	return largestArea / regionArea
+}
+
+func (lpe *LightPatternExtractor) analyzeLightElements(img gocv.Mat, lightRegions []lightRegion, lightType models.LightType) []models.LightElement {
 	elements := []models.LightElement{}
 	
-	for i, region := range lightRegions {
+	for _, region := range lightRegions {
 		element := models.LightElement{
 			Type:      lightType,
-			Position:  models.Point2D{X: float64(i * 100), Y: 50}, // Placeholder position
-			Shape:     lpe.classifyLightShape(region),
-			Size:      lpe.calculateLightSize(region),
-			Intensity: lpe.calculateLightIntensity(region),
+			Position:  regionCenter(region.rect),
+			Shape:     lpe.classifyLightShape(region.Mat),
+			Size:      lpe.calculateLightSize(region.Mat),
+			Intensity: lpe.calculateLightIntensity(region.Mat),
+		}
+		if lpe.relativeIntensity {
+			element.Intensity = lpe.calculateRelativeIntensity(img, region.rect)
 		}
 		
 		elements = append(elements, element)
@@ -255,6 +369,14 @@ func (lpe *LightPatternExtractor) analyzeLightElements(lightRegions []gocv.Mat,
 	return elements
 }
 
+// regionCenter is the center of a light's bounding rect in image coordinates
+func regionCenter(rect image.Rectangle) models.Point2D {
+	return models.Point2D{
+		X: float64(rect.Min.X+rect.Max.X) / 2.0,
+		Y: float64(rect.Min.Y+rect.Max.Y) / 2.0,
+	}
+}
+
 func (lpe *LightPatternExtractor) classifyLightShape(region gocv.Mat) models.LightShape {
 	// Analyze region shape to classify light type
 	aspectRatio := float64(region.Cols()) / float64(region.Rows())
@@ -327,26 +449,85 @@ func (lpe *LightPatternExtractor) calculateLightIntensity(region gocv.Mat) float
 		gray = region.Clone()
 	}
 	
-	meanMat := gocv.NewMat()
-	stddevMat := gocv.NewMat()
-	defer meanMat.Close()
-	defer stddevMat.Close()
-	gocv.MeanStdDev(gray, &meanMat, &stddevMat)
-	meanScalar := gocv.Scalar{Val1: 128.0} // Default mean value
-	return meanScalar.Val1 / 255.0
+	return matstat.MeanOf(gray) / 255.0
+}
+
+// calculateRelativeIntensity returns the contrast of the light at rect against a
+// ring of its surroundings, (light - ring) / (light + ring) clamped to [0, 1]. Both
+// means scale together with exposure, so the ratio does not. Falls back to the
+// absolute intensity when no ring fits inside the image.
+func (lpe *LightPatternExtractor) calculateRelativeIntensity(img gocv.Mat, rect image.Rectangle) float64 {
+	gray := gocv.NewMat()
+	defer gray.Close()
+	
+	if img.Channels() > 1 {
+		gocv.CvtColor(img, &gray, gocv.ColorBGRToGray)
+	} else {
+		img.CopyTo(&gray)
+	}
+	
+	bounds := image.Rect(0, 0, gray.Cols(), gray.Rows())
+	inner := rect.Intersect(bounds)
+	if inner.Empty() {
+		return 0.0
+	}
+	
+	ring := max(2, int(float64(min(inner.Dx(), inner.Dy()))*lightRingWidth))
+	outer := inner.Inset(-ring).Intersect(bounds)
+	
+	innerRegion := gray.Region(inner)
+	defer innerRegion.Close()
+	outerRegion := gray.Region(outer)
+	defer outerRegion.Close()
+	
+	innerArea := float64(inner.Dx() * inner.Dy())
+	ringArea := float64(outer.Dx()*outer.Dy()) - innerArea
+	lightMean := matstat.MeanOf(innerRegion)
+	if ringArea <= 0 {
+		return lightMean / 255.0
+	}
+	
+	// The ring mean is the outer sum less the light's own sum
+	ringMean := (matstat.MeanOf(outerRegion)*float64(outer.Dx()*outer.Dy()) - lightMean*innerArea) / ringArea
+	if lightMean+ringMean <= 0 {
+		return 0.0
+	}
+	
+	return math.Max(0.0, math.Min(1.0, (lightMean-ringMean)/(lightMean+ringMean)))
+}
+
+// Signature codes for light shapes, keyed by the stable shape names rather than the
+// LightShape values so reordering the constants cannot change signatures. The codes
+// match the original constant order, keeping existing signatures valid.
+var lightShapeCodes = map[string]float64{
+	"Rectangular": 0,
+	"Round":       1,
+	"Angular":     2,
+	"Custom":      3,
+}
+
+// lightShapeCode returns the signature code for a shape; unknown shapes encode as
+// ShapeCustom
+func lightShapeCode(shape fmt.Stringer) float64 {
+	if code, ok := lightShapeCodes[shape.String()]; ok {
+		return code
+	}
+	return lightShapeCodes["Custom"]
 }
 
 func (lpe *LightPatternExtractor) generatePatternSignature(elements []models.LightElement) []float64 {
-	// Generate a feature vector representing the light pattern
-	signature := make([]float64, 10) // Fixed-size signature
+	// Generate a feature vector representing the light pattern: one slot per
+	// element up to maxElements, then average intensity and symmetry at fixed slots
+	signature := make([]float64, lpe.maxElements+models.PatternSummarySlots)
+	averageSlot, symmetrySlot := lpe.maxElements, lpe.maxElements+1
 	
 	for i, element := range elements {
-		if i >= len(signature) {
+		if i >= lpe.maxElements {
 			break
 		}
 		
 		// Encode element characteristics into signature
-		signature[i] = element.Size + element.Intensity + float64(element.Shape)
+		signature[i] = element.Size + element.Intensity + lightShapeCode(element.Shape)
 	}
 	
 	// Add pattern-level features
@@ -358,13 +539,10 @@ func (lpe *LightPatternExtractor) generatePatternSignature(elements []models.Lig
 		}
 		avgIntensity /= float64(len(elements))
 		
-		// Add to signature if space available
-		if len(signature) > len(elements) {
-			signature[len(elements)] = avgIntensity
-		}
+		signature[averageSlot] = avgIntensity
 		
 		// Symmetry measure (simplified)
-		if len(elements) >= 2 && len(signature) > len(elements)+1 {
+		if len(elements) >= 2 {
 			leftElements := 0
 			rightElements := 0
 			centerX := 0.0
@@ -385,7 +563,7 @@ func (lpe *LightPatternExtractor) generatePatternSignature(elements []models.Lig
 			}
 			
 			symmetry := 1.0 - math.Abs(float64(leftElements-rightElements))/float64(len(elements))
-			signature[len(elements)+1] = symmetry
+			signature[symmetrySlot] = symmetry
 		}
 	}
 	
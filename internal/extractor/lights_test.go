@@ -0,0 +1,205 @@
+package extractor
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+	"github.com/choff5507/vehicle-image-comparison/internal/testutil"
+	"gocv.io/x/gocv"
+)
+
+func TestFilterLightCandidatesRejectsSparseRegions(t *testing.T) {
+	lpe := NewLightPatternExtractor()
+
+	solid := gocv.NewMatWithSizeFromScalar(gocv.NewScalar(255, 255, 255, 0), 60, 60, gocv.MatTypeCV8UC3)
+	defer solid.Close()
+
+	// Scattered bright specks, like glare on a wet road
+	sparse := gocv.NewMatWithSizeFromScalar(gocv.NewScalar(0, 0, 0, 0), 60, 60, gocv.MatTypeCV8UC3)
+	defer sparse.Close()
+	for y := 2; y < 60; y += 12 {
+		for x := 2; x < 60; x += 12 {
+			gocv.Rectangle(&sparse, image.Rect(x, y, x+3, y+3), color.RGBA{R: 255, G: 255, B: 255}, -1)
+		}
+	}
+
+	if ratio := lpe.calculateFillRatio(solid); ratio < lpe.minFillRatio {
+		t.Errorf("Expected solid region to pass fill ratio, got %.3f", ratio)
+	}
+	if ratio := lpe.calculateFillRatio(sparse); ratio >= lpe.minFillRatio {
+		t.Errorf("Expected sparse region to fail fill ratio, got %.3f", ratio)
+	}
+
+	for name, filter := range map[string]func([]lightRegion, gocv.Mat) []lightRegion{
+		"headlight": lpe.filterHeadlightCandidates,
+		"taillight": lpe.filterTaillightCandidates,
+	} {
+		filtered := filter([]lightRegion{{Mat: solid}, {Mat: sparse}}, solid)
+		if len(filtered) != 1 {
+			t.Errorf("%s filter: expected only the solid region to pass, got %d candidates", name, len(filtered))
+		}
+		for _, region := range filtered {
+			region.Close()
+		}
+	}
+}
+
+func TestIRTaillightsDetectedBelowPlateBrightness(t *testing.T) {
+	// IR-like rear view: the plate saturates, the taillights are only mid-bright
+	img := gocv.NewMatWithSizeFromScalar(gocv.NewScalar(30, 30, 30, 0), 480, 640, gocv.MatTypeCV8UC3)
+	defer img.Close()
+
+	midBright := color.RGBA{R: 150, G: 150, B: 150}
+	gocv.Rectangle(&img, image.Rect(140, 140, 180, 200), midBright, -1)
+	gocv.Rectangle(&img, image.Rect(460, 140, 500, 200), midBright, -1)
+	gocv.Rectangle(&img, image.Rect(260, 340, 380, 380), color.RGBA{R: 255, G: 255, B: 255}, -1)
+
+	lpe := NewLightPatternExtractor()
+	features, err := lpe.ExtractLightPatterns(img, gocv.Mat{}, models.ViewRear, models.LightingInfrared)
+	if err != nil {
+		t.Fatalf("Light pattern extraction failed: %v", err)
+	}
+
+	if len(features.LightElements) != 2 {
+		t.Errorf("Expected both taillights and not the plate, got %d light elements", len(features.LightElements))
+	}
+}
+
+// reorderedShape simulates LightShape with its constants declared in another order
+type reorderedShape int
+
+const (
+	reorderedCustom reorderedShape = iota
+	reorderedAngular
+	reorderedRound
+	reorderedRectangular
+)
+
+func (s reorderedShape) String() string {
+	return [...]string{"Custom", "Angular", "Round", "Rectangular"}[s]
+}
+
+func TestLightShapeCodeIsIndependentOfEnumOrder(t *testing.T) {
+	pairs := []struct {
+		shape     models.LightShape
+		reordered reorderedShape
+	}{
+		{models.ShapeRectangular, reorderedRectangular},
+		{models.ShapeRound, reorderedRound},
+		{models.ShapeAngular, reorderedAngular},
+		{models.ShapeCustom, reorderedCustom},
+	}
+
+	for _, p := range pairs {
+		if int(p.shape) == int(p.reordered) {
+			t.Fatalf("Expected %v to have a different value in the reordered enum", p.shape)
+		}
+		if code, reordered := lightShapeCode(p.shape), lightShapeCode(p.reordered); code != reordered {
+			t.Errorf("%v: expected the same code after reordering, got %.1f and %.1f", p.shape, code, reordered)
+		}
+	}
+
+	lpe := NewLightPatternExtractor()
+	element := models.LightElement{Shape: models.ShapeRound, Size: 100, Intensity: 0.5}
+	if signature := lpe.generatePatternSignature([]models.LightElement{element}); signature[0] != 100+0.5+lightShapeCodes["Round"] {
+		t.Errorf("Expected the signature to use the stable shape code, got %.2f", signature[0])
+	}
+}
+
+func TestPatternSignatureSummaryAtFixedSlots(t *testing.T) {
+	lpe := NewLightPatternExtractor()
+	lpe.SetMaxElements(2)
+
+	// More lights than element slots
+	elements := []models.LightElement{
+		{Position: models.Point2D{X: 100}, Shape: models.ShapeRound, Size: 400, Intensity: 0.2},
+		{Position: models.Point2D{X: 500}, Shape: models.ShapeRound, Size: 400, Intensity: 0.4},
+		{Position: models.Point2D{X: 900}, Shape: models.ShapeRound, Size: 400, Intensity: 0.6},
+	}
+
+	signature := lpe.generatePatternSignature(elements)
+	if len(signature) != 2+models.PatternSummarySlots {
+		t.Fatalf("Expected 2 element slots and the summary slots, got %d slots", len(signature))
+	}
+	if want := 400 + 0.4 + lightShapeCodes["Round"]; signature[1] != want {
+		t.Errorf("Expected the last element slot to hold the second light, got %.2f want %.2f", signature[1], want)
+	}
+	if math.Abs(signature[2]-0.4) > 1e-9 {
+		t.Errorf("Expected the average intensity of all lights in slot 2, got %.2f", signature[2])
+	}
+	if want := 1.0 - 1.0/3.0; math.Abs(signature[3]-want) > 1e-9 {
+		t.Errorf("Expected the symmetry in slot 3, got %.2f want %.2f", signature[3], want)
+	}
+}
+
+func TestRelativeLightIntensityIsExposureInvariant(t *testing.T) {
+	// The same taillight against the same bumper, at full and at 60% exposure
+	capture := func(exposure float64) gocv.Mat {
+		level := func(v float64) uint8 { return uint8(v * exposure) }
+		img := gocv.NewMatWithSizeFromScalar(gocv.NewScalar(float64(level(60)), float64(level(60)), float64(level(60)), 0), 200, 200, gocv.MatTypeCV8UC3)
+		gocv.Rectangle(&img, image.Rect(80, 70, 120, 130), color.RGBA{R: level(220), G: level(220), B: level(220)}, -1)
+		return img
+	}
+	bright := capture(1.0)
+	defer bright.Close()
+	dim := capture(0.6)
+	defer dim.Close()
+
+	lpe := NewLightPatternExtractor()
+	rect := image.Rect(80, 70, 120, 130)
+
+	intensities := func(img gocv.Mat) (absolute, relative float64) {
+		region := img.Region(rect)
+		defer region.Close()
+		return lpe.calculateLightIntensity(region), lpe.calculateRelativeIntensity(img, rect)
+	}
+	brightAbsolute, brightRelative := intensities(bright)
+	dimAbsolute, dimRelative := intensities(dim)
+
+	if math.Abs(brightAbsolute-dimAbsolute) < 0.2 {
+		t.Errorf("Expected absolute intensity to follow the exposure, got %.3f and %.3f", brightAbsolute, dimAbsolute)
+	}
+	if math.Abs(brightRelative-dimRelative) > 0.02 {
+		t.Errorf("Expected relative intensity to stay stable across exposures, got %.3f and %.3f", brightRelative, dimRelative)
+	}
+	if brightRelative <= 0 {
+		t.Errorf("Expected a light brighter than its surroundings to have positive relative intensity, got %.3f", brightRelative)
+	}
+}
+
+func TestLightElementPositionsFollowTheLights(t *testing.T) {
+	lpe := NewLightPatternExtractor()
+
+	for _, offsetX := range []int{0, 60} {
+		spec := testutil.DefaultSpec()
+		spec.OffsetX = offsetX
+		img := testutil.RearVehicle(spec)
+
+		features, err := lpe.ExtractLightPatterns(img, gocv.Mat{}, models.ViewRear, models.LightingDaylight)
+		img.Close()
+		if err != nil {
+			t.Fatalf("Light pattern extraction failed: %v", err)
+		}
+		if len(features.LightElements) != 2 {
+			t.Fatalf("Expected both taillights, got %d light elements", len(features.LightElements))
+		}
+
+		// The taillights are centered LightSpacing apart around the shifted vehicle center
+		centerX := float64(testutil.ImageWidth/2 + offsetX)
+		half := float64(spec.LightSpacing) / 2
+		for _, e := range features.LightElements {
+			left := math.Abs(e.Position.X - (centerX - half))
+			right := math.Abs(e.Position.X - (centerX + half))
+			if math.Min(left, right) > 2 || math.Abs(e.Position.Y-float64(spec.LightY)) > 2 {
+				t.Errorf("offset %d: expected a taillight at x %.0f or %.0f and y %d, got (%.1f, %.1f)",
+					offsetX, centerX-half, centerX+half, spec.LightY, e.Position.X, e.Position.Y)
+			}
+		}
+		if span := math.Abs(features.LightElements[0].Position.X - features.LightElements[1].Position.X); math.Abs(span-float64(spec.LightSpacing)) > 2 {
+			t.Errorf("offset %d: expected the taillights %d px apart, got %.1f", offsetX, spec.LightSpacing, span)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package extractor
+
+import (
+	"image"
+
+	"gocv.io/x/gocv"
+)
+
+// HasMask reports whether mask restricts extraction to part of the image. Extractors
+// take the caller's region of interest as a single-channel 8-bit Mat of the image's
+// size, nonzero on the region; the zero Mat, or an empty one, stands for the whole
+// image.
+func HasMask(mask gocv.Mat) bool {
+	return mask.Ptr() != nil && !mask.Empty()
+}
+
+// maskBinary clears the pixels of binary, a threshold or edge image of the area rect
+// of the full image, that fall outside mask, so no contour or line is found there.
+// Masking the binary image rather than the input adds no edges along the mask
+// boundary.
+func maskBinary(binary *gocv.Mat, mask gocv.Mat, rect image.Rectangle) {
+	if !HasMask(mask) {
+		return
+	}
+
+	region := mask.Region(rect)
+	defer region.Close()
+	gocv.BitwiseAnd(*binary, region, binary)
+}
+
+// fullRect is the area of the whole image, for maskBinary
+func fullRect(img gocv.Mat) image.Rectangle {
+	return image.Rect(0, 0, img.Cols(), img.Rows())
+}
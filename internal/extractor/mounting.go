@@ -0,0 +1,100 @@
+package extractor
+
+import (
+	"image"
+	"math"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+	"gocv.io/x/gocv"
+)
+
+type MountingPointExtractor struct {
+	searchMargin   float64 // Search area beyond the plate on each side, as a fraction of plate size
+	minDiameter    float64 // Screw/bracket diameter range as a fraction of plate height
+	maxDiameter    float64
+	minCircularity float64
+}
+
+func NewMountingPointExtractor() *MountingPointExtractor {
+	return &MountingPointExtractor{
+		searchMargin:   0.25,
+		minDiameter:    0.05,
+		maxDiameter:    0.3,
+		minCircularity: 0.6,
+	}
+}
+
+// ExtractMountingPoints finds small dark circular features (screw heads, bracket
+// holes) on and around the plate. Their positions relative to the plate survive a
+// plate swap, so they are a strong signal for plate-swap forensics. Features outside
+// mask (see HasMask) are ignored.
+func (mpe *MountingPointExtractor) ExtractMountingPoints(img, mask gocv.Mat, plate models.Bounds) []models.Point2D {
+	points := []models.Point2D{}
+	if plate.Width <= 0 || plate.Height <= 0 {
+		return points
+	}
+
+	marginX := int(float64(plate.Width) * mpe.searchMargin)
+	marginY := int(float64(plate.Height) * mpe.searchMargin)
+	search := image.Rect(plate.X-marginX, plate.Y-marginY, plate.X+plate.Width+marginX, plate.Y+plate.Height+marginY).
+		Intersect(image.Rect(0, 0, img.Cols(), img.Rows()))
+	if search.Empty() {
+		return points
+	}
+
+	gray := gocv.NewMat()
+	defer gray.Close()
+
+	if img.Channels() > 1 {
+		gocv.CvtColor(img, &gray, gocv.ColorBGRToGray)
+	} else {
+		img.CopyTo(&gray)
+	}
+
+	region := gray.Region(search)
+	defer region.Close()
+
+	// Dark spots against their local surroundings
+	dark := gocv.NewMat()
+	defer dark.Close()
+	blockSize := clampKernelSize(int(float64(plate.Height)*mpe.maxDiameter)*2+1, region.Rows(), region.Cols())
+	if blockSize < 3 {
+		return points
+	}
+	gocv.AdaptiveThreshold(region, &dark, 255, gocv.AdaptiveThresholdMean, gocv.ThresholdBinaryInv, blockSize, 10)
+	maskBinary(&dark, mask, search)
+
+	// List rather than external contours: screws sit inside the dark ring the threshold
+	// leaves around a plate edge
+	contours := gocv.FindContours(dark, gocv.RetrievalList, gocv.ChainApproxSimple)
+	defer contours.Close()
+
+	minDiameter := float64(plate.Height) * mpe.minDiameter
+	maxDiameter := float64(plate.Height) * mpe.maxDiameter
+
+	for i := 0; i < contours.Size(); i++ {
+		contour := contours.At(i)
+		rect := gocv.BoundingRect(contour)
+
+		diameter := float64(rect.Dx()+rect.Dy()) / 2.0
+		if diameter < minDiameter || diameter > maxDiameter {
+			continue
+		}
+
+		area := gocv.ContourArea(contour)
+		perimeter := gocv.ArcLength(contour, true)
+		if perimeter == 0 {
+			continue
+		}
+		if circularity := 4 * math.Pi * area / (perimeter * perimeter); circularity < mpe.minCircularity {
+			continue
+		}
+
+		points = append(points, models.Point2D{
+			X: float64(search.Min.X) + float64(rect.Min.X+rect.Max.X)/2.0,
+			Y: float64(search.Min.Y) + float64(rect.Min.Y+rect.Max.Y)/2.0,
+		})
+	}
+
+	return points
+}
@@ -0,0 +1,46 @@
+package extractor
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+	"gocv.io/x/gocv"
+)
+
+func TestExtractMountingPoints(t *testing.T) {
+	mpe := NewMountingPointExtractor()
+
+	img := gocv.NewMatWithSizeFromScalar(gocv.NewScalar(30, 30, 30, 0), 480, 640, gocv.MatTypeCV8UC3)
+	defer img.Close()
+
+	plate := models.Bounds{X: 230, Y: 330, Width: 180, Height: 60}
+	gocv.Rectangle(&img, image.Rect(plate.X, plate.Y, plate.X+plate.Width, plate.Y+plate.Height), color.RGBA{R: 255, G: 255, B: 255}, -1)
+
+	if points := mpe.ExtractMountingPoints(img, gocv.Mat{}, plate); len(points) != 0 {
+		t.Errorf("Expected no mounting points on a bare plate, got %v", points)
+	}
+
+	screws := []image.Point{{X: 255, Y: 360}, {X: 385, Y: 360}}
+	for _, s := range screws {
+		gocv.Circle(&img, s, 5, color.RGBA{R: 20, G: 20, B: 20}, -1)
+	}
+
+	points := mpe.ExtractMountingPoints(img, gocv.Mat{}, plate)
+	if len(points) != len(screws) {
+		t.Fatalf("Expected %d mounting points, got %v", len(screws), points)
+	}
+	for _, s := range screws {
+		found := false
+		for _, p := range points {
+			if math.Hypot(p.X-float64(s.X), p.Y-float64(s.Y)) <= 2 {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Expected a mounting point near %v, got %v", s, points)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package extractor
 
 import (
+	"github.com/choff5507/vehicle-image-comparison/internal/matstat"
 	"github.com/choff5507/vehicle-image-comparison/internal/models"
 	"gocv.io/x/gocv"
 	"image"
@@ -14,6 +15,32 @@ type LicensePlateExtractor struct {
 	maxPlateHeight int
 	aspectRatioMin float64
 	aspectRatioMax float64
+	
+	minDetectionConfidence float64 // Candidates scoring below this are not reported as detected
+	
+	positionPriors map[models.VehicleView]PlatePositionPrior
+}
+
+// PlatePositionPrior describes where plates are expected in a view. Candidates whose
+// top edge is in the upper third of the image get UpperThirdScore as their vertical
+// position score instead of 1, and CenterWeight of the position score goes to how
+// close the candidate is to the horizontal center.
+type PlatePositionPrior struct {
+	UpperThirdScore float64
+	CenterWeight    float64
+}
+
+// score rates a candidate's position in a rows x cols image, 0-1
+func (prior PlatePositionPrior) score(rect image.Rectangle, rows, cols int) float64 {
+	vertical := 1.0
+	if rect.Min.Y < rows/3 {
+		vertical = prior.UpperThirdScore
+	}
+	
+	offCenter := math.Abs(float64(rect.Min.X+rect.Max.X)/2.0/float64(cols)-0.5) * 2.0
+	horizontal := 1.0 - math.Min(1.0, offCenter)
+	
+	return vertical * (1.0 - prior.CenterWeight + prior.CenterWeight*horizontal)
 }
 
 func NewLicensePlateExtractor() *LicensePlateExtractor {
@@ -24,11 +51,38 @@ func NewLicensePlateExtractor() *LicensePlateExtractor {
 		maxPlateHeight: 120,  // Maximum plate height in pixels
 		aspectRatioMin: 2.0,  // US plates are roughly 2:1 to 4:1 ratio
 		aspectRatioMax: 4.5,
+		
+		minDetectionConfidence: 0.5,
+		
+		positionPriors: map[models.VehicleView]PlatePositionPrior{
+			// Front plates are mounted off-center or left off entirely in some regions
+			models.ViewFront: {UpperThirdScore: 0.75, CenterWeight: 0.0},
+			// Rear plates are reliably centered in the lower part of the vehicle
+			models.ViewRear:    {UpperThirdScore: 0.25, CenterWeight: 0.5},
+			models.ViewUnknown: {UpperThirdScore: 0.5, CenterWeight: 0.0},
+		},
 	}
 }
 
-// DetectLicensePlate finds the license plate region in IR images
-func (lpe *LicensePlateExtractor) DetectLicensePlate(img gocv.Mat) (*models.LicensePlateRegion, error) {
+// SetPositionPrior sets where plates are expected in a view. Views without a prior
+// use the ViewUnknown one.
+func (lpe *LicensePlateExtractor) SetPositionPrior(view models.VehicleView, prior PlatePositionPrior) {
+	lpe.positionPriors[view] = prior
+}
+
+func (lpe *LicensePlateExtractor) positionPrior(view models.VehicleView) PlatePositionPrior {
+	if prior, ok := lpe.positionPriors[view]; ok {
+		return prior
+	}
+	return lpe.positionPriors[models.ViewUnknown]
+}
+
+// DetectLicensePlate finds the license plate region in IR images. It always returns a
+// region, falling back to the brightest plate-shaped area or the bottom center of the
+// image; Detected is only set for real plate candidates above the confidence floor.
+// Candidates are scored against the position prior for the view, and only found
+// inside mask (see HasMask).
+func (lpe *LicensePlateExtractor) DetectLicensePlate(img, mask gocv.Mat, view models.VehicleView) (*models.LicensePlateRegion, error) {
 	// Convert to grayscale if not already
 	gray := gocv.NewMat()
 	defer gray.Close()
@@ -56,6 +110,7 @@ func (lpe *LicensePlateExtractor) DetectLicensePlate(img gocv.Mat) (*models.Lice
 	combined := gocv.NewMat()
 	defer combined.Close()
 	gocv.BitwiseOr(thresh, brightThresh, &combined)
+	maskBinary(&combined, mask, fullRect(img))
 	
 	// Find contours
 	contours := gocv.FindContours(combined, gocv.RetrievalExternal, gocv.ChainApproxSimple)
@@ -71,7 +126,7 @@ func (lpe *LicensePlateExtractor) DetectLicensePlate(img gocv.Mat) (*models.Lice
 		
 		// Check if dimensions match typical license plate proportions
 		if lpe.isValidPlateSize(rect) {
-			score := lpe.scorePlateCandidate(gray, rect, contour)
+			score := lpe.scorePlateCandidate(gray, rect, contour, lpe.positionPrior(view))
 			
 			if score > bestScore {
 				bestScore = score
@@ -90,12 +145,14 @@ func (lpe *LicensePlateExtractor) DetectLicensePlate(img gocv.Mat) (*models.Lice
 		}
 	}
 	
-	if bestCandidate == nil {
-		// Fallback: find brightest rectangular region
-		bestCandidate = lpe.findBrightestRectangularRegion(gray)
+	if bestCandidate != nil {
+		bestCandidate.Detected = bestScore >= lpe.minDetectionConfidence
+		return bestCandidate, nil
 	}
 	
-	return bestCandidate, nil
+	// Fallback: find brightest rectangular region. The bounds are still returned so
+	// callers have a region to work with, but it is not reported as a detected plate.
+	return lpe.findBrightestRectangularRegion(gray), nil
 }
 
 func (lpe *LicensePlateExtractor) isValidPlateSize(rect image.Rectangle) bool {
@@ -119,7 +176,7 @@ func (lpe *LicensePlateExtractor) isValidPlateSize(rect image.Rectangle) bool {
 	return true
 }
 
-func (lpe *LicensePlateExtractor) scorePlateCandidate(gray gocv.Mat, rect image.Rectangle, contour gocv.PointVector) float64 {
+func (lpe *LicensePlateExtractor) scorePlateCandidate(gray gocv.Mat, rect image.Rectangle, contour gocv.PointVector, prior PlatePositionPrior) float64 {
 	score := 0.0
 	
 	// Score based on brightness (higher is better for IR retroreflective plates)
@@ -139,12 +196,8 @@ func (lpe *LicensePlateExtractor) scorePlateCandidate(gray gocv.Mat, rect image.
 	ratioScore := 1.0 - math.Abs(aspectRatio-idealRatio)/idealRatio
 	score += ratioScore * 0.2
 	
-	// Score based on position (plates typically in lower portion of image)
-	positionScore := 1.0
-	if rect.Min.Y < gray.Rows()/3 {
-		positionScore = 0.5 // Penalize plates in upper portion
-	}
-	score += positionScore * 0.1
+	// Score based on where plates are expected in this view
+	score += prior.score(rect, gray.Rows(), gray.Cols()) * 0.1
 	
 	return math.Max(0.0, math.Min(1.0, score))
 }
@@ -154,13 +207,7 @@ func (lpe *LicensePlateExtractor) calculateAverageBrightness(gray gocv.Mat, rect
 	roi := gray.Region(rect)
 	defer roi.Close()
 	
-	// Calculate mean brightness
-	mean := gocv.NewMat()
-	defer mean.Close()
-	gocv.MeanStdDev(roi, &mean, nil)
-	
-	// Get the mean value from the result
-	return mean.GetDoubleAt(0, 0)
+	return matstat.MeanOf(roi)
 }
 
 func (lpe *LicensePlateExtractor) isReflectiveRegion(gray gocv.Mat, rect image.Rectangle) bool {
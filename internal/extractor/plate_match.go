@@ -0,0 +1,82 @@
+package extractor
+
+import (
+	"image"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/matstat"
+	"gocv.io/x/gocv"
+)
+
+// Plate crops are resized to a common size before correlating, so plates captured
+// at different distances line up character for character
+const (
+	plateMatchWidth  = 240
+	plateMatchHeight = 80
+
+	// Fraction of each dimension trimmed from the template crop, so plate bounds
+	// detected a few pixels apart still line up somewhere in the search
+	plateMatchMargin = 0.1
+
+	// Crops flatter than this (gray level standard deviation) have no characters to
+	// correlate
+	minPlateMatchContrast = 2.0
+)
+
+// PlateCorrelation is the normalized cross-correlation (TM_CCOEFF_NORMED) of two
+// license plate crops, from -1 to 1. The same physical plate, with the same
+// characters and wear, correlates close to 1; different plates correlate far lower.
+// The crops are converted to gray and resized to a common size, and each is matched
+// against the other with a small positional tolerance; the result is the mean of
+// both directions, so it does not depend on the order of the arguments. Returns 0
+// for empty or featureless crops.
+func PlateCorrelation(crop1, crop2 gocv.Mat) float64 {
+	if crop1.Empty() || crop2.Empty() {
+		return 0.0
+	}
+
+	plate1 := normalizedPlate(crop1)
+	defer plate1.Close()
+	plate2 := normalizedPlate(crop2)
+	defer plate2.Close()
+
+	if matstat.StddevOf(plate1) < minPlateMatchContrast || matstat.StddevOf(plate2) < minPlateMatchContrast {
+		return 0.0
+	}
+
+	correlation := (matchPlate(plate1, plate2) + matchPlate(plate2, plate1)) / 2.0
+	return min(max(correlation, -1.0), 1.0)
+}
+
+// normalizedPlate converts a plate crop to gray at the common matching size
+func normalizedPlate(crop gocv.Mat) gocv.Mat {
+	gray := gocv.NewMat()
+	defer gray.Close()
+
+	if crop.Channels() > 1 {
+		gocv.CvtColor(crop, &gray, gocv.ColorBGRToGray)
+	} else {
+		crop.CopyTo(&gray)
+	}
+
+	resized := gocv.NewMat()
+	gocv.Resize(gray, &resized, image.Pt(plateMatchWidth, plateMatchHeight), 0, 0, gocv.InterpolationArea)
+	return resized
+}
+
+// matchPlate is the best correlation of the center of template anywhere within the
+// margin of search
+func matchPlate(search, template gocv.Mat) float64 {
+	marginX := int(plateMatchWidth * plateMatchMargin / 2)
+	marginY := int(plateMatchHeight * plateMatchMargin / 2)
+	center := template.Region(image.Rect(marginX, marginY, plateMatchWidth-marginX, plateMatchHeight-marginY))
+	defer center.Close()
+
+	result := gocv.NewMat()
+	defer result.Close()
+	mask := gocv.NewMat()
+	defer mask.Close()
+	gocv.MatchTemplate(search, center, &result, gocv.TmCcoeffNormed, mask)
+
+	_, best, _, _ := gocv.MinMaxLoc(result)
+	return float64(best)
+}
@@ -0,0 +1,54 @@
+package extractor
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"gocv.io/x/gocv"
+)
+
+// plateCrop renders a dark-on-white plate reading text
+func plateCrop(text string) gocv.Mat {
+	crop := gocv.NewMatWithSizeFromScalar(gocv.NewScalar(235, 235, 235, 0), 100, 240, gocv.MatTypeCV8UC3)
+	gocv.Rectangle(&crop, image.Rect(3, 3, 237, 97), color.RGBA{R: 20, G: 20, B: 20}, 2)
+	gocv.PutText(&crop, text, image.Pt(14, 68), gocv.FontHersheySimplex, 1.4, color.RGBA{R: 20, G: 20, B: 20}, 4)
+	return crop
+}
+
+func TestPlateCorrelationIdentifiesSamePlate(t *testing.T) {
+	plate := plateCrop("ABC 123")
+	defer plate.Close()
+	other := plateCrop("XYZ 789")
+	defer other.Close()
+
+	// The same plate captured from further away
+	distant := gocv.NewMat()
+	defer distant.Close()
+	gocv.Resize(plate, &distant, image.Pt(160, 67), 0, 0, gocv.InterpolationArea)
+
+	same := PlateCorrelation(plate, distant)
+	if same < 0.9 {
+		t.Errorf("Expected identical plate crops to correlate above 0.9, got %.3f", same)
+	}
+
+	different := PlateCorrelation(plate, other)
+	if different > 0.6 {
+		t.Errorf("Expected different plate crops to correlate below 0.6, got %.3f", different)
+	}
+
+	if reversed := PlateCorrelation(distant, plate); reversed != same {
+		t.Errorf("Expected the correlation not to depend on argument order, got %.3f and %.3f", same, reversed)
+	}
+}
+
+func TestPlateCorrelationOfFeaturelessCrop(t *testing.T) {
+	plate := plateCrop("ABC 123")
+	defer plate.Close()
+	blank := gocv.NewMatWithSizeFromScalar(gocv.NewScalar(235, 235, 235, 0), 100, 240, gocv.MatTypeCV8UC3)
+	defer blank.Close()
+
+	if correlation := PlateCorrelation(plate, blank); correlation != 0 {
+		t.Errorf("Expected a blank crop to correlate 0, got %.3f", correlation)
+	}
+}
@@ -0,0 +1,70 @@
+package extractor
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+	"gocv.io/x/gocv"
+)
+
+func TestDetectLicensePlateReportsFallback(t *testing.T) {
+	lpe := NewLicensePlateExtractor()
+
+	empty := gocv.NewMatWithSizeFromScalar(gocv.NewScalar(30, 30, 30, 0), 480, 640, gocv.MatTypeCV8UC3)
+	defer empty.Close()
+
+	region, err := lpe.DetectLicensePlate(empty, gocv.Mat{}, models.ViewRear)
+	if err != nil {
+		t.Fatalf("DetectLicensePlate failed: %v", err)
+	}
+	if region.Detected {
+		t.Errorf("Expected no plate to be detected in a plate-less image, got %+v", region)
+	}
+	if region.Bounds.Width == 0 || region.Bounds.Height == 0 {
+		t.Errorf("Expected fallback bounds to still be returned, got %+v", region.Bounds)
+	}
+
+	withPlate := empty.Clone()
+	defer withPlate.Close()
+	gocv.Rectangle(&withPlate, image.Rect(230, 330, 410, 390), color.RGBA{R: 255, G: 255, B: 255}, -1)
+
+	region, err = lpe.DetectLicensePlate(withPlate, gocv.Mat{}, models.ViewRear)
+	if err != nil {
+		t.Fatalf("DetectLicensePlate failed: %v", err)
+	}
+	if !region.Detected {
+		t.Errorf("Expected the plate to be detected, got %+v", region)
+	}
+}
+
+func TestRearPriorFavorsCenteredPlate(t *testing.T) {
+	lpe := NewLicensePlateExtractor()
+
+	// Two identical plate-shaped reflectors in the lower part of a rear view, one
+	// centered and one near the edge
+	img := gocv.NewMatWithSizeFromScalar(gocv.NewScalar(30, 30, 30, 0), 480, 640, gocv.MatTypeCV8UC3)
+	defer img.Close()
+	centered := image.Rect(230, 330, 410, 390)
+	offCenter := image.Rect(20, 330, 200, 390)
+	gocv.Rectangle(&img, centered, color.RGBA{R: 255, G: 255, B: 255}, -1)
+	gocv.Rectangle(&img, offCenter, color.RGBA{R: 255, G: 255, B: 255}, -1)
+
+	rear := lpe.positionPrior(models.ViewRear)
+	if rear.score(centered, img.Rows(), img.Cols()) <= rear.score(offCenter, img.Rows(), img.Cols()) {
+		t.Error("Expected the rear prior to score a centered plate above an off-center one")
+	}
+	front := lpe.positionPrior(models.ViewFront)
+	if front.score(centered, img.Rows(), img.Cols()) != front.score(offCenter, img.Rows(), img.Cols()) {
+		t.Error("Expected the front prior to accept off-center plates")
+	}
+
+	region, err := lpe.DetectLicensePlate(img, gocv.Mat{}, models.ViewRear)
+	if err != nil {
+		t.Fatalf("DetectLicensePlate failed: %v", err)
+	}
+	if region.Bounds.X != centered.Min.X {
+		t.Errorf("Expected the centered plate to be detected, got %+v", region.Bounds)
+	}
+}
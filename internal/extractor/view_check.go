@@ -0,0 +1,62 @@
+package extractor
+
+import (
+	"github.com/choff5507/vehicle-image-comparison/internal/matstat"
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+	"gocv.io/x/gocv"
+)
+
+// Lights come in pairs, so a single light of the wrong kind is not evidence against
+// the classified view
+const minContradictingLights = 2
+
+// CheckView cross-checks a classified front or rear view of a daylight image against
+// its lights inside mask, found as ExtractLightPatterns finds them for either view: bright white
+// headlights for the front, red taillights for the rear. When at least a pair of
+// lights of the opposite view's kind is found and they outnumber the lights of
+// view's own kind, such as red taillights in an image classified as the front, it
+// returns the opposite view and true; otherwise view and false. Monochrome images,
+// whose taillights cannot be told from headlights, are never corrected.
+func (lpe *LightPatternExtractor) CheckView(img, mask gocv.Mat, view models.VehicleView) (models.VehicleView, bool) {
+	var opposite models.VehicleView
+	switch view {
+	case models.ViewFront:
+		opposite = models.ViewRear
+	case models.ViewRear:
+		opposite = models.ViewFront
+	default:
+		return view, false
+	}
+
+	if matstat.IsMonochrome(img) {
+		return view, false
+	}
+
+	headlights, taillights := lpe.countLightCandidates(img, mask)
+	own, contradicting := headlights, taillights
+	if view == models.ViewRear {
+		own, contradicting = taillights, headlights
+	}
+
+	if contradicting >= minContradictingLights && contradicting > own {
+		return opposite, true
+	}
+	return view, false
+}
+
+// countLightCandidates runs the headlight and taillight searches of
+// ExtractLightPatterns over img and counts the candidates each keeps inside mask
+func (lpe *LightPatternExtractor) countLightCandidates(img, mask gocv.Mat) (headlights, taillights int) {
+	bright := lpe.findBrightRegions(img, mask, models.LightingDaylight)
+	headlightCandidates := lpe.filterHeadlightCandidates(bright, img)
+	red := lpe.findRedRegions(img, mask)
+	taillightCandidates := lpe.filterTaillightCandidates(red, img)
+
+	headlights, taillights = len(headlightCandidates), len(taillightCandidates)
+	for _, regions := range [][]lightRegion{bright, headlightCandidates, red, taillightCandidates} {
+		for _, region := range regions {
+			region.Close()
+		}
+	}
+	return headlights, taillights
+}
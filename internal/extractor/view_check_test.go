@@ -0,0 +1,55 @@
+package extractor
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+	"github.com/choff5507/vehicle-image-comparison/internal/testutil"
+	"gocv.io/x/gocv"
+)
+
+func TestCheckViewCorrectsMisclassifiedView(t *testing.T) {
+	lpe := NewLightPatternExtractor()
+
+	// Red taillights and a white plate
+	rear := testutil.RearVehicle(testutil.DefaultSpec())
+	defer rear.Close()
+
+	// White headlights and plate on a blue body, so the image is not monochrome
+	frontSpec := testutil.DefaultSpec()
+	frontSpec.BodyColor = color.RGBA{R: 40, G: 60, B: 160}
+	front := testutil.FrontVehicle(frontSpec)
+	defer front.Close()
+
+	for _, tc := range []struct {
+		name       string
+		img        gocv.Mat
+		classified models.VehicleView
+		want       models.VehicleView
+		corrected  bool
+	}{
+		{"rear classified as front", rear, models.ViewFront, models.ViewRear, true},
+		{"rear classified as rear", rear, models.ViewRear, models.ViewRear, false},
+		{"front classified as rear", front, models.ViewRear, models.ViewFront, true},
+		{"front classified as front", front, models.ViewFront, models.ViewFront, false},
+		{"side view", rear, models.ViewSide, models.ViewSide, false},
+	} {
+		view, corrected := lpe.CheckView(tc.img, gocv.Mat{}, tc.classified)
+		if view != tc.want || corrected != tc.corrected {
+			t.Errorf("%s: got %s (corrected %v), want %s (corrected %v)", tc.name, view, corrected, tc.want, tc.corrected)
+		}
+	}
+}
+
+func TestCheckViewLeavesMonochromeImages(t *testing.T) {
+	lpe := NewLightPatternExtractor()
+
+	// The default gray front view has no color to tell its lights apart by
+	front := testutil.FrontVehicle(testutil.DefaultSpec())
+	defer front.Close()
+
+	if view, corrected := lpe.CheckView(front, gocv.Mat{}, models.ViewRear); corrected || view != models.ViewRear {
+		t.Errorf("Expected a monochrome image to keep its classified view, got %s (corrected %v)", view, corrected)
+	}
+}
@@ -0,0 +1,86 @@
+// Package matstat computes summary statistics of OpenCV matrices. gocv.MeanStdDev
+// reports one value per channel in a Mat of its own; reading it through these
+// helpers keeps callers from misreading, or skipping, that result.
+package matstat
+
+import (
+	"math"
+
+	"gocv.io/x/gocv"
+)
+
+// MeanOf returns the mean of every element of mat across all its channels, or 0
+// for an empty Mat
+func MeanOf(mat gocv.Mat) float64 {
+	mean, _ := meanStdDev(mat)
+	return mean
+}
+
+// StddevOf returns the population standard deviation of every element of mat
+// across all its channels, or 0 for an empty Mat
+func StddevOf(mat gocv.Mat) float64 {
+	_, stddev := meanStdDev(mat)
+	return stddev
+}
+
+// meanStdDev pools the per-channel statistics. Every channel has the same number
+// of elements, so the overall mean is the mean of the channel means and the overall
+// variance the mean of each channel's second moment less the squared overall mean.
+func meanStdDev(mat gocv.Mat) (float64, float64) {
+	if mat.Empty() {
+		return 0.0, 0.0
+	}
+
+	means := gocv.NewMat()
+	defer means.Close()
+	stddevs := gocv.NewMat()
+	defer stddevs.Close()
+	gocv.MeanStdDev(mat, &means, &stddevs)
+
+	channels := means.Rows()
+	if channels == 0 {
+		return 0.0, 0.0
+	}
+
+	meanSum, secondMomentSum := 0.0, 0.0
+	for c := 0; c < channels; c++ {
+		mean := means.GetDoubleAt(c, 0)
+		stddev := stddevs.GetDoubleAt(c, 0)
+		meanSum += mean
+		secondMomentSum += stddev*stddev + mean*mean
+	}
+
+	mean := meanSum / float64(channels)
+	variance := secondMomentSum/float64(channels) - mean*mean
+	return mean, math.Sqrt(math.Max(variance, 0.0))
+}
+
+// Largest difference (gray levels) between the channels of a pixel that IsMonochrome
+// still treats as gray, absorbing the chroma noise of JPEG-compressed gray images
+const monochromeTolerance = 2.0
+
+// IsMonochrome reports whether every channel of mat matches the first within
+// monochromeTolerance at every pixel, as in an IR or grayscale capture loaded as
+// color. Single-channel and empty Mats are monochrome.
+func IsMonochrome(mat gocv.Mat) bool {
+	if mat.Empty() || mat.Channels() == 1 {
+		return true
+	}
+
+	channels := gocv.Split(mat)
+	defer func() {
+		for _, channel := range channels {
+			channel.Close()
+		}
+	}()
+
+	diff := gocv.NewMat()
+	defer diff.Close()
+	for _, channel := range channels[1:] {
+		gocv.AbsDiff(channels[0], channel, &diff)
+		if _, maxDiff, _, _ := gocv.MinMaxLoc(diff); float64(maxDiff) > monochromeTolerance {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,83 @@
+package matstat
+
+import (
+	"image"
+	"math"
+	"testing"
+
+	"gocv.io/x/gocv"
+)
+
+func TestMeanAndStddevOfKnownMatrices(t *testing.T) {
+	constant := gocv.NewMatWithSizeFromScalar(gocv.NewScalar(100, 0, 0, 0), 8, 8, gocv.MatTypeCV8U)
+	defer constant.Close()
+
+	// Half 0, half 200
+	halves := gocv.NewMatWithSizeFromScalar(gocv.NewScalar(0, 0, 0, 0), 8, 8, gocv.MatTypeCV8U)
+	defer halves.Close()
+	right := halves.Region(image.Rect(4, 0, 8, 8))
+	right.SetTo(gocv.NewScalar(200, 0, 0, 0))
+	right.Close()
+
+	// Constant channels 10, 20 and 30: the channel means differ, each channel is flat
+	color := gocv.NewMatWithSizeFromScalar(gocv.NewScalar(10, 20, 30, 0), 8, 8, gocv.MatTypeCV8UC3)
+	defer color.Close()
+
+	floats := gocv.NewMatWithSizeFromScalar(gocv.NewScalar(-2.5, 0, 0, 0), 4, 4, gocv.MatTypeCV64F)
+	defer floats.Close()
+
+	empty := gocv.NewMat()
+	defer empty.Close()
+
+	for _, tc := range []struct {
+		name   string
+		mat    gocv.Mat
+		mean   float64
+		stddev float64
+	}{
+		{"all 100", constant, 100, 0},
+		{"half 0 half 200", halves, 100, 100},
+		{"three constant channels", color, 20, math.Sqrt(200.0 / 3.0)},
+		{"negative floats", floats, -2.5, 0},
+		{"empty", empty, 0, 0},
+	} {
+		if mean := MeanOf(tc.mat); math.Abs(mean-tc.mean) > 1e-9 {
+			t.Errorf("%s: MeanOf = %v, want %v", tc.name, mean, tc.mean)
+		}
+		if stddev := StddevOf(tc.mat); math.Abs(stddev-tc.stddev) > 1e-9 {
+			t.Errorf("%s: StddevOf = %v, want %v", tc.name, stddev, tc.stddev)
+		}
+	}
+}
+
+func TestIsMonochrome(t *testing.T) {
+	gray := gocv.NewMatWithSizeFromScalar(gocv.NewScalar(90, 90, 90, 0), 8, 8, gocv.MatTypeCV8UC3)
+	defer gray.Close()
+
+	// Off by one level in one channel, as JPEG chroma noise leaves it
+	noisy := gocv.NewMatWithSizeFromScalar(gocv.NewScalar(90, 91, 90, 0), 8, 8, gocv.MatTypeCV8UC3)
+	defer noisy.Close()
+
+	// Gray except for one red pixel
+	tinted := gray.Clone()
+	defer tinted.Close()
+	tinted.SetUCharAt(3, 3*3+2, 200)
+
+	single := gocv.NewMatWithSizeFromScalar(gocv.NewScalar(90, 0, 0, 0), 8, 8, gocv.MatTypeCV8U)
+	defer single.Close()
+
+	for _, tc := range []struct {
+		name string
+		mat  gocv.Mat
+		want bool
+	}{
+		{"equal channels", gray, true},
+		{"chroma noise", noisy, true},
+		{"one colored pixel", tinted, false},
+		{"single channel", single, true},
+	} {
+		if got := IsMonochrome(tc.mat); got != tc.want {
+			t.Errorf("%s: IsMonochrome = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
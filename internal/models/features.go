@@ -3,6 +3,7 @@ package models
 // VehicleFeatures holds all extracted features for a vehicle image
 type VehicleFeatures struct {
 	View              VehicleView         `json:"view"`
+	Views             ViewSet             `json:"views,omitempty"` // All views shown, e.g. Front+Side for a 3/4 capture; zero means only View
 	Lighting          LightingType        `json:"lighting"`
 	
 	// Universal features (work in all lighting)
@@ -16,7 +17,73 @@ type VehicleFeatures struct {
 	DaylightFeatures  *DaylightFeatures   `json:"daylight_features,omitempty"`
 	InfraredFeatures  *InfraredFeatures   `json:"infrared_features,omitempty"`
 	
-	ExtractionQuality float64             `json:"extraction_quality"`
+	Extraction        ExtractionBreakdown `json:"extraction"`         // What extraction actually found
+	ExtractionQuality float64             `json:"extraction_quality"` // Scalar rollup of Extraction, see ExtractionBreakdown.Quality
+	
+	// JPEG blocking of the source image, 0 (none) to 1 (severe). Block edges pass for
+	// texture, so texture terms are downweighted as it rises.
+	BlockArtifacts    float64             `json:"block_artifacts,omitempty"`
+	
+	// Vehicle region the features were measured in; positions and sizes are
+	// compared relative to it. Zero means the canonical 1280x960 frame.
+	VehicleBounds     Bounds              `json:"vehicle_bounds"`
+	
+	// Width in pixels of the image the features were extracted at. Sets extracted at
+	// different widths are rescaled to a common one before comparison, so stored
+	// reference sets survive a change of extraction scale. Zero means unknown, and
+	// such sets are never rescaled.
+	CanonicalWidth    int                 `json:"canonical_width,omitempty"`
+}
+
+// AllViews returns every view the image shows, View included
+func (vf VehicleFeatures) AllViews() ViewSet {
+	return vf.Views | ViewSetOf(vf.View)
+}
+
+// ExtractionBreakdown records how much of each feature group extraction found, so
+// callers can tell a weak score from missing data
+type ExtractionBreakdown struct {
+	ReferencePoints    int  `json:"reference_points"`
+	StructuralElements int  `json:"structural_elements"`
+	LightElements      int  `json:"light_elements"`
+	MountingPoints     int  `json:"mounting_points"`
+	ColorFeatures      bool `json:"color_features"`    // Daylight color profile present
+	InfraredFeatures   bool `json:"infrared_features"` // IR features present
+	IRSignature        bool `json:"ir_signature"`      // IR signature anchored on a detected plate
+}
+
+// BreakdownOf counts what was extracted into features
+func BreakdownOf(features VehicleFeatures) ExtractionBreakdown {
+	breakdown := ExtractionBreakdown{
+		ReferencePoints:    len(features.GeometricFeatures.ReferencePoints),
+		StructuralElements: len(features.GeometricFeatures.StructuralElements),
+		LightElements:      len(features.LightPatterns.LightElements),
+		MountingPoints:     len(features.BumperFeatures.MountingPoints),
+		ColorFeatures:      features.DaylightFeatures != nil,
+		InfraredFeatures:   features.InfraredFeatures != nil,
+	}
+	if ir := features.InfraredFeatures; ir != nil && ir.IRSignature != nil {
+		breakdown.IRSignature = ir.IRSignature.PlateRegion.Detected
+	}
+	return breakdown
+}
+
+// Quality rolls the breakdown up into a single 0-1 score: the mean over geometry,
+// lights and lighting-specific features of a fixed credit for each group found
+func (eb ExtractionBreakdown) Quality() float64 {
+	quality := 0.0
+	
+	if eb.ReferencePoints > 0 {
+		quality += 0.8
+	}
+	if eb.LightElements > 0 {
+		quality += 0.9
+	}
+	if eb.ColorFeatures || eb.InfraredFeatures {
+		quality += 0.7
+	}
+	
+	return quality / 3.0
 }
 
 // GeometricFeatures - work in all lighting conditions
@@ -24,13 +91,30 @@ type GeometricFeatures struct {
 	VehicleProportions VehicleProportions `json:"vehicle_proportions"`
 	StructuralElements []StructuralElement `json:"structural_elements"`
 	ReferencePoints    []Point2D          `json:"reference_points"`
+	Keypoints          []Keypoint         `json:"keypoints,omitempty"` // Only extracted when keypoint matching is enabled
+	Grille             *GrilleFeatures    `json:"grille,omitempty"`    // Front views only; nil when no grille was found
+}
+
+// GrilleFeatures describes the grille pattern, which is highly model-specific
+type GrilleFeatures struct {
+	Region      Bounds  `json:"region"`
+	LineSpacing float64 `json:"line_spacing"` // Dominant horizontal slat/mesh line spacing in pixels, 0 if none
+	EdgeDensity float64 `json:"edge_density"` // Fraction of grille pixels that are edges
+}
+
+// Keypoint is a detected corner-like feature with its binary (ORB) descriptor
+type Keypoint struct {
+	Position   Point2D `json:"position"`
+	Descriptor []byte  `json:"descriptor"`
 }
 
-// VehicleProportions holds dimensional ratios
+// VehicleProportions holds dimensional ratios. Roofless marks an open-top vehicle,
+// whose UpperLowerRatio depends on whether the top is up and is not compared.
 type VehicleProportions struct {
 	WidthHeightRatio  float64 `json:"width_height_ratio"`
 	UpperLowerRatio   float64 `json:"upper_lower_ratio"`
 	LicensePlateRatio float64 `json:"license_plate_ratio"`
+	Roofless          bool    `json:"roofless,omitempty"`
 }
 
 // StructuralElement represents a structural component
@@ -40,10 +124,15 @@ type StructuralElement struct {
 	Size     float64 `json:"size"`
 }
 
+// PatternSummarySlots is the number of pattern-level slots, average intensity then
+// symmetry, at the end of a PatternSignature. The slots before them hold one light
+// element each.
+const PatternSummarySlots = 2
+
 // LightPatternFeatures for headlights/taillights
 type LightPatternFeatures struct {
 	LightElements      []LightElement     `json:"light_elements"`
-	PatternSignature   []float64          `json:"pattern_signature"`
+	PatternSignature   []float64          `json:"pattern_signature"` // Element slots, then PatternSummarySlots
 	LightConfiguration LightConfiguration `json:"light_configuration"`
 }
 
@@ -64,6 +153,23 @@ const (
 	ShapeCustom
 )
 
+// String returns the shape's name. Names are stable: pattern signatures encode
+// shapes by name, so they must not change when constants are added or reordered.
+func (s LightShape) String() string {
+	switch s {
+	case ShapeRectangular:
+		return "Rectangular"
+	case ShapeRound:
+		return "Round"
+	case ShapeAngular:
+		return "Angular"
+	case ShapeCustom:
+		return "Custom"
+	default:
+		return "Unknown"
+	}
+}
+
 type LightType int
 const (
 	TypeHeadlight LightType = iota
@@ -73,6 +179,24 @@ const (
 	TypeBrakeLight
 )
 
+// String returns the light type's name. Like LightShape names, these are stable.
+func (t LightType) String() string {
+	switch t {
+	case TypeHeadlight:
+		return "Headlight"
+	case TypeTaillight:
+		return "Taillight"
+	case TypeDRL:
+		return "DRL"
+	case TypeFogLight:
+		return "FogLight"
+	case TypeBrakeLight:
+		return "BrakeLight"
+	default:
+		return "Unknown"
+	}
+}
+
 // LightConfiguration represents the overall light setup
 type LightConfiguration struct {
 	NumElements int     `json:"num_elements"`
@@ -109,6 +233,11 @@ type InfraredFeatures struct {
 type ColorProfile struct {
 	DominantColors []Color `json:"dominant_colors"`
 	Histogram      []int   `json:"histogram"`
+	
+	// Normalized (sum to 1) hue and saturation distributions. Brightness is left out
+	// so exposure differences don't dominate; hue only counts chromatic pixels.
+	HueHistogram        []float64 `json:"hue_histogram,omitempty"`
+	SaturationHistogram []float64 `json:"saturation_histogram,omitempty"`
 }
 
 type Color struct {
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBreakdownOfReflectsExtractedFeatures(t *testing.T) {
+	features := VehicleFeatures{
+		GeometricFeatures: GeometricFeatures{
+			ReferencePoints:    make([]Point2D, 3),
+			StructuralElements: make([]StructuralElement, 5),
+		},
+		LightPatterns:  LightPatternFeatures{LightElements: make([]LightElement, 2)},
+		BumperFeatures: BumperFeatures{MountingPoints: make([]Point2D, 4)},
+		InfraredFeatures: &InfraredFeatures{
+			IRSignature: &IRSignature{PlateRegion: LicensePlateRegion{Detected: true}},
+		},
+	}
+
+	breakdown := BreakdownOf(features)
+	expected := ExtractionBreakdown{
+		ReferencePoints:    3,
+		StructuralElements: 5,
+		LightElements:      2,
+		MountingPoints:     4,
+		InfraredFeatures:   true,
+		IRSignature:        true,
+	}
+	if breakdown != expected {
+		t.Errorf("Expected breakdown %+v, got %+v", expected, breakdown)
+	}
+	if quality := breakdown.Quality(); math.Abs(quality-(0.8+0.9+0.7)/3.0) > 1e-9 {
+		t.Errorf("Expected full rollup quality, got %.3f", quality)
+	}
+
+	// Lights missing and the signature built around a fallback plate region
+	features.LightPatterns.LightElements = nil
+	features.InfraredFeatures.IRSignature.PlateRegion.Detected = false
+
+	breakdown = BreakdownOf(features)
+	if breakdown.LightElements != 0 || breakdown.IRSignature {
+		t.Errorf("Expected no lights and no anchored IR signature, got %+v", breakdown)
+	}
+	if quality := breakdown.Quality(); math.Abs(quality-(0.8+0.7)/3.0) > 1e-9 {
+		t.Errorf("Expected the rollup to drop the light credit, got %.3f", quality)
+	}
+
+	if quality := BreakdownOf(VehicleFeatures{}).Quality(); quality != 0 {
+		t.Errorf("Expected zero quality for empty features, got %.3f", quality)
+	}
+}
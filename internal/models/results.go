@@ -1,14 +1,79 @@
 package models
 
-import "math"
+import (
+	"math"
+	"strconv"
+)
 
 // ComparisonResult holds the final comparison results
 type ComparisonResult struct {
-	IsSameVehicle   bool            `json:"is_same_vehicle"`
-	SimilarityScore float64         `json:"similarity_score"`
-	ConfidenceLevel ConfidenceLevel `json:"confidence_level"`
-	DetailedScores  DetailedScores  `json:"detailed_scores"`
-	ProcessingInfo  ProcessingInfo  `json:"processing_info"`
+	IsSameVehicle    bool                `json:"is_same_vehicle"`
+	Decision         Decision            `json:"decision"` // Uncertain when the score is too close to the threshold to call
+	SimilarityScore  float64             `json:"similarity_score"`
+	ConfidenceLevel  ConfidenceLevel     `json:"confidence_level"`
+	DetailedScores   DetailedScores      `json:"detailed_scores"`
+	ProcessingInfo   ProcessingInfo      `json:"processing_info"`
+	EffectiveWeights FeatureWeights      `json:"effective_weights"`
+	Availability     FeatureAvailability `json:"feature_availability"`  // Which DetailedScores were computed, and why not
+	SubScores        *SubScores          `json:"sub_scores,omitempty"`  // Unweighted components, only when requested
+	PlateMatch       *PlateMatch         `json:"plate_match,omitempty"` // Plate identity check, only when requested
+	Warnings         []string            `json:"warnings,omitempty"`    // Non-fatal issues that may reduce reliability
+}
+
+// PlateMatch is the plate identity check: whether the same physical plate, with the
+// same characters and wear, appears in both images, independently of whether the
+// vehicles match. The same plate on different vehicles indicates plate reuse.
+type PlateMatch struct {
+	Detected    bool    `json:"detected"`    // A plate was detected in both images; the other fields are zero otherwise
+	Correlation float64 `json:"correlation"` // Normalized cross-correlation of the plate crops, -1 to 1
+	SamePlate   bool    `json:"same_plate"`  // Correlation is high enough to be the identical plate
+}
+
+// Decision is the verdict of a comparison. IsSameVehicle always carries the hard
+// threshold decision; Decision additionally flags scores within the uncertainty band
+// around the threshold for manual review, and pairs with no features to compare.
+type Decision int
+const (
+	DecisionSame Decision = iota
+	DecisionDifferent
+	DecisionUncertain
+	DecisionUndetermined // Neither image yielded features to compare; the score means nothing
+)
+
+func (d Decision) String() string {
+	switch d {
+	case DecisionSame:
+		return "Same"
+	case DecisionDifferent:
+		return "Different"
+	case DecisionUncertain:
+		return "Uncertain"
+	case DecisionUndetermined:
+		return "Undetermined"
+	default:
+		return "Unknown"
+	}
+}
+
+// FeaturePath is the lighting-specific feature set a comparison used: color under
+// daylight, thermal and the IR signature under infrared. It says whether
+// ColorSimilarity or ThermalSimilarity carries a score.
+type FeaturePath int
+const (
+	FeaturePathUnknown FeaturePath = iota // No path was recorded
+	FeaturePathDaylight
+	FeaturePathInfrared
+)
+
+func (fp FeaturePath) String() string {
+	switch fp {
+	case FeaturePathDaylight:
+		return "Daylight"
+	case FeaturePathInfrared:
+		return "Infrared"
+	default:
+		return "Unknown"
+	}
 }
 
 type ConfidenceLevel int
@@ -18,6 +83,19 @@ const (
 	ConfidenceLow
 )
 
+func (cl ConfidenceLevel) String() string {
+	switch cl {
+	case ConfidenceHigh:
+		return "High"
+	case ConfidenceMedium:
+		return "Medium"
+	case ConfidenceLow:
+		return "Low"
+	default:
+		return "Unknown"
+	}
+}
+
 // DetailedScores breaks down similarity by feature type
 type DetailedScores struct {
 	GeometricSimilarity    float64 `json:"geometric_similarity"`
@@ -27,14 +105,169 @@ type DetailedScores struct {
 	ThermalSimilarity      float64 `json:"thermal_similarity,omitempty"`
 }
 
+// SubScores holds the unweighted components behind DetailedScores, each 0-1, for
+// callers applying their own weighting. Components are from the full-view
+// comparison; Color and Thermal are 0 when Availability reports them unavailable.
+type SubScores struct {
+	Geometry GeometrySubScores `json:"geometry"`
+	Lights   LightSubScores    `json:"lights"`
+	Bumper   BumperSubScores   `json:"bumper"`
+	Color    float64           `json:"color"`
+	Thermal  float64           `json:"thermal"`
+}
+
+type GeometrySubScores struct {
+	Proportions float64 `json:"proportions"`
+	Structural  float64 `json:"structural"`
+	Alignment   float64 `json:"alignment"`
+}
+
+type LightSubScores struct {
+	Signature     float64 `json:"signature"`
+	Elements      float64 `json:"elements"`
+	Configuration float64 `json:"configuration"`
+	Arrangement   float64 `json:"arrangement"` // Neutral 0.5 when either image has fewer than three lights
+}
+
+type BumperSubScores struct {
+	Contour   float64 `json:"contour"`
+	Texture   float64 `json:"texture"`
+	Mounting  float64 `json:"mounting"`
+	PlateArea float64 `json:"plate_area"`
+}
+
+// FeatureWeights holds the weight each feature score carried in the overall similarity
+type FeatureWeights struct {
+	Geometric    float64 `json:"geometric"`
+	LightPattern float64 `json:"light_pattern"`
+	Bumper       float64 `json:"bumper"`
+	Color        float64 `json:"color"`
+	Thermal      float64 `json:"thermal"`
+}
+
+// FeatureStatus records whether a feature was compared
+type FeatureStatus int
+const (
+	FeatureExtracted   FeatureStatus = iota
+	FeatureSkipped                   // Disabled by an option or an early exit
+	FeatureUnavailable               // The images hold no data for the feature
+)
+
+func (fs FeatureStatus) String() string {
+	switch fs {
+	case FeatureExtracted:
+		return "Extracted"
+	case FeatureSkipped:
+		return "Skipped"
+	case FeatureUnavailable:
+		return "Unavailable"
+	default:
+		return "Unknown"
+	}
+}
+
+// FeatureState is a feature's status with the reason it was not extracted
+type FeatureState struct {
+	Status FeatureStatus `json:"status"`
+	Reason string        `json:"reason,omitempty"`
+}
+
+// FeatureAvailability records per feature whether its score in DetailedScores was
+// computed, so a 0 color score on an infrared pair is not read as "colors very
+// different"
+type FeatureAvailability struct {
+	Geometric    FeatureState `json:"geometric"`
+	LightPattern FeatureState `json:"light_pattern"`
+	Bumper       FeatureState `json:"bumper"`
+	Color        FeatureState `json:"color"`
+	Thermal      FeatureState `json:"thermal"`
+}
+
+// FeatureContributions holds each feature's share of the overall similarity (see
+// ComparisonResult.Contributions)
+type FeatureContributions struct {
+	Geometric    float64 `json:"geometric"`
+	LightPattern float64 `json:"light_pattern"`
+	Bumper       float64 `json:"bumper"`
+	Color        float64 `json:"color"`
+	Thermal      float64 `json:"thermal"`
+}
+
+// Total sums the contributions, which equals the overall similarity score
+func (fc FeatureContributions) Total() float64 {
+	return fc.Geometric + fc.LightPattern + fc.Bumper + fc.Color + fc.Thermal
+}
+
 // ProcessingInfo holds processing metadata
 type ProcessingInfo struct {
-	ProcessingTimeMs    int64   `json:"processing_time_ms"`
-	Image1Quality       float64 `json:"image1_quality"`
-	Image2Quality       float64 `json:"image2_quality"`
-	AlignmentQuality    float64 `json:"alignment_quality"`
-	ViewConsistency     bool    `json:"view_consistency"`
-	LightingConsistency bool    `json:"lighting_consistency"`
+	ProcessingTimeMs    int64          `json:"processing_time_ms"`
+	Image1Quality       float64        `json:"image1_quality"`  // -1 when quality assessment was skipped
+	Image2Quality       float64        `json:"image2_quality"`  // -1 when quality assessment was skipped
+	Image1View          VehicleView    `json:"image1_view"`     // As classified, after any correction such as the view cross-check
+	Image1Lighting      LightingType   `json:"image1_lighting"` // As classified, after any move of a colorless image to the IR path
+	Image2View          VehicleView    `json:"image2_view"`
+	Image2Lighting      LightingType   `json:"image2_lighting"`
+	AlignmentQuality    float64        `json:"alignment_quality"`
+	ViewConsistency     bool           `json:"view_consistency"`
+	LightingConsistency bool           `json:"lighting_consistency"`
+	RotationApplied     bool           `json:"rotation_applied"`     // Image 2 was rotated 180° to recover an upside-down capture
+	EarlyExit           bool           `json:"early_exit"`           // Cheap features ruled out a match; the rest were not extracted
+	Provisional         bool           `json:"provisional"`          // A quick thumbnail estimate from CompareProgressive, refined later
+	ViewpointDelta      ViewpointDelta `json:"viewpoint_delta"`      // Estimated capture-angle difference between the images
+	FeaturePath         FeaturePath    `json:"feature_path"`         // Lighting-specific features the comparison used
+	DecisionThreshold   float64        `json:"decision_threshold"`   // Similarity threshold IsSameVehicle was decided against
+	ThresholdMargin     float64        `json:"threshold_margin"`     // SimilarityScore minus DecisionThreshold; small magnitudes are borderline
+	PlateRelativeFrame  bool           `json:"plate_relative_frame"` // Layout was measured from the license plate rather than the vehicle bounds
+	LightingFallback    bool           `json:"lighting_fallback"`    // Lighting was ambiguous; the best of the feature paths both images share was kept
+	IdenticalInput      bool           `json:"identical_input"`      // Both inputs were the same path or data; the image was processed once, not compared
+	ConfigFingerprint   string         `json:"config_fingerprint"`   // Engine configuration and version the result was scored under; results cached under another are stale
+	
+	SimilarityInterval *SimilarityInterval `json:"similarity_interval,omitempty"` // Bootstrap confidence interval, only when requested
+}
+
+// SimilarityInterval is a confidence interval around SimilarityScore, e.g.
+// [0.68, 0.81]. A wide interval, or one straddling the threshold, means the decision
+// rests on few or inconsistent feature matches.
+type SimilarityInterval struct {
+	Lower      float64 `json:"lower"`
+	Upper      float64 `json:"upper"`
+	Confidence float64 `json:"confidence"` // Nominal coverage, e.g. 0.95
+}
+
+// ViewpointDelta is the estimated difference in capture angle between two images,
+// in degrees. Positive angles mean image 2 was captured more obliquely than image 1.
+// Proportions and light spacing drift with angle, so large deltas make scores less
+// reliable.
+type ViewpointDelta struct {
+	YawDegrees   float64 `json:"yaw_degrees"`
+	PitchDegrees float64 `json:"pitch_degrees"`
+	Estimated    bool    `json:"estimated"` // False when neither image carries the cues the estimate needs
+}
+
+// Contributions breaks the similarity score down into each feature's weighted share,
+// e.g. to show that geometry contributed 0.27 of a 0.78 total. Each share is the
+// feature's score x weight; under an aggregation other than the arithmetic mean the
+// shares are scaled in proportion so they still sum to SimilarityScore.
+func (cr *ComparisonResult) Contributions() FeatureContributions {
+	contributions := FeatureContributions{
+		Geometric:    cr.DetailedScores.GeometricSimilarity * cr.EffectiveWeights.Geometric,
+		LightPattern: cr.DetailedScores.LightPatternSimilarity * cr.EffectiveWeights.LightPattern,
+		Bumper:       cr.DetailedScores.BumperSimilarity * cr.EffectiveWeights.Bumper,
+		Color:        cr.DetailedScores.ColorSimilarity * cr.EffectiveWeights.Color,
+		Thermal:      cr.DetailedScores.ThermalSimilarity * cr.EffectiveWeights.Thermal,
+	}
+
+	weightedMean := contributions.Total()
+	if weightedMean <= 0 {
+		return contributions
+	}
+	scale := cr.SimilarityScore / weightedMean
+	contributions.Geometric *= scale
+	contributions.LightPattern *= scale
+	contributions.Bumper *= scale
+	contributions.Color *= scale
+	contributions.Thermal *= scale
+	return contributions
 }
 
 // ValidateAndSanitize ensures all float values in the result are valid for JSON marshaling
@@ -47,9 +280,63 @@ func (cr *ComparisonResult) ValidateAndSanitize() {
 	cr.DetailedScores.ColorSimilarity = sanitizeFloat64(cr.DetailedScores.ColorSimilarity, 0.0)
 	cr.DetailedScores.ThermalSimilarity = sanitizeFloat64(cr.DetailedScores.ThermalSimilarity, 0.0)
 	
+	if sub := cr.SubScores; sub != nil {
+		for _, score := range []*float64{
+			&sub.Geometry.Proportions, &sub.Geometry.Structural, &sub.Geometry.Alignment,
+			&sub.Lights.Signature, &sub.Lights.Elements, &sub.Lights.Configuration, &sub.Lights.Arrangement,
+			&sub.Bumper.Contour, &sub.Bumper.Texture, &sub.Bumper.Mounting, &sub.Bumper.PlateArea,
+			&sub.Color, &sub.Thermal,
+		} {
+			*score = sanitizeFloat64(*score, 0.0)
+		}
+	}
+	
 	cr.ProcessingInfo.Image1Quality = sanitizeFloat64(cr.ProcessingInfo.Image1Quality, 0.0)
+	cr.ProcessingInfo.ViewpointDelta.YawDegrees = sanitizeFloat64(cr.ProcessingInfo.ViewpointDelta.YawDegrees, 0.0)
+	cr.ProcessingInfo.ViewpointDelta.PitchDegrees = sanitizeFloat64(cr.ProcessingInfo.ViewpointDelta.PitchDegrees, 0.0)
 	cr.ProcessingInfo.Image2Quality = sanitizeFloat64(cr.ProcessingInfo.Image2Quality, 0.0)
 	cr.ProcessingInfo.AlignmentQuality = sanitizeFloat64(cr.ProcessingInfo.AlignmentQuality, 0.0)
+	
+	if interval := cr.ProcessingInfo.SimilarityInterval; interval != nil {
+		interval.Lower = sanitizeFloat64(interval.Lower, 0.0)
+		interval.Upper = sanitizeFloat64(interval.Upper, 0.0)
+	}
+}
+
+// CSVHeader returns the column names matching ComparisonResult.CSVRecord
+func CSVHeader() []string {
+	return []string{
+		"is_same_vehicle",
+		"similarity_score",
+		"confidence_level",
+		"geometric_similarity",
+		"light_pattern_similarity",
+		"bumper_similarity",
+		"color_similarity",
+		"thermal_similarity",
+		"processing_time_ms",
+		"decision",
+	}
+}
+
+// CSVRecord flattens the result into a single CSV row (see CSVHeader)
+func (cr *ComparisonResult) CSVRecord() []string {
+	return []string{
+		strconv.FormatBool(cr.IsSameVehicle),
+		formatCSVFloat(cr.SimilarityScore),
+		cr.ConfidenceLevel.String(),
+		formatCSVFloat(cr.DetailedScores.GeometricSimilarity),
+		formatCSVFloat(cr.DetailedScores.LightPatternSimilarity),
+		formatCSVFloat(cr.DetailedScores.BumperSimilarity),
+		formatCSVFloat(cr.DetailedScores.ColorSimilarity),
+		formatCSVFloat(cr.DetailedScores.ThermalSimilarity),
+		strconv.FormatInt(cr.ProcessingInfo.ProcessingTimeMs, 10),
+		cr.Decision.String(),
+	}
+}
+
+func formatCSVFloat(value float64) string {
+	return strconv.FormatFloat(sanitizeFloat64(value, 0.0), 'f', 4, 64)
 }
 
 func sanitizeFloat64(value float64, defaultValue float64) float64 {
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestCSVRecordMatchesHeader(t *testing.T) {
+	result := &ComparisonResult{
+		IsSameVehicle:   true,
+		SimilarityScore: 0.8123,
+		ConfidenceLevel: ConfidenceMedium,
+		DetailedScores: DetailedScores{
+			GeometricSimilarity:    0.9,
+			LightPatternSimilarity: 0.7,
+			BumperSimilarity:       0.6,
+			ColorSimilarity:        0.8,
+		},
+		ProcessingInfo: ProcessingInfo{ProcessingTimeMs: 420},
+	}
+
+	header := CSVHeader()
+	record := result.CSVRecord()
+
+	if len(record) != len(header) {
+		t.Fatalf("CSV record has %d columns, header has %d", len(record), len(header))
+	}
+
+	if record[0] != "true" || record[1] != "0.8123" || record[2] != "Medium" {
+		t.Errorf("Unexpected leading columns: %v", record[:3])
+	}
+}
+
+func TestValidateAndSanitizeMakesSubScoresMarshalable(t *testing.T) {
+	result := &ComparisonResult{SubScores: &SubScores{
+		Lights: LightSubScores{Signature: math.NaN(), Arrangement: math.Inf(1)},
+		Bumper: BumperSubScores{PlateArea: math.NaN()},
+	}}
+
+	result.ValidateAndSanitize()
+
+	if _, err := json.Marshal(result); err != nil {
+		t.Errorf("Expected a sanitized result to marshal, got %v", err)
+	}
+}
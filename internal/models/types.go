@@ -11,8 +11,57 @@ const (
 	ViewFront VehicleView = iota
 	ViewRear
 	ViewUnknown
+	ViewSide // Only a secondary label in a ViewSet; features are extracted for front or rear
 )
 
+func (v VehicleView) String() string {
+	switch v {
+	case ViewFront:
+		return "Front"
+	case ViewRear:
+		return "Rear"
+	case ViewSide:
+		return "Side"
+	default:
+		return "Unknown"
+	}
+}
+
+// ViewSet is a multi-label view classification. A 3/4 capture shows the front or
+// rear of the vehicle and its side at once.
+type ViewSet uint8
+
+// ViewSetOf returns the set holding the given views
+func ViewSetOf(views ...VehicleView) ViewSet {
+	var set ViewSet
+	for _, v := range views {
+		set |= 1 << v
+	}
+	return set
+}
+
+// Has reports whether the set includes view
+func (vs ViewSet) Has(view VehicleView) bool {
+	return vs&ViewSetOf(view) != 0
+}
+
+func (vs ViewSet) String() string {
+	labels := ""
+	for _, v := range []VehicleView{ViewFront, ViewRear, ViewSide, ViewUnknown} {
+		if !vs.Has(v) {
+			continue
+		}
+		if labels != "" {
+			labels += "+"
+		}
+		labels += v.String()
+	}
+	if labels == "" {
+		return "None"
+	}
+	return labels
+}
+
 // LightingType represents lighting conditions
 type LightingType int
 
@@ -22,22 +71,150 @@ const (
 	LightingUnknown
 )
 
+func (l LightingType) String() string {
+	switch l {
+	case LightingDaylight:
+		return "Daylight"
+	case LightingInfrared:
+		return "Infrared"
+	default:
+		return "Unknown"
+	}
+}
+
+// HistogramMetric selects how color histograms are compared
+type HistogramMetric int
+
+const (
+	HistogramIntersection HistogramMetric = iota
+	HistogramBhattacharyya
+	HistogramChiSquare
+)
+
+func (m HistogramMetric) String() string {
+	switch m {
+	case HistogramIntersection:
+		return "Intersection"
+	case HistogramBhattacharyya:
+		return "Bhattacharyya"
+	case HistogramChiSquare:
+		return "ChiSquare"
+	default:
+		return "Unknown"
+	}
+}
+
+// ColorSpace selects the space dominant colors are compared in
+type ColorSpace int
+
+const (
+	ColorSpaceRGB ColorSpace = iota // Euclidean RGB distance
+	ColorSpaceHSV                   // Hue/saturation distance, with brightness down-weighted
+	ColorSpaceLab                   // CIE94 color difference in CIELab, with lightness down-weighted
+)
+
+func (cs ColorSpace) String() string {
+	switch cs {
+	case ColorSpaceRGB:
+		return "RGB"
+	case ColorSpaceHSV:
+		return "HSV"
+	case ColorSpaceLab:
+		return "Lab"
+	default:
+		return "Unknown"
+	}
+}
+
+// DecisionMode selects how the per-feature scores decide IsSameVehicle
+type DecisionMode int
+
+const (
+	DecisionWeightedMean  DecisionMode = iota // The weighted mean must exceed the threshold
+	DecisionAllMustExceed                     // In addition, every computed feature score must exceed its floor
+)
+
+func (dm DecisionMode) String() string {
+	switch dm {
+	case DecisionWeightedMean:
+		return "WeightedMean"
+	case DecisionAllMustExceed:
+		return "AllMustExceed"
+	default:
+		return "Unknown"
+	}
+}
+
+// Aggregation selects how the weighted per-feature scores combine into the overall
+// similarity. The alternatives to the arithmetic mean are more conservative: one low
+// feature score drags the result down further.
+type Aggregation int
+
+const (
+	AggregationArithmetic Aggregation = iota // Weighted arithmetic mean
+	AggregationGeometric                     // Weighted geometric mean
+	AggregationHarmonic                      // Weighted harmonic mean, the most sensitive to one low score
+	AggregationMinBiased                     // Midway between the weighted mean and the lowest weighted score
+)
+
+func (a Aggregation) String() string {
+	switch a {
+	case AggregationArithmetic:
+		return "Arithmetic"
+	case AggregationGeometric:
+		return "Geometric"
+	case AggregationHarmonic:
+		return "Harmonic"
+	case AggregationMinBiased:
+		return "MinBiased"
+	default:
+		return "Unknown"
+	}
+}
+
+// FeatureFloors holds the score each feature must exceed under
+// DecisionAllMustExceed
+type FeatureFloors struct {
+	Geometric    float64 `json:"geometric"`
+	LightPattern float64 `json:"light_pattern"`
+	Bumper       float64 `json:"bumper"`
+	Color        float64 `json:"color"`
+	Thermal      float64 `json:"thermal"`
+}
+
 // VehicleImage holds image data and metadata
 type VehicleImage struct {
-	Image          gocv.Mat            `json:"-"`
-	View           VehicleView         `json:"view"`
-	Lighting       LightingType        `json:"lighting"`
-	QualityScore   float64             `json:"quality_score"`
-	ProcessingMeta ProcessingMetadata  `json:"processing_meta"`
+	Image              gocv.Mat           `json:"-"`
+	Mask               gocv.Mat           `json:"-"` // Caller's region of interest at Image's size; the zero Mat for the whole image
+	View               VehicleView        `json:"view"`
+	Views              ViewSet            `json:"views"` // All views shown, including View
+	ViewConfidence     float64            `json:"view_confidence"`
+	ViewCorrected      bool               `json:"view_corrected"` // The lights contradicted the classified view, which was swapped
+	Lighting           LightingType       `json:"lighting"`
+	LightingConfidence float64            `json:"lighting_confidence"`
+	LightingAmbiguous  bool               `json:"lighting_ambiguous"` // Lighting too uncertain to trust; both feature paths are tried
+	Roofless           bool               `json:"roofless"`           // No roofline found, e.g. a convertible with the top down
+	QualityScore       float64            `json:"quality_score"`
+	ProcessingMeta     ProcessingMetadata `json:"processing_meta"`
 }
 
 // ProcessingMetadata holds processing information
 type ProcessingMetadata struct {
-	OriginalWidth    int    `json:"original_width"`
-	OriginalHeight   int    `json:"original_height"`
-	VehicleBounds    Bounds `json:"vehicle_bounds"`
-	NormalizedWidth  int    `json:"normalized_width"`
-	NormalizedHeight int    `json:"normalized_height"`
+	OriginalWidth    int        `json:"original_width"`
+	OriginalHeight   int        `json:"original_height"`
+	VehicleBounds    Bounds     `json:"vehicle_bounds"`
+	NormalizedWidth  int        `json:"normalized_width"`
+	NormalizedHeight int        `json:"normalized_height"`
+	BorderTrim       BorderTrim `json:"border_trim"` // Black borders removed before processing
+	BlockArtifacts   float64    `json:"block_artifacts"` // JPEG blocking of the decoded image, 0 (none) to 1 (severe)
+}
+
+// BorderTrim is how many pixels were trimmed from each side of an image
+type BorderTrim struct {
+	Top    int `json:"top"`
+	Bottom int `json:"bottom"`
+	Left   int `json:"left"`
+	Right  int `json:"right"`
 }
 
 // Bounds represents a bounding rectangle
@@ -56,6 +233,7 @@ type LicensePlateRegion struct {
 	Confidence    float64 `json:"confidence"`
 	AvgBrightness float64 `json:"avg_brightness"`
 	IsReflective  bool    `json:"is_reflective"`
+	Detected      bool    `json:"detected"` // False when Bounds is a fallback guess rather than a found plate
 }
 
 // IRSignature represents the infrared signature around a license plate
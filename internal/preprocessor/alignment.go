@@ -0,0 +1,168 @@
+package preprocessor
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+
+	"gocv.io/x/gocv"
+)
+
+// Canonical frame that both images of a pair are warped into before alignment
+const (
+	CanonicalWidth  = 1280
+	CanonicalHeight = 960
+)
+
+type ImageAligner struct {
+	width  int
+	height int
+
+	downscaleInterpolation gocv.InterpolationFlags
+	upscaleInterpolation   gocv.InterpolationFlags
+	padToAspect            bool // Letterbox into the canonical frame instead of stretching
+}
+
+func NewImageAligner() *ImageAligner {
+	return &ImageAligner{
+		width:                  CanonicalWidth,
+		height:                 CanonicalHeight,
+		downscaleInterpolation: gocv.InterpolationArea, // Averages, so fine detail cannot alias
+		upscaleInterpolation:   gocv.InterpolationLinear,
+	}
+}
+
+// SetInterpolation sets the interpolation used when resizing into the canonical
+// frame, separately for images larger than the frame (default area) and smaller
+// ones (default linear). Consistent choices keep scores reproducible across
+// resolutions.
+func (ia *ImageAligner) SetInterpolation(downscale, upscale gocv.InterpolationFlags) {
+	ia.downscaleInterpolation = downscale
+	ia.upscaleInterpolation = upscale
+}
+
+// SetPadToAspect scales images into the canonical frame preserving their aspect
+// ratio, padding the rest with black, instead of stretching them to fill it
+func (ia *ImageAligner) SetPadToAspect(enabled bool) {
+	ia.padToAspect = enabled
+}
+
+// AlignPair resizes both images into the canonical frame and translates the second
+// image onto the first. The caller owns (and must Close) both returned Mats.
+// The returned quality is the normalized correlation of the aligned pair (0-1).
+func (ia *ImageAligner) AlignPair(img1, img2 gocv.Mat) (gocv.Mat, gocv.Mat, float64, error) {
+	if img1.Empty() || img2.Empty() {
+		return gocv.NewMat(), gocv.NewMat(), 0.0, fmt.Errorf("cannot align empty image")
+	}
+
+	canonical1 := ia.ToCanonical(img1)
+	canonical2 := ia.ToCanonical(img2)
+	defer canonical2.Close()
+
+	gray1 := toFloatGray(canonical1)
+	defer gray1.Close()
+	gray2 := toFloatGray(canonical2)
+	defer gray2.Close()
+
+	// Estimate the translation of image 2 relative to image 1
+	window := gocv.NewMat()
+	defer window.Close()
+	shift, _ := gocv.PhaseCorrelate(gray1, gray2, window)
+
+	// Shift image 2 back onto image 1
+	warp := gocv.NewMatWithSize(2, 3, gocv.MatTypeCV64F)
+	defer warp.Close()
+	warp.SetDoubleAt(0, 0, 1)
+	warp.SetDoubleAt(0, 1, 0)
+	warp.SetDoubleAt(0, 2, -float64(shift.X))
+	warp.SetDoubleAt(1, 0, 0)
+	warp.SetDoubleAt(1, 1, 1)
+	warp.SetDoubleAt(1, 2, -float64(shift.Y))
+
+	aligned2 := gocv.NewMat()
+	gocv.WarpAffine(canonical2, &aligned2, warp, image.Pt(ia.width, ia.height))
+
+	quality := ia.alignmentQuality(canonical1, aligned2)
+
+	return canonical1, aligned2, quality, nil
+}
+
+// ToCanonical resizes img into the canonical frame with the configured
+// interpolation and padding. The caller owns the returned Mat.
+func (ia *ImageAligner) ToCanonical(img gocv.Mat) gocv.Mat {
+	canonical := gocv.NewMat()
+	if img.Cols() == ia.width && img.Rows() == ia.height {
+		img.CopyTo(&canonical)
+		return canonical
+	}
+
+	size := image.Pt(ia.width, ia.height)
+	if ia.padToAspect {
+		scale := math.Min(float64(ia.width)/float64(img.Cols()), float64(ia.height)/float64(img.Rows()))
+		size = image.Pt(
+			max(1, int(math.Round(float64(img.Cols())*scale))),
+			max(1, int(math.Round(float64(img.Rows())*scale))))
+	}
+
+	interpolation := ia.upscaleInterpolation
+	if img.Cols()*img.Rows() > size.X*size.Y {
+		interpolation = ia.downscaleInterpolation
+	}
+
+	if !ia.padToAspect {
+		gocv.Resize(img, &canonical, size, 0, 0, interpolation)
+		return canonical
+	}
+
+	resized := gocv.NewMat()
+	defer resized.Close()
+	gocv.Resize(img, &resized, size, 0, 0, interpolation)
+
+	left := (ia.width - size.X) / 2
+	top := (ia.height - size.Y) / 2
+	gocv.CopyMakeBorder(resized, &canonical, top, ia.height-size.Y-top, left, ia.width-size.X-left,
+		gocv.BorderConstant, color.RGBA{})
+	return canonical
+}
+
+func (ia *ImageAligner) alignmentQuality(img1, img2 gocv.Mat) float64 {
+	gray1 := toFloatGray(img1)
+	defer gray1.Close()
+	gray2 := toFloatGray(img2)
+	defer gray2.Close()
+
+	// Same-size template matching yields a single normalized correlation value
+	result := gocv.NewMat()
+	defer result.Close()
+	mask := gocv.NewMat()
+	defer mask.Close()
+	gocv.MatchTemplate(gray1, gray2, &result, gocv.TmCcoeffNormed, mask)
+
+	if result.Empty() {
+		return 0.0
+	}
+
+	correlation := float64(result.GetFloatAt(0, 0))
+	if math.IsNaN(correlation) || math.IsInf(correlation, 0) {
+		return 0.0
+	}
+	return math.Max(0.0, math.Min(1.0, correlation))
+}
+
+// toFloatGray converts an image to single-channel CV_32F as required by
+// PhaseCorrelate and correlation-based matching
+func toFloatGray(img gocv.Mat) gocv.Mat {
+	gray := gocv.NewMat()
+	defer gray.Close()
+
+	if img.Channels() > 1 {
+		gocv.CvtColor(img, &gray, gocv.ColorBGRToGray)
+	} else {
+		img.CopyTo(&gray)
+	}
+
+	floatGray := gocv.NewMat()
+	gray.ConvertTo(&floatGray, gocv.MatTypeCV32F)
+	return floatGray
+}
@@ -0,0 +1,88 @@
+package preprocessor
+
+import (
+	"gocv.io/x/gocv"
+)
+
+// JPEG codes 8x8 blocks independently, so heavy compression leaves intensity steps
+// on the block grid that are stronger than the steps between pixels inside blocks
+const (
+	jpegBlockSize = 8
+
+	// Step ratio (grid over interior) above 1 at which blocking saturates at 1
+	blockArtifactRatioSpan = 1.0
+
+	// Below this mean step (gray levels) an image is too flat to measure blocking
+	minBlockStepEnergy = 0.5
+)
+
+// MeasureBlockArtifacts estimates JPEG blocking in img from 0 (none) to 1
+// (severe): how much stronger the intensity steps across the 8-pixel block grid are
+// than the steps inside blocks, averaged over both directions. Measure the image as
+// decoded, since trimming, cropping or resizing moves the grid.
+func MeasureBlockArtifacts(img gocv.Mat) float64 {
+	if img.Rows() <= jpegBlockSize || img.Cols() <= jpegBlockSize {
+		return 0.0
+	}
+
+	gray := gocv.NewMat()
+	defer gray.Close()
+
+	if img.Channels() > 1 {
+		gocv.CvtColor(img, &gray, gocv.ColorBGRToGray)
+	} else {
+		img.CopyTo(&gray)
+	}
+
+	transposed := gocv.NewMat()
+	defer transposed.Close()
+	gocv.Transpose(gray, &transposed)
+
+	ratio := (gridStepRatio(gray) + gridStepRatio(transposed)) / 2.0
+	return min(max((ratio-1.0)/blockArtifactRatioSpan, 0.0), 1.0)
+}
+
+// gridStepRatio is the mean horizontal step across block-grid column boundaries
+// over the mean step between the other columns. Returns 1 (no blocking) for images
+// too flat to tell.
+func gridStepRatio(gray gocv.Mat) float64 {
+	left := gray.ColRange(0, gray.Cols()-1)
+	defer left.Close()
+	right := gray.ColRange(1, gray.Cols())
+	defer right.Close()
+
+	steps := gocv.NewMat()
+	defer steps.Close()
+	gocv.AbsDiff(left, right, &steps)
+
+	// Mean step per column boundary, over all rows
+	profile := gocv.NewMat()
+	defer profile.Close()
+	gocv.Reduce(steps, &profile, 0, gocv.ReduceAvg, gocv.MatTypeCV32F)
+
+	gridSum, interiorSum := 0.0, 0.0
+	gridCount, interiorCount := 0, 0
+	for x := 0; x < profile.Cols(); x++ {
+		step := float64(profile.GetFloatAt(0, x))
+		// Step x is between columns x and x+1
+		if (x+1)%jpegBlockSize == 0 {
+			gridSum += step
+			gridCount++
+		} else {
+			interiorSum += step
+			interiorCount++
+		}
+	}
+
+	if gridCount == 0 || interiorCount == 0 {
+		return 1.0
+	}
+
+	gridMean := gridSum / float64(gridCount)
+	interiorMean := interiorSum / float64(interiorCount)
+	if gridMean < minBlockStepEnergy && interiorMean < minBlockStepEnergy {
+		return 1.0
+	}
+
+	return gridMean / max(interiorMean, minBlockStepEnergy)
+}
@@ -0,0 +1,73 @@
+package preprocessor
+
+import (
+	"image"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+	"gocv.io/x/gocv"
+)
+
+const (
+	borderMaxLevel  = 16   // Gray level at or below which a pixel counts as letterbox black
+	borderDarkShare = 0.85 // Share of a row/column that must be black; the rest allows for timestamp overlays
+	maxBorderShare  = 0.4  // Never trim more than this share of the image from one side
+)
+
+// DetectBorders finds the uniform black letterbox/pillarbox borders DVR software and
+// screenshots add around a frame, tolerating timestamp text drawn on them. It
+// returns the region inside the borders and the trim; the region is the whole image
+// when there are none.
+func DetectBorders(img gocv.Mat) (image.Rectangle, models.BorderTrim) {
+	gray := gocv.NewMat()
+	defer gray.Close()
+
+	if img.Channels() > 1 {
+		gocv.CvtColor(img, &gray, gocv.ColorBGRToGray)
+	} else {
+		img.CopyTo(&gray)
+	}
+
+	dark := gocv.NewMat()
+	defer dark.Close()
+	gocv.Threshold(gray, &dark, borderMaxLevel, 255, gocv.ThresholdBinaryInv)
+
+	rows, cols := dark.Rows(), dark.Cols()
+	isDark := func(rect image.Rectangle) bool {
+		region := dark.Region(rect)
+		defer region.Close()
+		return float64(gocv.CountNonZero(region)) >= borderDarkShare*float64(rect.Dx()*rect.Dy())
+	}
+
+	maxRows := int(float64(rows) * maxBorderShare)
+	maxCols := int(float64(cols) * maxBorderShare)
+
+	var trim models.BorderTrim
+	for trim.Top < maxRows && isDark(image.Rect(0, trim.Top, cols, trim.Top+1)) {
+		trim.Top++
+	}
+	for trim.Bottom < maxRows && isDark(image.Rect(0, rows-trim.Bottom-1, cols, rows-trim.Bottom)) {
+		trim.Bottom++
+	}
+
+	// Columns are only scanned between the horizontal borders, so text in a bottom
+	// band does not hide a pillarbox
+	top, bottom := trim.Top, rows-trim.Bottom
+	for trim.Left < maxCols && isDark(image.Rect(trim.Left, top, trim.Left+1, bottom)) {
+		trim.Left++
+	}
+	for trim.Right < maxCols && isDark(image.Rect(cols-trim.Right-1, top, cols-trim.Right, bottom)) {
+		trim.Right++
+	}
+
+	return image.Rect(trim.Left, trim.Top, cols-trim.Right, rows-trim.Bottom), trim
+}
+
+// TrimBorders returns a copy of img with its black borders (see DetectBorders)
+// removed, and the trim applied. The caller owns the returned Mat.
+func TrimBorders(img gocv.Mat) (gocv.Mat, models.BorderTrim) {
+	content, trim := DetectBorders(img)
+
+	region := img.Region(content)
+	defer region.Close()
+	return region.Clone(), trim
+}
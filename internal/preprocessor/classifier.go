@@ -1,6 +1,7 @@
 package preprocessor
 
 import (
+	"github.com/choff5507/vehicle-image-comparison/internal/matstat"
 	"github.com/choff5507/vehicle-image-comparison/internal/models"
 	"gocv.io/x/gocv"
 	"image"
@@ -30,6 +31,27 @@ func (vlc *ViewLightingClassifier) ClassifyView(img gocv.Mat) (models.VehicleVie
 	return models.ViewUnknown, 0.0, nil
 }
 
+// Left/right edge energy imbalance above which the side of the vehicle is taken to
+// be in view as well. Straight front and rear views are close to mirror-symmetric.
+const sideViewAsymmetry = 0.25
+
+// ClassifyViews is the multi-label form of ClassifyView: it returns the primary
+// front/rear view with its confidence, and the set of all views shown, which adds
+// ViewSide for 3/4 captures.
+func (vlc *ViewLightingClassifier) ClassifyViews(img gocv.Mat) (models.VehicleView, models.ViewSet, float64, error) {
+	view, confidence, err := vlc.ClassifyView(img)
+	if err != nil {
+		return view, 0, confidence, err
+	}
+	
+	views := models.ViewSetOf(view)
+	if vlc.calculateSideAsymmetry(img) > sideViewAsymmetry {
+		views |= models.ViewSetOf(models.ViewSide)
+	}
+	
+	return view, views, confidence, nil
+}
+
 // ClassifyLighting determines if image is daylight or infrared
 func (vlc *ViewLightingClassifier) ClassifyLighting(img gocv.Mat) (models.LightingType, float64, error) {
 	// Convert to grayscale for analysis
@@ -54,7 +76,146 @@ func (vlc *ViewLightingClassifier) ClassifyLighting(img gocv.Mat) (models.Lighti
 		return models.LightingInfrared, 0.8, nil
 	}
 	
-	return models.LightingUnknown, 0.0, nil
+	// Dim or colorless captures without IR-like contrast stay on the daylight path;
+	// extraction moves colorless ones to the IR path
+	return models.LightingDaylight, 0.6, nil
+}
+
+// ClassifyOrientation estimates whether the vehicle appears upside-down.
+// Bumpers, plates and grilles put most horizontal edges in the lower half of an
+// upright vehicle; confidence is the normalized imbalance between the halves.
+func (vlc *ViewLightingClassifier) ClassifyOrientation(img gocv.Mat) (bool, float64, error) {
+	gray := gocv.NewMat()
+	defer gray.Close()
+	
+	if img.Channels() > 1 {
+		gocv.CvtColor(img, &gray, gocv.ColorBGRToGray)
+	} else {
+		img.CopyTo(&gray)
+	}
+	
+	gradY := gocv.NewMat()
+	defer gradY.Close()
+	gocv.Sobel(gray, &gradY, gocv.MatTypeCV16S, 0, 1, 3, 1, 0, gocv.BorderDefault)
+	
+	edges := gocv.NewMat()
+	defer edges.Close()
+	gocv.ConvertScaleAbs(gradY, &edges, 1, 0)
+	
+	half := edges.Rows() / 2
+	if half == 0 {
+		return false, 0.0, nil
+	}
+	
+	upper := edges.Region(image.Rect(0, 0, edges.Cols(), half))
+	defer upper.Close()
+	lower := edges.Region(image.Rect(0, half, edges.Cols(), edges.Rows()))
+	defer lower.Close()
+	
+	upperEnergy := matstat.MeanOf(upper)
+	lowerEnergy := matstat.MeanOf(lower)
+	total := upperEnergy + lowerEnergy
+	if total == 0 {
+		return false, 0.0, nil
+	}
+	
+	return upperEnergy > lowerEnergy, math.Abs(lowerEnergy-upperEnergy) / total, nil
+}
+
+// A roofline is a near-horizontal edge at least this share of the image width within
+// the top roofSearchShare of the image. Open-top vehicles show only the windscreen
+// frame or seat backs there, which are shorter or missing.
+const (
+	roofSearchShare   = 0.4
+	minRooflineLength = 0.3
+)
+
+// ClassifyRoof is a coarse check for a roof: whether a long horizontal roofline edge
+// is present near the top of the image. Convertibles with the top down have none, and
+// their upper/lower proportions are not comparable with a top-up capture. Confidence
+// grows with the distance of the longest edge from the roofline length threshold.
+func (vlc *ViewLightingClassifier) ClassifyRoof(img gocv.Mat) (bool, float64, error) {
+	gray := gocv.NewMat()
+	defer gray.Close()
+	
+	if img.Channels() > 1 {
+		gocv.CvtColor(img, &gray, gocv.ColorBGRToGray)
+	} else {
+		img.CopyTo(&gray)
+	}
+	
+	searchHeight := int(float64(gray.Rows()) * roofSearchShare)
+	if searchHeight == 0 || gray.Cols() == 0 {
+		return true, 0.0, nil
+	}
+	
+	upper := gray.Region(image.Rect(0, 0, gray.Cols(), searchHeight))
+	defer upper.Close()
+	
+	edges := gocv.NewMat()
+	defer edges.Close()
+	gocv.Canny(upper, &edges, 50, 150)
+	
+	lines := gocv.NewMat()
+	defer lines.Close()
+	gocv.HoughLinesP(edges, &lines, 1, math.Pi/180, 50)
+	
+	longest := 0.0
+	for i := 0; i < lines.Rows(); i++ {
+		// HoughLinesP returns CV_32SC4 rows of x1, y1, x2, y2
+		line := lines.GetVeciAt(i, 0)
+		dx := math.Abs(float64(line[2] - line[0]))
+		dy := math.Abs(float64(line[3] - line[1]))
+		if dy < 10 && dx > longest {
+			longest = dx
+		}
+	}
+	
+	threshold := minRooflineLength * float64(gray.Cols())
+	if longest >= threshold {
+		return true, math.Min(1.0, (longest-threshold)/threshold), nil
+	}
+	return false, (threshold - longest) / threshold, nil
+}
+
+// calculateSideAsymmetry measures how unevenly edge energy is split between the
+// left and right halves. The body side of a 3/4 view adds edges to one half only.
+func (vlc *ViewLightingClassifier) calculateSideAsymmetry(img gocv.Mat) float64 {
+	gray := gocv.NewMat()
+	defer gray.Close()
+	
+	if img.Channels() > 1 {
+		gocv.CvtColor(img, &gray, gocv.ColorBGRToGray)
+	} else {
+		img.CopyTo(&gray)
+	}
+	
+	gradX := gocv.NewMat()
+	defer gradX.Close()
+	gocv.Sobel(gray, &gradX, gocv.MatTypeCV16S, 1, 0, 3, 1, 0, gocv.BorderDefault)
+	
+	edges := gocv.NewMat()
+	defer edges.Close()
+	gocv.ConvertScaleAbs(gradX, &edges, 1, 0)
+	
+	half := edges.Cols() / 2
+	if half == 0 {
+		return 0.0
+	}
+	
+	left := edges.Region(image.Rect(0, 0, half, edges.Rows()))
+	defer left.Close()
+	right := edges.Region(image.Rect(edges.Cols()-half, 0, edges.Cols(), edges.Rows()))
+	defer right.Close()
+	
+	leftEnergy := matstat.MeanOf(left)
+	rightEnergy := matstat.MeanOf(right)
+	total := leftEnergy + rightEnergy
+	if total == 0 {
+		return 0.0
+	}
+	
+	return math.Abs(leftEnergy-rightEnergy) / total
 }
 
 func (vlc *ViewLightingClassifier) calculateFrontScore(img gocv.Mat) float64 {
@@ -92,13 +253,7 @@ func (vlc *ViewLightingClassifier) calculateRearScore(img gocv.Mat) float64 {
 }
 
 func (vlc *ViewLightingClassifier) calculateBrightness(gray gocv.Mat) float64 {
-	meanMat := gocv.NewMat()
-	stddevMat := gocv.NewMat()
-	defer meanMat.Close()
-	defer stddevMat.Close()
-	gocv.MeanStdDev(gray, &meanMat, &stddevMat)
-	meanScalar := gocv.Scalar{Val1: 128.0} // Default mean value
-	return meanScalar.Val1 / 255.0
+	return matstat.MeanOf(gray) / 255.0
 }
 
 func (vlc *ViewLightingClassifier) calculateContrastPattern(gray gocv.Mat) float64 {
@@ -107,18 +262,12 @@ func (vlc *ViewLightingClassifier) calculateContrastPattern(gray gocv.Mat) float
 	defer laplacian.Close()
 	gocv.Laplacian(gray, &laplacian, gocv.MatTypeCV64F, 1, 1, 0, gocv.BorderDefault)
 	
-	meanMat := gocv.NewMat()
-	stddevMat := gocv.NewMat()
-	defer meanMat.Close()
-	defer stddevMat.Close()
-	gocv.MeanStdDev(laplacian, &meanMat, &stddevMat)
-	stddevScalar := gocv.Scalar{Val1: 50.0} // Default stddev value
-	return stddevScalar.Val1 / 100.0 // Normalize
+	return matstat.StddevOf(laplacian) / 100.0 // Normalize
 }
 
 func (vlc *ViewLightingClassifier) calculateColorSaturation(img gocv.Mat) float64 {
-	if img.Channels() == 1 {
-		return 0.0 // Grayscale image has no color saturation
+	if matstat.IsMonochrome(img) {
+		return 0.0 // Grayscale image, possibly stored as color, has no color saturation
 	}
 	
 	// Convert to HSV and measure saturation
@@ -135,14 +284,7 @@ func (vlc *ViewLightingClassifier) calculateColorSaturation(img gocv.Mat) float6
 	}()
 	
 	if len(channels) > 1 {
-		saturation := channels[1]
-		meanMat := gocv.NewMat()
-		stddevMat := gocv.NewMat()
-		defer meanMat.Close()
-		defer stddevMat.Close()
-		gocv.MeanStdDev(saturation, &meanMat, &stddevMat)
-		meanScalar := gocv.Scalar{Val1: 128.0} // Default mean value
-		return meanScalar.Val1 / 255.0
+		return matstat.MeanOf(channels[1]) / 255.0
 	}
 	
 	return 0.0
@@ -243,7 +385,7 @@ func (vlc *ViewLightingClassifier) detectGrillePattern(img gocv.Mat) float64 {
 
 func (vlc *ViewLightingClassifier) detectTaillightPattern(img gocv.Mat) float64 {
 	// Look for vertical light elements (typical of taillights)
-	if img.Channels() > 1 {
+	if !matstat.IsMonochrome(img) {
 		// Try to detect red regions first
 		return vlc.detectRedLightRegions(img)
 	}
@@ -0,0 +1,22 @@
+package preprocessor
+
+import (
+	"gocv.io/x/gocv"
+)
+
+// Bilateral filter settings tuned to flatten 8x8 JPEG block noise while
+// keeping vehicle edges (bumper lines, light outlines) sharp
+const (
+	recompressionFilterDiameter   = 5
+	recompressionFilterSigmaColor = 30.0
+	recompressionFilterSigmaSpace = 5.0
+)
+
+// SmoothRecompressionArtifacts returns a smoothed copy of img with JPEG
+// recompression artifacts suppressed. The caller owns the returned Mat.
+func SmoothRecompressionArtifacts(img gocv.Mat) gocv.Mat {
+	smoothed := gocv.NewMat()
+	gocv.BilateralFilter(img, &smoothed, recompressionFilterDiameter,
+		recompressionFilterSigmaColor, recompressionFilterSigmaSpace)
+	return smoothed
+}
@@ -0,0 +1,101 @@
+package preprocessor
+
+import (
+	"gocv.io/x/gocv"
+)
+
+// MatchExposure returns a copy of img whose intensity distribution matches
+// reference's, removing global exposure and gamma differences between captures.
+// Color images are matched on luma only so hues are preserved. The caller owns the
+// returned Mat.
+func MatchExposure(img, reference gocv.Mat) gocv.Mat {
+	referenceLuma := luma(reference)
+	defer referenceLuma.Close()
+
+	if img.Channels() == 1 {
+		matched := gocv.NewMat()
+		applyHistogramMatch(img, referenceLuma, &matched)
+		return matched
+	}
+
+	ycrcb := gocv.NewMat()
+	defer ycrcb.Close()
+	gocv.CvtColor(img, &ycrcb, gocv.ColorBGRToYCrCb)
+
+	channels := gocv.Split(ycrcb)
+	defer func() {
+		for _, ch := range channels {
+			ch.Close()
+		}
+	}()
+
+	matchedLuma := gocv.NewMat()
+	defer matchedLuma.Close()
+	applyHistogramMatch(channels[0], referenceLuma, &matchedLuma)
+
+	gocv.Merge([]gocv.Mat{matchedLuma, channels[1], channels[2]}, &ycrcb)
+
+	matched := gocv.NewMat()
+	gocv.CvtColor(ycrcb, &matched, gocv.ColorYCrCbToBGR)
+	return matched
+}
+
+// applyHistogramMatch maps each intensity of src to the reference intensity at the
+// same cumulative share, writing the result to dst
+func applyHistogramMatch(src, reference gocv.Mat, dst *gocv.Mat) {
+	srcCDF := cumulativeHistogram(src)
+	referenceCDF := cumulativeHistogram(reference)
+
+	table := make([]byte, 256)
+	target := 0
+	for value := range table {
+		for target < 255 && referenceCDF[target] < srcCDF[value] {
+			target++
+		}
+		table[value] = byte(target)
+	}
+
+	lut, err := gocv.NewMatFromBytes(1, 256, gocv.MatTypeCV8U, table)
+	if err != nil {
+		src.CopyTo(dst)
+		return
+	}
+	defer lut.Close()
+
+	gocv.LUT(src, lut, dst)
+}
+
+// cumulativeHistogram returns the normalized cumulative histogram of an 8-bit
+// single-channel image
+func cumulativeHistogram(img gocv.Mat) [256]float64 {
+	var cdf [256]float64
+
+	total := img.Rows() * img.Cols()
+	if total == 0 {
+		return cdf
+	}
+
+	for y := 0; y < img.Rows(); y++ {
+		for x := 0; x < img.Cols(); x++ {
+			cdf[img.GetUCharAt(y, x)]++
+		}
+	}
+
+	running := 0.0
+	for i := range cdf {
+		running += cdf[i]
+		cdf[i] = running / float64(total)
+	}
+	return cdf
+}
+
+// luma returns the single-channel intensity of img. The caller owns the returned Mat.
+func luma(img gocv.Mat) gocv.Mat {
+	gray := gocv.NewMat()
+	if img.Channels() > 1 {
+		gocv.CvtColor(img, &gray, gocv.ColorBGRToGray)
+	} else {
+		img.CopyTo(&gray)
+	}
+	return gray
+}
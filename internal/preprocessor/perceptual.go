@@ -0,0 +1,96 @@
+package preprocessor
+
+import (
+	"image"
+	"math"
+
+	"gocv.io/x/gocv"
+)
+
+// Neighbor correlations of MSCN coefficients mapped to perceptual quality 1 and 0.
+// In a sharp image the coefficients of adjacent pixels are nearly independent in at
+// least one direction; blur and upscaling spread every edge over several pixels
+// and correlate them in all four.
+const (
+	sharpMSCNCorrelation   = 0.35
+	blurredMSCNCorrelation = 0.95
+)
+
+// AssessPerceptualQuality scores how usable an image looks from its natural scene
+// statistics, as BRISQUE does: local intensities are normalized by the mean and
+// contrast of their neighborhood (mean-subtracted contrast-normalized, MSCN,
+// coefficients), and the correlation between neighboring coefficients is measured
+// horizontally, vertically and along both diagonals. The least correlated direction
+// is mapped to 0-1 by fixed calibration points instead of BRISQUE's trained
+// regressor. Unlike the Laplacian variance, it is not satisfied by a few strong
+// edges surviving in an otherwise soft image.
+func (qa *QualityAssessor) AssessPerceptualQuality(img gocv.Mat) float64 {
+	mscn, width, height := mscnCoefficients(img)
+	if width < 2 || height < 2 {
+		return 0.0
+	}
+
+	correlation := math.Inf(1)
+	for _, step := range [][2]int{{1, 0}, {0, 1}, {1, 1}, {1, -1}} {
+		products, energy := 0.0, 0.0
+		for y := max(0, -step[1]); y < min(height, height-step[1]); y++ {
+			for x := 0; x < width-step[0]; x++ {
+				a := mscn[y*width+x]
+				b := mscn[(y+step[1])*width+x+step[0]]
+				products += a * b
+				energy += (a*a + b*b) / 2.0
+			}
+		}
+		if energy == 0 {
+			// No structure at all: nothing to recognize a vehicle by
+			return 0.0
+		}
+		correlation = math.Min(correlation, products/energy)
+	}
+
+	score := (blurredMSCNCorrelation - correlation) / (blurredMSCNCorrelation - sharpMSCNCorrelation)
+	return math.Max(0.0, math.Min(score, 1.0))
+}
+
+// mscnCoefficients returns the MSCN coefficients of img's intensities in row-major
+// order, with a 7x7 Gaussian neighborhood as in BRISQUE
+func mscnCoefficients(img gocv.Mat) ([]float64, int, int) {
+	gray := gocv.NewMat()
+	defer gray.Close()
+
+	if img.Channels() > 1 {
+		gocv.CvtColor(img, &gray, gocv.ColorBGRToGray)
+	} else {
+		img.CopyTo(&gray)
+	}
+
+	intensity := gocv.NewMat()
+	defer intensity.Close()
+	gray.ConvertTo(&intensity, gocv.MatTypeCV64F)
+
+	squared := gocv.NewMat()
+	defer squared.Close()
+	gocv.Multiply(intensity, intensity, &squared)
+
+	mean := gocv.NewMat()
+	defer mean.Close()
+	gocv.GaussianBlur(intensity, &mean, image.Pt(7, 7), 7.0/6.0, 7.0/6.0, gocv.BorderDefault)
+
+	meanSquare := gocv.NewMat()
+	defer meanSquare.Close()
+	gocv.GaussianBlur(squared, &meanSquare, image.Pt(7, 7), 7.0/6.0, 7.0/6.0, gocv.BorderDefault)
+
+	values, err1 := intensity.DataPtrFloat64()
+	means, err2 := mean.DataPtrFloat64()
+	meanSquares, err3 := meanSquare.DataPtrFloat64()
+	if err1 != nil || err2 != nil || err3 != nil {
+		return nil, 0, 0
+	}
+
+	mscn := make([]float64, len(values))
+	for i, v := range values {
+		deviation := math.Sqrt(math.Abs(meanSquares[i] - means[i]*means[i]))
+		mscn[i] = (v - means[i]) / (deviation + 1.0)
+	}
+	return mscn, intensity.Cols(), intensity.Rows()
+}
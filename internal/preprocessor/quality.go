@@ -1,6 +1,7 @@
 package preprocessor
 
 import (
+	"github.com/choff5507/vehicle-image-comparison/internal/matstat"
 	"gocv.io/x/gocv"
 	"image"
 	"math"
@@ -49,13 +50,8 @@ func (qa *QualityAssessor) assessBlur(img gocv.Mat) float64 {
 	defer laplacian.Close()
 	gocv.Laplacian(gray, &laplacian, gocv.MatTypeCV64F, 1, 1, 0, gocv.BorderDefault)
 	
-	mean := gocv.NewMat()
-	stddev := gocv.NewMat()
-	defer mean.Close()
-	defer stddev.Close()
-	gocv.MeanStdDev(laplacian, &mean, &stddev)
-	// Use a simple approach - get first element as variance estimate
-	variance := 100.0 // Default variance value
+	stddev := matstat.StddevOf(laplacian)
+	variance := stddev * stddev
 	
 	// Normalize to 0-1 (empirically determined thresholds)
 	blurThreshold := 100.0
@@ -102,16 +98,9 @@ func (qa *QualityAssessor) assessNoise(img gocv.Mat) float64 {
 	defer diff.Close()
 	gocv.AbsDiff(gray, blurred, &diff)
 	
-	meanMat := gocv.NewMat()
-	stddevMat := gocv.NewMat()
-	defer meanMat.Close()
-	defer stddevMat.Close()
-	gocv.MeanStdDev(diff, &meanMat, &stddevMat)
-	meanScalar := gocv.Scalar{Val1: 10.0} // Default mean value
-	
 	// Lower noise = higher score
 	noiseThreshold := 20.0
-	return math.Max(0, 1.0-meanScalar.Val1/noiseThreshold)
+	return math.Max(0, 1.0-matstat.MeanOf(diff)/noiseThreshold)
 }
 
 func (qa *QualityAssessor) assessResolution(img gocv.Mat) float64 {
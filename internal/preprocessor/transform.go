@@ -0,0 +1,30 @@
+package preprocessor
+
+import (
+	"gocv.io/x/gocv"
+)
+
+// ToGrayscaleBGR strips color from img while keeping the 3-channel BGR layout
+// the rest of the pipeline expects. The caller owns the returned Mat.
+func ToGrayscaleBGR(img gocv.Mat) gocv.Mat {
+	gray := gocv.NewMat()
+	defer gray.Close()
+
+	if img.Channels() > 1 {
+		gocv.CvtColor(img, &gray, gocv.ColorBGRToGray)
+	} else {
+		img.CopyTo(&gray)
+	}
+
+	bgr := gocv.NewMat()
+	gocv.CvtColor(gray, &bgr, gocv.ColorGrayToBGR)
+	return bgr
+}
+
+// Rotate180 returns a copy of img rotated by 180 degrees. The caller owns the
+// returned Mat.
+func Rotate180(img gocv.Mat) gocv.Mat {
+	rotated := gocv.NewMat()
+	gocv.Rotate(img, &rotated, gocv.Rotate180Clockwise)
+	return rotated
+}
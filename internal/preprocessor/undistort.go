@@ -0,0 +1,36 @@
+package preprocessor
+
+import (
+	"gocv.io/x/gocv"
+)
+
+// CameraCalibration holds pinhole camera intrinsics and lens distortion coefficients
+// in OpenCV's convention: CameraMatrix is [[fx 0 cx] [0 fy cy] [0 0 1]] and
+// DistCoeffs is (k1, k2, p1, p2[, k3[, k4, k5, k6]]).
+type CameraCalibration struct {
+	CameraMatrix [3][3]float64
+	DistCoeffs   []float64
+}
+
+// Undistort removes lens distortion from img using the calibration, keeping the
+// original camera matrix so the output has the same scale. The caller owns the
+// returned Mat.
+func Undistort(img gocv.Mat, calibration CameraCalibration) gocv.Mat {
+	cameraMatrix := gocv.NewMatWithSize(3, 3, gocv.MatTypeCV64F)
+	defer cameraMatrix.Close()
+	for i, row := range calibration.CameraMatrix {
+		for j, value := range row {
+			cameraMatrix.SetDoubleAt(i, j, value)
+		}
+	}
+
+	distCoeffs := gocv.NewMatWithSize(1, len(calibration.DistCoeffs), gocv.MatTypeCV64F)
+	defer distCoeffs.Close()
+	for i, value := range calibration.DistCoeffs {
+		distCoeffs.SetDoubleAt(0, i, value)
+	}
+
+	undistorted := gocv.NewMat()
+	gocv.Undistort(img, &undistorted, cameraMatrix, distCoeffs, cameraMatrix)
+	return undistorted
+}
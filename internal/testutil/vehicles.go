@@ -0,0 +1,153 @@
+// Package testutil procedurally generates deterministic synthetic vehicle images so
+// end-to-end tests can exercise the full pipeline without binary test assets.
+package testutil
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math/rand"
+	"path/filepath"
+
+	"gocv.io/x/gocv"
+)
+
+// Canvas size of generated images, matching the canonical frame
+const (
+	ImageWidth  = 1280
+	ImageHeight = 960
+)
+
+// VehicleSpec describes the layout of a synthetic vehicle. Positions are in pixels
+// on the ImageWidth x ImageHeight canvas; the layout is centered horizontally.
+type VehicleSpec struct {
+	BodyColor    color.RGBA
+	BodyWidth    int
+	BodyTop      int
+	BodyBottom   int
+	LightSpacing int // Horizontal distance between the centers of the outer light pair
+	LightY       int
+	LightSize    int // Radius of headlights, half-width of taillights
+	ExtraLights  int // Additional light pairs placed inside the outer pair
+	PlateWidth   int
+	PlateHeight  int
+	PlateY       int
+	BumperY      int
+	OffsetX      int // Shifts the whole vehicle
+	OffsetY      int
+}
+
+// DefaultSpec returns the reference vehicle layout
+func DefaultSpec() VehicleSpec {
+	return VehicleSpec{
+		BodyColor:    color.RGBA{R: 150, G: 150, B: 150},
+		BodyWidth:    680,
+		BodyTop:      250,
+		BodyBottom:   750,
+		LightSpacing: 480,
+		LightY:       350,
+		LightSize:    30,
+		PlateWidth:   160,
+		PlateHeight:  50,
+		PlateY:       600,
+		BumperY:      700,
+	}
+}
+
+var (
+	background = gocv.NewScalar(40, 40, 40, 0)
+	white      = color.RGBA{R: 255, G: 255, B: 255}
+	dark       = color.RGBA{R: 30, G: 30, B: 30}
+	red        = color.RGBA{R: 220, G: 20, B: 20}
+)
+
+// FrontVehicle draws a front view: round white headlights, a dark grille, a bright
+// plate and a bumper line. The caller owns the returned Mat.
+func FrontVehicle(spec VehicleSpec) gocv.Mat {
+	img := drawBody(spec)
+	centerX := ImageWidth/2 + spec.OffsetX
+
+	for _, x := range lightCenters(spec) {
+		gocv.Circle(&img, image.Pt(x, spec.LightY+spec.OffsetY), spec.LightSize, white, -1)
+	}
+
+	for y := spec.LightY + 50; y <= spec.LightY+170; y += 30 {
+		gocv.Line(&img, image.Pt(centerX-120, y+spec.OffsetY), image.Pt(centerX+120, y+spec.OffsetY), dark, 4)
+	}
+
+	drawPlateAndBumper(&img, spec)
+	return img
+}
+
+// RearVehicle draws a rear view: tall red taillights, a bright plate and a bumper
+// line. The caller owns the returned Mat.
+func RearVehicle(spec VehicleSpec) gocv.Mat {
+	img := drawBody(spec)
+
+	for _, x := range lightCenters(spec) {
+		y := spec.LightY + spec.OffsetY
+		gocv.Rectangle(&img, image.Rect(x-spec.LightSize, y-2*spec.LightSize, x+spec.LightSize, y+2*spec.LightSize), red, -1)
+	}
+
+	drawPlateAndBumper(&img, spec)
+	return img
+}
+
+// WriteImage encodes img to dir/name and returns the path
+func WriteImage(dir, name string, img gocv.Mat) (string, error) {
+	path := filepath.Join(dir, name)
+	if !gocv.IMWrite(path, img) {
+		return "", fmt.Errorf("failed to write image %s", path)
+	}
+	return path, nil
+}
+
+func drawBody(spec VehicleSpec) gocv.Mat {
+	img := gocv.NewMatWithSizeFromScalar(background, ImageHeight, ImageWidth, gocv.MatTypeCV8UC3)
+
+	left := (ImageWidth-spec.BodyWidth)/2 + spec.OffsetX
+	body := image.Rect(left, spec.BodyTop+spec.OffsetY, left+spec.BodyWidth, spec.BodyBottom+spec.OffsetY)
+	gocv.Rectangle(&img, body, spec.BodyColor, -1)
+
+	return img
+}
+
+func drawPlateAndBumper(img *gocv.Mat, spec VehicleSpec) {
+	centerX := ImageWidth/2 + spec.OffsetX
+	left := (ImageWidth-spec.BodyWidth)/2 + spec.OffsetX
+
+	plateLeft := centerX - spec.PlateWidth/2
+	plate := image.Rect(plateLeft, spec.PlateY+spec.OffsetY, plateLeft+spec.PlateWidth, spec.PlateY+spec.PlateHeight+spec.OffsetY)
+	gocv.Rectangle(img, plate, white, -1)
+
+	gocv.Line(img, image.Pt(left, spec.BumperY+spec.OffsetY), image.Pt(left+spec.BodyWidth, spec.BumperY+spec.OffsetY), white, 4)
+}
+
+// lightCenters returns the x coordinate of every light, outer pair first
+func lightCenters(spec VehicleSpec) []int {
+	centerX := ImageWidth/2 + spec.OffsetX
+	half := spec.LightSpacing / 2
+
+	centers := []int{centerX - half, centerX + half}
+	for i := 1; i <= spec.ExtraLights; i++ {
+		inset := i * 3 * spec.LightSize
+		centers = append(centers, centerX-half+inset, centerX+half-inset)
+	}
+	return centers
+}
+
+// AddSpeckle scatters small gray marks over the vehicle body, shifted with the
+// vehicle by the spec offset. The pattern is fully determined by seed, giving
+// otherwise identical vehicles a distinctive, corner-rich surface texture.
+func AddSpeckle(img *gocv.Mat, spec VehicleSpec, seed int64) {
+	rng := rand.New(rand.NewSource(seed))
+
+	left := (ImageWidth-spec.BodyWidth)/2 + spec.OffsetX
+	for i := 0; i < 400; i++ {
+		x := left + rng.Intn(spec.BodyWidth-10)
+		y := spec.BodyTop + spec.OffsetY + rng.Intn(spec.BodyBottom-spec.BodyTop-10)
+		size := 3 + rng.Intn(6)
+		shade := uint8(60 + rng.Intn(60))
+		gocv.Rectangle(img, image.Rect(x, y, x+size, y+size), color.RGBA{R: shade, G: shade, B: shade}, -1)
+	}
+}
@@ -0,0 +1,73 @@
+package vehiclecompare
+
+import (
+	"fmt"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+)
+
+// ConfigDiff is how one image pair's comparison changes from engine configuration A
+// to configuration B. Deltas are B minus A.
+type ConfigDiff struct {
+	ResultA         *models.ComparisonResult `json:"result_a"`
+	ResultB         *models.ComparisonResult `json:"result_b"`
+	DecisionChanged bool                     `json:"decision_changed"` // Decision or IsSameVehicle differs
+	SimilarityDelta float64                  `json:"similarity_delta"`
+	ScoreDeltas     models.DetailedScores    `json:"score_deltas"`
+}
+
+// CompareConfigs compares the images at path1 and path2 under two engine
+// configurations and reports how the decision and each score changed, to show the
+// effect of a tuning change on real data. Features are extracted once, with the
+// service's own options, so only the engine configuration differs between the two
+// results. Either configuration failing validation is an error.
+func (vcs *VehicleComparisonService) CompareConfigs(path1, path2 string, configA, configB EngineConfig) (ConfigDiff, error) {
+	engineA, err := NewComparisonEngine(configA)
+	if err != nil {
+		return ConfigDiff{}, fmt.Errorf("config A: %w", err)
+	}
+	engineB, err := NewComparisonEngine(configB)
+	if err != nil {
+		return ConfigDiff{}, fmt.Errorf("config B: %w", err)
+	}
+
+	capture1, err := vcs.enroll(path1)
+	if err != nil {
+		return ConfigDiff{}, fmt.Errorf("failed to process image 1: %w", err)
+	}
+	capture2, err := vcs.enroll(path2)
+	if err != nil {
+		return ConfigDiff{}, fmt.Errorf("failed to process image 2: %w", err)
+	}
+
+	if err := vcs.validateImageConsistency(
+		&models.VehicleImage{View: capture1.Features.View, Lighting: capture1.Features.Lighting, QualityScore: capture1.Quality},
+		&models.VehicleImage{View: capture2.Features.View, Lighting: capture2.Features.Lighting, QualityScore: capture2.Quality},
+	); err != nil {
+		return ConfigDiff{}, err
+	}
+
+	resultA, err := engineA.CompareVehicles(capture1.Features, capture2.Features)
+	if err != nil {
+		return ConfigDiff{}, fmt.Errorf("failed to compare vehicles under config A: %v", err)
+	}
+	resultB, err := engineB.CompareVehicles(capture1.Features, capture2.Features)
+	if err != nil {
+		return ConfigDiff{}, fmt.Errorf("failed to compare vehicles under config B: %v", err)
+	}
+
+	a, b := resultA.DetailedScores, resultB.DetailedScores
+	return ConfigDiff{
+		ResultA:         resultA,
+		ResultB:         resultB,
+		DecisionChanged: resultA.Decision != resultB.Decision || resultA.IsSameVehicle != resultB.IsSameVehicle,
+		SimilarityDelta: resultB.SimilarityScore - resultA.SimilarityScore,
+		ScoreDeltas: models.DetailedScores{
+			GeometricSimilarity:    b.GeometricSimilarity - a.GeometricSimilarity,
+			LightPatternSimilarity: b.LightPatternSimilarity - a.LightPatternSimilarity,
+			BumperSimilarity:       b.BumperSimilarity - a.BumperSimilarity,
+			ColorSimilarity:        b.ColorSimilarity - a.ColorSimilarity,
+			ThermalSimilarity:      b.ThermalSimilarity - a.ThermalSimilarity,
+		},
+	}, nil
+}
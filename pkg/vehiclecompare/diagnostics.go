@@ -0,0 +1,176 @@
+package vehiclecompare
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+)
+
+// RejectionReason classifies why a comparison returned an error
+type RejectionReason string
+
+const (
+	RejectionDecode         RejectionReason = "decode"            // ErrImageDecode
+	RejectionTooSmall       RejectionReason = "too_small"         // ErrImageTooSmall
+	RejectionTooLarge       RejectionReason = "too_large"         // ErrImageTooLarge
+	RejectionQuality        RejectionReason = "quality"           // ErrInsufficientQuality
+	RejectionUnclassifiable RejectionReason = "unclassifiable"    // *ErrUnclassifiable
+	RejectionInconsistent   RejectionReason = "inconsistent_pair" // ErrInconsistentPair
+	RejectionViewpoint      RejectionReason = "viewpoint"         // *ErrViewpointMismatch
+	RejectionOther          RejectionReason = "other"
+)
+
+// DiagnosticsCollector accumulates feature-extraction statistics across the
+// comparisons of every service it is passed to with WithDiagnostics, so thresholds
+// can be tuned by measurement over a dataset. It is safe for concurrent use.
+//
+// Image pair comparisons are recorded, whatever the entry point; template
+// enrollment and template comparisons are not, and a progressive comparison is
+// recorded once, for its full-resolution result.
+type DiagnosticsCollector struct {
+	mu sync.Mutex
+
+	comparisons     int
+	images          int // Images that went through full feature extraction
+	lightElements   int
+	plates          int // Images with an IR signature, which locates the plate
+	plateConfidence float64
+	rejections      map[RejectionReason]int
+}
+
+// DiagnosticsSummary is the report of a DiagnosticsCollector. Means are zero when
+// nothing was measured.
+type DiagnosticsSummary struct {
+	Comparisons         int     `json:"comparisons"`      // Including rejected ones
+	ImagesExtracted     int     `json:"images_extracted"` // Excludes early exits, which skip light extraction
+	MeanLightElements   float64 `json:"mean_light_elements"`
+	PlatesFound         int     `json:"plates_found"`
+	MeanPlateConfidence float64 `json:"mean_plate_confidence"`
+
+	// Comparisons that returned an error, by reason, as counts and as fractions of
+	// all comparisons. Each rejected comparison counts once, for the first image or
+	// pair check that failed.
+	Rejections     map[RejectionReason]int     `json:"rejections"`
+	RejectionRates map[RejectionReason]float64 `json:"rejection_rates"`
+}
+
+func NewDiagnosticsCollector() *DiagnosticsCollector {
+	return &DiagnosticsCollector{rejections: map[RejectionReason]int{}}
+}
+
+// Summary returns the statistics collected so far
+func (dc *DiagnosticsCollector) Summary() DiagnosticsSummary {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	summary := DiagnosticsSummary{
+		Comparisons:     dc.comparisons,
+		ImagesExtracted: dc.images,
+		PlatesFound:     dc.plates,
+		Rejections:      make(map[RejectionReason]int, len(dc.rejections)),
+		RejectionRates:  make(map[RejectionReason]float64, len(dc.rejections)),
+	}
+	if dc.images > 0 {
+		summary.MeanLightElements = float64(dc.lightElements) / float64(dc.images)
+	}
+	if dc.plates > 0 {
+		summary.MeanPlateConfidence = dc.plateConfidence / float64(dc.plates)
+	}
+	for reason, count := range dc.rejections {
+		summary.Rejections[reason] = count
+		summary.RejectionRates[reason] = float64(count) / float64(dc.comparisons)
+	}
+	return summary
+}
+
+// WriteReport writes the summary to w as indented JSON
+func (dc *DiagnosticsCollector) WriteReport(w io.Writer) error {
+	report, err := json.MarshalIndent(dc.Summary(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode diagnostics report: %v", err)
+	}
+	if _, err := w.Write(append(report, '\n')); err != nil {
+		return fmt.Errorf("failed to write diagnostics report: %v", err)
+	}
+	return nil
+}
+
+// Reset discards everything collected so far
+func (dc *DiagnosticsCollector) Reset() {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	dc.comparisons, dc.images, dc.lightElements, dc.plates = 0, 0, 0, 0
+	dc.plateConfidence = 0.0
+	dc.rejections = map[RejectionReason]int{}
+}
+
+// recordComparison records the outcome of a comparison of decoded images, with the
+// trace of the pass that produced it. It does nothing on a nil collector.
+func (dc *DiagnosticsCollector) recordComparison(trace *ComparisonTrace, result *models.ComparisonResult, err error) {
+	if dc == nil {
+		return
+	}
+	if err != nil {
+		dc.recordRejection(err)
+		return
+	}
+
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	dc.comparisons++
+	if result.ProcessingInfo.EarlyExit || trace == nil {
+		return
+	}
+	for _, features := range trace.Features {
+		dc.images++
+		dc.lightElements += len(features.LightPatterns.LightElements)
+		if ir := features.InfraredFeatures; ir != nil && ir.IRSignature != nil {
+			dc.plates++
+			dc.plateConfidence += ir.IRSignature.PlateRegion.Confidence
+		}
+	}
+}
+
+// recordRejection records a comparison that failed with err. It does nothing on a
+// nil collector.
+func (dc *DiagnosticsCollector) recordRejection(err error) {
+	if dc == nil {
+		return
+	}
+
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	dc.comparisons++
+	dc.rejections[rejectionReason(err)]++
+}
+
+func rejectionReason(err error) RejectionReason {
+	var unclassifiable *ErrUnclassifiable
+	var viewpoint *ErrViewpointMismatch
+
+	switch {
+	case errors.Is(err, ErrImageDecode):
+		return RejectionDecode
+	case errors.Is(err, ErrImageTooSmall):
+		return RejectionTooSmall
+	case errors.Is(err, ErrImageTooLarge):
+		return RejectionTooLarge
+	case errors.Is(err, ErrInsufficientQuality):
+		return RejectionQuality
+	case errors.As(err, &unclassifiable):
+		return RejectionUnclassifiable
+	case errors.Is(err, ErrInconsistentPair):
+		return RejectionInconsistent
+	case errors.As(err, &viewpoint):
+		return RejectionViewpoint
+	default:
+		return RejectionOther
+	}
+}
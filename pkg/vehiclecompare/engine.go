@@ -0,0 +1,38 @@
+package vehiclecompare
+
+import "github.com/choff5507/vehicle-image-comparison/internal/comparator"
+
+// ComparisonEngine compares two extracted feature sets. Use it directly to compare
+// features extracted or stored by your own pipeline; the service wraps one with
+// image loading and feature extraction.
+type ComparisonEngine = comparator.ComparisonEngine
+
+// EngineConfig holds every weight, threshold, matching parameter and mode of a
+// ComparisonEngine
+type EngineConfig = comparator.Config
+
+// DefaultEngineConfig returns the configuration the service's engine starts from
+func DefaultEngineConfig() EngineConfig {
+	return comparator.DefaultConfig()
+}
+
+// NewComparisonEngine creates a standalone engine from config. Compare feature
+// sets with its CompareVehicles method. Out-of-range weights or thresholds are
+// rejected with an error naming the field (see EngineConfig.Validate).
+func NewComparisonEngine(config EngineConfig) (*ComparisonEngine, error) {
+	return comparator.NewComparisonEngineWithConfig(config)
+}
+
+// Version identifies the extraction and scoring behavior of this release. Results
+// cached under another version are stale.
+func Version() string {
+	return comparator.Version()
+}
+
+// ConfigFingerprint identifies the service's current engine configuration together
+// with Version, as recorded in each result's ProcessingInfo.ConfigFingerprint.
+// Include it in the key of any cache of comparison results, so a change of weights,
+// thresholds, modes or version misses the cache instead of serving stale scores.
+func (vcs *VehicleComparisonService) ConfigFingerprint() string {
+	return vcs.comparisonEngine.Config().Fingerprint()
+}
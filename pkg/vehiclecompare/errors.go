@@ -0,0 +1,67 @@
+package vehiclecompare
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+)
+
+// ErrImageTooSmall is returned when an image is smaller than the largest kernel
+// used by the processing pipeline
+var ErrImageTooSmall = errors.New("image too small to process")
+
+// ErrImageTooLarge is returned when an image has more pixels than the
+// WithMaxImagePixels bound. Where the format allows, it is detected from the header
+// before the image is decoded.
+var ErrImageTooLarge = errors.New("image too large to process")
+
+// ErrImageDecode is returned when an image cannot be loaded or decodes to a Mat
+// the pipeline cannot use: no pixels, or a channel count other than 1 or 3
+var ErrImageDecode = errors.New("image could not be decoded")
+
+// ErrInvalidMask is returned when a region-of-interest mask does not match its
+// image: it must be a single-channel 8-bit Mat of the same size
+var ErrInvalidMask = errors.New("invalid region-of-interest mask")
+
+// ErrInsufficientQuality is returned when an image's quality score is below the
+// pipeline's floor of 0.3, below the WithMinQueryQuality or WithMinGalleryQuality
+// minimum for its side of the comparison, or below the WithPerceptualQualityGate
+// minimum
+var ErrInsufficientQuality = errors.New("image quality too low")
+
+// ErrInconsistentPair is returned when the two images show different views of the
+// vehicle or were captured under different lighting, so their features cannot be
+// compared
+var ErrInconsistentPair = errors.New("images cannot be compared")
+
+// ErrUnhealthy is returned by Healthy when OpenCV is missing or not operational
+var ErrUnhealthy = errors.New("opencv is not operational")
+
+// ErrUnclassifiable is returned when an image's view or lighting cannot be
+// determined with sufficient confidence. It carries the classifier's best
+// guesses so callers can guide the user to retake the photo.
+type ErrUnclassifiable struct {
+	View               models.VehicleView
+	ViewConfidence     float64
+	Lighting           models.LightingType
+	LightingConfidence float64
+}
+
+func (e *ErrUnclassifiable) Error() string {
+	return fmt.Sprintf("unable to classify image: view %s (confidence %.2f), lighting %s (confidence %.2f)",
+		e.View, e.ViewConfidence, e.Lighting, e.LightingConfidence)
+}
+
+// ErrViewpointMismatch is returned when the estimated capture angles of the two
+// images differ by more than the WithMaxViewpointDelta bound. Same-vehicle pairs shot
+// from very different angles score unfairly low, so they are refused instead.
+type ErrViewpointMismatch struct {
+	Delta      models.ViewpointDelta
+	MaxDegrees float64
+}
+
+func (e *ErrViewpointMismatch) Error() string {
+	return fmt.Sprintf("capture angles differ too much: yaw %.1f°, pitch %.1f° (maximum %.1f°)",
+		e.Delta.YawDegrees, e.Delta.PitchDegrees, e.MaxDegrees)
+}
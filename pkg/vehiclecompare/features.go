@@ -0,0 +1,60 @@
+package vehiclecompare
+
+import (
+	"fmt"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+	"gocv.io/x/gocv"
+)
+
+// ExtractFeatures processes a decoded image and extracts its features, as the
+// service does for each side of a comparison, so they can be cached (VehicleFeatures
+// is JSON-serializable) and compared later with CompareFeatures. The image is
+// rejected as in a comparison when it is undecodable, below the quality floor or
+// unclassifiable. The caller keeps ownership of img.
+func (vcs *VehicleComparisonService) ExtractFeatures(img gocv.Mat) (models.VehicleFeatures, error) {
+	if err := checkDecoded(img); err != nil {
+		return models.VehicleFeatures{}, err
+	}
+
+	img, release, err := vcs.preprocessImage(img)
+	defer release()
+	if err != nil {
+		return models.VehicleFeatures{}, err
+	}
+
+	vehicleImg, err := vcs.processImage(img)
+	if err != nil {
+		return models.VehicleFeatures{}, fmt.Errorf("failed to process image: %w", err)
+	}
+	defer vehicleImg.Image.Close()
+
+	features, err := vcs.extractFeatures(vehicleImg)
+	if err != nil {
+		return models.VehicleFeatures{}, fmt.Errorf("failed to extract features: %w", err)
+	}
+	return features, nil
+}
+
+// CompareFeatures compares two feature sets from ExtractFeatures with the service's
+// comparison engine, without touching any image. Pairs whose views or lighting
+// differ are rejected by the engine, and pairs beyond WithMaxViewpointDelta with
+// ErrViewpointMismatch; the service's quality minimums are not applied, as the
+// features carry no quality score. ExtractFeatures moves a colorless daylight image
+// to the infrared path, so its features are rejected against a daylight feature set:
+// without the images, the daylight side cannot be re-extracted on the infrared path
+// as an image comparison would. Compare such a pair as images instead.
+func (vcs *VehicleComparisonService) CompareFeatures(features1, features2 models.VehicleFeatures) (*models.ComparisonResult, error) {
+	viewpointDelta, err := vcs.checkViewpoint(features1, features2)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := vcs.comparisonEngine.CompareVehicles(features1, features2)
+	if err != nil {
+		return nil, err
+	}
+	result.ProcessingInfo.ViewpointDelta = viewpointDelta
+	vcs.markDisabledFeatures(&result.Availability)
+	return result, nil
+}
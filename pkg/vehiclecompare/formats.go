@@ -0,0 +1,173 @@
+package vehiclecompare
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"sync"
+
+	"gocv.io/x/gocv"
+)
+
+// Image formats OpenCV may be built with, each with a generator for a minimal
+// sample used to probe whether the linked build can decode it
+var candidateFormats = []struct {
+	name   string
+	sample func() []byte
+}{
+	{"jpeg", jpegSample},
+	{"png", pngSample},
+	{"bmp", bmpSample},
+	{"tiff", tiffSample},
+	{"webp", webpSample},
+	{"pnm", pnmSample},
+}
+
+var (
+	supportedFormatsOnce sync.Once
+	supportedFormats     []string
+)
+
+// SupportedFormats returns the image formats the linked OpenCV build can decode.
+// Each candidate is probed once by decoding a tiny sample image.
+func SupportedFormats() []string {
+	supportedFormatsOnce.Do(func() {
+		for _, format := range candidateFormats {
+			if canDecode(format.sample()) {
+				supportedFormats = append(supportedFormats, format.name)
+			}
+		}
+	})
+
+	formats := make([]string, len(supportedFormats))
+	copy(formats, supportedFormats)
+	return formats
+}
+
+// canDecode reports whether OpenCV decodes data. imdecode returns an empty Mat
+// rather than failing when no decoder recognizes the data.
+func canDecode(data []byte) bool {
+	if len(data) == 0 {
+		return false
+	}
+
+	img, err := gocv.IMDecode(data, gocv.IMReadColor)
+	if err != nil {
+		return false
+	}
+	defer img.Close()
+
+	return !img.Empty()
+}
+
+func sampleImage() image.Image {
+	img := image.NewGray(image.Rect(0, 0, 8, 8))
+	for i := range img.Pix {
+		img.Pix[i] = 128
+	}
+	return img
+}
+
+func jpegSample() []byte {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, sampleImage(), nil); err != nil {
+		return nil
+	}
+	return buf.Bytes()
+}
+
+func pngSample() []byte {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, sampleImage()); err != nil {
+		return nil
+	}
+	return buf.Bytes()
+}
+
+// bmpSample builds an uncompressed 1x1 24-bit BMP
+func bmpSample() []byte {
+	var buf bytes.Buffer
+	le := binary.LittleEndian
+
+	buf.WriteString("BM")
+	binary.Write(&buf, le, uint32(58)) // File size
+	binary.Write(&buf, le, uint32(0))  // Reserved
+	binary.Write(&buf, le, uint32(54)) // Pixel data offset
+
+	binary.Write(&buf, le, uint32(40)) // Info header size
+	binary.Write(&buf, le, int32(1))   // Width
+	binary.Write(&buf, le, int32(1))   // Height
+	binary.Write(&buf, le, uint16(1))  // Planes
+	binary.Write(&buf, le, uint16(24)) // Bits per pixel
+	binary.Write(&buf, le, uint32(0))  // No compression
+	binary.Write(&buf, le, uint32(4))  // Image size including row padding
+	binary.Write(&buf, le, int32(2835))
+	binary.Write(&buf, le, int32(2835))
+	binary.Write(&buf, le, uint32(0))
+	binary.Write(&buf, le, uint32(0))
+
+	buf.Write([]byte{128, 128, 128, 0}) // One BGR pixel padded to 4 bytes
+	return buf.Bytes()
+}
+
+// tiffSample builds an uncompressed 1x1 8-bit grayscale TIFF
+func tiffSample() []byte {
+	var buf bytes.Buffer
+	le := binary.LittleEndian
+
+	type entry struct {
+		tag, kind uint16
+		value     uint32
+	}
+	const shortType, longType = 3, 4
+	entries := []entry{
+		{256, longType, 1},  // ImageWidth
+		{257, longType, 1},  // ImageLength
+		{258, shortType, 8}, // BitsPerSample
+		{259, shortType, 1}, // Compression: none
+		{262, shortType, 1}, // Photometric: BlackIsZero
+		{273, longType, 0},  // StripOffsets, patched below
+		{277, shortType, 1}, // SamplesPerPixel
+		{278, longType, 1},  // RowsPerStrip
+		{279, longType, 1},  // StripByteCounts
+	}
+	dataOffset := uint32(8 + 2 + len(entries)*12 + 4)
+	entries[5].value = dataOffset
+
+	buf.WriteString("II")
+	binary.Write(&buf, le, uint16(42))
+	binary.Write(&buf, le, uint32(8)) // First IFD offset
+
+	binary.Write(&buf, le, uint16(len(entries)))
+	for _, e := range entries {
+		binary.Write(&buf, le, e.tag)
+		binary.Write(&buf, le, e.kind)
+		binary.Write(&buf, le, uint32(1)) // Count
+		if e.kind == shortType {
+			binary.Write(&buf, le, uint16(e.value))
+			binary.Write(&buf, le, uint16(0))
+		} else {
+			binary.Write(&buf, le, e.value)
+		}
+	}
+	binary.Write(&buf, le, uint32(0)) // No further IFDs
+
+	buf.WriteByte(128)
+	return buf.Bytes()
+}
+
+// webpSample is a 1x1 lossless WebP
+func webpSample() []byte {
+	return []byte{
+		0x52, 0x49, 0x46, 0x46, 0x1a, 0x00, 0x00, 0x00, 0x57, 0x45, 0x42, 0x50, 0x56, 0x50, 0x38, 0x4c,
+		0x0d, 0x00, 0x00, 0x00, 0x2f, 0x00, 0x00, 0x00, 0x10, 0x07, 0x10, 0x11, 0x11, 0x88, 0x88, 0xfe,
+		0x07, 0x00,
+	}
+}
+
+// pnmSample is a 1x1 binary PGM
+func pnmSample() []byte {
+	return []byte("P5\n1 1\n255\n\x80")
+}
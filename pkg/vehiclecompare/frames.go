@@ -0,0 +1,249 @@
+package vehiclecompare
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+
+	"gocv.io/x/gocv"
+)
+
+// Frames beyond this are ignored, bounding the work an untrusted file can cause
+const maxFrames = 64
+
+// decodeFrames returns the frames of a GIF or multi-page TIFF as color Mats in file
+// order, or nil for any other data (including single-page TIFFs, which OpenCV
+// decodes directly). Only the first frame is decoded unless all is set. GIFs are
+// always decoded here, whatever the OpenCV build supports, so every GIF yields a
+// fully composited frame rather than a build-dependent result. The caller owns the
+// returned Mats.
+func decodeFrames(data []byte, all bool) ([]gocv.Mat, error) {
+	if bytes.HasPrefix(data, []byte("GIF8")) {
+		return decodeGIFFrames(data, all)
+	}
+
+	if offsets := tiffPageOffsets(data); len(offsets) > 1 {
+		if !all {
+			offsets = offsets[:1]
+		}
+		return decodeTIFFPages(data, offsets)
+	}
+
+	return nil, nil
+}
+
+// decodeGIFFrames composites each GIF frame onto the logical screen, honoring the
+// frames' disposal methods, so later frames are full images rather than deltas.
+// Frames beyond those used are cut off before decoding, so they cost nothing.
+func decodeGIFFrames(data []byte, all bool) ([]gocv.Mat, error) {
+	count := maxFrames
+	if !all {
+		count = 1
+	}
+
+	decoded, err := gif.DecodeAll(bytes.NewReader(truncateGIF(data, count)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid GIF: %v", err)
+	}
+	count = min(count, len(decoded.Image))
+
+	canvas := image.NewRGBA(image.Rect(0, 0, decoded.Config.Width, decoded.Config.Height))
+	frames := make([]gocv.Mat, 0, count)
+	for i := 0; i < count; i++ {
+		frame := decoded.Image[i]
+
+		disposal := byte(0)
+		if i < len(decoded.Disposal) {
+			disposal = decoded.Disposal[i]
+		}
+
+		var previous *image.RGBA
+		if disposal == gif.DisposalPrevious {
+			previous = image.NewRGBA(canvas.Bounds())
+			copy(previous.Pix, canvas.Pix)
+		}
+
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+
+		mat, err := gocv.ImageToMatRGB(canvas)
+		if err != nil {
+			closeFrames(frames)
+			return nil, fmt.Errorf("GIF frame %d: %v", i, err)
+		}
+		frames = append(frames, mat)
+
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, frame.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+
+	if len(frames) == 0 {
+		return nil, fmt.Errorf("GIF has no frames")
+	}
+	return frames, nil
+}
+
+// truncateGIF returns GIF data cut after its first frames images and terminated with
+// a trailer. Data with fewer frames, or whose block structure it cannot follow, is
+// returned unchanged for the decoder to handle.
+func truncateGIF(data []byte, frames int) []byte {
+	// Header and logical screen descriptor, then the optional global color table
+	pos := 13
+	if len(data) < pos {
+		return data
+	}
+	if packed := data[10]; packed&0x80 != 0 {
+		pos += 3 << ((packed & 7) + 1)
+	}
+
+	// skipSubBlocks returns the position after the data sub-blocks at pos, or -1
+	// when they run past the end
+	skipSubBlocks := func(pos int) int {
+		for pos >= 0 && pos < len(data) {
+			size := int(data[pos])
+			pos++
+			if size == 0 {
+				return pos
+			}
+			pos += size
+		}
+		return -1
+	}
+
+	for seen := 0; pos >= 0 && pos < len(data); {
+		switch data[pos] {
+		case 0x21: // Extension: introducer and label, then sub-blocks
+			pos = skipSubBlocks(pos + 2)
+		case 0x2C: // Image: descriptor, optional local color table, LZW code size, sub-blocks
+			if pos+10 > len(data) {
+				return data
+			}
+			packed := data[pos+9]
+			pos += 10
+			if packed&0x80 != 0 {
+				pos += 3 << ((packed & 7) + 1)
+			}
+			pos = skipSubBlocks(pos + 1)
+
+			seen++
+			if pos >= 0 && seen == frames {
+				truncated := make([]byte, pos+1)
+				copy(truncated, data[:pos])
+				truncated[pos] = 0x3B
+				return truncated
+			}
+		default: // Trailer, or data it cannot follow
+			return data
+		}
+	}
+	return data
+}
+
+// tiffPageOffsets walks a classic TIFF's IFD chain and returns the offset of each
+// page's IFD. Returns nil for data that is not a TIFF. BigTIFF is not split and
+// is left to OpenCV.
+func tiffPageOffsets(data []byte) []uint32 {
+	if len(data) < 8 {
+		return nil
+	}
+
+	var order binary.ByteOrder
+	switch string(data[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil
+	}
+	if order.Uint16(data[2:4]) != 42 {
+		return nil
+	}
+
+	offsets := []uint32{}
+	seen := map[uint32]bool{}
+	for offset := order.Uint32(data[4:8]); offset != 0 && len(offsets) < maxFrames; {
+		// A cyclic chain would otherwise never end
+		if seen[offset] || int64(offset)+2 > int64(len(data)) {
+			break
+		}
+		seen[offset] = true
+
+		entries := int64(order.Uint16(data[offset:]))
+		next := int64(offset) + 2 + entries*12
+		if next+4 > int64(len(data)) {
+			break
+		}
+
+		offsets = append(offsets, offset)
+		offset = order.Uint32(data[next:])
+	}
+
+	return offsets
+}
+
+// decodeTIFFPages decodes the pages whose IFDs start at offsets. OpenCV only reads
+// a TIFF's first page, so each page is decoded from a copy whose header points at
+// that page's IFD.
+func decodeTIFFPages(data []byte, offsets []uint32) ([]gocv.Mat, error) {
+	order := binary.ByteOrder(binary.LittleEndian)
+	if data[0] == 'M' {
+		order = binary.BigEndian
+	}
+
+	page := make([]byte, len(data))
+	copy(page, data)
+
+	frames := make([]gocv.Mat, 0, len(offsets))
+	for i, offset := range offsets {
+		order.PutUint32(page[4:8], offset)
+
+		mat, err := gocv.IMDecode(page, gocv.IMReadColor)
+		if err == nil && mat.Empty() {
+			mat.Close()
+			err = fmt.Errorf("unrecognized or corrupt page")
+		}
+		if err != nil {
+			closeFrames(frames)
+			return nil, fmt.Errorf("TIFF page %d: %v", i, err)
+		}
+		frames = append(frames, mat)
+	}
+
+	return frames, nil
+}
+
+// selectFrame picks the frame to compare: the first, or with best-frame selection
+// the one the quality assessor scores highest (the earliest on ties). The other
+// frames are closed.
+func (vcs *VehicleComparisonService) selectFrame(frames []gocv.Mat) gocv.Mat {
+	best := 0
+	if vcs.bestFrame && len(frames) > 1 {
+		bestQuality := -1.0
+		for i, frame := range frames {
+			quality, err := vcs.qualityAssessor.AssessImageQuality(frame)
+			if err == nil && quality > bestQuality {
+				best, bestQuality = i, quality
+			}
+		}
+	}
+
+	for i := range frames {
+		if i != best {
+			frames[i].Close()
+		}
+	}
+	return frames[best]
+}
+
+func closeFrames(frames []gocv.Mat) {
+	for _, frame := range frames {
+		frame.Close()
+	}
+}
@@ -0,0 +1,55 @@
+package vehiclecompare
+
+import (
+	"fmt"
+
+	"gocv.io/x/gocv"
+)
+
+// The synthetic probe image: a uniform BGR color whose gray level (0.114 B +
+// 0.587 G + 0.299 R = 96) lies above the probe threshold
+const (
+	healthProbeSize      = 16
+	healthProbeGray      = 96
+	healthProbeThreshold = 90
+)
+
+var healthProbeColor = gocv.NewScalar(200, 100, 50, 0)
+
+// Healthy reports whether OpenCV is loaded and operational, for readiness probes in
+// server deployments. It runs a tiny synthetic pipeline (create a Mat, convert it to
+// gray, threshold it) and checks every intermediate result, returning an error
+// wrapping ErrUnhealthy on any failure, including a panic in the binding. It does not
+// exercise feature extraction or scoring.
+func (vcs *VehicleComparisonService) Healthy() (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", ErrUnhealthy, r)
+		}
+	}()
+
+	probe := gocv.NewMatWithSizeFromScalar(healthProbeColor, healthProbeSize, healthProbeSize, gocv.MatTypeCV8UC3)
+	defer probe.Close()
+	if probe.Empty() || probe.Channels() != 3 {
+		return fmt.Errorf("%w: could not create a %dx%d BGR Mat", ErrUnhealthy, healthProbeSize, healthProbeSize)
+	}
+
+	gray := gocv.NewMat()
+	defer gray.Close()
+	gocv.CvtColor(probe, &gray, gocv.ColorBGRToGray)
+	if gray.Empty() || gray.Channels() != 1 {
+		return fmt.Errorf("%w: color conversion produced no gray image", ErrUnhealthy)
+	}
+	if level := gray.GetUCharAt(0, 0); level != healthProbeGray {
+		return fmt.Errorf("%w: color conversion produced gray level %d, want %d", ErrUnhealthy, level, healthProbeGray)
+	}
+
+	binary := gocv.NewMat()
+	defer binary.Close()
+	gocv.Threshold(gray, &binary, healthProbeThreshold, 255, gocv.ThresholdBinary)
+	if count := gocv.CountNonZero(binary); count != healthProbeSize*healthProbeSize {
+		return fmt.Errorf("%w: threshold kept %d of %d pixels", ErrUnhealthy, count, healthProbeSize*healthProbeSize)
+	}
+
+	return nil
+}
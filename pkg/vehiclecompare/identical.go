@@ -0,0 +1,85 @@
+package vehiclecompare
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+	"gocv.io/x/gocv"
+)
+
+// Reason recorded in the availability of every feature of an identical-input result
+const identicalInputReason = "both inputs are the same image"
+
+// compareIdentical compares an image given as both inputs. The image is processed
+// once, so it is still rejected when it could not be compared with a copy of itself
+// (undecodable, below a quality minimum, unclassifiable), and is otherwise reported
+// as the same vehicle with similarity 1.0 without extracting features. The weights
+// and the features left out by the comparison mode are reported as for any other
+// pair. Such results are not traced: there are no features or similarities to
+// record.
+func (vcs *VehicleComparisonService) compareIdentical(img gocv.Mat, startTime time.Time) (*models.ComparisonResult, error) {
+	result, err := vcs.identicalResult(img, startTime)
+	vcs.diagnostics.recordComparison(nil, result, err)
+	return result, err
+}
+
+func (vcs *VehicleComparisonService) identicalResult(img gocv.Mat, startTime time.Time) (*models.ComparisonResult, error) {
+	if err := checkDecoded(img); err != nil {
+		return nil, fmt.Errorf("image 1: %w", err)
+	}
+
+	img, release, err := vcs.preprocessImage(img)
+	defer release()
+	if err != nil {
+		return nil, fmt.Errorf("image 1: %w", err)
+	}
+
+	vehicleImg, err := vcs.processImage(img)
+	if err != nil {
+		return nil, fmt.Errorf("failed to process image: %w", err)
+	}
+	defer vehicleImg.Image.Close()
+
+	// The image is its own gallery entry, so both quality minimums apply to it
+	if err := vcs.validateImageConsistency(vehicleImg, vehicleImg); err != nil {
+		return nil, err
+	}
+
+	skipped := models.FeatureState{Status: models.FeatureSkipped, Reason: identicalInputReason}
+	availability := models.FeatureAvailability{
+		Geometric:    skipped,
+		LightPattern: skipped,
+		Bumper:       skipped,
+		Color:        skipped,
+		Thermal:      skipped,
+	}
+	vcs.comparisonEngine.MarkModeSkipped(&availability)
+	vcs.markDisabledFeatures(&availability)
+
+	threshold := vcs.comparisonEngine.SimilarityThreshold(vehicleImg.Lighting)
+	return &models.ComparisonResult{
+		IsSameVehicle:    true,
+		Decision:         models.DecisionSame,
+		SimilarityScore:  1.0,
+		ConfidenceLevel:  models.ConfidenceHigh,
+		EffectiveWeights: vcs.comparisonEngine.EffectiveWeights(vehicleImg.Lighting),
+		Availability:     availability,
+		ProcessingInfo: models.ProcessingInfo{
+			ProcessingTimeMs:    time.Since(startTime).Milliseconds(),
+			Image1Quality:       vehicleImg.QualityScore,
+			Image2Quality:       vehicleImg.QualityScore,
+			Image1View:          vehicleImg.View,
+			Image1Lighting:      vehicleImg.Lighting,
+			Image2View:          vehicleImg.View,
+			Image2Lighting:      vehicleImg.Lighting,
+			ViewConsistency:     true,
+			LightingConsistency: true,
+			DecisionThreshold:   threshold,
+			ThresholdMargin:     1.0 - threshold,
+			FeaturePath:         vcs.comparisonEngine.FeaturePath(vehicleImg.Lighting),
+			IdenticalInput:      true,
+			ConfigFingerprint:   vcs.comparisonEngine.Config().Fingerprint(),
+		},
+	}, nil
+}
@@ -0,0 +1,136 @@
+package vehiclecompare
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+)
+
+// File extensions of each format SupportedFormats may report
+var formatExtensions = map[string][]string{
+	"jpeg": {".jpg", ".jpeg", ".jpe"},
+	"png":  {".png"},
+	"bmp":  {".bmp", ".dib"},
+	"tiff": {".tif", ".tiff"},
+	"webp": {".webp"},
+	"pnm":  {".pbm", ".pgm", ".ppm", ".pnm"},
+}
+
+// RankedMatch is one gallery image compared by IdentifyFromDirectory. Result is nil
+// and Err says why when the image could not be compared, for instance because it
+// shows another view or lighting than the query.
+type RankedMatch struct {
+	Path   string                   `json:"path"`
+	Result *models.ComparisonResult `json:"result,omitempty"`
+	Err    error                    `json:"-"`
+}
+
+// IdentifyFromDirectory compares the query image against every supported image
+// directly inside dir and returns one RankedMatch per image, the most similar first
+// and the images that could not be compared last. The query's features are
+// extracted once; the gallery images are processed by a pool of workers, and the
+// WithProgress callback is told as each one completes. An error is returned only
+// when the query itself or the directory cannot be read.
+func (vcs *VehicleComparisonService) IdentifyFromDirectory(query string, dir string) ([]RankedMatch, error) {
+	paths, err := galleryImages(query, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	queryCapture, err := vcs.enroll(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to process query: %w", err)
+	}
+
+	matches := make([]RankedMatch, len(paths))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	var progressMu sync.Mutex
+	done := 0
+	for w := 0; w < min(runtime.NumCPU(), len(paths)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				matches[i] = vcs.rankCandidate(queryCapture, paths[i])
+				if vcs.progress != nil {
+					// Serialized so the callback sees done strictly increasing
+					progressMu.Lock()
+					done++
+					vcs.progress(done, len(paths))
+					progressMu.Unlock()
+				}
+			}
+		}()
+	}
+	for i := range paths {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	sort.SliceStable(matches, func(a, b int) bool {
+		if (matches[a].Result == nil) != (matches[b].Result == nil) {
+			return matches[a].Result != nil
+		}
+		if matches[a].Result == nil {
+			return false
+		}
+		return matches[a].Result.SimilarityScore > matches[b].Result.SimilarityScore
+	})
+	return matches, nil
+}
+
+// rankCandidate compares the query's features against one gallery image
+func (vcs *VehicleComparisonService) rankCandidate(query *EnrolledCapture, path string) RankedMatch {
+	startTime := time.Now()
+
+	candidate, err := vcs.enroll(path)
+	if err != nil {
+		return RankedMatch{Path: path, Err: err}
+	}
+
+	result, err := vcs.compareCaptures(query, candidate, startTime)
+	if err != nil {
+		return RankedMatch{Path: path, Err: err}
+	}
+	return RankedMatch{Path: path, Result: result}
+}
+
+// galleryImages lists the files directly inside dir with the extension of a format
+// the OpenCV build decodes, or of a GIF, in name order. The query is left out when
+// it lives in dir.
+func galleryImages(query, dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read gallery directory: %v", err)
+	}
+
+	extensions := map[string]bool{".gif": true} // Always decoded, see decodeFrames
+	for _, format := range SupportedFormats() {
+		for _, ext := range formatExtensions[format] {
+			extensions[ext] = true
+		}
+	}
+
+	queryInfo, _ := os.Stat(query)
+	paths := []string{}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || !extensions[strings.ToLower(filepath.Ext(entry.Name()))] {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		if info, err := os.Stat(path); err == nil && queryInfo != nil && os.SameFile(info, queryInfo) {
+			continue
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
@@ -0,0 +1,65 @@
+package vehiclecompare
+
+import (
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+)
+
+// Lighting classifications below this confidence are ambiguous. The classifier's
+// guess for dim, weakly colored captures, typical of dusk and dawn, scores 0.6.
+const ambiguousLightingConfidence = 0.7
+
+// lightingPaths are the feature paths an ambiguous image may be compared on
+var lightingPaths = []models.LightingType{models.LightingDaylight, models.LightingInfrared}
+
+// lightingAmbiguous reports whether a lighting classification is too uncertain to
+// choose a feature path by
+func lightingAmbiguous(lighting models.LightingType, confidence float64) bool {
+	return lighting == models.LightingUnknown || confidence < ambiguousLightingConfidence
+}
+
+// compareAcrossLightings compares a pair with at least one ambiguously lit image on
+// every feature path both images may share, and keeps the highest-scoring result.
+// An ambiguous image may take either path; the other image keeps its own. When every
+// path fails, the error of the first is returned.
+func (vcs *VehicleComparisonService) compareAcrossLightings(vehicleImg1, vehicleImg2 *models.VehicleImage, trace *ComparisonTrace) (*models.ComparisonResult, error) {
+	var best *models.ComparisonResult
+	var bestTrace *ComparisonTrace
+	var firstErr error
+
+	for _, lighting := range lightingPaths {
+		if !vehicleImg1.LightingAmbiguous && vehicleImg1.Lighting != lighting ||
+			!vehicleImg2.LightingAmbiguous && vehicleImg2.Lighting != lighting {
+			continue
+		}
+
+		// Shallow copies share the processed Mats, which comparePass closes
+		candidate1, candidate2 := *vehicleImg1, *vehicleImg2
+		candidate1.Lighting, candidate2.Lighting = lighting, lighting
+
+		var candidateTrace *ComparisonTrace
+		if trace != nil {
+			candidateTrace = &ComparisonTrace{Inputs: trace.Inputs}
+		}
+
+		result, err := vcs.compareProcessed(&candidate1, &candidate2, candidateTrace)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if best == nil || result.SimilarityScore > best.SimilarityScore {
+			best, bestTrace = result, candidateTrace
+		}
+	}
+
+	if best == nil {
+		return nil, firstErr
+	}
+
+	best.ProcessingInfo.LightingFallback = true
+	if trace != nil {
+		*trace = *bestTrace
+	}
+	return best, nil
+}
@@ -0,0 +1,51 @@
+package vehiclecompare
+
+import "github.com/choff5507/vehicle-image-comparison/internal/models"
+
+// The types and constants below select the comparison modes of the options and
+// EngineConfig fields of the same names. They are the internal models types,
+// re-exported so callers outside this module can name them.
+
+// DecisionMode selects how IsSameVehicle is decided (see WithDecisionMode)
+type DecisionMode = models.DecisionMode
+
+const (
+	DecisionWeightedMean  = models.DecisionWeightedMean
+	DecisionAllMustExceed = models.DecisionAllMustExceed
+)
+
+// Aggregation selects how weighted feature scores combine (see WithAggregation)
+type Aggregation = models.Aggregation
+
+const (
+	AggregationArithmetic = models.AggregationArithmetic
+	AggregationGeometric  = models.AggregationGeometric
+	AggregationHarmonic   = models.AggregationHarmonic
+	AggregationMinBiased  = models.AggregationMinBiased
+)
+
+// FeatureFloors holds the per-feature floors of DecisionAllMustExceed (see
+// WithFeatureFloors)
+type FeatureFloors = models.FeatureFloors
+
+// FeatureWeights holds per-feature weights, as in EngineConfig.DaylightWeights
+type FeatureWeights = models.FeatureWeights
+
+// HistogramMetric selects how color histograms are compared (see
+// WithHistogramMetric)
+type HistogramMetric = models.HistogramMetric
+
+const (
+	HistogramIntersection  = models.HistogramIntersection
+	HistogramBhattacharyya = models.HistogramBhattacharyya
+	HistogramChiSquare     = models.HistogramChiSquare
+)
+
+// ColorSpace selects the space dominant colors are compared in (see WithColorSpace)
+type ColorSpace = models.ColorSpace
+
+const (
+	ColorSpaceRGB = models.ColorSpaceRGB
+	ColorSpaceHSV = models.ColorSpaceHSV
+	ColorSpaceLab = models.ColorSpaceLab
+)
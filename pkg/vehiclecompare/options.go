@@ -0,0 +1,500 @@
+package vehiclecompare
+
+import (
+	"io"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/preprocessor"
+	"gocv.io/x/gocv"
+)
+
+// Option configures optional behaviour of a VehicleComparisonService
+type Option func(*VehicleComparisonService)
+
+// WithRecompressionSmoothing applies a mild edge-preserving smoothing to each image
+// before feature extraction. This suppresses JPEG recompression artifacts so that an
+// original and a re-saved copy of the same photo extract near-identical features.
+func WithRecompressionSmoothing(enabled bool) Option {
+	return func(vcs *VehicleComparisonService) {
+		vcs.smoothRecompression = enabled
+	}
+}
+
+// WithIRSurroundingExpansion sets how far the IR signature region extends beyond the
+// detected plate on each side, as a fraction of plate size (default 0.75). Larger
+// values capture distinctive structure that sits farther from the plate.
+func WithIRSurroundingExpansion(factor float64) Option {
+	return func(vcs *VehicleComparisonService) {
+		vcs.irSignatureExtractor.SetExpansionFactor(factor)
+	}
+}
+
+// WithIRMaterialBands sets the intensity boundaries (0-255) that divide the IR
+// surroundings of the plate into material bands for the material signature; n
+// boundaries give n+1 bands. The default {80, 200} separates low (plastic, rubber),
+// medium (paint) and high (metal, chrome) reflectivity. Every pixel falls in exactly
+// one band, so the band fractions always sum to 1. Out-of-range and repeated
+// boundaries are ignored, keeping the defaults when none remain. Compare only
+// signatures extracted with the same bands.
+func WithIRMaterialBands(boundaries ...float64) Option {
+	return func(vcs *VehicleComparisonService) {
+		vcs.irSignatureExtractor.SetMaterialBands(boundaries)
+	}
+}
+
+// WithMaxLightElements sets how many light elements are encoded in each light
+// pattern signature (default 8).
+func WithMaxLightElements(n int) Option {
+	return func(vcs *VehicleComparisonService) {
+		vcs.lightPatternExtractor.SetMaxElements(n)
+	}
+}
+
+// WithRelativeLightIntensity measures each light element's intensity as its contrast
+// against the surrounding bodywork rather than its absolute brightness, so the same
+// lights captured at different exposures score alike.
+func WithRelativeLightIntensity(enabled bool) Option {
+	return func(vcs *VehicleComparisonService) {
+		vcs.lightPatternExtractor.SetRelativeIntensity(enabled)
+	}
+}
+
+// WithBorderTrim trims uniform black letterbox/pillarbox borders, such as those DVR
+// software and screenshots add, from each image before processing. Timestamp text on
+// the borders is tolerated. The borders would otherwise skew proportions and add
+// false edges.
+func WithBorderTrim(enabled bool) Option {
+	return func(vcs *VehicleComparisonService) {
+		vcs.borderTrim = enabled
+	}
+}
+
+// WithExposureMatching matches image 2's intensity distribution to image 1's before
+// feature extraction, so captures of the same vehicle under different exposure or
+// gamma settings do not shift intensity thresholds and IR reflectivity. Color images
+// are matched on luma only. Applied only when both images have the same lighting.
+func WithExposureMatching(enabled bool) Option {
+	return func(vcs *VehicleComparisonService) {
+		vcs.exposureMatching = enabled
+	}
+}
+
+// WithSameCamera states whether both images come from the same camera (default
+// true). Different sensors and IR illuminators have different brightness baselines,
+// so with false image 2's intensities are normalized to image 1's as with
+// WithExposureMatching, and the comparison ignores absolute light intensity and
+// reflectivity level, keeping only their patterns. Use false when matching across
+// sites.
+func WithSameCamera(same bool) Option {
+	return func(vcs *VehicleComparisonService) {
+		vcs.crossCamera = !same
+		vcs.comparisonEngine.SetCrossCamera(!same)
+	}
+}
+
+// WithGrayscaleOnly converts both images to grayscale before processing and compares
+// them on intensity-based features only (geometry, lights, bumper and the IR-style
+// plate signature), skipping color regardless of lighting. Use it when mixing color
+// photos with grayscale captures. Results report infrared lighting, since the
+// intensity-only feature path is used.
+func WithGrayscaleOnly(enabled bool) Option {
+	return func(vcs *VehicleComparisonService) {
+		vcs.grayscaleOnly = enabled
+	}
+}
+
+// WithMinMatchedLights sets how many light elements must match between the images
+// before their similarity is trusted (default 2). With fewer, the light element score
+// is pulled towards neutral, so one matched light does not overstate the match.
+func WithMinMatchedLights(count int) Option {
+	return func(vcs *VehicleComparisonService) {
+		vcs.comparisonEngine.SetMinMatchedLights(count)
+	}
+}
+
+// WithLayoutOnly compares structural layout only: geometric proportions, structural
+// element positions and light count/symmetry/spacing. Color, texture, light
+// appearance and IR material signatures are ignored, so the result answers "is this
+// the same body style" rather than "is this the same exact car".
+func WithLayoutOnly(enabled bool) Option {
+	return func(vcs *VehicleComparisonService) {
+		vcs.comparisonEngine.SetLayoutOnly(enabled)
+	}
+}
+
+// WithMountingGeometryOnly compares only the layout of the plate mounting points
+// (screw heads, bracket holes) relative to the plate. The layout belongs to the car
+// rather than the plate, so it can link two images across a plate swap. Takes
+// precedence over WithLayoutOnly.
+func WithMountingGeometryOnly(enabled bool) Option {
+	return func(vcs *VehicleComparisonService) {
+		vcs.mountingOnly = enabled
+		vcs.comparisonEngine.SetMountingOnly(enabled)
+	}
+}
+
+// WithPlateRelativeLayout measures the structural layout (light positions, element
+// sizes) relative to the detected license plate's center and width instead of the
+// vehicle bounds, so captures cropped or zoomed differently by the camera still line
+// up. It applies to pairs with a plate detected in both images; the others are
+// compared as usual. result.ProcessingInfo.PlateRelativeFrame reports which frame
+// was used. Disables WithEarlyExit, whose bound is computed before plates are found.
+func WithPlateRelativeLayout(enabled bool) Option {
+	return func(vcs *VehicleComparisonService) {
+		vcs.plateRelative = enabled
+		vcs.comparisonEngine.SetPlateRelativeFrame(enabled)
+	}
+}
+
+// WithIRSignatureOnly bases the decision entirely on the IR signature around the
+// plate (reflectivity, material, illumination, shadows and texture), for plate-swap
+// forensics on IR ANPR feeds. Nothing else is extracted, so whole-vehicle geometry
+// that is unreliable in tight crops plays no part. Pairs without a reliable
+// signature in both images score a neutral 0.5. Takes precedence over
+// WithMountingGeometryOnly and WithLayoutOnly.
+func WithIRSignatureOnly(enabled bool) Option {
+	return func(vcs *VehicleComparisonService) {
+		vcs.irSignatureOnly = enabled
+		vcs.comparisonEngine.SetIRSignatureOnly(enabled)
+	}
+}
+
+// WithMaxImagePixels sets the largest image, in pixels, the service accepts
+// (default 25 megapixels). Larger images fail with ErrImageTooLarge, detected from
+// the header of JPEG, PNG, GIF, BMP and TIFF data before decoding, so an oversized
+// upload cannot exhaust memory. Zero disables the check.
+func WithMaxImagePixels(pixels int) Option {
+	return func(vcs *VehicleComparisonService) {
+		vcs.maxImagePixels = pixels
+	}
+}
+
+// WithMaxViewpointDelta refuses to compare images whose estimated capture angles
+// (see ProcessingInfo.ViewpointDelta) differ by more than degrees in yaw or pitch,
+// returning an *ErrViewpointMismatch instead of an unfairly low score. Zero, the
+// default, compares any pair.
+func WithMaxViewpointDelta(degrees float64) Option {
+	return func(vcs *VehicleComparisonService) {
+		if degrees >= 0 {
+			vcs.maxViewpointDelta = degrees
+		}
+	}
+}
+
+// WithPartialCrops compares images that may show only part of the vehicle (e.g. a
+// rear-left corner with the plate) against partial or full views. The images are
+// registered on shared structural elements and the plate, and only the region both
+// show is compared, so features missing from a crop do not count against a match.
+// Crops should come from captures of similar resolution.
+func WithPartialCrops(enabled bool) Option {
+	return func(vcs *VehicleComparisonService) {
+		vcs.comparisonEngine.SetPartialCrops(enabled)
+	}
+}
+
+// WithAlignmentHypotheses guards against a wrong alignment on low-feature images:
+// several alignments of image 2 onto image 1 are hypothesized from individual
+// matching elements, the comparison is scored under each and the best is kept.
+// result.ProcessingInfo.AlignmentQuality reports the share of elements it aligns.
+// Each comparison costs up to nine times as much.
+func WithAlignmentHypotheses(enabled bool) Option {
+	return func(vcs *VehicleComparisonService) {
+		vcs.comparisonEngine.SetAlignmentHypotheses(enabled)
+	}
+}
+
+// WithSimilarityInterval adds result.ProcessingInfo.SimilarityInterval, a 95%
+// bootstrap confidence interval around the similarity score obtained by resampling
+// the matched features. A wide interval, or one straddling the threshold, marks a
+// decision that rests on few or inconsistent matches. Each comparison runs 200 extra
+// feature comparisons (extraction is not repeated).
+func WithSimilarityInterval(enabled bool) Option {
+	return func(vcs *VehicleComparisonService) {
+		vcs.comparisonEngine.SetSimilarityInterval(enabled)
+	}
+}
+
+// WithSubScores adds result.SubScores: the unweighted components behind each feature
+// score (proportions, structure and alignment for geometry; signature, elements and
+// configuration for lights; contour, texture, mounting points and plate area for the
+// bumper), plus color and thermal. Use it to apply your own weighting downstream.
+func WithSubScores(enabled bool) Option {
+	return func(vcs *VehicleComparisonService) {
+		vcs.comparisonEngine.SetSubScores(enabled)
+	}
+}
+
+// WithKeypointMatching extracts ORB keypoints from each image and scores geometric
+// similarity by the fraction of unambiguous descriptor matches (Lowe's ratio test)
+// instead of the structural element and reference point comparison.
+func WithKeypointMatching(enabled bool) Option {
+	return func(vcs *VehicleComparisonService) {
+		vcs.keypointMatching = enabled
+		vcs.comparisonEngine.SetKeypointMatching(enabled)
+	}
+}
+
+// WithLightArrangement adds the arrangement of the light elements (a shape context of
+// the relative distances and angles between them) to the light pattern score, so
+// vehicles with similar lights laid out differently are told apart. It needs three
+// or more lights in both images and is invariant to position, rotation and scale.
+func WithLightArrangement(enabled bool) Option {
+	return func(vcs *VehicleComparisonService) {
+		vcs.comparisonEngine.SetLightArrangement(enabled)
+	}
+}
+
+// WithIRTaillightThreshold sets the gray level above which taillights are detected
+// in rear IR images (default 110). IR taillights are usually dimmer than the plate
+// and reflectors, so they use their own threshold and are only searched for in the
+// upper part of the frame.
+func WithIRTaillightThreshold(threshold float64) Option {
+	return func(vcs *VehicleComparisonService) {
+		vcs.lightPatternExtractor.SetIRTaillightThreshold(threshold)
+	}
+}
+
+// WithCanonicalInterpolation sets the interpolation used to resize images into the
+// canonical frame (AlignPair): downscale for images larger than the frame (default
+// gocv.InterpolationArea) and upscale for smaller ones (default
+// gocv.InterpolationLinear). Area averaging keeps fine detail such as LED light
+// segments from aliasing away when downscaling. It only affects AlignPair:
+// comparisons extract features at each image's own resolution and never resize
+// into the canonical frame.
+func WithCanonicalInterpolation(downscale, upscale gocv.InterpolationFlags) Option {
+	return func(vcs *VehicleComparisonService) {
+		vcs.imageAligner.SetInterpolation(downscale, upscale)
+	}
+}
+
+// WithCanonicalPadding scales images into the canonical frame preserving their
+// aspect ratio and pads the remainder with black, instead of stretching them. Like
+// WithCanonicalInterpolation, it only affects AlignPair.
+func WithCanonicalPadding(enabled bool) Option {
+	return func(vcs *VehicleComparisonService) {
+		vcs.imageAligner.SetPadToAspect(enabled)
+	}
+}
+
+// WithMinQueryQuality sets the minimum quality score (0-1, default 0.5) of the first
+// image, the query. Lower it to identify degraded live captures against a
+// well-enrolled gallery; images below 0.3 are always rejected.
+func WithMinQueryQuality(quality float64) Option {
+	return func(vcs *VehicleComparisonService) {
+		vcs.minQueryQuality = quality
+	}
+}
+
+// WithMinGalleryQuality sets the minimum quality score (0-1, default 0.5) of the
+// second image, the enrolled gallery entry. Raise it to keep poor captures from
+// being enrolled as references; images below 0.3 are always rejected.
+func WithMinGalleryQuality(quality float64) Option {
+	return func(vcs *VehicleComparisonService) {
+		vcs.minGalleryQuality = quality
+	}
+}
+
+// WithPerceptualQualityGate rejects, with ErrInsufficientQuality, any image whose
+// perceptual quality (0-1) is below minimum. The perceptual score measures the
+// natural scene statistics of the image, as BRISQUE does, and catches softness the
+// classical blur, contrast and noise metrics average away: a defocused or upscaled
+// capture with good contrast still passes them. 0, the default, disables the gate.
+func WithPerceptualQualityGate(minimum float64) Option {
+	return func(vcs *VehicleComparisonService) {
+		vcs.minPerceptual = minimum
+	}
+}
+
+// WithSkipQualityAssessment skips image quality assessment and every quality gate,
+// for pre-validated datasets where the assessment is pure overhead and can falsely
+// reject valid low-contrast IR crops. Image1Quality and Image2Quality report -1,
+// unknown; templates enrolled this way are never gated on quality either.
+// WithBestFrameSelection still assesses frames, having nothing else to rank them by.
+func WithSkipQualityAssessment(enabled bool) Option {
+	return func(vcs *VehicleComparisonService) {
+		vcs.skipQuality = enabled
+	}
+}
+
+// WithLightingFallback salvages images whose lighting cannot be classified with
+// confidence, such as dusk and dawn captures: instead of trusting a guess (or failing
+// with ErrUnclassifiable), the pair is compared on both the daylight and the infrared
+// feature path where both images can share it, and the higher-scoring result is kept.
+// result.ProcessingInfo.LightingFallback reports when it applied; FeaturePath names
+// the path kept. Template comparisons use the query's best guess.
+func WithLightingFallback(enabled bool) Option {
+	return func(vcs *VehicleComparisonService) {
+		vcs.lightingFallback = enabled
+	}
+}
+
+// WithPreprocessor injects custom preprocessing (denoising, sharpening,
+// domain-specific normalization) into every image comparison, applied to each
+// decoded image before quality assessment and classification. The hook must not
+// close or modify the Mat it is given; it returns either a new Mat, which the service
+// owns and closes, or its input unchanged. The result must have 1 or 3 channels.
+// An error fails the comparison, wrapped with the image it came from; the Mat
+// returned with it is ignored, so the hook must close anything it allocated.
+// CompareProgressive runs the hook on its thumbnails as well; templates and AlignPair
+// do not run it.
+func WithPreprocessor(preprocess func(gocv.Mat) (gocv.Mat, error)) Option {
+	return func(vcs *VehicleComparisonService) {
+		vcs.preprocess = preprocess
+	}
+}
+
+// WithProgress sets a callback told of each comparison IdentifyFromDirectory
+// completes, with the number done so far and the total, for progress bars and job
+// status reporting. It is called from the worker goroutines, but never concurrently
+// and with done strictly increasing from 1 to total; workers wait for it to return,
+// so it should be quick. No other entry point reports progress: single comparisons
+// and the CLI's manifest batches never call it.
+func WithProgress(progress func(done, total int)) Option {
+	return func(vcs *VehicleComparisonService) {
+		vcs.progress = progress
+	}
+}
+
+// WithUncertaintyBand sets the width of the band centred on the similarity threshold
+// in which results carry Decision = Uncertain for manual review (default 0.04).
+// IsSameVehicle still reports the hard threshold decision.
+func WithUncertaintyBand(width float64) Option {
+	return func(vcs *VehicleComparisonService) {
+		vcs.comparisonEngine.SetUncertaintyBand(width)
+	}
+}
+
+// WithOpenCVThreads sets how many threads OpenCV may use within each operation. When
+// running several comparisons concurrently, lower it (down to 1 for one comparison
+// per core) so OpenCV's own threads do not oversubscribe the CPU. Following OpenCV,
+// 0 also runs single-threaded and a negative n restores the default. The limit is
+// process-wide: it applies to every service, not only this one.
+func WithOpenCVThreads(n int) Option {
+	return func(vcs *VehicleComparisonService) {
+		gocv.SetNumThreads(n)
+	}
+}
+
+// WithEarlyExit scores geometry (and color, in daylight) before extracting the more
+// expensive light, bumper and IR features, and returns a non-match straight away if
+// the similarity could not reach the threshold even with those features matching
+// perfectly. Such results report ProcessingInfo.EarlyExit and an upper-bound score.
+// Useful in 1:N searches where most candidates are obvious mismatches.
+func WithEarlyExit(enabled bool) Option {
+	return func(vcs *VehicleComparisonService) {
+		vcs.earlyExit = enabled
+	}
+}
+
+// WithDecisionMode selects how IsSameVehicle is decided: DecisionWeightedMean
+// (default) compares the weighted mean to the threshold; DecisionAllMustExceed
+// also requires every computed feature score to exceed its floor (see
+// WithFeatureFloors), so a single catastrophic mismatch vetoes the match.
+func WithDecisionMode(mode DecisionMode) Option {
+	return func(vcs *VehicleComparisonService) {
+		vcs.comparisonEngine.SetDecisionMode(mode)
+	}
+}
+
+// WithAggregation selects how the weighted feature scores combine into the overall
+// similarity: AggregationArithmetic (default), or the more conservative
+// AggregationGeometric, AggregationHarmonic and
+// AggregationMinBiased, under which one low feature score drags the result
+// down further.
+func WithAggregation(aggregation Aggregation) Option {
+	return func(vcs *VehicleComparisonService) {
+		vcs.comparisonEngine.SetAggregation(aggregation)
+	}
+}
+
+// WithFeatureFloors sets the score each feature must exceed under
+// DecisionAllMustExceed (default 0.5 for every feature).
+func WithFeatureFloors(floors FeatureFloors) Option {
+	return func(vcs *VehicleComparisonService) {
+		vcs.comparisonEngine.SetFeatureFloors(floors)
+	}
+}
+
+// WithHistogramMetric selects how daylight hue and saturation histograms are
+// compared: HistogramIntersection (default), HistogramBhattacharyya
+// or HistogramChiSquare.
+func WithHistogramMetric(metric HistogramMetric) Option {
+	return func(vcs *VehicleComparisonService) {
+		vcs.comparisonEngine.SetHistogramMetric(metric)
+	}
+}
+
+// WithColorSpace selects the space dominant colors are compared in:
+// ColorSpaceRGB (default), ColorSpaceHSV or ColorSpaceLab.
+// HSV and Lab follow perceived color difference and discount the brightness changes
+// shading causes on saturated colors.
+func WithColorSpace(space ColorSpace) Option {
+	return func(vcs *VehicleComparisonService) {
+		vcs.comparisonEngine.SetColorSpace(space)
+	}
+}
+
+// WithBestFrameSelection changes which frame of an animated GIF or multi-page TIFF
+// is compared. By default it is the first frame; when enabled every frame is
+// decoded and the one with the highest image quality score is used.
+func WithBestFrameSelection(enabled bool) Option {
+	return func(vcs *VehicleComparisonService) {
+		vcs.bestFrame = enabled
+	}
+}
+
+// WithCameraCalibration undistorts both images before processing, using the camera
+// matrix [[fx 0 cx] [0 fy cy] [0 0 1]] and OpenCV distortion coefficients
+// (k1, k2, p1, p2[, k3...]) of the capturing lens. Use it for wide-angle dashcam and
+// security feeds, where barrel distortion bends the bumper line and skews proportions.
+// Invalid calibrations (non-positive focal length, or a coefficient count OpenCV does
+// not accept: 4, 5, 8, 12 or 14) are ignored.
+func WithCameraCalibration(cameraMatrix [3][3]float64, distCoeffs []float64) Option {
+	return func(vcs *VehicleComparisonService) {
+		if cameraMatrix[0][0] <= 0 || cameraMatrix[1][1] <= 0 {
+			return
+		}
+		switch len(distCoeffs) {
+		case 4, 5, 8, 12, 14:
+		default:
+			return
+		}
+		vcs.calibration = &preprocessor.CameraCalibration{
+			CameraMatrix: cameraMatrix,
+			DistCoeffs:   append([]float64(nil), distCoeffs...),
+		}
+	}
+}
+
+// WithComparisonTrace writes a ComparisonTrace for every successful image pair
+// comparison to w, one JSON object per line: input pixel hashes, the classified
+// view and lighting with confidences, the full features of both images, the
+// effective weights and every similarity behind the score. It lets a scoring anomaly
+// be reproduced and debugged without the original images. Writes from concurrent
+// comparisons are serialized. A trace that cannot be written is reported in
+// result.Warnings and does not fail the comparison.
+func WithComparisonTrace(w io.Writer) Option {
+	return func(vcs *VehicleComparisonService) {
+		vcs.traceWriter = w
+	}
+}
+
+// WithPlateIdentityCheck adds result.PlateMatch: whether the identical physical
+// plate, with the same characters and wear, appears in both images, from the
+// normalized cross-correlation of the detected plate crops. It is reported whatever
+// the vehicle decision, so the same plate on two different vehicles, as in plate
+// reuse fraud, is visible. Template comparisons have no plate image and never report
+// it.
+func WithPlateIdentityCheck(enabled bool) Option {
+	return func(vcs *VehicleComparisonService) {
+		vcs.plateIdentity = enabled
+	}
+}
+
+// WithDiagnostics records every image pair comparison with collector: the number of
+// light elements detected, plate confidences and why comparisons were rejected. One
+// collector can be shared by several services to aggregate over a whole dataset.
+func WithDiagnostics(collector *DiagnosticsCollector) Option {
+	return func(vcs *VehicleComparisonService) {
+		vcs.diagnostics = collector
+	}
+}
@@ -0,0 +1,57 @@
+package vehiclecompare
+
+import (
+	"image"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/extractor"
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+	"gocv.io/x/gocv"
+)
+
+// Plate crop correlation above which two plates are the identical physical plate.
+// Different plates, even with the same layout and font, stay well below it.
+const plateIdentityThreshold = 0.8
+
+// matchPlates runs the plate identity check on two processed images, or returns
+// nil when it is off
+func (vcs *VehicleComparisonService) matchPlates(img1, img2 *models.VehicleImage) *models.PlateMatch {
+	if !vcs.plateIdentity {
+		return nil
+	}
+
+	crop1, ok := vcs.plateCrop(img1)
+	if !ok {
+		return &models.PlateMatch{}
+	}
+	defer crop1.Close()
+
+	crop2, ok := vcs.plateCrop(img2)
+	if !ok {
+		return &models.PlateMatch{}
+	}
+	defer crop2.Close()
+
+	correlation := extractor.PlateCorrelation(crop1, crop2)
+	return &models.PlateMatch{
+		Detected:    true,
+		Correlation: correlation,
+		SamePlate:   correlation >= plateIdentityThreshold,
+	}
+}
+
+// plateCrop is the detected plate region of img; ok is false when only a fallback
+// region was found
+func (vcs *VehicleComparisonService) plateCrop(img *models.VehicleImage) (crop gocv.Mat, ok bool) {
+	plate, err := vcs.plateExtractor.DetectLicensePlate(img.Image, img.Mask, img.View)
+	if err != nil || !plate.Detected {
+		return gocv.Mat{}, false
+	}
+
+	bounds := image.Rect(plate.Bounds.X, plate.Bounds.Y,
+		plate.Bounds.X+plate.Bounds.Width, plate.Bounds.Y+plate.Bounds.Height)
+	bounds = bounds.Intersect(image.Rect(0, 0, img.Image.Cols(), img.Image.Rows()))
+	if bounds.Empty() {
+		return gocv.Mat{}, false
+	}
+	return img.Image.Region(bounds), true
+}
@@ -0,0 +1,48 @@
+package vehiclecompare
+
+import (
+	"fmt"
+
+	"gocv.io/x/gocv"
+)
+
+// preprocessPair runs the WithPreprocessor hook on both images. release closes the
+// Mats the hook returned, except an input it handed back unchanged; it must be
+// called once the images are no longer needed, whatever the error.
+func (vcs *VehicleComparisonService) preprocessPair(img1, img2 gocv.Mat) (gocv.Mat, gocv.Mat, func(), error) {
+	processed1, release1, err := vcs.preprocessImage(img1)
+	if err != nil {
+		return img1, img2, func() {}, fmt.Errorf("image 1: %w", err)
+	}
+
+	processed2, release2, err := vcs.preprocessImage(img2)
+	if err != nil {
+		release1()
+		return img1, img2, func() {}, fmt.Errorf("image 2: %w", err)
+	}
+
+	return processed1, processed2, func() { release1(); release2() }, nil
+}
+
+// preprocessImage runs the WithPreprocessor hook on one image, with the same release
+// contract as preprocessPair
+func (vcs *VehicleComparisonService) preprocessImage(img gocv.Mat) (gocv.Mat, func(), error) {
+	if vcs.preprocess == nil {
+		return img, func() {}, nil
+	}
+
+	out, err := vcs.preprocess(img)
+	if err != nil {
+		return img, func() {}, fmt.Errorf("preprocessor failed: %w", err)
+	}
+
+	release := func() {}
+	if out.Ptr() != img.Ptr() {
+		release = func() { out.Close() }
+	}
+	if err := checkDecoded(out); err != nil {
+		release()
+		return img, func() {}, fmt.Errorf("preprocessor output: %w", err)
+	}
+	return out, release, nil
+}
@@ -0,0 +1,80 @@
+package vehiclecompare
+
+import (
+	"image"
+	"time"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+	"gocv.io/x/gocv"
+)
+
+// Longest side of the thumbnails compared for a provisional result. Smaller
+// thumbnails fall below the resolution the quality assessment expects.
+const progressiveThumbnailSize = 640
+
+// CompareProgressive compares images from file paths in two stages for interactive
+// use. Downscaled thumbnails are compared first and the estimate, marked
+// ProcessingInfo.Provisional, is passed to onPartial; the full-resolution result is
+// then returned. When neither image is larger than a thumbnail, or the thumbnail
+// comparison fails, onPartial receives the final result instead. onPartial is
+// called exactly once, on the calling goroutine, before CompareProgressive returns
+// without error.
+func (vcs *VehicleComparisonService) CompareProgressive(image1Path, image2Path string, onPartial func(*models.ComparisonResult)) (*models.ComparisonResult, error) {
+	startTime := time.Now()
+
+	img1, img2, err := vcs.loadPair(image1Path, image2Path)
+	if err != nil {
+		return nil, err
+	}
+	defer img1.Close()
+	defer img2.Close()
+
+	provisional := false
+	if onPartial != nil && (img1.Cols() > progressiveThumbnailSize || img1.Rows() > progressiveThumbnailSize ||
+		img2.Cols() > progressiveThumbnailSize || img2.Rows() > progressiveThumbnailSize) {
+		provisional = vcs.compareThumbnails(img1, img2, startTime, onPartial)
+	}
+
+	result, err := vcs.compareImages(img1, img2, startTime)
+	if err != nil {
+		return nil, err
+	}
+
+	if onPartial != nil && !provisional {
+		onPartial(result)
+	}
+	return result, nil
+}
+
+// compareThumbnails passes the comparison of downscaled copies of img1 and img2 to
+// onPartial, reporting whether it succeeded
+func (vcs *VehicleComparisonService) compareThumbnails(img1, img2 gocv.Mat, startTime time.Time, onPartial func(*models.ComparisonResult)) bool {
+	thumbnail1 := thumbnail(img1)
+	defer thumbnail1.Close()
+	thumbnail2 := thumbnail(img2)
+	defer thumbnail2.Close()
+
+	// Not recorded with the diagnostics collector, which sees the full comparison
+	partial, _, err := vcs.comparePair(thumbnail1, gocv.Mat{}, thumbnail2, gocv.Mat{}, startTime)
+	if err != nil {
+		return false
+	}
+
+	partial.ProcessingInfo.Provisional = true
+	onPartial(partial)
+	return true
+}
+
+// thumbnail returns a copy of img whose longest side is at most
+// progressiveThumbnailSize. The caller owns the returned Mat.
+func thumbnail(img gocv.Mat) gocv.Mat {
+	scale := float64(progressiveThumbnailSize) / float64(max(img.Cols(), img.Rows()))
+	if scale >= 1.0 {
+		return img.Clone()
+	}
+
+	size := image.Pt(max(int(float64(img.Cols())*scale), 1), max(int(float64(img.Rows())*scale), 1))
+	resized := gocv.NewMat()
+	gocv.Resize(img, &resized, size, 0, 0, gocv.InterpolationArea)
+	return resized
+}
@@ -6,11 +6,29 @@ import (
 	"github.com/choff5507/vehicle-image-comparison/internal/extractor"
 	"github.com/choff5507/vehicle-image-comparison/internal/comparator"
 	"gocv.io/x/gocv"
+	"bytes"
 	"encoding/base64"
 	"fmt"
+	"image"
+	"io"
+	"math"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// Both images must be at least this confident in their orientation for the
+// upside-down retry to be skipped
+const minOrientationConfidence = 0.15
+
+// Smallest width/height the pipeline accepts; matches the largest kernel used
+// (the 15px adaptive threshold window in plate detection)
+const minImageDimension = 15
+
+// Quality score recorded for images processed with WithSkipQualityAssessment
+const unknownQuality = -1.0
+
 type VehicleComparisonService struct {
 	qualityAssessor        *preprocessor.QualityAssessor
 	viewLightingClassifier *preprocessor.ViewLightingClassifier
@@ -18,100 +36,604 @@ type VehicleComparisonService struct {
 	lightPatternExtractor  *extractor.LightPatternExtractor
 	irSignatureExtractor   *extractor.IRSignatureExtractor
 	comparisonEngine       *comparator.ComparisonEngine
+	imageAligner           *preprocessor.ImageAligner
+	keypointExtractor      *extractor.KeypointExtractor
+	colorExtractor         *extractor.ColorExtractor
+	plateExtractor         *extractor.LicensePlateExtractor
+	mountingPointExtractor *extractor.MountingPointExtractor
+	bumperExtractor        *extractor.BumperExtractor
+	
+	smoothRecompression bool
+	grayscaleOnly       bool
+	keypointMatching    bool
+	earlyExit           bool
+	calibration         *preprocessor.CameraCalibration
+	mountingOnly        bool
+	irSignatureOnly     bool
+	minQueryQuality     float64
+	minGalleryQuality   float64
+	skipQuality         bool // Quality is neither assessed nor gated; scores are unknownQuality
+	exposureMatching    bool
+	crossCamera         bool
+	maxViewpointDelta   float64 // Degrees; 0 disables the check
+	maxImagePixels      int     // Larger inputs are rejected with ErrImageTooLarge; 0 disables the check
+	minPerceptual       float64 // Perceptual quality each image must reach; 0 disables the gate
+	borderTrim          bool
+	bestFrame           bool
+	plateIdentity       bool
+	plateRelative       bool
+	lightingFallback    bool // Compare ambiguously lit images on both feature paths
+	diagnostics         *DiagnosticsCollector // Accumulates extraction statistics; nil disables collection
+	traceWriter         io.Writer  // Receives a ComparisonTrace per comparison; nil disables tracing
+	traceMu             sync.Mutex // Serializes trace writes from concurrent comparisons
+	
+	// Caller's hook run on each decoded image (WithPreprocessor); nil for none
+	preprocess func(gocv.Mat) (gocv.Mat, error)
+
+	// Caller's callback told of each completed batch comparison (WithProgress); nil for none
+	progress func(done, total int)
 }
 
-func NewVehicleComparisonService() *VehicleComparisonService {
-	return &VehicleComparisonService{
+func NewVehicleComparisonService(opts ...Option) *VehicleComparisonService {
+	vcs := &VehicleComparisonService{
 		qualityAssessor:        preprocessor.NewQualityAssessor(),
 		viewLightingClassifier: preprocessor.NewViewLightingClassifier(),
 		geometricExtractor:     extractor.NewGeometricExtractor(),
 		lightPatternExtractor:  extractor.NewLightPatternExtractor(),
 		irSignatureExtractor:   extractor.NewIRSignatureExtractor(),
 		comparisonEngine:       comparator.NewComparisonEngine(),
+		imageAligner:           preprocessor.NewImageAligner(),
+		keypointExtractor:      extractor.NewKeypointExtractor(),
+		colorExtractor:         extractor.NewColorExtractor(),
+		plateExtractor:         extractor.NewLicensePlateExtractor(),
+		mountingPointExtractor: extractor.NewMountingPointExtractor(),
+		bumperExtractor:        extractor.NewBumperExtractor(),
+		minQueryQuality:        0.5,
+		minGalleryQuality:      0.5,
+		maxImagePixels:         defaultMaxImagePixels,
+	}
+	
+	for _, opt := range opts {
+		opt(vcs)
 	}
+	
+	return vcs
 }
 
 // CompareVehicleImages is the main entry point for vehicle comparison from file paths
 func (vcs *VehicleComparisonService) CompareVehicleImages(image1Path, image2Path string) (*models.ComparisonResult, error) {
 	startTime := time.Now()
 	
+	// The same file twice is loaded and processed once
+	if filepath.Clean(image1Path) == filepath.Clean(image2Path) {
+		img, err := vcs.loadImage(image1Path)
+		if err != nil {
+			vcs.diagnostics.recordRejection(err)
+			return nil, err
+		}
+		defer img.Close()
+		return vcs.compareIdentical(img, startTime)
+	}
+	
 	// Load images
-	img1 := gocv.IMRead(image1Path, gocv.IMReadColor)
-	img2 := gocv.IMRead(image2Path, gocv.IMReadColor)
+	img1, img2, err := vcs.loadPair(image1Path, image2Path)
+	if err != nil {
+		return nil, err
+	}
+	defer img1.Close()
+	defer img2.Close()
+	
+	return vcs.compareImages(img1, img2, startTime)
+}
+
+// loadPair loads both images of a comparison, recording a failure with the
+// diagnostics collector. Neither Mat is left open on error.
+func (vcs *VehicleComparisonService) loadPair(image1Path, image2Path string) (gocv.Mat, gocv.Mat, error) {
+	img1, err := vcs.loadImage(image1Path)
+	if err != nil {
+		vcs.diagnostics.recordRejection(err)
+		return gocv.Mat{}, gocv.Mat{}, err
+	}
+	
+	img2, err := vcs.loadImage(image2Path)
+	if err != nil {
+		img1.Close()
+		vcs.diagnostics.recordRejection(err)
+		return gocv.Mat{}, gocv.Mat{}, err
+	}
+	
+	return img1, img2, nil
+}
+
+// CompareVehicleImagesWithMasks compares images from file paths, restricting all
+// feature extraction to the vehicle pixels of each image. Masks come from the
+// caller's own segmentation: single-channel 8-bit Mats of the image size, nonzero on
+// the vehicle. Extractors ignore whatever their thresholds and edge searches find
+// outside the mask, such as street lights or other vehicles; view, lighting and
+// quality are still assessed on the whole image. An empty Mat leaves that image
+// unmasked. The masks are not closed.
+func (vcs *VehicleComparisonService) CompareVehicleImagesWithMasks(image1Path string, mask1 gocv.Mat, image2Path string, mask2 gocv.Mat) (*models.ComparisonResult, error) {
+	startTime := time.Now()
+	
+	img1, img2, err := vcs.loadPair(image1Path, image2Path)
+	if err != nil {
+		return nil, err
+	}
 	defer img1.Close()
 	defer img2.Close()
 	
-	if img1.Empty() || img2.Empty() {
-		return nil, fmt.Errorf("failed to load one or both images")
+	if err := checkMask(img1, mask1); err != nil {
+		return nil, fmt.Errorf("image 1: %w", err)
+	}
+	if err := checkMask(img2, mask2); err != nil {
+		return nil, fmt.Errorf("image 2: %w", err)
 	}
 	
-	return vcs.compareImages(img1, img2, startTime)
+	return vcs.compareMaskedImages(img1, mask1, img2, mask2, startTime)
+}
+
+// checkMask rejects a region-of-interest mask that is not a single-channel 8-bit Mat
+// of img's size. An empty mask passes.
+func checkMask(img, mask gocv.Mat) error {
+	if !extractor.HasMask(mask) {
+		return nil
+	}
+	
+	if mask.Rows() != img.Rows() || mask.Cols() != img.Cols() {
+		return fmt.Errorf("%w: mask is %dx%d, image is %dx%d", ErrInvalidMask,
+			mask.Cols(), mask.Rows(), img.Cols(), img.Rows())
+	}
+	if mask.Type() != gocv.MatTypeCV8U {
+		return fmt.Errorf("%w: mask must be single-channel 8-bit, got type %v", ErrInvalidMask, mask.Type())
+	}
+	return nil
 }
 
 // CompareVehicleImagesFromBase64 compares images from base64 encoded strings
 func (vcs *VehicleComparisonService) CompareVehicleImagesFromBase64(image1Base64, image2Base64 string) (*models.ComparisonResult, error) {
 	startTime := time.Now()
 	
+	if image1Base64 == image2Base64 {
+		img, err := vcs.decodeBase64Image(image1Base64)
+		if err != nil {
+			vcs.diagnostics.recordRejection(err)
+			return nil, err
+		}
+		defer img.Close()
+		return vcs.compareIdentical(img, startTime)
+	}
+	
+	img1, img2, err := vcs.decodeBase64Pair(image1Base64, image2Base64)
+	if err != nil {
+		vcs.diagnostics.recordRejection(err)
+		return nil, err
+	}
+	defer img1.Close()
+	defer img2.Close()
+	
+	return vcs.compareImages(img1, img2, startTime)
+}
+
+// decodeBase64Pair decodes both base64 encoded images of a comparison. Neither Mat
+// is left open on error.
+func (vcs *VehicleComparisonService) decodeBase64Pair(image1Base64, image2Base64 string) (gocv.Mat, gocv.Mat, error) {
 	// Decode base64 images
 	img1Data, err := base64.StdEncoding.DecodeString(image1Base64)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode image1 base64: %v", err)
+		return gocv.Mat{}, gocv.Mat{}, fmt.Errorf("%w: failed to decode image1 base64: %v", ErrImageDecode, err)
 	}
 	
 	img2Data, err := base64.StdEncoding.DecodeString(image2Base64)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode image2 base64: %v", err)
+		return gocv.Mat{}, gocv.Mat{}, fmt.Errorf("%w: failed to decode image2 base64: %v", ErrImageDecode, err)
 	}
 	
 	// Create Mat from image data
-	img1, err := gocv.IMDecode(img1Data, gocv.IMReadColor)
+	img1, err := vcs.decodeImage(img1Data)
+	if err != nil {
+		return gocv.Mat{}, gocv.Mat{}, fmt.Errorf("failed to decode image1: %w", err)
+	}
+	
+	img2, err := vcs.decodeImage(img2Data)
+	if err != nil {
+		img1.Close()
+		return gocv.Mat{}, gocv.Mat{}, fmt.Errorf("failed to decode image2: %w", err)
+	}
+	
+	return img1, img2, nil
+}
+
+// CompareVehicleImagesFromReaders compares images read from streams, such as object
+// storage downloads or multipart uploads, without buffering them to disk. Each
+// reader is read to the end; a reader that yields no bytes or undecodable data is
+// rejected with ErrImageDecode.
+func (vcs *VehicleComparisonService) CompareVehicleImagesFromReaders(r1, r2 io.Reader) (*models.ComparisonResult, error) {
+	startTime := time.Now()
+	
+	img1Data, img2Data, err := readPair(r1, r2)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode image1: %v", err)
+		vcs.diagnostics.recordRejection(err)
+		return nil, err
 	}
-	img2, err := gocv.IMDecode(img2Data, gocv.IMReadColor)
+	
+	if bytes.Equal(img1Data, img2Data) {
+		img, err := vcs.decodeImage(img1Data)
+		if err != nil {
+			vcs.diagnostics.recordRejection(err)
+			return nil, fmt.Errorf("failed to decode image: %w", err)
+		}
+		defer img.Close()
+		return vcs.compareIdentical(img, startTime)
+	}
+	
+	img1, err := vcs.decodeImage(img1Data)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode image2: %v", err)
+		vcs.diagnostics.recordRejection(err)
+		return nil, fmt.Errorf("failed to decode image1: %w", err)
 	}
 	defer img1.Close()
-	defer img2.Close()
 	
-	if img1.Empty() || img2.Empty() {
-		return nil, fmt.Errorf("failed to decode one or both images")
+	img2, err := vcs.decodeImage(img2Data)
+	if err != nil {
+		vcs.diagnostics.recordRejection(err)
+		return nil, fmt.Errorf("failed to decode image2: %w", err)
 	}
+	defer img2.Close()
 	
 	return vcs.compareImages(img1, img2, startTime)
 }
 
+// readPair reads both image streams of a comparison to the end
+func readPair(r1, r2 io.Reader) ([]byte, []byte, error) {
+	for i, r := range []io.Reader{r1, r2} {
+		if r == nil {
+			return nil, nil, fmt.Errorf("%w: image%d reader is nil", ErrImageDecode, i+1)
+		}
+	}
+	
+	img1Data, err := io.ReadAll(r1)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: failed to read image1: %v", ErrImageDecode, err)
+	}
+	
+	img2Data, err := io.ReadAll(r2)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: failed to read image2: %v", ErrImageDecode, err)
+	}
+	
+	return img1Data, img2Data, nil
+}
+
+// decodeBase64Image decodes a base64 encoded image given as both inputs
+func (vcs *VehicleComparisonService) decodeBase64Image(imageBase64 string) (gocv.Mat, error) {
+	data, err := base64.StdEncoding.DecodeString(imageBase64)
+	if err != nil {
+		return gocv.Mat{}, fmt.Errorf("%w: failed to decode image base64: %v", ErrImageDecode, err)
+	}
+	
+	img, err := vcs.decodeImage(data)
+	if err != nil {
+		return gocv.Mat{}, fmt.Errorf("failed to decode image: %w", err)
+	}
+	return img, nil
+}
+
+// decodeImage decodes untrusted encoded image bytes into a color Mat. Animated
+// GIFs and multi-page TIFFs decode to a single frame (see selectFrame).
+// On error the returned Mat is the zero Mat, which holds nothing to close.
+func (vcs *VehicleComparisonService) decodeImage(data []byte) (gocv.Mat, error) {
+	if len(data) == 0 {
+		return gocv.Mat{}, fmt.Errorf("%w: image data is empty", ErrImageDecode)
+	}
+	
+	// Checked before decoding, which allocates the full image
+	if err := vcs.checkEncodedSize(data); err != nil {
+		return gocv.Mat{}, err
+	}
+	
+	frames, err := decodeFrames(data, vcs.bestFrame)
+	if err != nil {
+		return gocv.Mat{}, fmt.Errorf("%w: %v", ErrImageDecode, err)
+	}
+	if len(frames) > 0 {
+		return vcs.checkedImage(vcs.selectFrame(frames))
+	}
+	
+	img, err := gocv.IMDecode(data, gocv.IMReadColor)
+	if err != nil {
+		return gocv.Mat{}, fmt.Errorf("%w: %v", ErrImageDecode, err)
+	}
+	
+	if img.Empty() {
+		img.Close()
+		return gocv.Mat{}, fmt.Errorf("%w: unrecognized or corrupt image data", ErrImageDecode)
+	}
+	
+	return vcs.checkedImage(img)
+}
+
+// checkedImage returns img, or closes it and returns the error when it exceeds the
+// size limit in a format whose header could not be checked
+func (vcs *VehicleComparisonService) checkedImage(img gocv.Mat) (gocv.Mat, error) {
+	if err := vcs.checkDecodedSize(img); err != nil {
+		img.Close()
+		return gocv.Mat{}, err
+	}
+	return img, nil
+}
+
+// checkDecoded rejects Mats that are not Empty() yet hold no usable pixels, which
+// some corrupt files decode to and which would panic in CvtColor
+func checkDecoded(img gocv.Mat) error {
+	if img.Rows() <= 0 || img.Cols() <= 0 {
+		return fmt.Errorf("%w: decoded to %dx%d", ErrImageDecode, img.Cols(), img.Rows())
+	}
+	if channels := img.Channels(); channels != 1 && channels != 3 {
+		return fmt.Errorf("%w: decoded to %d channels, expected 1 or 3", ErrImageDecode, channels)
+	}
+	return nil
+}
+
+// AlignPair warps both vehicle images into the same canonical frame and returns them
+// PNG-encoded, so callers can run their own pixel diff or model on the aligned pair.
+// The returned quality is the normalized correlation of the aligned images (0-1).
+func (vcs *VehicleComparisonService) AlignPair(image1Path, image2Path string) ([]byte, []byte, float64, error) {
+	img1, err := vcs.loadImage(image1Path)
+	if err != nil {
+		return nil, nil, 0.0, err
+	}
+	defer img1.Close()
+	
+	img2, err := vcs.loadImage(image2Path)
+	if err != nil {
+		return nil, nil, 0.0, err
+	}
+	defer img2.Close()
+	
+	aligned1, aligned2, quality, err := vcs.imageAligner.AlignPair(img1, img2)
+	defer aligned1.Close()
+	defer aligned2.Close()
+	if err != nil {
+		return nil, nil, 0.0, fmt.Errorf("failed to align images: %v", err)
+	}
+	
+	alignedImg1, err := encodePNG(aligned1)
+	if err != nil {
+		return nil, nil, 0.0, fmt.Errorf("failed to encode aligned image 1: %v", err)
+	}
+	
+	alignedImg2, err := encodePNG(aligned2)
+	if err != nil {
+		return nil, nil, 0.0, fmt.Errorf("failed to encode aligned image 2: %v", err)
+	}
+	
+	return alignedImg1, alignedImg2, quality, nil
+}
+
+func encodePNG(img gocv.Mat) ([]byte, error) {
+	buf, err := gocv.IMEncode(gocv.PNGFileExt, img)
+	if err != nil {
+		return nil, err
+	}
+	defer buf.Close()
+	
+	// Copy out of the native buffer before it is released
+	data := make([]byte, buf.Len())
+	copy(data, buf.GetBytes())
+	return data, nil
+}
+
+// compareImages compares two decoded images, recording the outcome with the
+// diagnostics collector
 func (vcs *VehicleComparisonService) compareImages(img1, img2 gocv.Mat, startTime time.Time) (*models.ComparisonResult, error) {
+	return vcs.compareMaskedImages(img1, gocv.Mat{}, img2, gocv.Mat{}, startTime)
+}
+
+// compareMaskedImages is compareImages with a region-of-interest mask for each image
+// (the zero Mat for none)
+func (vcs *VehicleComparisonService) compareMaskedImages(img1, mask1, img2, mask2 gocv.Mat, startTime time.Time) (*models.ComparisonResult, error) {
+	result, trace, err := vcs.comparePair(img1, mask1, img2, mask2, startTime)
+	vcs.diagnostics.recordComparison(trace, result, err)
+	return result, err
+}
+
+// comparePair compares two decoded images, each restricted to its mask unless that
+// is the zero Mat, retrying with image 2 upside down when needed. The trace of the
+// pass that produced the result is returned when tracing or diagnostics are on.
+func (vcs *VehicleComparisonService) comparePair(img1, mask1, img2, mask2 gocv.Mat, startTime time.Time) (*models.ComparisonResult, *ComparisonTrace, error) {
+	// Checked up front as well as in processImage: the orientation retry below
+	// converts both images before processing
+	if err := checkDecoded(img1); err != nil {
+		return nil, nil, fmt.Errorf("image 1: %w", err)
+	}
+	if err := checkDecoded(img2); err != nil {
+		return nil, nil, fmt.Errorf("image 2: %w", err)
+	}
+	
+	// Traces identify the inputs as given, before the caller's preprocessing
+	trace := vcs.newTrace(img1, img2)
+	
+	img1, img2, release, err := vcs.preprocessPair(img1, img2)
+	defer release()
+	if err != nil {
+		return nil, nil, err
+	}
+	
+	result, err := vcs.comparePass(img1, mask1, img2, mask2, trace)
+	
+	// An upside-down capture fails view/feature matching; retry with image 2
+	// rotated when the pair cannot be confidently oriented the same way
+	if (err != nil || !result.IsSameVehicle) && !vcs.orientationConsistent(img1, img2) {
+		var rotatedTrace *ComparisonTrace
+		if trace != nil {
+			rotatedTrace = &ComparisonTrace{Inputs: trace.Inputs}
+		}
+		
+		rotated := preprocessor.Rotate180(img2)
+		rotatedMask := gocv.Mat{}
+		if extractor.HasMask(mask2) {
+			rotatedMask = preprocessor.Rotate180(mask2)
+		}
+		rotatedResult, rotatedErr := vcs.comparePass(img1, mask1, rotated, rotatedMask, rotatedTrace)
+		rotated.Close()
+		rotatedMask.Close()
+		
+		if rotatedErr == nil && (err != nil || rotatedResult.SimilarityScore > result.SimilarityScore) {
+			result, err = rotatedResult, nil
+			result.ProcessingInfo.RotationApplied = true
+			trace = rotatedTrace
+		}
+	}
+	
+	if err != nil {
+		return nil, trace, err
+	}
+	
+	result.ProcessingInfo.ProcessingTimeMs = time.Since(startTime).Milliseconds()
+	
+	if vcs.traceWriter != nil {
+		// A debugging artifact: losing it must not lose the comparison
+		if err := vcs.writeTrace(trace, result); err != nil {
+			result.Warnings = append(result.Warnings, err.Error())
+		}
+	}
+	return result, trace, nil
+}
+
+// orientationConsistent reports whether both images are confidently oriented
+// the same way up
+func (vcs *VehicleComparisonService) orientationConsistent(img1, img2 gocv.Mat) bool {
+	upsideDown1, confidence1, err := vcs.viewLightingClassifier.ClassifyOrientation(img1)
+	if err != nil {
+		return false
+	}
+	upsideDown2, confidence2, err := vcs.viewLightingClassifier.ClassifyOrientation(img2)
+	if err != nil {
+		return false
+	}
+	
+	return upsideDown1 == upsideDown2 && math.Min(confidence1, confidence2) >= minOrientationConfidence
+}
+
+// comparePass runs a single processing, validation and comparison pass, recording
+// the classification and features in trace unless it is nil
+func (vcs *VehicleComparisonService) comparePass(img1, mask1, img2, mask2 gocv.Mat, trace *ComparisonTrace) (*models.ComparisonResult, error) {
 	// Process both images
-	vehicleImg1, err := vcs.processImage(img1)
+	vehicleImg1, err := vcs.processMaskedImage(img1, mask1)
 	if err != nil {
-		return nil, fmt.Errorf("failed to process image 1: %v", err)
+		return nil, fmt.Errorf("failed to process image 1: %w", err)
 	}
 	defer vehicleImg1.Image.Close()
+	defer vehicleImg1.Mask.Close()
 	
-	vehicleImg2, err := vcs.processImage(img2)
+	vehicleImg2, err := vcs.processMaskedImage(img2, mask2)
 	if err != nil {
-		return nil, fmt.Errorf("failed to process image 2: %v", err)
+		return nil, fmt.Errorf("failed to process image 2: %w", err)
 	}
 	defer vehicleImg2.Image.Close()
+	defer vehicleImg2.Mask.Close()
 	
+	if vcs.lightingFallback && (vehicleImg1.LightingAmbiguous || vehicleImg2.LightingAmbiguous) {
+		return vcs.compareAcrossLightings(vehicleImg1, vehicleImg2, trace)
+	}
+	return vcs.compareProcessed(vehicleImg1, vehicleImg2, trace)
+}
+
+// compareProcessed validates, extracts and compares a pair of processed images,
+// recording the classification and features in trace unless it is nil
+func (vcs *VehicleComparisonService) compareProcessed(vehicleImg1, vehicleImg2 *models.VehicleImage, trace *ComparisonTrace) (*models.ComparisonResult, error) {
 	// Validate consistency
 	if err := vcs.validateImageConsistency(vehicleImg1, vehicleImg2); err != nil {
 		return nil, err
 	}
 	
-	// Extract features
-	features1, err := vcs.extractFeatures(vehicleImg1)
+	// Lighting matches, so remaining intensity differences are exposure (or, across
+	// cameras, sensor and illuminator baselines)
+	if vcs.exposureMatching || vcs.crossCamera {
+		matched := preprocessor.MatchExposure(vehicleImg2.Image, vehicleImg1.Image)
+		defer matched.Close()
+		
+		// The caller closes the processed Mat, so compare on a shallow copy
+		exposed := *vehicleImg2
+		exposed.Image = matched
+		vehicleImg2 = &exposed
+	}
+	
+	// Extract the cheap features first
+	features1, err := vcs.extractPrimaryFeatures(vehicleImg1)
 	if err != nil {
 		return nil, fmt.Errorf("failed to extract features from image 1: %v", err)
 	}
 	
-	features2, err := vcs.extractFeatures(vehicleImg2)
+	features2, err := vcs.extractPrimaryFeatures(vehicleImg2)
 	if err != nil {
 		return nil, fmt.Errorf("failed to extract features from image 2: %v", err)
 	}
 	
+	// A colorless "daylight" image was moved to the IR path during extraction. Color
+	// cannot be compared against it, so move the other image to the IR path as well.
+	if features1.Lighting != features2.Lighting {
+		if features1.Lighting == models.LightingDaylight {
+			vehicleImg1.Lighting = models.LightingInfrared
+			if features1, err = vcs.extractPrimaryFeatures(vehicleImg1); err != nil {
+				return nil, fmt.Errorf("failed to extract features from image 1: %v", err)
+			}
+		}
+		if features2.Lighting == models.LightingDaylight {
+			vehicleImg2.Lighting = models.LightingInfrared
+			if features2, err = vcs.extractPrimaryFeatures(vehicleImg2); err != nil {
+				return nil, fmt.Errorf("failed to extract features from image 2: %v", err)
+			}
+		}
+	}
+	
+	// Skip the expensive features when the cheap ones already rule out a match
+	if vcs.earlyExit {
+		if result, ok := vcs.comparisonEngine.EarlyMismatch(features1, features2); ok {
+			// A non-match from incomparable angles would be unfair
+			viewpointDelta, err := vcs.checkViewpoint(features1, features2)
+			if err != nil {
+				return nil, err
+			}
+			
+			result.ProcessingInfo = models.ProcessingInfo{
+				Image1Quality:       vehicleImg1.QualityScore,
+				Image2Quality:       vehicleImg2.QualityScore,
+				Image1View:          vehicleImg1.View,
+				Image1Lighting:      vehicleImg1.Lighting,
+				Image2View:          vehicleImg2.View,
+				Image2Lighting:      vehicleImg2.Lighting,
+				ViewConsistency:     vehicleImg1.View == vehicleImg2.View,
+				LightingConsistency: vehicleImg1.Lighting == vehicleImg2.Lighting,
+				EarlyExit:           true,
+				ViewpointDelta:      viewpointDelta,
+				FeaturePath:         result.ProcessingInfo.FeaturePath,
+				DecisionThreshold:   result.ProcessingInfo.DecisionThreshold,
+				ThresholdMargin:     result.ProcessingInfo.ThresholdMargin,
+				ConfigFingerprint:   result.ProcessingInfo.ConfigFingerprint,
+			}
+			result.PlateMatch = vcs.matchPlates(vehicleImg1, vehicleImg2)
+			vcs.markDisabledFeatures(&result.Availability)
+			if trace != nil {
+				trace.recordPass(vehicleImg1, vehicleImg2, features1, features2)
+			}
+			return result, nil
+		}
+	}
+	
+	if err := vcs.extractRemainingFeatures(vehicleImg1, &features1); err != nil {
+		return nil, fmt.Errorf("failed to extract features from image 1: %v", err)
+	}
+	
+	if err := vcs.extractRemainingFeatures(vehicleImg2, &features2); err != nil {
+		return nil, fmt.Errorf("failed to extract features from image 2: %v", err)
+	}
+	
+	viewpointDelta, err := vcs.checkViewpoint(features1, features2)
+	if err != nil {
+		return nil, err
+	}
+	
 	// Compare features
 	result, err := vcs.comparisonEngine.CompareVehicles(features1, features2)
 	if err != nil {
@@ -120,49 +642,198 @@ func (vcs *VehicleComparisonService) compareImages(img1, img2 gocv.Mat, startTim
 	
 	// Add processing information
 	result.ProcessingInfo = models.ProcessingInfo{
-		ProcessingTimeMs:    time.Since(startTime).Milliseconds(),
 		Image1Quality:       vehicleImg1.QualityScore,
 		Image2Quality:       vehicleImg2.QualityScore,
+		Image1View:          vehicleImg1.View,
+		Image1Lighting:      vehicleImg1.Lighting,
+		Image2View:          vehicleImg2.View,
+		Image2Lighting:      vehicleImg2.Lighting,
+		AlignmentQuality:    result.ProcessingInfo.AlignmentQuality,
 		ViewConsistency:     vehicleImg1.View == vehicleImg2.View,
 		LightingConsistency: vehicleImg1.Lighting == vehicleImg2.Lighting,
+		ViewpointDelta:      viewpointDelta,
+		FeaturePath:         result.ProcessingInfo.FeaturePath,
+		DecisionThreshold:   result.ProcessingInfo.DecisionThreshold,
+		ThresholdMargin:     result.ProcessingInfo.ThresholdMargin,
+		PlateRelativeFrame:  result.ProcessingInfo.PlateRelativeFrame,
+		SimilarityInterval:  result.ProcessingInfo.SimilarityInterval,
+		ConfigFingerprint:   result.ProcessingInfo.ConfigFingerprint,
+	}
+	result.PlateMatch = vcs.matchPlates(vehicleImg1, vehicleImg2)
+	vcs.markDisabledFeatures(&result.Availability)
+	if trace != nil {
+		trace.recordPass(vehicleImg1, vehicleImg2, features1, features2)
 	}
 	
 	return result, nil
 }
 
+// checkViewpoint estimates the capture-angle difference between the images and
+// rejects it when it exceeds the configured maximum
+func (vcs *VehicleComparisonService) checkViewpoint(features1, features2 models.VehicleFeatures) (models.ViewpointDelta, error) {
+	delta := vcs.comparisonEngine.EstimateViewpointDelta(features1, features2)
+	
+	if vcs.maxViewpointDelta > 0 && delta.Estimated &&
+		math.Max(math.Abs(delta.YawDegrees), math.Abs(delta.PitchDegrees)) > vcs.maxViewpointDelta {
+		return delta, &ErrViewpointMismatch{Delta: delta, MaxDegrees: vcs.maxViewpointDelta}
+	}
+	
+	return delta, nil
+}
+
+// markDisabledFeatures reports features turned off by service options, which the
+// comparison engine only sees as missing data
+func (vcs *VehicleComparisonService) markDisabledFeatures(availability *models.FeatureAvailability) {
+	if vcs.grayscaleOnly {
+		availability.Color = models.FeatureState{Status: models.FeatureSkipped, Reason: "grayscale-only mode"}
+	}
+}
+
 func (vcs *VehicleComparisonService) processImage(img gocv.Mat) (*models.VehicleImage, error) {
-	// Assess image quality
-	quality, err := vcs.qualityAssessor.AssessImageQuality(img)
-	if err != nil {
+	return vcs.processMaskedImage(img, gocv.Mat{})
+}
+
+// processMaskedImage is processImage with a region-of-interest mask, the zero Mat for
+// none. The mask follows the image through trimming and undistortion; the returned
+// VehicleImage owns a copy of it, which the caller closes along with the image.
+func (vcs *VehicleComparisonService) processMaskedImage(img, mask gocv.Mat) (*models.VehicleImage, error) {
+	if err := checkDecoded(img); err != nil {
 		return nil, err
 	}
 	
-	if quality < 0.3 {
-		return nil, fmt.Errorf("image quality too low: %f", quality)
+	// A WithPreprocessor hook may have resized the image since checkMask
+	if err := checkMask(img, mask); err != nil {
+		return nil, err
+	}
+	
+	originalWidth, originalHeight := img.Cols(), img.Rows()
+	
+	// Measured before trimming or undistortion move the 8x8 block grid
+	blockArtifacts := preprocessor.MeasureBlockArtifacts(img)
+	
+	// DVR letterboxing skews proportions and its overlays add false edges. Trimmed
+	// before undistortion, which expects the camera's own frame.
+	var trim models.BorderTrim
+	if vcs.borderTrim {
+		var trimmed gocv.Mat
+		trimmed, trim = preprocessor.TrimBorders(img)
+		defer trimmed.Close()
+		
+		if extractor.HasMask(mask) {
+			trimmedMask := mask.Region(image.Rect(trim.Left, trim.Top, img.Cols()-trim.Right, img.Rows()-trim.Bottom))
+			defer trimmedMask.Close()
+			mask = trimmedMask
+		}
+		img = trimmed
+	}
+	
+	if img.Rows() < minImageDimension || img.Cols() < minImageDimension {
+		return nil, fmt.Errorf("%w: %dx%d, minimum is %dx%d", ErrImageTooSmall,
+			img.Cols(), img.Rows(), minImageDimension, minImageDimension)
+	}
+	
+	// Straighten lens distortion before anything measures geometry
+	if vcs.calibration != nil {
+		undistorted := preprocessor.Undistort(img, *vcs.calibration)
+		defer undistorted.Close()
+		img = undistorted
+		
+		if extractor.HasMask(mask) {
+			undistortedMask := preprocessor.Undistort(mask, *vcs.calibration)
+			defer undistortedMask.Close()
+			mask = undistortedMask
+		}
+	}
+	
+	// Discard color up front so both images are compared on intensity alone
+	if vcs.grayscaleOnly {
+		gray := preprocessor.ToGrayscaleBGR(img)
+		defer gray.Close()
+		img = gray
+	}
+	
+	// Assess image quality
+	quality := unknownQuality
+	if !vcs.skipQuality {
+		assessed, err := vcs.qualityAssessor.AssessImageQuality(img)
+		if err != nil {
+			return nil, err
+		}
+		
+		if assessed < 0.3 {
+			return nil, fmt.Errorf("%w: %f", ErrInsufficientQuality, assessed)
+		}
+		quality = assessed
+		
+		if vcs.minPerceptual > 0 {
+			if perceptual := vcs.qualityAssessor.AssessPerceptualQuality(img); perceptual < vcs.minPerceptual {
+				return nil, fmt.Errorf("%w: perceptual quality %.2f (minimum %.2f)", ErrInsufficientQuality, perceptual, vcs.minPerceptual)
+			}
+		}
 	}
 	
 	// Classify view and lighting
-	view, viewConfidence, err := vcs.viewLightingClassifier.ClassifyView(img)
+	view, views, viewConfidence, err := vcs.viewLightingClassifier.ClassifyViews(img)
 	if err != nil {
 		return nil, err
 	}
 	
-	if viewConfidence < 0.5 {
-		return nil, fmt.Errorf("unable to determine vehicle view with sufficient confidence: %f", viewConfidence)
+	// The roofline is only checked where the body side shows it. Square-on, the roof
+	// may be out of frame and a long edge in the top of the image is as likely to be
+	// a tailgate or bonnet line, so such captures are taken to have one.
+	hasRoof := true
+	if views.Has(models.ViewSide) {
+		if hasRoof, _, err = vcs.viewLightingClassifier.ClassifyRoof(img); err != nil {
+			return nil, err
+		}
 	}
 	
-	lighting, lightingConfidence, err := vcs.viewLightingClassifier.ClassifyLighting(img)
-	if err != nil {
-		return nil, err
+	lighting, lightingConfidence := models.LightingInfrared, 1.0 // Intensity-only feature path
+	if !vcs.grayscaleOnly {
+		lighting, lightingConfidence, err = vcs.viewLightingClassifier.ClassifyLighting(img)
+		if err != nil {
+			return nil, err
+		}
+	}
+	
+	// With the fallback, uncertain lighting is settled during comparison instead
+	ambiguous := vcs.lightingFallback && lightingAmbiguous(lighting, lightingConfidence)
+	if ambiguous && lighting == models.LightingUnknown {
+		lighting = models.LightingDaylight
+	}
+	
+	if viewConfidence < 0.5 || (lightingConfidence < 0.5 && !ambiguous) {
+		return nil, &ErrUnclassifiable{
+			View:               view,
+			ViewConfidence:     viewConfidence,
+			Lighting:           lighting,
+			LightingConfidence: lightingConfidence,
+		}
 	}
 	
-	if lightingConfidence < 0.5 {
-		return nil, fmt.Errorf("unable to determine lighting conditions with sufficient confidence: %f", lightingConfidence)
+	// Red taillights in a "front" image, or white headlights in a "rear" one, mean
+	// the view was misclassified; comparing it as classified would fail
+	viewCorrected := false
+	if lighting == models.LightingDaylight {
+		var checked models.VehicleView
+		if checked, viewCorrected = vcs.lightPatternExtractor.CheckView(img, mask, view); viewCorrected {
+			views = views&^models.ViewSetOf(view) | models.ViewSetOf(checked)
+			view = checked
+		}
 	}
 	
 	// For this implementation, we'll use the full image as the vehicle region
 	// In a full implementation, you would use vehicle detection here
-	croppedVehicle := img.Clone()
+	var croppedVehicle gocv.Mat
+	if vcs.smoothRecompression {
+		croppedVehicle = preprocessor.SmoothRecompressionArtifacts(img)
+	} else {
+		croppedVehicle = img.Clone()
+	}
+	ownedMask := gocv.Mat{}
+	if extractor.HasMask(mask) {
+		ownedMask = mask.Clone()
+	}
 	bounds := models.Bounds{
 		X: 0, Y: 0, 
 		Width: img.Cols(), 
@@ -170,94 +841,183 @@ func (vcs *VehicleComparisonService) processImage(img gocv.Mat) (*models.Vehicle
 	}
 	
 	return &models.VehicleImage{
-		Image:        croppedVehicle,
-		View:         view,
-		Lighting:     lighting,
-		QualityScore: quality,
+		Image:              croppedVehicle,
+		Mask:               ownedMask,
+		View:               view,
+		Views:              views,
+		ViewConfidence:     viewConfidence,
+		Lighting:           lighting,
+		LightingConfidence: lightingConfidence,
+		LightingAmbiguous:  ambiguous,
+		ViewCorrected:      viewCorrected,
+		Roofless:           !hasRoof,
+		QualityScore:       quality,
 		ProcessingMeta: models.ProcessingMetadata{
-			OriginalWidth:    img.Cols(),
-			OriginalHeight:   img.Rows(),
+			OriginalWidth:    originalWidth,
+			OriginalHeight:   originalHeight,
 			VehicleBounds:    bounds,
 			NormalizedWidth:  croppedVehicle.Cols(),
 			NormalizedHeight: croppedVehicle.Rows(),
+			BorderTrim:       trim,
+			BlockArtifacts:   blockArtifacts,
 		},
 	}, nil
 }
 
 func (vcs *VehicleComparisonService) validateImageConsistency(img1, img2 *models.VehicleImage) error {
 	if img1.View != img2.View {
-		return fmt.Errorf("vehicle views do not match: %v vs %v", img1.View, img2.View)
+		return fmt.Errorf("%w: vehicle views do not match: %v vs %v", ErrInconsistentPair, img1.View, img2.View)
 	}
 	
 	if img1.Lighting != img2.Lighting {
-		return fmt.Errorf("lighting conditions do not match: %v vs %v", img1.Lighting, img2.Lighting)
+		return fmt.Errorf("%w: lighting conditions do not match: %v vs %v", ErrInconsistentPair, img1.Lighting, img2.Lighting)
 	}
 	
-	if img1.QualityScore < 0.5 || img2.QualityScore < 0.5 {
-		return fmt.Errorf("one or both images have insufficient quality")
+	// Image 1 is the query and image 2 the gallery entry it is compared against. An
+	// unknown quality, from a capture processed without assessment, is not gated.
+	if vcs.skipQuality {
+		return nil
+	}
+	if belowQuality(img1.QualityScore, vcs.minQueryQuality) || belowQuality(img2.QualityScore, vcs.minGalleryQuality) {
+		return fmt.Errorf("%w: query %.2f (minimum %.2f), gallery %.2f (minimum %.2f)", ErrInsufficientQuality,
+			img1.QualityScore, vcs.minQueryQuality, img2.QualityScore, vcs.minGalleryQuality)
 	}
 	
 	return nil
 }
 
-func (vcs *VehicleComparisonService) extractFeatures(vehicleImg *models.VehicleImage) (models.VehicleFeatures, error) {
+// belowQuality reports whether a known quality score falls short of minimum
+func belowQuality(quality, minimum float64) bool {
+	return quality != unknownQuality && quality < minimum
+}
+
+// extractPrimaryFeatures extracts the features that are cheap to compute: geometry,
+// keypoints and, for daylight images, the color profile.
+//
+// It may correct vehicleImg.Lighting: an image classified as daylight whose dominant
+// colors are effectively gray is re-labelled infrared and extracted on the IR path,
+// since color features from it would be degenerate.
+func (vcs *VehicleComparisonService) extractPrimaryFeatures(vehicleImg *models.VehicleImage) (models.VehicleFeatures, error) {
+	var colorProfile models.ColorProfile
+	if vehicleImg.Lighting == models.LightingDaylight {
+		colorProfile = vcs.colorExtractor.ExtractColorProfile(vehicleImg.Image, vehicleImg.Mask)
+		if extractor.IsGrayscaleProfile(colorProfile) {
+			vehicleImg.Lighting = models.LightingInfrared
+		}
+	}
+	
 	features := models.VehicleFeatures{
 		View:     vehicleImg.View,
+		Views:    vehicleImg.Views,
 		Lighting: vehicleImg.Lighting,
+		VehicleBounds: models.Bounds{
+			Width:  vehicleImg.Image.Cols(),
+			Height: vehicleImg.Image.Rows(),
+		},
+		CanonicalWidth: vehicleImg.Image.Cols(),
+		BlockArtifacts: vehicleImg.ProcessingMeta.BlockArtifacts,
+	}
+	
+	// Only the IR signature is compared; whole-vehicle geometry may not even be
+	// measurable in a tight ANPR crop
+	if vcs.irSignatureOnly {
+		if vehicleImg.Lighting == models.LightingInfrared {
+			features.InfraredFeatures = vcs.extractInfraredFeatures(vehicleImg.Image, vehicleImg.Mask, vehicleImg.View)
+		}
+		return features, nil
 	}
 	
 	// Extract geometric features (universal)
-	geometricFeatures, err := vcs.geometricExtractor.ExtractGeometricFeatures(vehicleImg.Image, vehicleImg.View)
+	geometricFeatures, err := vcs.geometricExtractor.ExtractGeometricFeatures(vehicleImg.Image, vehicleImg.Mask, vehicleImg.View)
 	if err != nil {
 		return features, err
 	}
 	features.GeometricFeatures = geometricFeatures
+	features.GeometricFeatures.VehicleProportions.Roofless = vehicleImg.Roofless
+	
+	if vcs.keypointMatching {
+		features.GeometricFeatures.Keypoints = vcs.keypointExtractor.ExtractKeypoints(vehicleImg.Image, vehicleImg.Mask)
+	}
+	
+	if vehicleImg.Lighting == models.LightingDaylight {
+		// Extract daylight-specific features (simplified)
+		features.DaylightFeatures = vcs.extractDaylightFeatures(colorProfile)
+	}
+	
+	return features, nil
+}
+
+// remainingExtractions counts calls to extractRemainingFeatures, so tests can verify
+// that an early exit skips the expensive extractors
+var remainingExtractions atomic.Int64
+
+// extractRemainingFeatures completes features from extractPrimaryFeatures with the
+// light patterns, bumper and IR features and the extraction quality
+func (vcs *VehicleComparisonService) extractRemainingFeatures(vehicleImg *models.VehicleImage, features *models.VehicleFeatures) error {
+	remainingExtractions.Add(1)
+	
+	if vcs.irSignatureOnly {
+		features.Extraction = models.BreakdownOf(*features)
+		features.ExtractionQuality = features.Extraction.Quality()
+		return nil
+	}
 	
 	// Extract light patterns
-	lightPatterns, err := vcs.lightPatternExtractor.ExtractLightPatterns(vehicleImg.Image, vehicleImg.View, vehicleImg.Lighting)
+	lightPatterns, err := vcs.lightPatternExtractor.ExtractLightPatterns(vehicleImg.Image, vehicleImg.Mask, vehicleImg.View, vehicleImg.Lighting)
 	if err != nil {
-		return features, err
+		return err
 	}
 	features.LightPatterns = lightPatterns
 	
-	// Extract bumper features (simplified implementation)
-	features.BumperFeatures = vcs.extractBumperFeatures(vehicleImg.Image)
-	
 	// Extract lighting-specific features
-	if vehicleImg.Lighting == models.LightingDaylight {
-		// Extract daylight-specific features (simplified)
-		features.DaylightFeatures = vcs.extractDaylightFeatures(vehicleImg.Image)
-	} else if vehicleImg.Lighting == models.LightingInfrared {
+	var plate *models.LicensePlateRegion
+	if vehicleImg.Lighting == models.LightingInfrared {
 		// Extract infrared-specific features (simplified)
-		features.InfraredFeatures = vcs.extractInfraredFeatures(vehicleImg.Image)
+		features.InfraredFeatures = vcs.extractInfraredFeatures(vehicleImg.Image, vehicleImg.Mask, vehicleImg.View)
+		if features.InfraredFeatures.IRSignature != nil {
+			plate = &features.InfraredFeatures.IRSignature.PlateRegion
+		}
+	} else if vcs.mountingOnly || vcs.plateRelative {
+		// Daylight features do not locate the plate, but mounting points and the
+		// plate-relative frame are measured relative to it
+		if detected, err := vcs.plateExtractor.DetectLicensePlate(vehicleImg.Image, vehicleImg.Mask, vehicleImg.View); err == nil {
+			plate = detected
+		}
 	}
 	
-	// Calculate extraction quality
-	features.ExtractionQuality = vcs.calculateExtractionQuality(features)
+	// Extract bumper features (simplified implementation)
+	features.BumperFeatures = vcs.extractBumperFeatures(vehicleImg.Image, vehicleImg.Mask, plate)
 	
-	return features, nil
+	// Record what was extracted
+	features.Extraction = models.BreakdownOf(*features)
+	features.ExtractionQuality = features.Extraction.Quality()
+	
+	return nil
 }
 
-func (vcs *VehicleComparisonService) extractBumperFeatures(img gocv.Mat) models.BumperFeatures {
-	// Simplified bumper feature extraction
+// extractBumperFeatures traces the bumper outline and records the plate area and its
+// mounting points only when plate is a real detection; fallback plate regions leave
+// them empty
+func (vcs *VehicleComparisonService) extractBumperFeatures(img, mask gocv.Mat, plate *models.LicensePlateRegion) models.BumperFeatures {
+	plateArea := models.Bounds{}
+	mountingPoints := []models.Point2D{}
+	if plate != nil && plate.Detected {
+		plateArea = plate.Bounds
+		mountingPoints = vcs.mountingPointExtractor.ExtractMountingPoints(img, mask, plate.Bounds)
+	}
+	
 	return models.BumperFeatures{
-		ContourSignature: []models.Point2D{},
+		ContourSignature: vcs.bumperExtractor.ExtractContourSignature(img, mask),
 		TextureFeatures:  []float64{0.5, 0.3, 0.7}, // Placeholder
-		MountingPoints:   []models.Point2D{},
-		LicensePlateArea: models.Bounds{},
+		MountingPoints:   mountingPoints,
+		LicensePlateArea: plateArea,
 	}
 }
 
-func (vcs *VehicleComparisonService) extractDaylightFeatures(img gocv.Mat) *models.DaylightFeatures {
+func (vcs *VehicleComparisonService) extractDaylightFeatures(colorProfile models.ColorProfile) *models.DaylightFeatures {
 	// Simplified daylight feature extraction
 	return &models.DaylightFeatures{
-		ColorProfile: models.ColorProfile{
-			DominantColors: []models.Color{
-				{R: 128, G: 128, B: 128, Weight: 0.5},
-				{R: 64, G: 64, B: 64, Weight: 0.3},
-			},
-			Histogram: make([]int, 256),
-		},
+		ColorProfile:    colorProfile,
 		BadgeLocations:  []models.BadgeFeature{},
 		TrimDetails:     []models.TrimFeature{},
 		SurfaceTexture: models.TextureSignature{
@@ -267,9 +1027,9 @@ func (vcs *VehicleComparisonService) extractDaylightFeatures(img gocv.Mat) *mode
 	}
 }
 
-func (vcs *VehicleComparisonService) extractInfraredFeatures(img gocv.Mat) *models.InfraredFeatures {
+func (vcs *VehicleComparisonService) extractInfraredFeatures(img, mask gocv.Mat, view models.VehicleView) *models.InfraredFeatures {
 	// Extract real IR signature around license plate
-	irSignature, err := vcs.irSignatureExtractor.ExtractIRSignature(img)
+	irSignature, err := vcs.irSignatureExtractor.ExtractIRSignature(img, mask, view)
 	if err != nil {
 		// Fallback to simplified features if IR signature extraction fails
 		return &models.InfraredFeatures{
@@ -287,33 +1047,4 @@ func (vcs *VehicleComparisonService) extractInfraredFeatures(img gocv.Mat) *mode
 		MaterialSignature:  irSignature.MaterialSignature,
 		IRSignature:        irSignature,
 	}
-}
-
-func (vcs *VehicleComparisonService) calculateExtractionQuality(features models.VehicleFeatures) float64 {
-	quality := 0.0
-	components := 0
-	
-	// Geometric features quality
-	if len(features.GeometricFeatures.ReferencePoints) > 0 {
-		quality += 0.8
-	}
-	components++
-	
-	// Light pattern quality
-	if len(features.LightPatterns.LightElements) > 0 {
-		quality += 0.9
-	}
-	components++
-	
-	// Lighting-specific features quality
-	if features.DaylightFeatures != nil || features.InfraredFeatures != nil {
-		quality += 0.7
-	}
-	components++
-	
-	if components == 0 {
-		return 0.0
-	}
-	
-	return quality / float64(components)
 }
\ No newline at end of file
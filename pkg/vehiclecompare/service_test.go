@@ -0,0 +1,186 @@
+package vehiclecompare
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/comparator"
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+	"github.com/choff5507/vehicle-image-comparison/internal/testutil"
+	"gocv.io/x/gocv"
+)
+
+func TestAsymmetricQualityRequirements(t *testing.T) {
+	query := &models.VehicleImage{View: models.ViewRear, Lighting: models.LightingDaylight, QualityScore: 0.4}
+	gallery := &models.VehicleImage{View: models.ViewRear, Lighting: models.LightingDaylight, QualityScore: 0.9}
+
+	if err := NewVehicleComparisonService().validateImageConsistency(query, gallery); err == nil {
+		t.Error("Expected the default symmetric gate to reject a 0.4-quality query")
+	}
+
+	service := NewVehicleComparisonService(WithMinQueryQuality(0.35), WithMinGalleryQuality(0.8))
+	if err := service.validateImageConsistency(query, gallery); err != nil {
+		t.Errorf("Expected a degraded query against a good gallery entry to proceed, got %v", err)
+	}
+
+	// The gallery requirement still applies
+	if err := service.validateImageConsistency(gallery, query); err == nil {
+		t.Error("Expected a 0.4-quality gallery entry to be rejected")
+	}
+}
+
+func TestCheckDecodedRejectsUnusableMats(t *testing.T) {
+	empty := gocv.NewMat()
+	defer empty.Close()
+	if err := checkDecoded(empty); !errors.Is(err, ErrImageDecode) {
+		t.Errorf("Expected ErrImageDecode for a Mat with no pixels, got %v", err)
+	}
+
+	fourChannel := gocv.NewMatWithSize(10, 10, gocv.MatTypeCV8UC4)
+	defer fourChannel.Close()
+	if err := checkDecoded(fourChannel); !errors.Is(err, ErrImageDecode) {
+		t.Errorf("Expected ErrImageDecode for a 4-channel Mat, got %v", err)
+	}
+
+	for _, matType := range []gocv.MatType{gocv.MatTypeCV8UC1, gocv.MatTypeCV8UC3} {
+		img := gocv.NewMatWithSize(10, 10, matType)
+		if err := checkDecoded(img); err != nil {
+			t.Errorf("Expected a %d-channel Mat to pass, got %v", img.Channels(), err)
+		}
+		img.Close()
+	}
+}
+
+func TestMaxViewpointDeltaRejectsObliquePairs(t *testing.T) {
+	squareOn := models.VehicleFeatures{
+		VehicleBounds: models.Bounds{Width: 1280, Height: 960},
+		GeometricFeatures: models.GeometricFeatures{
+			VehicleProportions: models.VehicleProportions{WidthHeightRatio: 1.33, UpperLowerRatio: 1.2},
+		},
+		LightPatterns: models.LightPatternFeatures{LightElements: []models.LightElement{
+			{Position: models.Point2D{X: 240, Y: 400}, Size: 900},
+			{Position: models.Point2D{X: 1040, Y: 400}, Size: 900},
+		}},
+	}
+	// Taillight span compressed by cos(60°): a steep 3/4 capture of the same face
+	oblique := squareOn
+	oblique.LightPatterns.LightElements = []models.LightElement{
+		{Position: models.Point2D{X: 120, Y: 400}, Size: 450},
+		{Position: models.Point2D{X: 520, Y: 400}, Size: 450},
+	}
+
+	if _, err := NewVehicleComparisonService().checkViewpoint(squareOn, oblique); err != nil {
+		t.Errorf("Expected no viewpoint check by default, got %v", err)
+	}
+
+	service := NewVehicleComparisonService(WithMaxViewpointDelta(20))
+
+	delta, err := service.checkViewpoint(squareOn, oblique)
+	var mismatch *ErrViewpointMismatch
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("Expected ErrViewpointMismatch for a %.1f° yaw delta, got %v", delta.YawDegrees, err)
+	}
+	if mismatch.MaxDegrees != 20 || mismatch.Delta.YawDegrees < 55 {
+		t.Errorf("Expected the mismatch to carry the bound and a ~60° delta, got %+v", mismatch)
+	}
+
+	if _, err := service.checkViewpoint(squareOn, squareOn); err != nil {
+		t.Errorf("Expected identical capture angles to pass, got %v", err)
+	}
+
+	// Cached features are held to the same limit as images
+	if _, err := service.CompareFeatures(squareOn, oblique); !errors.As(err, &mismatch) {
+		t.Errorf("Expected CompareFeatures to reject the oblique pair, got %v", err)
+	}
+}
+
+func TestWriteTraceReplacesNonFiniteValues(t *testing.T) {
+	var traces bytes.Buffer
+	service := NewVehicleComparisonService(WithComparisonTrace(&traces))
+
+	features := models.VehicleFeatures{View: models.ViewRear, Lighting: models.LightingDaylight}
+	features.GeometricFeatures.VehicleProportions.WidthHeightRatio = math.NaN()
+	trace := &ComparisonTrace{Features: [2]models.VehicleFeatures{features, features}}
+	result := &models.ComparisonResult{SimilarityScore: math.Inf(1)}
+
+	if err := service.writeTrace(trace, result); err != nil {
+		t.Fatalf("Expected a trace despite non-finite values, got %v", err)
+	}
+	if !math.IsNaN(trace.Features[0].GeometricFeatures.VehicleProportions.WidthHeightRatio) || !math.IsInf(result.SimilarityScore, 1) {
+		t.Error("Expected the caller's features and result to be left untouched")
+	}
+
+	var written ComparisonTrace
+	if err := json.Unmarshal(bytes.TrimSpace(traces.Bytes()), &written); err != nil {
+		t.Fatalf("Trace does not decode: %v", err)
+	}
+	if written.Features[0].GeometricFeatures.VehicleProportions.WidthHeightRatio != 0 || written.Similarities.Overall != 0 {
+		t.Errorf("Expected non-finite values written as 0, got %+v", written.Similarities)
+	}
+}
+
+func TestEarlyExitSkipsExpensiveExtraction(t *testing.T) {
+	dir := t.TempDir()
+
+	// A red vehicle and a much wider blue one with a different light layout
+	spec1 := testutil.DefaultSpec()
+	spec1.BodyColor = color.RGBA{R: 200, G: 30, B: 30}
+	spec2 := testutil.DefaultSpec()
+	spec2.BodyColor = color.RGBA{R: 30, G: 30, B: 200}
+	spec2.BodyWidth = 900
+	spec2.BodyTop = 320
+	spec2.LightSpacing = 760
+	spec2.LightY = 420
+	spec2.ExtraLights = 1
+
+	paths := make([]string, 2)
+	for i, spec := range []testutil.VehicleSpec{spec1, spec2} {
+		img := testutil.FrontVehicle(spec)
+		path, err := testutil.WriteImage(dir, fmt.Sprintf("vehicle%d.png", i+1), img)
+		img.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		paths[i] = path
+	}
+
+	// A strict threshold, which geometry and color alone can rule out
+	config := comparator.DefaultConfig()
+	config.DaylightThreshold = 0.95
+	engine, err := comparator.NewComparisonEngineWithConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	service := NewVehicleComparisonService(WithEarlyExit(true))
+	service.comparisonEngine = engine
+
+	before := remainingExtractions.Load()
+	result, err := service.CompareVehicleImages(paths[0], paths[1])
+	if err != nil {
+		t.Fatalf("Comparison failed: %v", err)
+	}
+	if !result.ProcessingInfo.EarlyExit {
+		t.Fatalf("Expected an early exit, got score %.3f", result.SimilarityScore)
+	}
+	if extractions := remainingExtractions.Load() - before; extractions != 0 {
+		t.Errorf("Expected no light, bumper or IR extraction after an early exit, got %d", extractions)
+	}
+
+	// Without early exit both images go through them
+	service = NewVehicleComparisonService()
+	service.comparisonEngine = engine
+
+	before = remainingExtractions.Load()
+	if _, err := service.CompareVehicleImages(paths[0], paths[1]); err != nil {
+		t.Fatalf("Comparison failed: %v", err)
+	}
+	if extractions := remainingExtractions.Load() - before; extractions < 2 {
+		t.Errorf("Expected the expensive features of both images to be extracted, got %d extractions", extractions)
+	}
+}
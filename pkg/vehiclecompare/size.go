@@ -0,0 +1,125 @@
+package vehiclecompare
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	_ "image/gif"  // Registers GIF headers with image.DecodeConfig
+	_ "image/jpeg" // Registers JPEG headers with image.DecodeConfig
+	_ "image/png"  // Registers PNG headers with image.DecodeConfig
+
+	"gocv.io/x/gocv"
+)
+
+// Every intermediate Mat is the image's size or larger (the CV64F Laplacian is 8
+// bytes a pixel), so 25 megapixels already costs a few hundred MB per comparison
+const defaultMaxImagePixels = 25_000_000
+
+// TIFF tags holding a page's dimensions
+const (
+	tiffTagImageWidth  = 256
+	tiffTagImageLength = 257
+)
+
+// checkEncodedSize rejects data whose header declares more pixels than the
+// configured maximum, before anything is decoded. Formats whose header it cannot
+// read are checked after decoding by checkDecodedSize.
+func (vcs *VehicleComparisonService) checkEncodedSize(data []byte) error {
+	if vcs.maxImagePixels <= 0 {
+		return nil
+	}
+
+	width, height, ok := encodedDimensions(data)
+	if !ok {
+		return nil
+	}
+	return vcs.checkDimensions(width, height)
+}
+
+// checkDecodedSize is the fallback for formats checkEncodedSize cannot read; it
+// still runs before the pipeline allocates anything
+func (vcs *VehicleComparisonService) checkDecodedSize(img gocv.Mat) error {
+	if vcs.maxImagePixels <= 0 {
+		return nil
+	}
+	return vcs.checkDimensions(img.Cols(), img.Rows())
+}
+
+func (vcs *VehicleComparisonService) checkDimensions(width, height int) error {
+	if int64(width)*int64(height) > int64(vcs.maxImagePixels) {
+		return fmt.Errorf("%w: %dx%d exceeds the maximum of %d pixels", ErrImageTooLarge,
+			width, height, vcs.maxImagePixels)
+	}
+	return nil
+}
+
+// encodedDimensions reads the image size from the header of JPEG, PNG, GIF, BMP and
+// classic TIFF data; for a multi-page TIFF it returns the largest page. ok is false
+// for other formats and unreadable headers.
+func encodedDimensions(data []byte) (width, height int, ok bool) {
+	if config, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+		return config.Width, config.Height, true
+	}
+
+	// BITMAPINFOHEADER and later: signed 32-bit width and height, height negative
+	// for top-down bitmaps
+	if len(data) >= 26 && bytes.HasPrefix(data, []byte("BM")) {
+		width = int(int32(binary.LittleEndian.Uint32(data[18:22])))
+		height = int(int32(binary.LittleEndian.Uint32(data[22:26])))
+		return abs(width), abs(height), true
+	}
+
+	largest := int64(-1)
+	for _, offset := range tiffPageOffsets(data) {
+		pageWidth, pageHeight, pageOK := tiffPageDimensions(data, offset)
+		if pageOK && int64(pageWidth)*int64(pageHeight) > largest {
+			width, height, largest = pageWidth, pageHeight, int64(pageWidth)*int64(pageHeight)
+		}
+	}
+	return width, height, largest >= 0
+}
+
+// tiffPageDimensions reads the width and length tags of the IFD at offset, which
+// tiffPageOffsets has already bounds-checked
+func tiffPageDimensions(data []byte, offset uint32) (width, height int, ok bool) {
+	order := binary.ByteOrder(binary.LittleEndian)
+	if data[0] == 'M' {
+		order = binary.BigEndian
+	}
+
+	entries := int(order.Uint16(data[offset:]))
+	found := 0
+	for i := 0; i < entries; i++ {
+		entry := data[int(offset)+2+i*12:]
+		tag, fieldType := order.Uint16(entry[0:2]), order.Uint16(entry[2:4])
+
+		// SHORT or LONG, stored in the value field
+		var value int
+		switch fieldType {
+		case 3:
+			value = int(order.Uint16(entry[8:10]))
+		case 4:
+			value = int(order.Uint32(entry[8:12]))
+		default:
+			continue
+		}
+
+		switch tag {
+		case tiffTagImageWidth:
+			width = value
+			found++
+		case tiffTagImageLength:
+			height = value
+			found++
+		}
+	}
+	return width, height, found == 2
+}
+
+func abs(value int) int {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
@@ -0,0 +1,318 @@
+package vehiclecompare
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+	"gocv.io/x/gocv"
+)
+
+// Template is a vehicle enrolled with a daylight and/or an infrared capture. A query
+// of either modality is compared against the capture of the same modality, so one
+// enrollment serves day and night queries.
+type Template struct {
+	Daylight *EnrolledCapture `json:"daylight,omitempty"`
+	Infrared *EnrolledCapture `json:"infrared,omitempty"`
+}
+
+// EnrolledCapture is the extracted features of one enrolled image
+type EnrolledCapture struct {
+	Features models.VehicleFeatures `json:"features"`
+	Quality  float64                `json:"quality"`
+}
+
+// capture returns the enrolled capture for lighting, or nil
+func (t Template) capture(lighting models.LightingType) *EnrolledCapture {
+	if lighting == models.LightingDaylight {
+		return t.Daylight
+	}
+	return t.Infrared
+}
+
+// BuildTemplate enrolls a vehicle from a daylight and an infrared image of it. Either
+// path may be empty to enroll a single modality. Each image must be classified as
+// the modality it is enrolled as; a grayscale "daylight" image, which is compared on
+// the IR path, is rejected as a daylight capture.
+func (vcs *VehicleComparisonService) BuildTemplate(daylightPath, irPath string) (Template, error) {
+	if daylightPath == "" && irPath == "" {
+		return Template{}, fmt.Errorf("no images to enroll")
+	}
+
+	var template Template
+	for _, enrollment := range []struct {
+		path     string
+		lighting models.LightingType
+		capture  **EnrolledCapture
+	}{
+		{daylightPath, models.LightingDaylight, &template.Daylight},
+		{irPath, models.LightingInfrared, &template.Infrared},
+	} {
+		if enrollment.path == "" {
+			continue
+		}
+
+		capture, err := vcs.enroll(enrollment.path)
+		if err != nil {
+			return Template{}, fmt.Errorf("failed to enroll %s: %w", enrollment.path, err)
+		}
+		if capture.Features.Lighting != enrollment.lighting {
+			return Template{}, fmt.Errorf("%s was enrolled as %v but classified as %v",
+				enrollment.path, enrollment.lighting, capture.Features.Lighting)
+		}
+		*enrollment.capture = capture
+	}
+
+	return template, nil
+}
+
+// CompareToTemplate compares a query image against the template capture of the same
+// modality. It fails when the template has no capture of the query's modality.
+func (vcs *VehicleComparisonService) CompareToTemplate(queryPath string, template Template) (*models.ComparisonResult, error) {
+	startTime := time.Now()
+
+	img, err := vcs.loadImage(queryPath)
+	if err != nil {
+		return nil, err
+	}
+	defer img.Close()
+
+	vehicleImg, err := vcs.processImage(img)
+	if err != nil {
+		return nil, fmt.Errorf("failed to process query: %w", err)
+	}
+	defer vehicleImg.Image.Close()
+
+	features, err := vcs.extractFeatures(vehicleImg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract features from query: %v", err)
+	}
+
+	// Extraction may have moved a colorless daylight query to the IR path
+	enrolled := template.capture(features.Lighting)
+	if enrolled == nil {
+		return nil, fmt.Errorf("template has no %v capture to compare the query against", features.Lighting)
+	}
+
+	query := &EnrolledCapture{Features: features, Quality: vehicleImg.QualityScore}
+	return vcs.compareCaptures(query, enrolled, startTime)
+}
+
+// compareCaptures compares the extracted features of a query against those of a
+// gallery entry, with the same consistency and viewpoint checks as an image pair
+func (vcs *VehicleComparisonService) compareCaptures(query, enrolled *EnrolledCapture, startTime time.Time) (*models.ComparisonResult, error) {
+	// The query is image 1 and the enrolled capture the gallery entry
+	queryImg := &models.VehicleImage{
+		View:         query.Features.View,
+		Lighting:     query.Features.Lighting,
+		QualityScore: query.Quality,
+	}
+	gallery := &models.VehicleImage{
+		View:         enrolled.Features.View,
+		Lighting:     enrolled.Features.Lighting,
+		QualityScore: enrolled.Quality,
+	}
+	if err := vcs.validateImageConsistency(queryImg, gallery); err != nil {
+		return nil, err
+	}
+
+	viewpointDelta, err := vcs.checkViewpoint(query.Features, enrolled.Features)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := vcs.comparisonEngine.CompareVehicles(query.Features, enrolled.Features)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare vehicles: %v", err)
+	}
+
+	result.ProcessingInfo = models.ProcessingInfo{
+		ProcessingTimeMs:    time.Since(startTime).Milliseconds(),
+		Image1Quality:       query.Quality,
+		Image2Quality:       enrolled.Quality,
+		Image1View:          queryImg.View,
+		Image1Lighting:      queryImg.Lighting,
+		Image2View:          gallery.View,
+		Image2Lighting:      gallery.Lighting,
+		AlignmentQuality:    result.ProcessingInfo.AlignmentQuality,
+		ViewConsistency:     true,
+		LightingConsistency: true,
+		ViewpointDelta:      viewpointDelta,
+		FeaturePath:         result.ProcessingInfo.FeaturePath,
+		DecisionThreshold:   result.ProcessingInfo.DecisionThreshold,
+		ThresholdMargin:     result.ProcessingInfo.ThresholdMargin,
+		PlateRelativeFrame:  result.ProcessingInfo.PlateRelativeFrame,
+		SimilarityInterval:  result.ProcessingInfo.SimilarityInterval,
+		ConfigFingerprint:   result.ProcessingInfo.ConfigFingerprint,
+	}
+	vcs.markDisabledFeatures(&result.Availability)
+
+	return result, nil
+}
+
+// enroll loads an image and extracts its features for a template
+func (vcs *VehicleComparisonService) enroll(path string) (*EnrolledCapture, error) {
+	img, err := vcs.loadImage(path)
+	if err != nil {
+		return nil, err
+	}
+	defer img.Close()
+
+	vehicleImg, err := vcs.processImage(img)
+	if err != nil {
+		return nil, err
+	}
+	defer vehicleImg.Image.Close()
+
+	features, err := vcs.extractFeatures(vehicleImg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &EnrolledCapture{Features: features, Quality: vehicleImg.QualityScore}, nil
+}
+
+// extractFeatures runs full feature extraction on a single image
+func (vcs *VehicleComparisonService) extractFeatures(vehicleImg *models.VehicleImage) (models.VehicleFeatures, error) {
+	features, err := vcs.extractPrimaryFeatures(vehicleImg)
+	if err != nil {
+		return features, err
+	}
+
+	if err := vcs.extractRemainingFeatures(vehicleImg, &features); err != nil {
+		return features, err
+	}
+
+	return features, nil
+}
+
+// loadImage reads an image file, failing with ErrImageDecode when it is missing,
+// corrupt or unusable
+func (vcs *VehicleComparisonService) loadImage(path string) (gocv.Mat, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return gocv.Mat{}, fmt.Errorf("%w: failed to load %s: %v", ErrImageDecode, path, err)
+	}
+
+	img, err := vcs.decodeImage(data)
+	if err != nil {
+		return gocv.Mat{}, fmt.Errorf("failed to load %s: %w", path, err)
+	}
+
+	if err := checkDecoded(img); err != nil {
+		img.Close()
+		return gocv.Mat{}, err
+	}
+
+	return img, nil
+}
+
+// Update folds a confirmed match into the template's capture of the same modality,
+// so a template tracking one vehicle through a sequence adapts to changing
+// conditions. Each signature becomes a running average: weight (0-1] of features
+// and the rest of the current capture. Only pass features of high-confidence
+// matches; a small weight limits how far a single mistaken update can pull the
+// template. Element positions, and signatures whose length differs from the
+// enrolled one, are left as enrolled. Features for an update can be taken from a
+// single-modality BuildTemplate of the new frame.
+func (t *Template) Update(features models.VehicleFeatures, weight float64) error {
+	if weight <= 0 || weight > 1 {
+		return fmt.Errorf("update weight %v outside (0, 1]", weight)
+	}
+
+	slot := &t.Infrared
+	if features.Lighting == models.LightingDaylight {
+		slot = &t.Daylight
+	}
+	if *slot == nil {
+		return fmt.Errorf("template has no %v capture to update", features.Lighting)
+	}
+	if (*slot).Features.View != features.View {
+		return fmt.Errorf("cannot update a %v capture with a %v view", (*slot).Features.View, features.View)
+	}
+
+	// Updated as a copy, so templates sharing a capture are unaffected
+	updated := **slot
+	updated.Features = blendFeatures(updated.Features, features, weight)
+	*slot = &updated
+	return nil
+}
+
+// blendFeatures returns current with its signatures moved weight of the way
+// towards those of update
+func blendFeatures(current, update models.VehicleFeatures, weight float64) models.VehicleFeatures {
+	blended := current
+
+	proportions := &blended.GeometricFeatures.VehicleProportions
+	next := update.GeometricFeatures.VehicleProportions
+	proportions.WidthHeightRatio = blendRatio(proportions.WidthHeightRatio, next.WidthHeightRatio, weight)
+	proportions.UpperLowerRatio = blendRatio(proportions.UpperLowerRatio, next.UpperLowerRatio, weight)
+	proportions.LicensePlateRatio = blendRatio(proportions.LicensePlateRatio, next.LicensePlateRatio, weight)
+
+	blended.LightPatterns.PatternSignature = blendSignature(current.LightPatterns.PatternSignature, update.LightPatterns.PatternSignature, weight)
+	blended.BumperFeatures.TextureFeatures = blendSignature(current.BumperFeatures.TextureFeatures, update.BumperFeatures.TextureFeatures, weight)
+
+	if current.DaylightFeatures != nil && update.DaylightFeatures != nil {
+		day := *current.DaylightFeatures
+		day.ColorProfile.HueHistogram = blendSignature(day.ColorProfile.HueHistogram, update.DaylightFeatures.ColorProfile.HueHistogram, weight)
+		day.ColorProfile.SaturationHistogram = blendSignature(day.ColorProfile.SaturationHistogram, update.DaylightFeatures.ColorProfile.SaturationHistogram, weight)
+		day.SurfaceTexture.Features = blendSignature(day.SurfaceTexture.Features, update.DaylightFeatures.SurfaceTexture.Features, weight)
+		blended.DaylightFeatures = &day
+	}
+
+	if current.InfraredFeatures != nil && update.InfraredFeatures != nil {
+		ir := *current.InfraredFeatures
+		ir.ThermalSignature = blendSignature(ir.ThermalSignature, update.InfraredFeatures.ThermalSignature, weight)
+		ir.MaterialSignature = blendSignature(ir.MaterialSignature, update.InfraredFeatures.MaterialSignature, weight)
+
+		if ir.IRSignature != nil && update.InfraredFeatures.IRSignature != nil {
+			signature := *ir.IRSignature
+			next := update.InfraredFeatures.IRSignature
+			signature.MaterialSignature = blendSignature(signature.MaterialSignature, next.MaterialSignature, weight)
+			signature.IlluminationGradient = blendSignature(signature.IlluminationGradient, next.IlluminationGradient, weight)
+			signature.TextureFeatures = blendSignature(signature.TextureFeatures, next.TextureFeatures, weight)
+			if len(signature.ReflectivityMap) == len(next.ReflectivityMap) {
+				reflectivity := make([][]float64, len(signature.ReflectivityMap))
+				for i, row := range signature.ReflectivityMap {
+					reflectivity[i] = blendSignature(row, next.ReflectivityMap[i], weight)
+				}
+				signature.ReflectivityMap = reflectivity
+			}
+			ir.IRSignature = &signature
+		}
+		blended.InfraredFeatures = &ir
+	}
+
+	return blended
+}
+
+// blendSignature returns a new running average of two equal-length signatures, or
+// current unchanged when the lengths differ
+func blendSignature(current, update []float64, weight float64) []float64 {
+	if len(current) != len(update) {
+		return current
+	}
+
+	blended := make([]float64, len(current))
+	for i := range current {
+		blended[i] = blendValue(current[i], update[i], weight)
+	}
+	return blended
+}
+
+// blendRatio blends a ratio, where zero or negative means not measured: an
+// unmeasured update is ignored and an unmeasured current value is replaced
+func blendRatio(current, update, weight float64) float64 {
+	if update <= 0 {
+		return current
+	}
+	if current <= 0 {
+		return update
+	}
+	return blendValue(current, update, weight)
+}
+
+func blendValue(current, update, weight float64) float64 {
+	return current + (update-current)*weight
+}
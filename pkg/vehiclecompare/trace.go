@@ -0,0 +1,167 @@
+package vehiclecompare
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+	"gocv.io/x/gocv"
+)
+
+// ComparisonTrace is the artifact WithComparisonTrace writes for each comparison:
+// everything needed to reproduce and debug a score without the original images.
+// Index 0 of each pair is image 1.
+type ComparisonTrace struct {
+	Inputs           [2]TraceInput             `json:"inputs"`
+	Classification   [2]TraceClassification    `json:"classification"`
+	Features         [2]models.VehicleFeatures `json:"features"`
+	EffectiveWeights models.FeatureWeights     `json:"effective_weights"`
+	Similarities     TraceSimilarities         `json:"similarities"`
+	Result           *models.ComparisonResult  `json:"result"`
+}
+
+// TraceInput identifies an input image by its decoded pixels, so the hash does not
+// depend on how the file was encoded or transported
+type TraceInput struct {
+	SHA256   string `json:"sha256"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+	Channels int    `json:"channels"`
+}
+
+// TraceClassification is how an image was classified before extraction
+type TraceClassification struct {
+	View               models.VehicleView  `json:"view"`
+	ViewConfidence     float64             `json:"view_confidence"`
+	ViewCorrected      bool                `json:"view_corrected"` // Swapped by the light cross-check
+	Lighting           models.LightingType `json:"lighting"`
+	LightingConfidence float64             `json:"lighting_confidence"`
+	LightingAmbiguous  bool                `json:"lighting_ambiguous"` // Compared on every feature path both images share
+	Quality            float64             `json:"quality"`
+}
+
+// TraceSimilarities holds every similarity behind the score: the overall score, the
+// per-feature scores, their unweighted components and their weighted shares
+type TraceSimilarities struct {
+	Overall       float64                     `json:"overall"`
+	Detailed      models.DetailedScores       `json:"detailed"`
+	SubScores     *models.SubScores           `json:"sub_scores"`
+	Contributions models.FeatureContributions `json:"contributions"`
+}
+
+// newTrace starts a trace of the comparison of img1 and img2, or returns nil when
+// neither tracing nor diagnostics are on
+func (vcs *VehicleComparisonService) newTrace(img1, img2 gocv.Mat) *ComparisonTrace {
+	if vcs.traceWriter == nil && vcs.diagnostics == nil {
+		return nil
+	}
+	return &ComparisonTrace{Inputs: [2]TraceInput{traceInput(img1), traceInput(img2)}}
+}
+
+func traceInput(img gocv.Mat) TraceInput {
+	sum := sha256.Sum256(img.ToBytes())
+	return TraceInput{
+		SHA256:   hex.EncodeToString(sum[:]),
+		Width:    img.Cols(),
+		Height:   img.Rows(),
+		Channels: img.Channels(),
+	}
+}
+
+// recordPass stores what a comparison pass classified and extracted
+func (trace *ComparisonTrace) recordPass(img1, img2 *models.VehicleImage, features1, features2 models.VehicleFeatures) {
+	trace.Classification = [2]TraceClassification{traceClassification(img1), traceClassification(img2)}
+	trace.Features = [2]models.VehicleFeatures{features1, features2}
+}
+
+func traceClassification(img *models.VehicleImage) TraceClassification {
+	return TraceClassification{
+		View:               img.View,
+		ViewConfidence:     img.ViewConfidence,
+		ViewCorrected:      img.ViewCorrected,
+		Lighting:           img.Lighting,
+		LightingConfidence: img.LightingConfidence,
+		LightingAmbiguous:  img.LightingAmbiguous,
+		Quality:            img.QualityScore,
+	}
+}
+
+// writeTrace completes trace with the final result and writes it as one line of
+// JSON. Concurrent comparisons never interleave their lines. NaN and infinite values,
+// which JSON cannot represent, are written as 0.
+func (vcs *VehicleComparisonService) writeTrace(trace *ComparisonTrace, result *models.ComparisonResult) error {
+	trace.Result = result
+	trace.EffectiveWeights = result.EffectiveWeights
+	trace.Similarities = TraceSimilarities{
+		Overall:       result.SimilarityScore,
+		Detailed:      result.DetailedScores,
+		SubScores:     result.SubScores,
+		Contributions: result.Contributions(),
+	}
+	if trace.Similarities.SubScores == nil {
+		trace.Similarities.SubScores = vcs.comparisonEngine.SubScores(trace.Features[0], trace.Features[1])
+	}
+
+	line, err := json.Marshal(finiteCopy(reflect.ValueOf(trace)).Interface())
+	if err != nil {
+		return fmt.Errorf("failed to encode comparison trace: %v", err)
+	}
+	line = append(line, '\n')
+
+	vcs.traceMu.Lock()
+	defer vcs.traceMu.Unlock()
+	if _, err := vcs.traceWriter.Write(line); err != nil {
+		return fmt.Errorf("failed to write comparison trace: %v", err)
+	}
+	return nil
+}
+
+// finiteCopy returns a deep copy of v with every NaN or infinite float replaced by
+// 0. The trace shares slices and pointers with the caller's features and result,
+// which are left untouched.
+func finiteCopy(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		out := reflect.New(v.Type()).Elem()
+		if f := v.Float(); !math.IsNaN(f) && !math.IsInf(f, 0) {
+			out.SetFloat(f)
+		}
+		return out
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.New(v.Type().Elem())
+		out.Elem().Set(finiteCopy(v.Elem()))
+		return out
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			out.Index(i).Set(finiteCopy(v.Index(i)))
+		}
+		return out
+	case reflect.Array:
+		out := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			out.Index(i).Set(finiteCopy(v.Index(i)))
+		}
+		return out
+	case reflect.Struct:
+		out := reflect.New(v.Type()).Elem()
+		out.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if out.Field(i).CanSet() {
+				out.Field(i).Set(finiteCopy(v.Field(i)))
+			}
+		}
+		return out
+	}
+	return v
+}
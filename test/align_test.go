@@ -0,0 +1,57 @@
+package test
+
+import (
+	"image"
+	"testing"
+
+	"github.com/choff5507/vehicle-image-comparison/pkg/vehiclecompare"
+	"gocv.io/x/gocv"
+)
+
+func TestAlignPairShiftedCopy(t *testing.T) {
+	dir := t.TempDir()
+	original := writeTestVehicle(t, dir, "original.png", 0, 0)
+	shifted := writeTestVehicle(t, dir, "shifted.png", 24, 16)
+
+	service := vehiclecompare.NewVehicleComparisonService()
+
+	aligned1, aligned2, quality, err := service.AlignPair(original, shifted)
+	if err != nil {
+		t.Fatalf("AlignPair failed: %v", err)
+	}
+
+	if quality < 0.9 {
+		t.Errorf("Expected high alignment quality for a shifted copy, got %.3f", quality)
+	}
+
+	img1, err := gocv.IMDecode(aligned1, gocv.IMReadGrayScale)
+	if err != nil {
+		t.Fatalf("failed to decode aligned image 1: %v", err)
+	}
+	defer img1.Close()
+
+	img2, err := gocv.IMDecode(aligned2, gocv.IMReadGrayScale)
+	if err != nil {
+		t.Fatalf("failed to decode aligned image 2: %v", err)
+	}
+	defer img2.Close()
+
+	if img1.Rows() != img2.Rows() || img1.Cols() != img2.Cols() {
+		t.Fatalf("Aligned images differ in size: %dx%d vs %dx%d", img1.Cols(), img1.Rows(), img2.Cols(), img2.Rows())
+	}
+
+	// Compare the interior so the border exposed by the shift is ignored
+	interior := image.Rect(100, 100, img1.Cols()-100, img1.Rows()-100)
+	region1 := img1.Region(interior)
+	defer region1.Close()
+	region2 := img2.Region(interior)
+	defer region2.Close()
+
+	diff := gocv.NewMat()
+	defer diff.Close()
+	gocv.AbsDiff(region1, region2, &diff)
+
+	if meanDiff := diff.Mean().Val1; meanDiff > 5.0 {
+		t.Errorf("Aligned images do not overlap closely: mean abs diff %.2f", meanDiff)
+	}
+}
@@ -0,0 +1,59 @@
+package test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/comparator"
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+	"github.com/choff5507/vehicle-image-comparison/internal/testutil"
+	"github.com/choff5507/vehicle-image-comparison/pkg/vehiclecompare"
+	"gocv.io/x/gocv"
+)
+
+// rearWithExtraLights renders the reference rear view shifted by dx, with a red light
+// added at each of centers (before the shift), and extracts its features with service
+func rearWithExtraLights(t testing.TB, service *vehiclecompare.VehicleComparisonService, dx int, centers ...image.Point) models.VehicleFeatures {
+	spec := testutil.DefaultSpec()
+	spec.OffsetX = dx
+	img := testutil.RearVehicle(spec)
+	defer img.Close()
+
+	for _, c := range centers {
+		x := c.X + dx
+		gocv.Rectangle(&img, image.Rect(x-20, c.Y-30, x+20, c.Y+30), color.RGBA{R: 220, G: 20, B: 20}, -1)
+	}
+
+	features, err := service.ExtractFeatures(img)
+	if err != nil {
+		t.Fatalf("Feature extraction failed: %v", err)
+	}
+	return features
+}
+
+func TestLightArrangementOnExtractedFeatures(t *testing.T) {
+	service := vehiclecompare.NewVehicleComparisonService()
+	engine := comparator.NewComparisonEngine()
+
+	// A high brake light off to the left and a low reverse light to the right of the
+	// taillights, and the same layout mirrored
+	layout := []image.Point{{X: 560, Y: 220}, {X: 820, Y: 540}}
+	mirrored := []image.Point{{X: testutil.ImageWidth - 560, Y: 220}, {X: testutil.ImageWidth - 820, Y: 540}}
+
+	reference := rearWithExtraLights(t, service, 0, layout...)
+	if n := len(reference.LightPatterns.LightElements); n != 4 {
+		t.Fatalf("Expected four light elements, got %d", n)
+	}
+
+	same := engine.SubScores(reference, rearWithExtraLights(t, service, 40, layout...)).Lights.Arrangement
+	if same < 0.9 {
+		t.Errorf("Expected the shifted layout to keep its arrangement, got %.3f", same)
+	}
+
+	// Placeholder positions gave every set of four lights the same arrangement
+	other := engine.SubScores(reference, rearWithExtraLights(t, service, 0, mirrored...)).Lights.Arrangement
+	if other >= same {
+		t.Errorf("Expected the mirrored layout (%.3f) to score below the shifted one (%.3f)", other, same)
+	}
+}
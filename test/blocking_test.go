@@ -0,0 +1,40 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/preprocessor"
+	"gocv.io/x/gocv"
+)
+
+func TestHeavyJPEGCompressionIsDetectedAsBlocking(t *testing.T) {
+	clean := newTestVehicle(0, 0)
+	defer clean.Close()
+
+	// Sensor noise gives the clean image texture between the block grid too
+	noise := gocv.NewMatWithSize(clean.Rows(), clean.Cols(), clean.Type())
+	defer noise.Close()
+	gocv.RandN(&noise, gocv.NewScalar(0, 0, 0, 0), gocv.NewScalar(6, 6, 6, 0))
+	gocv.Add(clean, noise, &clean)
+
+	buf, err := gocv.IMEncodeWithParams(gocv.JPEGFileExt, clean, []int{gocv.IMWriteJpegQuality, 5})
+	if err != nil {
+		t.Fatalf("failed to compress image: %v", err)
+	}
+	compressed, err := gocv.IMDecode(buf.GetBytes(), gocv.IMReadColor)
+	buf.Close()
+	if err != nil {
+		t.Fatalf("failed to decode compressed image: %v", err)
+	}
+	defer compressed.Close()
+
+	cleanBlocking := preprocessor.MeasureBlockArtifacts(clean)
+	compressedBlocking := preprocessor.MeasureBlockArtifacts(compressed)
+
+	if cleanBlocking > 0.2 {
+		t.Errorf("Expected little blocking in the clean image, got %.3f", cleanBlocking)
+	}
+	if compressedBlocking < cleanBlocking+0.3 {
+		t.Errorf("Expected heavy compression to be detected: %.3f compressed, %.3f clean", compressedBlocking, cleanBlocking)
+	}
+}
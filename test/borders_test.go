@@ -0,0 +1,56 @@
+package test
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/extractor"
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+	"github.com/choff5507/vehicle-image-comparison/internal/preprocessor"
+	"gocv.io/x/gocv"
+)
+
+func TestTrimBordersRemovesLetterbox(t *testing.T) {
+	vehicle := newTestVehicle(0, 0)
+	defer vehicle.Close()
+
+	// DVR-style letterbox with a timestamp burned into the bottom band
+	padded := gocv.NewMat()
+	defer padded.Close()
+	gocv.CopyMakeBorder(vehicle, &padded, 60, 50, 100, 80, gocv.BorderConstant, color.RGBA{})
+	gocv.PutText(&padded, "2024-03-14 08:15:42 CAM02", image.Pt(120, padded.Rows()-15),
+		gocv.FontHersheySimplex, 0.8, color.RGBA{R: 255, G: 255, B: 255}, 2)
+
+	trimmed, trim := preprocessor.TrimBorders(padded)
+	defer trimmed.Close()
+
+	expected := models.BorderTrim{Top: 60, Bottom: 50, Left: 100, Right: 80}
+	if trim != expected {
+		t.Errorf("Expected trim %+v, got %+v", expected, trim)
+	}
+	if trimmed.Cols() != vehicle.Cols() || trimmed.Rows() != vehicle.Rows() {
+		t.Errorf("Expected the trimmed image to be %dx%d, got %dx%d",
+			vehicle.Cols(), vehicle.Rows(), trimmed.Cols(), trimmed.Rows())
+	}
+
+	geometric := extractor.NewGeometricExtractor()
+	original, err := geometric.ExtractGeometricFeatures(vehicle, gocv.Mat{}, models.ViewFront)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recovered, err := geometric.ExtractGeometricFeatures(trimmed, gocv.Mat{}, models.ViewFront)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(original.VehicleProportions.WidthHeightRatio-recovered.VehicleProportions.WidthHeightRatio) > 0.01 {
+		t.Errorf("Expected trimmed proportions to match the unpadded image: %.3f vs %.3f",
+			recovered.VehicleProportions.WidthHeightRatio, original.VehicleProportions.WidthHeightRatio)
+	}
+
+	// A frame without borders is left alone
+	if _, trim := preprocessor.TrimBorders(vehicle); trim != (models.BorderTrim{}) {
+		t.Errorf("Expected no trim for an unpadded image, got %+v", trim)
+	}
+}
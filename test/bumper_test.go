@@ -0,0 +1,39 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/testutil"
+	"github.com/choff5507/vehicle-image-comparison/pkg/vehiclecompare"
+)
+
+func TestDifferentBumperOutlinesLowerContourScore(t *testing.T) {
+	dir := t.TempDir()
+	path := writeTestVehicle(t, dir, "vehicle.png", 0, 0)
+	copyPath := writeTestVehicle(t, dir, "copy.png", 0, 0)
+
+	// Same vehicle with a shallower lower body
+	spec := testutil.DefaultSpec()
+	spec.BodyBottom = 710
+	other := testutil.FrontVehicle(spec)
+	defer other.Close()
+	otherPath := writeTestImage(t, dir, "other.png", other)
+
+	service := vehiclecompare.NewVehicleComparisonService(vehiclecompare.WithSubScores(true))
+
+	same, err := service.CompareVehicleImages(path, copyPath)
+	if err != nil {
+		t.Fatalf("Comparison failed: %v", err)
+	}
+	different, err := service.CompareVehicleImages(path, otherPath)
+	if err != nil {
+		t.Fatalf("Comparison failed: %v", err)
+	}
+
+	if same.SubScores.Bumper.Contour != 1.0 {
+		t.Errorf("Expected identical outlines to score 1.0, got %.3f", same.SubScores.Bumper.Contour)
+	}
+	if different.SubScores.Bumper.Contour >= 1.0 {
+		t.Errorf("Expected different outlines to score below 1.0, got %.3f", different.SubScores.Bumper.Contour)
+	}
+}
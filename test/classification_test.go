@@ -0,0 +1,77 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+	"github.com/choff5507/vehicle-image-comparison/pkg/vehiclecompare"
+	"gocv.io/x/gocv"
+)
+
+func TestNonVehicleImageIsUnclassifiable(t *testing.T) {
+	dir := t.TempDir()
+
+	// A featureless frame has no lights, grille or bumper to classify
+	blank := gocv.NewMatWithSizeFromScalar(gocv.NewScalar(128, 128, 128, 0), 480, 640, gocv.MatTypeCV8UC3)
+	defer blank.Close()
+	blankPath := writeTestImage(t, dir, "blank.png", blank)
+	vehiclePath := writeTestVehicle(t, dir, "vehicle.png", 0, 0)
+
+	service := vehiclecompare.NewVehicleComparisonService()
+
+	_, err := service.CompareVehicleImages(blankPath, vehiclePath)
+
+	var unclassifiable *vehiclecompare.ErrUnclassifiable
+	if !errors.As(err, &unclassifiable) {
+		t.Fatalf("Expected ErrUnclassifiable, got %v", err)
+	}
+
+	if unclassifiable.View != models.ViewUnknown || unclassifiable.ViewConfidence >= 0.5 {
+		t.Errorf("Expected an unknown, low-confidence view guess, got %s (%.2f)", unclassifiable.View, unclassifiable.ViewConfidence)
+	}
+	if unclassifiable.LightingConfidence <= 0 {
+		t.Errorf("Expected a populated lighting guess, got %s (%.2f)", unclassifiable.Lighting, unclassifiable.LightingConfidence)
+	}
+}
+
+func TestProcessingInfoReportsPerImageClassification(t *testing.T) {
+	dir := t.TempDir()
+	path1 := writeTestVehicle(t, dir, "vehicle1.png", 0, 0)
+	path2 := writeTestVehicle(t, dir, "vehicle2.png", 6, 4)
+
+	var traces bytes.Buffer
+	service := vehiclecompare.NewVehicleComparisonService(vehiclecompare.WithComparisonTrace(&traces))
+
+	result, err := service.CompareVehicleImages(path1, path2)
+	if err != nil {
+		t.Fatalf("Comparison failed: %v", err)
+	}
+
+	var trace vehiclecompare.ComparisonTrace
+	if err := json.Unmarshal(bytes.TrimSpace(traces.Bytes()), &trace); err != nil {
+		t.Fatalf("Trace does not decode: %v", err)
+	}
+
+	// The trace records each image as processImage classified it, after extraction
+	info := result.ProcessingInfo
+	reported := [2]vehiclecompare.TraceClassification{
+		{View: info.Image1View, Lighting: info.Image1Lighting},
+		{View: info.Image2View, Lighting: info.Image2Lighting},
+	}
+	for i, classified := range trace.Classification {
+		if reported[i].View != classified.View || reported[i].Lighting != classified.Lighting {
+			t.Errorf("Image %d: expected %s/%s, got %s/%s", i+1,
+				classified.View, classified.Lighting, reported[i].View, reported[i].Lighting)
+		}
+	}
+	if info.Image1View == models.ViewUnknown || info.Image1Lighting == models.LightingUnknown {
+		t.Errorf("Expected a known view and lighting, got %s/%s", info.Image1View, info.Image1Lighting)
+	}
+	if info.ViewConsistency != (info.Image1View == info.Image2View) ||
+		info.LightingConsistency != (info.Image1Lighting == info.Image2Lighting) {
+		t.Errorf("Consistency flags disagree with the per-image classifications: %+v", info)
+	}
+}
@@ -0,0 +1,52 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/choff5507/vehicle-image-comparison/pkg/vehiclecompare"
+)
+
+func TestCompareConfigsReportsDecisionFlip(t *testing.T) {
+	dir := t.TempDir()
+	path1 := writeTestVehicle(t, dir, "vehicle1.png", 0, 0)
+	path2 := writeTestVehicle(t, dir, "vehicle2.png", 6, 4)
+
+	lenient := vehiclecompare.DefaultEngineConfig()
+	lenient.DaylightThreshold, lenient.InfraredThreshold = 0.05, 0.05
+	strict := vehiclecompare.DefaultEngineConfig()
+	strict.DaylightThreshold, strict.InfraredThreshold = 0.99, 0.99
+
+	service := vehiclecompare.NewVehicleComparisonService()
+
+	diff, err := service.CompareConfigs(path1, path2, lenient, strict)
+	if err != nil {
+		t.Fatalf("Config comparison failed: %v", err)
+	}
+
+	if !diff.ResultA.IsSameVehicle || diff.ResultB.IsSameVehicle {
+		t.Fatalf("Expected a match under the lenient threshold only, got %v and %v",
+			diff.ResultA.IsSameVehicle, diff.ResultB.IsSameVehicle)
+	}
+	if !diff.DecisionChanged {
+		t.Errorf("Expected the diff to report the decision change")
+	}
+
+	// Only the thresholds differ, so the scores do not move
+	scores := diff.ScoreDeltas
+	for name, delta := range map[string]float64{
+		"similarity":    diff.SimilarityDelta,
+		"geometric":     scores.GeometricSimilarity,
+		"light pattern": scores.LightPatternSimilarity,
+		"bumper":        scores.BumperSimilarity,
+		"color":         scores.ColorSimilarity,
+		"thermal":       scores.ThermalSimilarity,
+	} {
+		if delta != 0 {
+			t.Errorf("Expected no %s change between thresholds, got %+.3f", name, delta)
+		}
+	}
+
+	if _, err := service.CompareConfigs(path1, path2, lenient, vehiclecompare.EngineConfig{}); err == nil {
+		t.Errorf("Expected an invalid config B to be rejected")
+	}
+}
@@ -0,0 +1,50 @@
+package test
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/choff5507/vehicle-image-comparison/pkg/vehiclecompare"
+	"gocv.io/x/gocv"
+)
+
+func TestTruncatedJPEGReturnsErrImageDecode(t *testing.T) {
+	dir := t.TempDir()
+
+	vehicle := newTestVehicle(0, 0)
+	defer vehicle.Close()
+	encoded, err := gocv.IMEncode(gocv.JPEGFileExt, vehicle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer encoded.Close()
+
+	// SOI marker and the start of the first segment, cut off mid-header
+	truncated := append([]byte(nil), encoded.GetBytes()[:20]...)
+	truncatedPath := filepath.Join(dir, "truncated.jpg")
+	if err := os.WriteFile(truncatedPath, truncated, 0644); err != nil {
+		t.Fatal(err)
+	}
+	vehiclePath := writeTestVehicle(t, dir, "vehicle.png", 0, 0)
+
+	service := vehiclecompare.NewVehicleComparisonService()
+
+	_, err = service.CompareVehicleImages(truncatedPath, vehiclePath)
+	if !errors.Is(err, vehiclecompare.ErrImageDecode) {
+		t.Errorf("Expected ErrImageDecode from file input, got %v", err)
+	}
+
+	vehicleData, err := os.ReadFile(vehiclePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = service.CompareVehicleImagesFromBase64(
+		base64.StdEncoding.EncodeToString(truncated),
+		base64.StdEncoding.EncodeToString(vehicleData))
+	if !errors.Is(err, vehiclecompare.ErrImageDecode) {
+		t.Errorf("Expected ErrImageDecode from base64 input, got %v", err)
+	}
+}
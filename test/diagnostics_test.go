@@ -0,0 +1,95 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/choff5507/vehicle-image-comparison/pkg/vehiclecompare"
+	"gocv.io/x/gocv"
+)
+
+func TestDiagnosticsCollectorAggregates(t *testing.T) {
+	dir := t.TempDir()
+	vehicle1 := writeTestVehicle(t, dir, "vehicle1.png", 0, 0)
+	vehicle2 := writeTestVehicle(t, dir, "vehicle2.png", 6, 4)
+
+	tiny := gocv.NewMatWithSizeFromScalar(gocv.NewScalar(128, 128, 128, 0), 10, 10, gocv.MatTypeCV8UC3)
+	defer tiny.Close()
+	tinyPath := writeTestImage(t, dir, "tiny.png", tiny)
+	missingPath := filepath.Join(dir, "missing.png")
+
+	// Traces hold the features the collector should have aggregated
+	var traces bytes.Buffer
+	collector := vehiclecompare.NewDiagnosticsCollector()
+	service := vehiclecompare.NewVehicleComparisonService(
+		vehiclecompare.WithDiagnostics(collector),
+		vehiclecompare.WithComparisonTrace(&traces),
+	)
+
+	for _, pair := range [][2]string{{vehicle1, vehicle2}, {vehicle2, vehicle1}} {
+		if _, err := service.CompareVehicleImages(pair[0], pair[1]); err != nil {
+			t.Fatalf("Comparison failed: %v", err)
+		}
+	}
+	if _, err := service.CompareVehicleImages(tinyPath, vehicle1); err == nil {
+		t.Fatal("Expected the tiny image to be rejected")
+	}
+	if _, err := service.CompareVehicleImages(vehicle1, missingPath); err == nil {
+		t.Fatal("Expected the missing image to be rejected")
+	}
+
+	lights, plates, plateConfidence := 0, 0, 0.0
+	for _, line := range bytes.Split(bytes.TrimSpace(traces.Bytes()), []byte("\n")) {
+		var trace vehiclecompare.ComparisonTrace
+		if err := json.Unmarshal(line, &trace); err != nil {
+			t.Fatalf("Trace does not decode: %v", err)
+		}
+		for _, features := range trace.Features {
+			lights += len(features.LightPatterns.LightElements)
+			if ir := features.InfraredFeatures; ir != nil && ir.IRSignature != nil {
+				plates++
+				plateConfidence += ir.IRSignature.PlateRegion.Confidence
+			}
+		}
+	}
+
+	summary := collector.Summary()
+	if summary.Comparisons != 4 {
+		t.Errorf("Expected 4 comparisons, got %d", summary.Comparisons)
+	}
+	if summary.ImagesExtracted != 4 {
+		t.Errorf("Expected 4 extracted images, got %d", summary.ImagesExtracted)
+	}
+	if want := float64(lights) / 4.0; math.Abs(summary.MeanLightElements-want) > 1e-9 {
+		t.Errorf("Expected %.3f light elements per image, got %.3f", want, summary.MeanLightElements)
+	}
+	if summary.PlatesFound != plates {
+		t.Errorf("Expected %d plates, got %d", plates, summary.PlatesFound)
+	}
+	if plates > 0 && math.Abs(summary.MeanPlateConfidence-plateConfidence/float64(plates)) > 1e-9 {
+		t.Errorf("Expected mean plate confidence %.3f, got %.3f", plateConfidence/float64(plates), summary.MeanPlateConfidence)
+	}
+
+	for reason, want := range map[vehiclecompare.RejectionReason]int{
+		vehiclecompare.RejectionTooSmall: 1,
+		vehiclecompare.RejectionDecode:   1,
+	} {
+		if summary.Rejections[reason] != want {
+			t.Errorf("Expected %d %q rejections, got %d", want, reason, summary.Rejections[reason])
+		}
+		if summary.RejectionRates[reason] != 0.25 {
+			t.Errorf("Expected a %q rejection rate of 0.25, got %.3f", reason, summary.RejectionRates[reason])
+		}
+	}
+	if len(summary.Rejections) != 2 {
+		t.Errorf("Expected only too_small and decode rejections, got %v", summary.Rejections)
+	}
+
+	collector.Reset()
+	if summary := collector.Summary(); summary.Comparisons != 0 || len(summary.Rejections) != 0 {
+		t.Errorf("Expected an empty summary after Reset, got %+v", summary)
+	}
+}
@@ -0,0 +1,121 @@
+package test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/preprocessor"
+	"github.com/choff5507/vehicle-image-comparison/internal/testutil"
+	"github.com/choff5507/vehicle-image-comparison/pkg/vehiclecompare"
+	"gocv.io/x/gocv"
+)
+
+// gammaShift returns a copy of img with gamma applied, as a capture with a different
+// exposure curve would record it. The caller owns the returned Mat.
+func gammaShift(t testing.TB, img gocv.Mat, gamma float64) gocv.Mat {
+	table := make([]byte, 256)
+	for i := range table {
+		table[i] = byte(math.Round(255 * math.Pow(float64(i)/255, gamma)))
+	}
+	lut, err := gocv.NewMatFromBytes(1, 256, gocv.MatTypeCV8U, table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lut.Close()
+
+	shifted := gocv.NewMat()
+	gocv.LUT(img, lut, &shifted)
+	return shifted
+}
+
+func meanIntensityDifference(img1, img2 gocv.Mat) float64 {
+	diff := gocv.NewMat()
+	defer diff.Close()
+	gocv.AbsDiff(img1, img2, &diff)
+	mean := diff.Mean()
+	return (mean.Val1 + mean.Val2 + mean.Val3) / 3
+}
+
+func TestMatchExposureUndoesGammaShift(t *testing.T) {
+	original := newTestVehicle(0, 0)
+	defer original.Close()
+	shifted := gammaShift(t, original, 0.6)
+	defer shifted.Close()
+
+	matched := preprocessor.MatchExposure(shifted, original)
+	defer matched.Close()
+
+	before := meanIntensityDifference(original, shifted)
+	after := meanIntensityDifference(original, matched)
+	if after >= before/2 {
+		t.Errorf("Expected matching to remove most of the exposure difference, mean difference %.1f before and %.1f after", before, after)
+	}
+}
+
+func TestExposureMatchingImprovesSimilarity(t *testing.T) {
+	dir := t.TempDir()
+	original := newTestVehicle(0, 0)
+	defer original.Close()
+	shifted := gammaShift(t, original, 0.6)
+	defer shifted.Close()
+
+	path1 := writeTestImage(t, dir, "original.png", original)
+	path2 := writeTestImage(t, dir, "shifted.png", shifted)
+
+	unmatched, err := vehiclecompare.NewVehicleComparisonService().CompareVehicleImages(path1, path2)
+	if err != nil {
+		t.Fatalf("Comparison failed: %v", err)
+	}
+
+	service := vehiclecompare.NewVehicleComparisonService(vehiclecompare.WithExposureMatching(true))
+	matched, err := service.CompareVehicleImages(path1, path2)
+	if err != nil {
+		t.Fatalf("Comparison with exposure matching failed: %v", err)
+	}
+
+	// Without a margin the assertion would pass when matching changed nothing
+	if matched.SimilarityScore < unmatched.SimilarityScore+0.02 {
+		t.Errorf("Expected exposure matching to raise similarity by at least 0.02, %.3f without and %.3f with",
+			unmatched.SimilarityScore, matched.SimilarityScore)
+	}
+	if matched.SimilarityScore < 0.9 {
+		t.Errorf("Expected a gamma-shifted copy to match closely after exposure matching, got %.3f", matched.SimilarityScore)
+	}
+}
+
+func TestCrossCameraComparisonEndToEnd(t *testing.T) {
+	dir := t.TempDir()
+
+	reference := testutil.FrontVehicle(testutil.DefaultSpec())
+	defer reference.Close()
+	// A second camera with a brighter baseline records both candidates
+	same := gammaShift(t, reference, 0.6)
+	defer same.Close()
+	differentCapture := testutil.FrontVehicle(differentSpec())
+	defer differentCapture.Close()
+	different := gammaShift(t, differentCapture, 0.6)
+	defer different.Close()
+
+	referencePath := writeTestImage(t, dir, "reference.png", reference)
+	samePath := writeTestImage(t, dir, "same.png", same)
+	differentPath := writeTestImage(t, dir, "different.png", different)
+
+	service := vehiclecompare.NewVehicleComparisonService(vehiclecompare.WithSameCamera(false))
+
+	sameResult, err := service.CompareVehicleImages(referencePath, samePath)
+	if err != nil {
+		t.Fatalf("Cross-camera comparison of the same vehicle failed: %v", err)
+	}
+	if !sameResult.IsSameVehicle {
+		t.Errorf("Expected the same vehicle to match across cameras, got similarity %.3f", sameResult.SimilarityScore)
+	}
+
+	differentResult, err := service.CompareVehicleImages(referencePath, differentPath)
+	if err != nil {
+		t.Fatalf("Cross-camera comparison of different vehicles failed: %v", err)
+	}
+	if differentResult.SimilarityScore >= sameResult.SimilarityScore {
+		t.Errorf("Expected different vehicles (%.3f) to score below the same vehicle (%.3f) across cameras",
+			differentResult.SimilarityScore, sameResult.SimilarityScore)
+	}
+}
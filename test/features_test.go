@@ -0,0 +1,63 @@
+package test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+	"github.com/choff5507/vehicle-image-comparison/pkg/vehiclecompare"
+	"gocv.io/x/gocv"
+)
+
+func TestCachedFeaturesCompareLikeFreshOnes(t *testing.T) {
+	query := newTestVehicle(0, 0)
+	defer query.Close()
+	catalog := newTestVehicle(6, 4)
+	defer catalog.Close()
+
+	service := vehiclecompare.NewVehicleComparisonService()
+
+	queryFeatures, err := service.ExtractFeatures(query)
+	if err != nil {
+		t.Fatalf("Query extraction failed: %v", err)
+	}
+	catalogFeatures, err := service.ExtractFeatures(catalog)
+	if err != nil {
+		t.Fatalf("Catalog extraction failed: %v", err)
+	}
+
+	// Persist and reload the catalog side, as a cache would
+	stored, err := json.Marshal(catalogFeatures)
+	if err != nil {
+		t.Fatalf("Features do not encode: %v", err)
+	}
+	var cached models.VehicleFeatures
+	if err := json.Unmarshal(stored, &cached); err != nil {
+		t.Fatalf("Features do not decode: %v", err)
+	}
+
+	fresh, err := service.CompareFeatures(queryFeatures, catalogFeatures)
+	if err != nil {
+		t.Fatalf("Comparison failed: %v", err)
+	}
+	fromCache, err := service.CompareFeatures(queryFeatures, cached)
+	if err != nil {
+		t.Fatalf("Comparison with cached features failed: %v", err)
+	}
+
+	if fromCache.SimilarityScore != fresh.SimilarityScore || fromCache.Decision != fresh.Decision {
+		t.Errorf("Expected cached features to score %.4f (%v), got %.4f (%v)",
+			fresh.SimilarityScore, fresh.Decision, fromCache.SimilarityScore, fromCache.Decision)
+	}
+}
+
+func TestExtractFeaturesRejectsEmptyImage(t *testing.T) {
+	empty := gocv.NewMat()
+	defer empty.Close()
+
+	service := vehiclecompare.NewVehicleComparisonService()
+	if _, err := service.ExtractFeatures(empty); !errors.Is(err, vehiclecompare.ErrImageDecode) {
+		t.Errorf("Expected ErrImageDecode, got %v", err)
+	}
+}
@@ -0,0 +1,24 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/choff5507/vehicle-image-comparison/pkg/vehiclecompare"
+)
+
+func TestSupportedFormatsIncludesJPEGAndPNG(t *testing.T) {
+	formats := vehiclecompare.SupportedFormats()
+
+	for _, required := range []string{"jpeg", "png"} {
+		found := false
+		for _, format := range formats {
+			if format == required {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("Expected %q in supported formats, got %v", required, formats)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package test
+
+import (
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/testutil"
+	"github.com/choff5507/vehicle-image-comparison/pkg/vehiclecompare"
+	"gocv.io/x/gocv"
+)
+
+// writeTestGIF writes frames as an animated GIF to dir and returns its path. Frames
+// are mapped to the Plan 9 palette without dithering, so the same Mat always
+// quantizes to the same pixels.
+func writeTestGIF(t testing.TB, dir, name string, frames ...gocv.Mat) string {
+	animation := &gif.GIF{}
+	for _, frame := range frames {
+		img, err := frame.ToImage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		paletted := image.NewPaletted(img.Bounds(), palette.Plan9)
+		draw.Draw(paletted, paletted.Bounds(), img, img.Bounds().Min, draw.Src)
+
+		animation.Image = append(animation.Image, paletted)
+		animation.Delay = append(animation.Delay, 10)
+	}
+
+	path := filepath.Join(dir, name)
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if err := gif.EncodeAll(file, animation); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestAnimatedGIFUsesFirstFrame(t *testing.T) {
+	dir := t.TempDir()
+
+	reference := testutil.FrontVehicle(testutil.DefaultSpec())
+	defer reference.Close()
+	different := testutil.FrontVehicle(differentSpec())
+	defer different.Close()
+
+	animated := writeTestGIF(t, dir, "animated.gif", reference, different)
+	firstPath := writeTestGIF(t, dir, "first.gif", reference)
+	secondPath := writeTestGIF(t, dir, "second.gif", different)
+
+	service := vehiclecompare.NewVehicleComparisonService()
+
+	result, err := service.CompareVehicleImages(animated, firstPath)
+	if err != nil {
+		t.Fatalf("Comparison against the first frame failed: %v", err)
+	}
+	if !result.IsSameVehicle {
+		t.Errorf("Expected the animated GIF to match its first frame, got score %.3f", result.SimilarityScore)
+	}
+
+	result, err = service.CompareVehicleImages(animated, secondPath)
+	if err == nil && result.IsSameVehicle {
+		t.Errorf("Expected the animated GIF not to match its second frame, got score %.3f", result.SimilarityScore)
+	}
+}
+
+func TestAnimatedGIFDecodesOnlyTheFirstFrame(t *testing.T) {
+	dir := t.TempDir()
+
+	reference := testutil.FrontVehicle(testutil.DefaultSpec())
+	defer reference.Close()
+	different := testutil.FrontVehicle(differentSpec())
+	defer different.Close()
+
+	// Cut off mid-way through the second frame: only decoding it would fail
+	data, err := os.ReadFile(writeTestGIF(t, dir, "animated.gif", reference, different))
+	if err != nil {
+		t.Fatal(err)
+	}
+	truncated := filepath.Join(dir, "truncated.gif")
+	if err := os.WriteFile(truncated, data[:len(data)-200], 0o644); err != nil {
+		t.Fatal(err)
+	}
+	firstPath := writeTestGIF(t, dir, "first.gif", reference)
+
+	result, err := vehiclecompare.NewVehicleComparisonService().CompareVehicleImages(truncated, firstPath)
+	if err != nil {
+		t.Fatalf("Expected the unused second frame not to be decoded, got %v", err)
+	}
+	if !result.IsSameVehicle {
+		t.Errorf("Expected the GIF to match its first frame, got score %.3f", result.SimilarityScore)
+	}
+}
+
+func TestBestFrameSelectionUsesSharpestFrame(t *testing.T) {
+	dir := t.TempDir()
+
+	reference := testutil.FrontVehicle(testutil.DefaultSpec())
+	defer reference.Close()
+	different := testutil.FrontVehicle(differentSpec())
+	defer different.Close()
+
+	// A motion-blurred first frame of another vehicle, then a sharp reference frame
+	blurred := gocv.NewMat()
+	defer blurred.Close()
+	gocv.GaussianBlur(different, &blurred, image.Pt(31, 31), 0, 0, gocv.BorderDefault)
+
+	animated := writeTestGIF(t, dir, "animated.gif", blurred, reference)
+	referencePath := writeTestGIF(t, dir, "reference.gif", reference)
+
+	service := vehiclecompare.NewVehicleComparisonService(vehiclecompare.WithBestFrameSelection(true))
+
+	result, err := service.CompareVehicleImages(animated, referencePath)
+	if err != nil {
+		t.Fatalf("Comparison failed: %v", err)
+	}
+	if !result.IsSameVehicle {
+		t.Errorf("Expected the sharp second frame to be compared, got score %.3f", result.SimilarityScore)
+	}
+}
@@ -0,0 +1,76 @@
+package test
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/choff5507/vehicle-image-comparison/pkg/vehiclecompare"
+	"gocv.io/x/gocv"
+)
+
+// encodeSeedImage encodes a small solid image for use in the fuzz seed corpus
+func encodeSeedImage(f *testing.F, ext gocv.FileExt, rows, cols int) []byte {
+	img := gocv.NewMatWithSizeFromScalar(gocv.NewScalar(90, 120, 150, 0), rows, cols, gocv.MatTypeCV8UC3)
+	defer img.Close()
+
+	buf, err := gocv.IMEncode(ext, img)
+	if err != nil {
+		f.Fatalf("failed to encode seed image: %v", err)
+	}
+	defer buf.Close()
+
+	data := make([]byte, buf.Len())
+	copy(data, buf.GetBytes())
+	return data
+}
+
+func addDecodeSeeds(f *testing.F) {
+	png := encodeSeedImage(f, gocv.PNGFileExt, 8, 8)
+	jpeg := encodeSeedImage(f, gocv.JPEGFileExt, 16, 16)
+
+	f.Add(png)
+	f.Add(jpeg)
+	f.Add(png[:len(png)/2])   // Truncated PNG
+	f.Add(jpeg[:len(jpeg)/3]) // Truncated JPEG
+	f.Add([]byte{})
+	f.Add([]byte("not an image"))
+	f.Add([]byte{0x89, 'P', 'N', 'G', 0x0d, 0x0a, 0x1a, 0x0a}) // PNG signature only
+	f.Add([]byte{0xff, 0xd8, 0xff, 0xe0, 0x00, 0x10})          // JPEG SOI + APP0 only
+}
+
+// FuzzDecodeBase64Image feeds arbitrary bytes through the base64 comparison entry
+// point. Any input may be rejected with an error, but none may panic.
+func FuzzDecodeBase64Image(f *testing.F) {
+	addDecodeSeeds(f)
+
+	service := vehiclecompare.NewVehicleComparisonService()
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		encoded := base64.StdEncoding.EncodeToString(data)
+
+		result, err := service.CompareVehicleImagesFromBase64(encoded, encoded)
+		if err == nil && result == nil {
+			t.Fatal("nil result without an error")
+		}
+
+		// Raw (non-base64) input must also be rejected cleanly
+		service.CompareVehicleImagesFromBase64(string(data), encoded)
+	})
+}
+
+// FuzzIMDecode exercises the underlying byte-decode path directly
+func FuzzIMDecode(f *testing.F) {
+	addDecodeSeeds(f)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		img, err := gocv.IMDecode(data, gocv.IMReadColor)
+		if err != nil {
+			return
+		}
+		defer img.Close()
+
+		if !img.Empty() && (img.Rows() <= 0 || img.Cols() <= 0) {
+			t.Fatalf("decoded non-empty Mat with invalid size %dx%d", img.Cols(), img.Rows())
+		}
+	})
+}
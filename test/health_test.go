@@ -0,0 +1,15 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/choff5507/vehicle-image-comparison/pkg/vehiclecompare"
+)
+
+func TestHealthyInWorkingEnvironment(t *testing.T) {
+	service := vehiclecompare.NewVehicleComparisonService()
+
+	if err := service.Healthy(); err != nil {
+		t.Fatalf("Expected a healthy OpenCV, got %v", err)
+	}
+}
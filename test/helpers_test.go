@@ -0,0 +1,33 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/testutil"
+	"gocv.io/x/gocv"
+)
+
+// newTestVehicle draws the reference front-view vehicle offset by (dx, dy).
+// The caller owns the returned Mat.
+func newTestVehicle(dx, dy int) gocv.Mat {
+	spec := testutil.DefaultSpec()
+	spec.OffsetX = dx
+	spec.OffsetY = dy
+	return testutil.FrontVehicle(spec)
+}
+
+// writeTestVehicle writes a test vehicle offset by (dx, dy) to dir and returns its path
+func writeTestVehicle(t testing.TB, dir, name string, dx, dy int) string {
+	img := newTestVehicle(dx, dy)
+	defer img.Close()
+
+	return writeTestImage(t, dir, name, img)
+}
+
+func writeTestImage(t testing.TB, dir, name string, img gocv.Mat) string {
+	path, err := testutil.WriteImage(dir, name, img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
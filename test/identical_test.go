@@ -0,0 +1,115 @@
+package test
+
+import (
+	"encoding/base64"
+	"errors"
+	"math"
+	"os"
+	"testing"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+	"github.com/choff5507/vehicle-image-comparison/pkg/vehiclecompare"
+	"gocv.io/x/gocv"
+)
+
+func TestSamePathShortCircuits(t *testing.T) {
+	dir := t.TempDir()
+	path := writeTestVehicle(t, dir, "vehicle.png", 0, 0)
+
+	// Counts how often the image is processed
+	calls := 0
+	service := vehiclecompare.NewVehicleComparisonService(vehiclecompare.WithPreprocessor(func(img gocv.Mat) (gocv.Mat, error) {
+		calls++
+		return img, nil
+	}))
+
+	result, err := service.CompareVehicleImages(path, path)
+	if err != nil {
+		t.Fatalf("Comparison failed: %v", err)
+	}
+
+	if !result.ProcessingInfo.IdenticalInput {
+		t.Errorf("Expected the identical-input flag")
+	}
+	if calls != 1 {
+		t.Errorf("Expected the image to be processed once, got %d", calls)
+	}
+	if result.SimilarityScore != 1.0 || !result.IsSameVehicle || result.Decision != models.DecisionSame {
+		t.Errorf("Expected a same-vehicle result with similarity 1.0, got %v at %.3f", result.Decision, result.SimilarityScore)
+	}
+	if result.ConfidenceLevel != models.ConfidenceHigh {
+		t.Errorf("Expected high confidence, got %v", result.ConfidenceLevel)
+	}
+	if result.ProcessingInfo.Image1View != result.ProcessingInfo.Image2View || result.ProcessingInfo.Image1View == models.ViewUnknown {
+		t.Errorf("Expected the image's classified view on both sides, got %+v", result.ProcessingInfo)
+	}
+	if result.ProcessingInfo.FeaturePath == models.FeaturePathUnknown {
+		t.Errorf("Expected the feature path of the image's lighting, got %v", result.ProcessingInfo.FeaturePath)
+	}
+	if info := result.ProcessingInfo; info.DecisionThreshold <= 0 || math.Abs(info.ThresholdMargin-(1.0-info.DecisionThreshold)) > 1e-9 {
+		t.Errorf("Expected the lighting's threshold and the margin above it, got %.3f and %.3f", info.DecisionThreshold, info.ThresholdMargin)
+	}
+}
+
+func TestSameBase64ShortCircuits(t *testing.T) {
+	dir := t.TempDir()
+	data, err := os.ReadFile(writeTestVehicle(t, dir, "vehicle.png", 0, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded := base64.StdEncoding.EncodeToString(data)
+
+	service := vehiclecompare.NewVehicleComparisonService()
+
+	result, err := service.CompareVehicleImagesFromBase64(encoded, encoded)
+	if err != nil {
+		t.Fatalf("Comparison failed: %v", err)
+	}
+	if !result.ProcessingInfo.IdenticalInput || result.SimilarityScore != 1.0 {
+		t.Errorf("Expected an identical-input result with similarity 1.0, got %.3f", result.SimilarityScore)
+	}
+
+	if _, err := service.CompareVehicleImagesFromBase64("not base64!", "not base64!"); err == nil {
+		t.Errorf("Expected undecodable identical inputs to be rejected")
+	}
+}
+
+func TestIdenticalInputFollowsComparisonMode(t *testing.T) {
+	dir := t.TempDir()
+	path := writeTestVehicle(t, dir, "vehicle.png", 0, 0)
+	other := writeTestVehicle(t, dir, "shifted.png", 6, 4)
+
+	service := vehiclecompare.NewVehicleComparisonService(vehiclecompare.WithLayoutOnly(true))
+
+	identical, err := service.CompareVehicleImages(path, path)
+	if err != nil {
+		t.Fatalf("Comparison failed: %v", err)
+	}
+	compared, err := service.CompareVehicleImages(path, other)
+	if err != nil {
+		t.Fatalf("Comparison failed: %v", err)
+	}
+
+	// Same output shape as a pair that was actually compared
+	if identical.EffectiveWeights != compared.EffectiveWeights {
+		t.Errorf("Expected the layout-only weights %+v, got %+v", compared.EffectiveWeights, identical.EffectiveWeights)
+	}
+	if identical.Availability.Color != compared.Availability.Color || identical.Availability.Bumper != compared.Availability.Bumper {
+		t.Errorf("Expected color and bumper skipped by the mode, got %+v", identical.Availability)
+	}
+	if identical.ProcessingInfo.ConfigFingerprint != service.ConfigFingerprint() {
+		t.Errorf("Expected the service's config fingerprint, got %q", identical.ProcessingInfo.ConfigFingerprint)
+	}
+
+	// The query quality minimum applies as to any other pair
+	vehicle := newTestVehicle(0, 0)
+	defer vehicle.Close()
+	flat := lowContrast(vehicle)
+	defer flat.Close()
+	flatPath := writeIRCapture(t, dir, "flat.png", flat)
+
+	gated := vehiclecompare.NewVehicleComparisonService(vehiclecompare.WithMinQueryQuality(0.9))
+	if _, err := gated.CompareVehicleImages(flatPath, flatPath); !errors.Is(err, vehiclecompare.ErrInsufficientQuality) {
+		t.Errorf("Expected the quality gate to reject a low-contrast capture given twice, got %v", err)
+	}
+}
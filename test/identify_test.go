@@ -0,0 +1,91 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/testutil"
+	"github.com/choff5507/vehicle-image-comparison/pkg/vehiclecompare"
+)
+
+func TestIdentifyFromDirectoryRanksMatchFirst(t *testing.T) {
+	queryDir := t.TempDir()
+	query := writeTestVehicle(t, queryDir, "query.png", 0, 0)
+
+	gallery := t.TempDir()
+	other := testutil.FrontVehicle(differentSpec())
+	defer other.Close()
+	writeTestImage(t, gallery, "a_other.png", other)
+	writeTestVehicle(t, gallery, "b_match.png", 6, 4)
+	rear := testutil.RearVehicle(testutil.DefaultSpec())
+	defer rear.Close()
+	writeTestImage(t, gallery, "c_rear.png", rear)
+	if err := os.WriteFile(filepath.Join(gallery, "d_broken.jpg"), []byte("not an image"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(gallery, "notes.txt"), []byte("ignored"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	service := vehiclecompare.NewVehicleComparisonService()
+
+	matches, err := service.IdentifyFromDirectory(query, gallery)
+	if err != nil {
+		t.Fatalf("Identification failed: %v", err)
+	}
+	if len(matches) != 4 {
+		t.Fatalf("Expected the four images to be ranked, got %d", len(matches))
+	}
+
+	if filepath.Base(matches[0].Path) != "b_match.png" || matches[0].Result == nil {
+		t.Fatalf("Expected b_match.png to rank first, got %s (%v)", matches[0].Path, matches[0].Err)
+	}
+	if matches[1].Result == nil || matches[1].Result.SimilarityScore > matches[0].Result.SimilarityScore {
+		t.Errorf("Expected a_other.png ranked second below the match, got %+v", matches[1])
+	}
+
+	for _, match := range matches[2:] {
+		if match.Err == nil {
+			t.Errorf("Expected %s to be ranked last with an error", match.Path)
+		}
+	}
+}
+
+func TestIdentifyFromDirectoryReportsProgress(t *testing.T) {
+	queryDir := t.TempDir()
+	query := writeTestVehicle(t, queryDir, "query.png", 0, 0)
+
+	gallery := t.TempDir()
+	writeTestVehicle(t, gallery, "a_match.png", 6, 4)
+	writeTestVehicle(t, gallery, "b_match.png", 3, 2)
+	if err := os.WriteFile(filepath.Join(gallery, "c_broken.jpg"), []byte("not an image"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var dones, totals []int
+	service := vehiclecompare.NewVehicleComparisonService(
+		vehiclecompare.WithProgress(func(done, total int) {
+			dones = append(dones, done)
+			totals = append(totals, total)
+		}),
+	)
+
+	matches, err := service.IdentifyFromDirectory(query, gallery)
+	if err != nil {
+		t.Fatalf("Identification failed: %v", err)
+	}
+
+	// Failed images count as completed too
+	if len(dones) != len(matches) {
+		t.Fatalf("Expected %d progress calls, got %d", len(matches), len(dones))
+	}
+	for i, done := range dones {
+		if done != i+1 {
+			t.Errorf("Expected call %d to report done=%d, got %d", i, i+1, done)
+		}
+		if totals[i] != len(matches) {
+			t.Errorf("Expected call %d to report total=%d, got %d", i, len(matches), totals[i])
+		}
+	}
+}
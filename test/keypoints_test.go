@@ -0,0 +1,45 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/testutil"
+	"github.com/choff5507/vehicle-image-comparison/pkg/vehiclecompare"
+)
+
+func TestKeypointMatchingSeparatesCopiesFromDifferentVehicles(t *testing.T) {
+	dir := t.TempDir()
+
+	writeSpeckled := func(name string, dx, dy int, seed int64) string {
+		spec := testutil.DefaultSpec()
+		spec.OffsetX = dx
+		spec.OffsetY = dy
+		img := testutil.FrontVehicle(spec)
+		defer img.Close()
+		testutil.AddSpeckle(&img, spec, seed)
+		return writeTestImage(t, dir, name, img)
+	}
+
+	reference := writeSpeckled("reference.png", 0, 0, 1)
+	shiftedCopy := writeSpeckled("shifted.png", 8, 5, 1)
+	different := writeSpeckled("different.png", 0, 0, 2)
+
+	service := vehiclecompare.NewVehicleComparisonService(vehiclecompare.WithKeypointMatching(true))
+
+	copyResult, err := service.CompareVehicleImages(reference, shiftedCopy)
+	if err != nil {
+		t.Fatalf("Comparison with shifted copy failed: %v", err)
+	}
+	differentResult, err := service.CompareVehicleImages(reference, different)
+	if err != nil {
+		t.Fatalf("Comparison with different vehicle failed: %v", err)
+	}
+
+	if copyResult.DetailedScores.GeometricSimilarity < 0.5 {
+		t.Errorf("Expected many good keypoint matches for a shifted copy, got %.3f", copyResult.DetailedScores.GeometricSimilarity)
+	}
+	if differentResult.DetailedScores.GeometricSimilarity > copyResult.DetailedScores.GeometricSimilarity/2 {
+		t.Errorf("Expected few good keypoint matches for a different vehicle, got %.3f (copy %.3f)",
+			differentResult.DetailedScores.GeometricSimilarity, copyResult.DetailedScores.GeometricSimilarity)
+	}
+}
@@ -0,0 +1,78 @@
+package test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/comparator"
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+	"github.com/choff5507/vehicle-image-comparison/internal/testutil"
+	"github.com/choff5507/vehicle-image-comparison/pkg/vehiclecompare"
+	"gocv.io/x/gocv"
+)
+
+// extractImage extracts the features of img with service
+func extractImage(t testing.TB, service *vehiclecompare.VehicleComparisonService, img gocv.Mat) models.VehicleFeatures {
+	features, err := service.ExtractFeatures(img)
+	if err != nil {
+		t.Fatalf("Feature extraction failed: %v", err)
+	}
+	return features
+}
+
+func TestLightElementsMatchAcrossResolutions(t *testing.T) {
+	service := vehiclecompare.NewVehicleComparisonService()
+	engine := comparator.NewComparisonEngine()
+
+	reference := testutil.RearVehicle(testutil.DefaultSpec())
+	defer reference.Close()
+	smaller := gocv.NewMat()
+	defer smaller.Close()
+	gocv.Resize(reference, &smaller, image.Pt(reference.Cols()*3/4, reference.Rows()*3/4), 0, 0, gocv.InterpolationArea)
+	different := testutil.RearVehicle(differentSpec())
+	defer different.Close()
+
+	features := extractImage(t, service, reference)
+
+	same := engine.SubScores(features, extractImage(t, service, smaller)).Lights.Elements
+	if same < 0.9 {
+		t.Errorf("Expected the lights of a downscaled capture to match, got %.3f", same)
+	}
+
+	other := engine.SubScores(features, extractImage(t, service, different)).Lights.Elements
+	if other >= same {
+		t.Errorf("Expected a different light layout (%.3f) to score below the downscaled capture (%.3f)", other, same)
+	}
+}
+
+func TestPlateRelativeLayoutIgnoresFraming(t *testing.T) {
+	service := vehiclecompare.NewVehicleComparisonService()
+
+	reference := testutil.RearVehicle(testutil.DefaultSpec())
+	defer reference.Close()
+	// The same capture framed wider on the left, moving the vehicle within the image
+	padded := gocv.NewMat()
+	defer padded.Close()
+	gocv.CopyMakeBorder(reference, &padded, 0, 0, 320, 0, gocv.BorderConstant, color.RGBA{R: 40, G: 40, B: 40})
+
+	features1 := extractImage(t, service, reference)
+	features2 := extractImage(t, service, padded)
+	if features1.BumperFeatures.LicensePlateArea.Width == 0 || features2.BumperFeatures.LicensePlateArea.Width == 0 {
+		t.Fatal("Expected a license plate in both captures")
+	}
+
+	vehicleRelative := comparator.NewComparisonEngine().SubScores(features1, features2).Lights.Elements
+
+	engine := comparator.NewComparisonEngine()
+	engine.SetPlateRelativeFrame(true)
+	plateRelative := engine.SubScores(features1, features2).Lights.Elements
+
+	if plateRelative < 0.9 {
+		t.Errorf("Expected the lights to match relative to the plate, got %.3f", plateRelative)
+	}
+	if plateRelative <= vehicleRelative {
+		t.Errorf("Expected the plate frame (%.3f) to score the reframed capture above the image frame (%.3f)",
+			plateRelative, vehicleRelative)
+	}
+}
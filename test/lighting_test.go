@@ -0,0 +1,131 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+	"github.com/choff5507/vehicle-image-comparison/internal/preprocessor"
+	"github.com/choff5507/vehicle-image-comparison/pkg/vehiclecompare"
+	"gocv.io/x/gocv"
+)
+
+func TestColorlessDaylightImageFallsBackToIRPath(t *testing.T) {
+	dir := t.TempDir()
+
+	vehicle := newTestVehicle(0, 0)
+	defer vehicle.Close()
+
+	// A grayscale capture stored as 3-channel BGR is classified as daylight
+	gray := gocv.NewMat()
+	defer gray.Close()
+	gocv.CvtColor(vehicle, &gray, gocv.ColorBGRToGray)
+	grayBGR := gocv.NewMat()
+	defer grayBGR.Close()
+	gocv.CvtColor(gray, &grayBGR, gocv.ColorGrayToBGR)
+
+	path1 := writeTestImage(t, dir, "gray1.png", grayBGR)
+	path2 := writeTestImage(t, dir, "gray2.png", grayBGR)
+
+	service := vehiclecompare.NewVehicleComparisonService()
+
+	result, err := service.CompareVehicleImages(path1, path2)
+	if err != nil {
+		t.Fatalf("Comparison failed: %v", err)
+	}
+
+	if result.EffectiveWeights.Color != 0 || result.EffectiveWeights.Thermal == 0 {
+		t.Errorf("Expected IR feature weights, got %+v", result.EffectiveWeights)
+	}
+	if result.DetailedScores.ColorSimilarity != 0 {
+		t.Errorf("Expected color to be skipped, got color similarity %.3f", result.DetailedScores.ColorSimilarity)
+	}
+	if result.DetailedScores.ThermalSimilarity < 0.9 {
+		t.Errorf("Expected identical IR signatures, got thermal similarity %.3f", result.DetailedScores.ThermalSimilarity)
+	}
+	if !result.IsSameVehicle {
+		t.Errorf("Expected identical grayscale images to match, got similarity %.3f", result.SimilarityScore)
+	}
+}
+
+// dusk dims img and gives it a faint warm cast, too colorful for an IR capture and
+// too dark and gray for a confident daylight one. The caller owns the returned Mat.
+func TestDimCapturesClassifyAsDaylight(t *testing.T) {
+	classifier := preprocessor.NewViewLightingClassifier()
+
+	tests := []struct {
+		name  string
+		color gocv.Scalar
+	}{
+		{"dim colorful", gocv.NewScalar(60, 10, 10, 0)},
+		{"dim colorless", gocv.NewScalar(30, 30, 30, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := gocv.NewMatWithSizeFromScalar(tt.color, 480, 640, gocv.MatTypeCV8UC3)
+			defer img.Close()
+
+			lighting, confidence, err := classifier.ClassifyLighting(img)
+			if err != nil {
+				t.Fatalf("ClassifyLighting failed: %v", err)
+			}
+			if lighting != models.LightingDaylight {
+				t.Errorf("expected daylight, got %v", lighting)
+			}
+			if confidence < 0.5 {
+				t.Errorf("expected confidence of at least 0.5, got %.2f", confidence)
+			}
+		})
+	}
+}
+
+func dusk(img gocv.Mat) gocv.Mat {
+	channels := gocv.Split(img)
+	for i, gain := range []float64{0.55, 0.57, 0.6} { // B, G, R
+		channels[i].ConvertToWithParams(&channels[i], gocv.MatTypeCV8U, float32(gain), 0)
+	}
+	dim := gocv.NewMat()
+	gocv.Merge(channels, &dim)
+	for _, channel := range channels {
+		channel.Close()
+	}
+	return dim
+}
+
+func TestLightingFallbackKeepsBetterModality(t *testing.T) {
+	dir := t.TempDir()
+
+	vehicle1 := newTestVehicle(0, 0)
+	defer vehicle1.Close()
+	vehicle2 := newTestVehicle(6, 4)
+	defer vehicle2.Close()
+	dusk1 := dusk(vehicle1)
+	defer dusk1.Close()
+	dusk2 := dusk(vehicle2)
+	defer dusk2.Close()
+	path1 := writeTestImage(t, dir, "dusk1.png", dusk1)
+	path2 := writeTestImage(t, dir, "dusk2.png", dusk2)
+
+	guessed, err := vehiclecompare.NewVehicleComparisonService().CompareVehicleImages(path1, path2)
+	if err != nil {
+		t.Fatalf("Comparison failed: %v", err)
+	}
+	if guessed.ProcessingInfo.LightingFallback {
+		t.Error("Expected no lighting fallback unless enabled")
+	}
+
+	service := vehiclecompare.NewVehicleComparisonService(vehiclecompare.WithLightingFallback(true))
+	result, err := service.CompareVehicleImages(path1, path2)
+	if err != nil {
+		t.Fatalf("Comparison with lighting fallback failed: %v", err)
+	}
+	if !result.ProcessingInfo.LightingFallback {
+		t.Fatalf("Expected dusk captures to take the lighting fallback")
+	}
+
+	// The best-guess path is one of the candidates, so the kept result is never worse
+	if result.SimilarityScore < guessed.SimilarityScore-1e-9 {
+		t.Errorf("Expected the better-matching modality to be kept, got %.3f on the %v path against %.3f guessed",
+			result.SimilarityScore, result.ProcessingInfo.FeaturePath, guessed.SimilarityScore)
+	}
+}
@@ -0,0 +1,84 @@
+package test
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/extractor"
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+	"github.com/choff5507/vehicle-image-comparison/internal/testutil"
+	"github.com/choff5507/vehicle-image-comparison/pkg/vehiclecompare"
+	"gocv.io/x/gocv"
+)
+
+// vehicleMask marks the body of a synthetic vehicle drawn from spec
+func vehicleMask(spec testutil.VehicleSpec) gocv.Mat {
+	mask := gocv.NewMatWithSizeFromScalar(gocv.NewScalar(0, 0, 0, 0), testutil.ImageHeight, testutil.ImageWidth, gocv.MatTypeCV8U)
+	left := (testutil.ImageWidth - spec.BodyWidth) / 2
+	gocv.Rectangle(&mask, image.Rect(left, spec.BodyTop, left+spec.BodyWidth, spec.BodyBottom), color.RGBA{R: 255, G: 255, B: 255}, -1)
+	return mask
+}
+
+func TestMaskExcludesBackgroundLight(t *testing.T) {
+	spec := testutil.DefaultSpec()
+	img := testutil.FrontVehicle(spec)
+	defer img.Close()
+
+	// A street light beside the vehicle, as bright and round as a headlight
+	gocv.Circle(&img, image.Pt(120, 300), spec.LightSize, color.RGBA{R: 255, G: 255, B: 255}, -1)
+
+	mask := vehicleMask(spec)
+	defer mask.Close()
+
+	lpe := extractor.NewLightPatternExtractor()
+	unmasked, err := lpe.ExtractLightPatterns(img, gocv.Mat{}, models.ViewFront, models.LightingDaylight)
+	if err != nil {
+		t.Fatalf("Light extraction failed: %v", err)
+	}
+
+	maskedLights, err := lpe.ExtractLightPatterns(img, mask, models.ViewFront, models.LightingDaylight)
+	if err != nil {
+		t.Fatalf("Light extraction failed: %v", err)
+	}
+
+	if len(unmasked.LightElements) != 3 {
+		t.Errorf("Expected the street light to pass for a headlight without a mask, got %d lights", len(unmasked.LightElements))
+	}
+	if len(maskedLights.LightElements) != 2 {
+		t.Errorf("Expected only the two headlights inside the mask, got %d lights", len(maskedLights.LightElements))
+	}
+
+	dir := t.TempDir()
+	path1 := writeTestImage(t, dir, "with_light.png", img)
+	path2 := writeTestVehicle(t, dir, "plain.png", 0, 0)
+
+	unmaskedSecond := gocv.NewMat()
+	defer unmaskedSecond.Close()
+
+	service := vehiclecompare.NewVehicleComparisonService()
+	result, err := service.CompareVehicleImagesWithMasks(path1, mask, path2, unmaskedSecond)
+	if err != nil {
+		t.Fatalf("Masked comparison failed: %v", err)
+	}
+	if !result.IsSameVehicle {
+		t.Errorf("Expected the masked vehicle to match, got %.3f", result.SimilarityScore)
+	}
+}
+
+func TestMaskMustMatchImage(t *testing.T) {
+	dir := t.TempDir()
+	path := writeTestVehicle(t, dir, "vehicle.png", 0, 0)
+
+	small := gocv.NewMatWithSize(100, 100, gocv.MatTypeCV8U)
+	defer small.Close()
+	none := gocv.NewMat()
+	defer none.Close()
+
+	service := vehiclecompare.NewVehicleComparisonService()
+	_, err := service.CompareVehicleImagesWithMasks(path, small, path, none)
+	if !errors.Is(err, vehiclecompare.ErrInvalidMask) {
+		t.Errorf("Expected ErrInvalidMask for a mask of the wrong size, got %v", err)
+	}
+}
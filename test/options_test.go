@@ -0,0 +1,71 @@
+package test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/choff5507/vehicle-image-comparison/pkg/vehiclecompare"
+	"gocv.io/x/gocv"
+)
+
+func TestRecompressionSmoothingKeepsSimilarityHigh(t *testing.T) {
+	dir := t.TempDir()
+
+	original := newTestVehicle(0, 0)
+	defer original.Close()
+	originalPath := writeTestImage(t, dir, "original.png", original)
+
+	// Heavily recompress the same photo
+	buf, err := gocv.IMEncodeWithParams(gocv.JPEGFileExt, original, []int{gocv.IMWriteJpegQuality, 10})
+	if err != nil {
+		t.Fatalf("failed to recompress image: %v", err)
+	}
+	recompressed, err := gocv.IMDecode(buf.GetBytes(), gocv.IMReadColor)
+	buf.Close()
+	if err != nil {
+		t.Fatalf("failed to decode recompressed image: %v", err)
+	}
+	defer recompressed.Close()
+	recompressedPath := writeTestImage(t, dir, "recompressed.png", recompressed)
+
+	service := vehiclecompare.NewVehicleComparisonService(vehiclecompare.WithRecompressionSmoothing(true))
+
+	result, err := service.CompareVehicleImages(originalPath, recompressedPath)
+	if err != nil {
+		t.Fatalf("Comparison failed: %v", err)
+	}
+
+	if !result.IsSameVehicle || result.SimilarityScore < 0.75 {
+		t.Errorf("Expected recompressed copy to match the original, got similarity %.3f", result.SimilarityScore)
+	}
+}
+
+func TestGrayscaleOnlyMatchesColorAndGrayscaleCopies(t *testing.T) {
+	dir := t.TempDir()
+
+	colored := newTestVehicle(0, 0)
+	defer colored.Close()
+	// Tint the body so the color and grayscale copies genuinely differ
+	gocv.Rectangle(&colored, image.Rect(320, 540, 960, 580), color.RGBA{R: 200, G: 40, B: 40}, -1)
+	colorPath := writeTestImage(t, dir, "color.png", colored)
+
+	gray := gocv.NewMat()
+	defer gray.Close()
+	gocv.CvtColor(colored, &gray, gocv.ColorBGRToGray)
+	grayPath := writeTestImage(t, dir, "gray.png", gray)
+
+	service := vehiclecompare.NewVehicleComparisonService(vehiclecompare.WithGrayscaleOnly(true))
+
+	result, err := service.CompareVehicleImages(colorPath, grayPath)
+	if err != nil {
+		t.Fatalf("Comparison failed: %v", err)
+	}
+
+	if result.SimilarityScore < 0.85 {
+		t.Errorf("Expected high similarity between color and grayscale copies, got %.3f", result.SimilarityScore)
+	}
+	if result.DetailedScores.ColorSimilarity != 0 {
+		t.Errorf("Expected color to be skipped, got color similarity %.3f", result.DetailedScores.ColorSimilarity)
+	}
+}
@@ -0,0 +1,36 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/choff5507/vehicle-image-comparison/pkg/vehiclecompare"
+	"gocv.io/x/gocv"
+)
+
+func TestUpsideDownCopyIsRecovered(t *testing.T) {
+	dir := t.TempDir()
+
+	original := newTestVehicle(0, 0)
+	defer original.Close()
+	originalPath := writeTestImage(t, dir, "original.png", original)
+
+	rotated := gocv.NewMat()
+	defer rotated.Close()
+	gocv.Rotate(original, &rotated, gocv.Rotate180Clockwise)
+	rotatedPath := writeTestImage(t, dir, "rotated.png", rotated)
+
+	service := vehiclecompare.NewVehicleComparisonService()
+
+	result, err := service.CompareVehicleImages(originalPath, rotatedPath)
+	if err != nil {
+		t.Fatalf("Comparison failed: %v", err)
+	}
+
+	if !result.ProcessingInfo.RotationApplied {
+		t.Error("Expected the 180° rotation retry to be applied")
+	}
+
+	if !result.IsSameVehicle {
+		t.Errorf("Expected rotated copy to match the original, got similarity %.3f", result.SimilarityScore)
+	}
+}
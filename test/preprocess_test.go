@@ -0,0 +1,63 @@
+package test
+
+import (
+	"errors"
+	"math"
+	"sync/atomic"
+	"testing"
+
+	"github.com/choff5507/vehicle-image-comparison/pkg/vehiclecompare"
+	"gocv.io/x/gocv"
+)
+
+func invert(img gocv.Mat) gocv.Mat {
+	inverted := gocv.NewMat()
+	gocv.BitwiseNot(img, &inverted)
+	return inverted
+}
+
+func TestPreprocessorRunsOnBothInputs(t *testing.T) {
+	dir := t.TempDir()
+
+	vehicle := newTestVehicle(0, 0)
+	defer vehicle.Close()
+	inverted := invert(vehicle)
+	defer inverted.Close()
+	vehiclePath := writeTestImage(t, dir, "vehicle.png", vehicle)
+	invertedPath := writeTestImage(t, dir, "inverted.png", inverted)
+
+	var calls atomic.Int32
+	hooked := vehiclecompare.NewVehicleComparisonService(vehiclecompare.WithPreprocessor(func(img gocv.Mat) (gocv.Mat, error) {
+		calls.Add(1)
+		return invert(img), nil
+	}))
+	result, err := hooked.CompareVehicleImages(vehiclePath, invertedPath)
+	if calls.Load() != 2 {
+		t.Errorf("Expected the preprocessor to run once per input, ran %d times", calls.Load())
+	}
+
+	// Inverting both inputs turns the pair into the unhooked comparison of the
+	// inverted image against the original
+	expected, expectedErr := vehiclecompare.NewVehicleComparisonService().CompareVehicleImages(invertedPath, vehiclePath)
+	if (err == nil) != (expectedErr == nil) {
+		t.Fatalf("Expected the preprocessed comparison to fail as the transformed one does, got %v and %v", err, expectedErr)
+	}
+	if err == nil && math.Abs(result.SimilarityScore-expected.SimilarityScore) > 1e-9 {
+		t.Errorf("Expected the result to reflect the inverted inputs, got %.4f against %.4f",
+			result.SimilarityScore, expected.SimilarityScore)
+	}
+}
+
+func TestPreprocessorErrorFailsComparison(t *testing.T) {
+	dir := t.TempDir()
+	vehicle1 := writeTestVehicle(t, dir, "vehicle1.png", 0, 0)
+	vehicle2 := writeTestVehicle(t, dir, "vehicle2.png", 6, 4)
+
+	errDenoise := errors.New("denoiser unavailable")
+	service := vehiclecompare.NewVehicleComparisonService(vehiclecompare.WithPreprocessor(func(img gocv.Mat) (gocv.Mat, error) {
+		return img, errDenoise
+	}))
+	if _, err := service.CompareVehicleImages(vehicle1, vehicle2); !errors.Is(err, errDenoise) {
+		t.Errorf("Expected the preprocessor error, got %v", err)
+	}
+}
@@ -0,0 +1,51 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+	"github.com/choff5507/vehicle-image-comparison/pkg/vehiclecompare"
+)
+
+func TestCompareProgressiveReportsPartialFirst(t *testing.T) {
+	dir := t.TempDir()
+	path1 := writeTestVehicle(t, dir, "vehicle1.png", 0, 0)
+	path2 := writeTestVehicle(t, dir, "vehicle2.png", 6, 4)
+
+	service := vehiclecompare.NewVehicleComparisonService()
+
+	var final, partial *models.ComparisonResult
+	calls := 0
+	final, err := service.CompareProgressive(path1, path2, func(result *models.ComparisonResult) {
+		calls++
+		if final != nil {
+			t.Error("Expected the partial result before the final one")
+		}
+		partial = result
+	})
+	if err != nil {
+		t.Fatalf("CompareProgressive failed: %v", err)
+	}
+
+	if calls != 1 || partial == nil {
+		t.Fatalf("Expected one partial result, got %d calls", calls)
+	}
+	if !partial.ProcessingInfo.Provisional {
+		t.Error("Expected the thumbnail estimate to be marked provisional")
+	}
+	if final.ProcessingInfo.Provisional {
+		t.Error("Expected the full-resolution result not to be provisional")
+	}
+
+	for name, result := range map[string]*models.ComparisonResult{"partial": partial, "final": final} {
+		if result.SimilarityScore < 0 || result.SimilarityScore > 1 {
+			t.Errorf("%s: similarity %.3f outside [0, 1]", name, result.SimilarityScore)
+		}
+		if result.EffectiveWeights == (models.FeatureWeights{}) {
+			t.Errorf("%s: no effective weights", name)
+		}
+	}
+	if !final.IsSameVehicle {
+		t.Errorf("Expected the shifted vehicle to match, got %.3f", final.SimilarityScore)
+	}
+}
@@ -0,0 +1,88 @@
+package test
+
+import (
+	"errors"
+	"image"
+	"testing"
+
+	"github.com/choff5507/vehicle-image-comparison/pkg/vehiclecompare"
+	"gocv.io/x/gocv"
+)
+
+// lowContrast squeezes img's intensities into a narrow band around mid-gray, as a
+// flat IR crop would be. The caller owns the returned Mat.
+func lowContrast(img gocv.Mat) gocv.Mat {
+	flat := gocv.NewMat()
+	img.ConvertToWithParams(&flat, gocv.MatTypeCV8UC3, 0.35, 90)
+	return flat
+}
+
+func TestSkipQualityAssessmentAcceptsLowContrast(t *testing.T) {
+	dir := t.TempDir()
+
+	vehicle1 := newTestVehicle(0, 0)
+	defer vehicle1.Close()
+	vehicle2 := newTestVehicle(6, 4)
+	defer vehicle2.Close()
+	flat1 := lowContrast(vehicle1)
+	defer flat1.Close()
+	flat2 := lowContrast(vehicle2)
+	defer flat2.Close()
+	path1 := writeIRCapture(t, dir, "flat1.png", flat1)
+	path2 := writeIRCapture(t, dir, "flat2.png", flat2)
+
+	// A curated dataset's strict minimum rejects the flat captures
+	gated := vehiclecompare.NewVehicleComparisonService(
+		vehiclecompare.WithMinQueryQuality(0.9),
+		vehiclecompare.WithMinGalleryQuality(0.9),
+	)
+	if _, err := gated.CompareVehicleImages(path1, path2); !errors.Is(err, vehiclecompare.ErrInsufficientQuality) {
+		t.Fatalf("Expected the quality gate to reject low-contrast captures, got %v", err)
+	}
+
+	skipped := vehiclecompare.NewVehicleComparisonService(
+		vehiclecompare.WithMinQueryQuality(0.9),
+		vehiclecompare.WithMinGalleryQuality(0.9),
+		vehiclecompare.WithSkipQualityAssessment(true),
+	)
+	result, err := skipped.CompareVehicleImages(path1, path2)
+	if err != nil {
+		t.Fatalf("Expected low-contrast captures to proceed without the quality gate, got %v", err)
+	}
+	if result.ProcessingInfo.Image1Quality != -1 || result.ProcessingInfo.Image2Quality != -1 {
+		t.Errorf("Expected unknown (-1) image qualities, got %.2f and %.2f",
+			result.ProcessingInfo.Image1Quality, result.ProcessingInfo.Image2Quality)
+	}
+}
+
+func TestPerceptualQualityGateRejectsSoftCapture(t *testing.T) {
+	dir := t.TempDir()
+	path := writeTestVehicle(t, dir, "vehicle.png", 0, 0)
+
+	// Defocus keeps the contrast and the low noise the classical score rewards
+	vehicle := newTestVehicle(6, 4)
+	defer vehicle.Close()
+	soft := gocv.NewMat()
+	defer soft.Close()
+	gocv.GaussianBlur(vehicle, &soft, image.Pt(13, 13), 2.0, 2.0, gocv.BorderDefault)
+	softPath := writeTestImage(t, dir, "soft.png", soft)
+
+	classical := vehiclecompare.NewVehicleComparisonService()
+	result, err := classical.CompareVehicleImages(path, softPath)
+	if err != nil {
+		t.Fatalf("Expected the soft capture to pass the classical quality gate, got %v", err)
+	}
+	if result.ProcessingInfo.Image2Quality < 0.5 {
+		t.Fatalf("Expected a classically acceptable quality, got %.2f", result.ProcessingInfo.Image2Quality)
+	}
+
+	gated := vehiclecompare.NewVehicleComparisonService(vehiclecompare.WithPerceptualQualityGate(0.5))
+	if _, err := gated.CompareVehicleImages(path, softPath); !errors.Is(err, vehiclecompare.ErrInsufficientQuality) {
+		t.Errorf("Expected the perceptual gate to reject the soft capture, got %v", err)
+	}
+
+	sharpPath := writeTestVehicle(t, dir, "sharp.png", 6, 4)
+	if _, err := gated.CompareVehicleImages(path, sharpPath); err != nil {
+		t.Errorf("Expected sharp captures to pass the perceptual gate, got %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+package test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/choff5507/vehicle-image-comparison/pkg/vehiclecompare"
+)
+
+func TestCompareVehicleImagesFromReaders(t *testing.T) {
+	dir := t.TempDir()
+	path1 := writeTestVehicle(t, dir, "vehicle1.png", 0, 0)
+	path2 := writeTestVehicle(t, dir, "vehicle2.png", 6, 4)
+
+	service := vehiclecompare.NewVehicleComparisonService()
+
+	fromFiles, err := service.CompareVehicleImages(path1, path2)
+	if err != nil {
+		t.Fatalf("File comparison failed: %v", err)
+	}
+
+	file1, err := os.Open(path1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file1.Close()
+	file2, err := os.Open(path2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file2.Close()
+
+	fromReaders, err := service.CompareVehicleImagesFromReaders(file1, file2)
+	if err != nil {
+		t.Fatalf("Reader comparison failed: %v", err)
+	}
+	if fromReaders.SimilarityScore != fromFiles.SimilarityScore {
+		t.Errorf("Expected the file comparison's score %.3f, got %.3f", fromFiles.SimilarityScore, fromReaders.SimilarityScore)
+	}
+}
+
+func TestCompareVehicleImagesFromReadersRejectsBadStreams(t *testing.T) {
+	dir := t.TempDir()
+	data, err := os.ReadFile(writeTestVehicle(t, dir, "vehicle.png", 0, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	service := vehiclecompare.NewVehicleComparisonService()
+
+	for name, second := range map[string]func() *strings.Reader{
+		"empty":       func() *strings.Reader { return strings.NewReader("") },
+		"undecodable": func() *strings.Reader { return strings.NewReader("not an image") },
+	} {
+		_, err := service.CompareVehicleImagesFromReaders(bytes.NewReader(data), second())
+		if !errors.Is(err, vehiclecompare.ErrImageDecode) {
+			t.Errorf("%s: expected ErrImageDecode, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package test
+
+import (
+	"image"
+	"testing"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/extractor"
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+	"github.com/choff5507/vehicle-image-comparison/internal/preprocessor"
+	"github.com/choff5507/vehicle-image-comparison/internal/testutil"
+	"gocv.io/x/gocv"
+)
+
+// Factor the high-resolution capture is downscaled by into the canonical frame
+const captureScale = 4
+
+// ledCapture renders the reference front vehicle at captureScale times the canonical
+// resolution with LED headlights: every captureScale-th pixel column of each light,
+// starting at phase, is a dark gap between LED segments
+func ledCapture(phase int) gocv.Mat {
+	canonical := newTestVehicle(0, 0)
+	defer canonical.Close()
+
+	capture := gocv.NewMat()
+	gocv.Resize(canonical, &capture, image.Pt(0, 0), captureScale, captureScale, gocv.InterpolationNearestNeighbor)
+
+	for y := 0; y < capture.Rows(); y++ {
+		for x := phase; x < capture.Cols(); x += captureScale {
+			if y < testutil.ImageHeight*captureScale/2 && capture.GetUCharAt(y, x*3) == 255 {
+				for c := 0; c < 3; c++ {
+					capture.SetUCharAt(y, x*3+c, 30)
+				}
+			}
+		}
+	}
+	return capture
+}
+
+// unstablePhases counts the gap phases at which the headlights found after resizing
+// differ in number from those of the clean canonical image
+func unstablePhases(t *testing.T, interpolation gocv.InterpolationFlags) int {
+	lpe := extractor.NewLightPatternExtractor()
+
+	clean := newTestVehicle(0, 0)
+	defer clean.Close()
+	reference, err := lpe.ExtractLightPatterns(clean, gocv.Mat{}, models.ViewFront, models.LightingDaylight)
+	if err != nil {
+		t.Fatalf("Light extraction failed: %v", err)
+	}
+
+	aligner := preprocessor.NewImageAligner()
+	aligner.SetInterpolation(interpolation, gocv.InterpolationLinear)
+
+	unstable := 0
+	for phase := 0; phase < captureScale; phase++ {
+		capture := ledCapture(phase)
+		resized := aligner.ToCanonical(capture)
+		capture.Close()
+
+		lights, err := lpe.ExtractLightPatterns(resized, gocv.Mat{}, models.ViewFront, models.LightingDaylight)
+		resized.Close()
+		if err != nil {
+			t.Fatalf("Light extraction failed: %v", err)
+		}
+		if len(lights.LightElements) != len(reference.LightElements) {
+			unstable++
+		}
+	}
+	return unstable
+}
+
+func TestAreaDownscaleKeepsLEDLightsStable(t *testing.T) {
+	area := unstablePhases(t, gocv.InterpolationArea)
+	nearest := unstablePhases(t, gocv.InterpolationNearestNeighbor)
+
+	if area != 0 {
+		t.Errorf("Expected area interpolation to find the headlights at every LED phase, %d of %d phases differed", area, captureScale)
+	}
+	if nearest <= area {
+		t.Errorf("Expected nearest-neighbor downscaling to lose LED headlights at some phase: %d unstable phases vs %d with area", nearest, area)
+	}
+}
+
+func TestCanonicalPaddingPreservesAspect(t *testing.T) {
+	// A 2:1 image letterboxed into the 4:3 canonical frame
+	wide := gocv.NewMatWithSizeFromScalar(gocv.NewScalar(200, 200, 200, 0), 400, 800, gocv.MatTypeCV8UC3)
+	defer wide.Close()
+
+	aligner := preprocessor.NewImageAligner()
+	aligner.SetPadToAspect(true)
+	canonical := aligner.ToCanonical(wide)
+	defer canonical.Close()
+
+	if canonical.Cols() != preprocessor.CanonicalWidth || canonical.Rows() != preprocessor.CanonicalHeight {
+		t.Fatalf("Expected a %dx%d frame, got %dx%d", preprocessor.CanonicalWidth, preprocessor.CanonicalHeight, canonical.Cols(), canonical.Rows())
+	}
+
+	// Scaled to 1280x640, centered with 160px bars above and below
+	if v := canonical.GetUCharAt(80, 640*3); v != 0 {
+		t.Errorf("Expected a black bar above the image, got %d", v)
+	}
+	if v := canonical.GetUCharAt(480, 640*3); v != 200 {
+		t.Errorf("Expected the image in the middle of the frame, got %d", v)
+	}
+}
@@ -0,0 +1,43 @@
+package test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/preprocessor"
+	"gocv.io/x/gocv"
+)
+
+func TestClassifyRoofFindsRoofline(t *testing.T) {
+	body := color.RGBA{R: 200, G: 200, B: 200}
+	classifier := preprocessor.NewViewLightingClassifier()
+
+	// Body and roof as one block whose top edge spans most of the width
+	roofed := gocv.NewMatWithSize(480, 640, gocv.MatTypeCV8UC3)
+	defer roofed.Close()
+	gocv.Rectangle(&roofed, image.Rect(100, 60, 540, 400), body, -1)
+
+	hasRoof, confidence, err := classifier.ClassifyRoof(roofed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hasRoof || confidence <= 0 {
+		t.Errorf("Expected a roof from a full-width roofline, got %v at %.2f", hasRoof, confidence)
+	}
+
+	// Top down: the body starts below the search band and only seat backs rise into it
+	open := gocv.NewMatWithSize(480, 640, gocv.MatTypeCV8UC3)
+	defer open.Close()
+	gocv.Rectangle(&open, image.Rect(100, 250, 540, 400), body, -1)
+	gocv.Rectangle(&open, image.Rect(180, 150, 260, 250), body, -1)
+	gocv.Rectangle(&open, image.Rect(380, 150, 460, 250), body, -1)
+
+	hasRoof, confidence, err = classifier.ClassifyRoof(open)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hasRoof || confidence <= 0 {
+		t.Errorf("Expected no roof when only seat backs reach the top, got %v at %.2f", hasRoof, confidence)
+	}
+}
@@ -0,0 +1,83 @@
+package test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/choff5507/vehicle-image-comparison/pkg/vehiclecompare"
+	"gocv.io/x/gocv"
+)
+
+func TestTinyImageReturnsErrImageTooSmall(t *testing.T) {
+	dir := t.TempDir()
+
+	tiny := gocv.NewMatWithSizeFromScalar(gocv.NewScalar(128, 128, 128, 0), 10, 10, gocv.MatTypeCV8UC3)
+	defer tiny.Close()
+	tinyPath := writeTestImage(t, dir, "tiny.png", tiny)
+	vehiclePath := writeTestVehicle(t, dir, "vehicle.png", 0, 0)
+
+	service := vehiclecompare.NewVehicleComparisonService()
+
+	_, err := service.CompareVehicleImages(tinyPath, vehiclePath)
+	if !errors.Is(err, vehiclecompare.ErrImageTooSmall) {
+		t.Errorf("Expected ErrImageTooSmall, got %v", err)
+	}
+}
+
+// writeOversizedPNG writes a PNG whose header declares width x height but whose
+// pixel data is a single pixel, so only a check of the header can reject it as too
+// large; decoding it would fail as corrupt
+func writeOversizedPNG(t testing.TB, dir string, width, height uint32) string {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	// IHDR follows the 8-byte signature: length, type, width, height, ..., CRC
+	binary.BigEndian.PutUint32(data[16:20], width)
+	binary.BigEndian.PutUint32(data[20:24], height)
+	binary.BigEndian.PutUint32(data[29:33], crc32.ChecksumIEEE(data[12:29]))
+
+	path := filepath.Join(dir, "oversized.png")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestOversizedImageRejectedBeforeDecoding(t *testing.T) {
+	dir := t.TempDir()
+	oversizedPath := writeOversizedPNG(t, dir, 60000, 40000)
+	vehiclePath := writeTestVehicle(t, dir, "vehicle.png", 0, 0)
+
+	service := vehiclecompare.NewVehicleComparisonService()
+
+	_, err := service.CompareVehicleImages(oversizedPath, vehiclePath)
+	if !errors.Is(err, vehiclecompare.ErrImageTooLarge) {
+		t.Errorf("Expected ErrImageTooLarge, got %v", err)
+	}
+	if errors.Is(err, vehiclecompare.ErrImageDecode) {
+		t.Errorf("Expected rejection before decoding, got a decode error: %v", err)
+	}
+}
+
+func TestMaxImagePixelsIsConfigurable(t *testing.T) {
+	dir := t.TempDir()
+	path1 := writeTestVehicle(t, dir, "vehicle1.png", 0, 0)
+	path2 := writeTestVehicle(t, dir, "vehicle2.png", 4, 0)
+
+	service := vehiclecompare.NewVehicleComparisonService(vehiclecompare.WithMaxImagePixels(100 * 100))
+
+	_, err := service.CompareVehicleImages(path1, path2)
+	if !errors.Is(err, vehiclecompare.ErrImageTooLarge) {
+		t.Errorf("Expected ErrImageTooLarge under a 100x100 bound, got %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/testutil"
+	"github.com/choff5507/vehicle-image-comparison/pkg/vehiclecompare"
+	"gocv.io/x/gocv"
+)
+
+// differentSpec is a vehicle with a clearly different light and plate layout
+func differentSpec() testutil.VehicleSpec {
+	spec := testutil.DefaultSpec()
+	spec.BodyWidth = 900
+	spec.BodyTop = 320
+	spec.LightSpacing = 760
+	spec.LightY = 420
+	spec.LightSize = 18
+	spec.ExtraLights = 1
+	spec.PlateWidth = 240
+	spec.PlateY = 660
+	spec.BumperY = 600
+	return spec
+}
+
+func TestSyntheticVehicles(t *testing.T) {
+	views := []struct {
+		name   string
+		render func(testutil.VehicleSpec) gocv.Mat
+	}{
+		{"front", testutil.FrontVehicle},
+		{"rear", testutil.RearVehicle},
+	}
+
+	for _, view := range views {
+		t.Run(view.name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			reference := view.render(testutil.DefaultSpec())
+			defer reference.Close()
+			referencePath := writeTestImage(t, dir, "reference.png", reference)
+
+			same := view.render(testutil.DefaultSpec())
+			defer same.Close()
+			samePath := writeTestImage(t, dir, "same.png", same)
+
+			different := view.render(differentSpec())
+			defer different.Close()
+			differentPath := writeTestImage(t, dir, "different.png", different)
+
+			service := vehiclecompare.NewVehicleComparisonService()
+
+			sameResult, err := service.CompareVehicleImages(referencePath, samePath)
+			if err != nil {
+				t.Fatalf("Comparison of identical vehicles failed: %v", err)
+			}
+			if !sameResult.IsSameVehicle || sameResult.SimilarityScore < 0.9 {
+				t.Errorf("Expected identical vehicles to score high, got similarity %.3f", sameResult.SimilarityScore)
+			}
+
+			differentResult, err := service.CompareVehicleImages(referencePath, differentPath)
+			if err != nil {
+				t.Fatalf("Comparison of different vehicles failed: %v", err)
+			}
+			if differentResult.IsSameVehicle {
+				t.Errorf("Expected different vehicles not to match, got similarity %.3f", differentResult.SimilarityScore)
+			}
+			if differentResult.SimilarityScore >= sameResult.SimilarityScore {
+				t.Errorf("Expected different vehicles (%.3f) to score below identical ones (%.3f)",
+					differentResult.SimilarityScore, sameResult.SimilarityScore)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+	"github.com/choff5507/vehicle-image-comparison/internal/testutil"
+	"github.com/choff5507/vehicle-image-comparison/pkg/vehiclecompare"
+	"gocv.io/x/gocv"
+)
+
+// writeIRCapture writes img as a colorless capture, which is compared on the IR path
+func writeIRCapture(t testing.TB, dir, name string, img gocv.Mat) string {
+	gray := gocv.NewMat()
+	defer gray.Close()
+	gocv.CvtColor(img, &gray, gocv.ColorBGRToGray)
+
+	return writeTestImage(t, dir, name, gray)
+}
+
+func TestCompareToTemplateMatchesEitherModality(t *testing.T) {
+	dir := t.TempDir()
+
+	enrolled := newTestVehicle(0, 0)
+	defer enrolled.Close()
+	query := newTestVehicle(6, 4)
+	defer query.Close()
+	other := testutil.FrontVehicle(differentSpec())
+	defer other.Close()
+
+	service := vehiclecompare.NewVehicleComparisonService()
+
+	template, err := service.BuildTemplate(
+		writeTestImage(t, dir, "enrolled_day.png", enrolled),
+		writeIRCapture(t, dir, "enrolled_ir.png", enrolled))
+	if err != nil {
+		t.Fatalf("BuildTemplate failed: %v", err)
+	}
+	if template.Daylight == nil || template.Infrared == nil {
+		t.Fatalf("Expected both modalities enrolled, got %+v", template)
+	}
+
+	for _, tc := range []struct {
+		name    string
+		path    string
+		thermal bool
+		same    bool
+	}{
+		{"daylight query", writeTestImage(t, dir, "query_day.png", query), false, true},
+		{"IR query", writeIRCapture(t, dir, "query_ir.png", query), true, true},
+		{"different vehicle", writeTestImage(t, dir, "other_day.png", other), false, false},
+	} {
+		result, err := service.CompareToTemplate(tc.path, template)
+		if err != nil {
+			t.Fatalf("%s: CompareToTemplate failed: %v", tc.name, err)
+		}
+		if usedIR := result.EffectiveWeights.Thermal > 0; usedIR != tc.thermal {
+			t.Errorf("%s: expected IR capture used %v, got weights %+v", tc.name, tc.thermal, result.EffectiveWeights)
+		}
+		if result.IsSameVehicle != tc.same {
+			t.Errorf("%s: expected same vehicle %v, got similarity %.3f", tc.name, tc.same, result.SimilarityScore)
+		}
+	}
+
+	// A single-modality template cannot answer a query of the other modality
+	dayOnly, err := service.BuildTemplate(writeTestImage(t, dir, "day_only.png", enrolled), "")
+	if err != nil {
+		t.Fatalf("BuildTemplate failed: %v", err)
+	}
+	if _, err := service.CompareToTemplate(writeIRCapture(t, dir, "ir_query.png", query), dayOnly); err == nil {
+		t.Error("Expected an IR query against a daylight-only template to fail")
+	}
+}
+
+func TestTemplateUpdateTracksConsistentVehicle(t *testing.T) {
+	truth := []float64{0.8, 0.6, 0.4, 0.2}
+	frameOf := func(signature []float64) models.VehicleFeatures {
+		return models.VehicleFeatures{
+			View:          models.ViewFront,
+			Lighting:      models.LightingDaylight,
+			LightPatterns: models.LightPatternFeatures{PatternSignature: signature},
+		}
+	}
+	distance := func(a, b []float64) float64 {
+		sum := 0.0
+		for i := range a {
+			sum += (a[i] - b[i]) * (a[i] - b[i])
+		}
+		return math.Sqrt(sum)
+	}
+
+	// Enrolled under poor conditions, off the vehicle's consistent signature
+	enrolled := frameOf([]float64{0.5, 0.9, 0.1, 0.5})
+	template := vehiclecompare.Template{Daylight: &vehiclecompare.EnrolledCapture{Features: enrolled, Quality: 0.8}}
+	initial := distance(template.Daylight.Features.LightPatterns.PatternSignature, truth)
+
+	// Confirmed frames scatter slightly around the consistent signature
+	rng := rand.New(rand.NewSource(3))
+	confirmedFrame := func() models.VehicleFeatures {
+		signature := make([]float64, len(truth))
+		for i, v := range truth {
+			signature[i] = v + (rng.Float64()-0.5)*0.04
+		}
+		return frameOf(signature)
+	}
+
+	for i := 0; i < 10; i++ {
+		if err := template.Update(confirmedFrame(), 0.3); err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+	}
+	converged := distance(template.Daylight.Features.LightPatterns.PatternSignature, truth)
+	if converged >= initial/4 {
+		t.Errorf("Expected the template to converge on the consistent signature: distance %.3f, initially %.3f", converged, initial)
+	}
+
+	// A mistaken update pulls the template only part way, and later frames pull it back
+	outlier := []float64{0.1, 0.1, 0.9, 0.9}
+	if err := template.Update(frameOf(outlier), 0.3); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		if err := template.Update(confirmedFrame(), 0.3); err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+	}
+	signature := template.Daylight.Features.LightPatterns.PatternSignature
+	if d := distance(signature, truth); d >= initial/4 || d >= distance(signature, outlier)/10 {
+		t.Errorf("Expected the template to recover from the outlier: %.3f from the vehicle, %.3f from the outlier",
+			d, distance(signature, outlier))
+	}
+
+	// The enrolled features are not modified in place
+	if enrolled.LightPatterns.PatternSignature[0] != 0.5 {
+		t.Error("Expected Update to leave the enrolled features untouched")
+	}
+
+	if err := template.Update(frameOf(truth), 0); err == nil {
+		t.Error("Expected a zero weight to be rejected")
+	}
+	infrared := frameOf(truth)
+	infrared.Lighting = models.LightingInfrared
+	if err := template.Update(infrared, 0.3); err == nil {
+		t.Error("Expected an update of a modality the template lacks to fail")
+	}
+}
@@ -0,0 +1,68 @@
+package test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/choff5507/vehicle-image-comparison/pkg/vehiclecompare"
+	"gocv.io/x/gocv"
+)
+
+func TestOpenCVThreadsOption(t *testing.T) {
+	previous := gocv.GetNumThreads()
+	t.Cleanup(func() { gocv.SetNumThreads(previous) })
+
+	service := vehiclecompare.NewVehicleComparisonService(vehiclecompare.WithOpenCVThreads(1))
+	if threads := gocv.GetNumThreads(); threads != 1 {
+		t.Fatalf("Expected OpenCV to use 1 thread, got %d", threads)
+	}
+
+	dir := t.TempDir()
+	path1 := writeTestVehicle(t, dir, "vehicle1.png", 0, 0)
+	path2 := writeTestVehicle(t, dir, "vehicle2.png", 6, 4)
+
+	// A small worker-pool batch, the setup the option is meant for
+	const workers = 4
+	errs := make([]error, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = service.CompareVehicleImages(path1, path2)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("Comparison %d failed: %v", i, err)
+		}
+	}
+}
+
+// BenchmarkParallelComparisons compares throughput of concurrent comparisons for
+// several OpenCV thread limits: go test ./test -run '^$' -bench ParallelComparisons
+func BenchmarkParallelComparisons(b *testing.B) {
+	previous := gocv.GetNumThreads()
+	defer gocv.SetNumThreads(previous)
+
+	dir := b.TempDir()
+	path1 := writeTestVehicle(b, dir, "vehicle1.png", 0, 0)
+	path2 := writeTestVehicle(b, dir, "vehicle2.png", 6, 4)
+
+	for _, threads := range []int{1, 2, previous} {
+		b.Run(fmt.Sprintf("threads=%d", threads), func(b *testing.B) {
+			service := vehiclecompare.NewVehicleComparisonService(vehiclecompare.WithOpenCVThreads(threads))
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if _, err := service.CompareVehicleImages(path1, path2); err != nil {
+						b.Error(err)
+					}
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/choff5507/vehicle-image-comparison/pkg/vehiclecompare"
+)
+
+func TestComparisonTraceSections(t *testing.T) {
+	dir := t.TempDir()
+	path1 := writeTestVehicle(t, dir, "vehicle1.png", 0, 0)
+	path2 := writeTestVehicle(t, dir, "vehicle2.png", 6, 4)
+
+	var traces bytes.Buffer
+	service := vehiclecompare.NewVehicleComparisonService(vehiclecompare.WithComparisonTrace(&traces))
+
+	result, err := service.CompareVehicleImages(path1, path2)
+	if err != nil {
+		t.Fatalf("Comparison failed: %v", err)
+	}
+
+	lines := bytes.Split(bytes.TrimSpace(traces.Bytes()), []byte("\n"))
+	if len(lines) != 1 {
+		t.Fatalf("Expected one trace line per comparison, got %d", len(lines))
+	}
+
+	var sections map[string]json.RawMessage
+	if err := json.Unmarshal(lines[0], &sections); err != nil {
+		t.Fatalf("Trace is not a JSON object: %v", err)
+	}
+	for _, name := range []string{"inputs", "classification", "features", "effective_weights", "similarities", "result"} {
+		if len(sections[name]) == 0 || string(sections[name]) == "null" {
+			t.Errorf("Trace is missing the %q section", name)
+		}
+	}
+
+	var trace vehiclecompare.ComparisonTrace
+	if err := json.Unmarshal(lines[0], &trace); err != nil {
+		t.Fatalf("Trace does not decode as a ComparisonTrace: %v", err)
+	}
+	if trace.Inputs[0].SHA256 == "" || trace.Inputs[0].SHA256 == trace.Inputs[1].SHA256 {
+		t.Errorf("Expected distinct input hashes, got %q and %q", trace.Inputs[0].SHA256, trace.Inputs[1].SHA256)
+	}
+	if trace.Classification[0].ViewConfidence == 0 || trace.Classification[0].LightingConfidence == 0 {
+		t.Errorf("Expected classification confidences, got %+v", trace.Classification[0])
+	}
+	if trace.Similarities.Overall != result.SimilarityScore || trace.Similarities.SubScores == nil {
+		t.Errorf("Expected the result's similarities with sub-scores, got %+v", trace.Similarities)
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestComparisonTraceWriteFailureKeepsResult(t *testing.T) {
+	dir := t.TempDir()
+	path1 := writeTestVehicle(t, dir, "vehicle1.png", 0, 0)
+	path2 := writeTestVehicle(t, dir, "vehicle2.png", 6, 4)
+
+	service := vehiclecompare.NewVehicleComparisonService(vehiclecompare.WithComparisonTrace(failingWriter{}))
+
+	result, err := service.CompareVehicleImages(path1, path2)
+	if err != nil {
+		t.Fatalf("Expected a failed trace write not to fail the comparison, got %v", err)
+	}
+
+	found := false
+	for _, warning := range result.Warnings {
+		if strings.Contains(warning, "comparison trace") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected a warning about the lost trace, got %v", result.Warnings)
+	}
+}
@@ -0,0 +1,87 @@
+package test
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/preprocessor"
+	"gocv.io/x/gocv"
+)
+
+func TestUndistortStraightensBumperLine(t *testing.T) {
+	calibration := preprocessor.CameraCalibration{
+		CameraMatrix: [3][3]float64{{500, 0, 320}, {0, 500, 240}, {0, 0, 1}},
+		DistCoeffs:   []float64{-0.4, 0, 0, 0, 0}, // Barrel distortion
+	}
+
+	// Draw a straight bumper line as the distorting lens would image it
+	distorted := gocv.NewMatWithSizeFromScalar(gocv.NewScalar(0, 0, 0, 0), 480, 640, gocv.MatTypeCV8UC3)
+	defer distorted.Close()
+
+	var points []image.Point
+	for u := 40.0; u <= 600; u += 2 {
+		points = append(points, distortPoint(u, 400, calibration))
+	}
+	curve := gocv.NewPointsVectorFromPoints([][]image.Point{points})
+	defer curve.Close()
+	gocv.Polylines(&distorted, curve, false, color.RGBA{R: 255, G: 255, B: 255}, 3)
+
+	corrected := preprocessor.Undistort(distorted, calibration)
+	defer corrected.Close()
+
+	before := lineBend(distorted, 100, 540)
+	after := lineBend(corrected, 100, 540)
+
+	if before < 5 {
+		t.Fatalf("Expected the synthetic distortion to bend the line, got %.1fpx", before)
+	}
+	if after > before/3 {
+		t.Errorf("Expected undistortion to straighten the line, bend %.1fpx before and %.1fpx after", before, after)
+	}
+}
+
+// distortPoint applies OpenCV's radial distortion model (k1, k2) to a pixel
+func distortPoint(u, v float64, calibration preprocessor.CameraCalibration) image.Point {
+	fx, cx := calibration.CameraMatrix[0][0], calibration.CameraMatrix[0][2]
+	fy, cy := calibration.CameraMatrix[1][1], calibration.CameraMatrix[1][2]
+	k1, k2 := calibration.DistCoeffs[0], calibration.DistCoeffs[1]
+
+	x := (u - cx) / fx
+	y := (v - cy) / fy
+	r2 := x*x + y*y
+	factor := 1 + k1*r2 + k2*r2*r2
+
+	return image.Pt(int(math.Round(x*factor*fx+cx)), int(math.Round(y*factor*fy+cy)))
+}
+
+// lineBend returns the spread (max - min) of the line's mean row across columns
+// [from, to), i.e. how far it departs from a horizontal line
+func lineBend(img gocv.Mat, from, to int) float64 {
+	gray := gocv.NewMat()
+	defer gray.Close()
+	gocv.CvtColor(img, &gray, gocv.ColorBGRToGray)
+
+	minRow, maxRow := math.Inf(1), math.Inf(-1)
+	for x := from; x < to; x++ {
+		sum, count := 0.0, 0.0
+		for y := 0; y < gray.Rows(); y++ {
+			if gray.GetUCharAt(y, x) > 127 {
+				sum += float64(y)
+				count++
+			}
+		}
+		if count == 0 {
+			continue
+		}
+		row := sum / count
+		minRow = math.Min(minRow, row)
+		maxRow = math.Max(maxRow, row)
+	}
+
+	if math.IsInf(minRow, 0) {
+		return 0
+	}
+	return maxRow - minRow
+}
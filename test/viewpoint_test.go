@@ -0,0 +1,85 @@
+package test
+
+import (
+	"errors"
+	"image"
+	"math"
+	"testing"
+
+	"github.com/choff5507/vehicle-image-comparison/internal/comparator"
+	"github.com/choff5507/vehicle-image-comparison/internal/models"
+	"github.com/choff5507/vehicle-image-comparison/internal/testutil"
+	"github.com/choff5507/vehicle-image-comparison/pkg/vehiclecompare"
+	"gocv.io/x/gocv"
+)
+
+// squeeze returns a copy of img compressed horizontally by factor, as the vehicle
+// face appears when captured off-axis. The caller owns the returned Mat.
+func squeeze(img gocv.Mat, factor float64) gocv.Mat {
+	squeezed := gocv.NewMat()
+	gocv.Resize(img, &squeezed, image.Pt(int(float64(img.Cols())*factor), img.Rows()), 0, 0, gocv.InterpolationLinear)
+	return squeezed
+}
+
+// extractRear renders a rear view of spec, compressed horizontally by factor, and
+// extracts its features with service
+func extractRear(t testing.TB, service *vehiclecompare.VehicleComparisonService, spec testutil.VehicleSpec, factor float64) models.VehicleFeatures {
+	rendered := testutil.RearVehicle(spec)
+	defer rendered.Close()
+	img := squeeze(rendered, factor)
+	defer img.Close()
+
+	features, err := service.ExtractFeatures(img)
+	if err != nil {
+		t.Fatalf("Feature extraction failed: %v", err)
+	}
+	return features
+}
+
+func TestViewpointDeltaFromExtractedFeatures(t *testing.T) {
+	service := vehiclecompare.NewVehicleComparisonService()
+	engine := comparator.NewComparisonEngine()
+
+	reference := extractRear(t, service, testutil.DefaultSpec(), 1.0)
+
+	// An extra inner pair changes the light count but not the span of the outer pair
+	moreLights := testutil.DefaultSpec()
+	moreLights.ExtraLights = 1
+	if delta := engine.EstimateViewpointDelta(reference, extractRear(t, service, moreLights, 1.0)); !delta.Estimated || math.Abs(delta.YawDegrees) > 5 {
+		t.Errorf("Expected no yaw between square-on captures with different light counts, got %+v", delta)
+	}
+
+	// Compressed to cos(45.6°) of its width
+	if delta := engine.EstimateViewpointDelta(reference, extractRear(t, service, testutil.DefaultSpec(), 0.7)); delta.YawDegrees < 35 {
+		t.Errorf("Expected a ~45° yaw for the compressed capture, got %+v", delta)
+	}
+}
+
+func TestMaxViewpointDeltaOnExtractedFeatures(t *testing.T) {
+	service := vehiclecompare.NewVehicleComparisonService(vehiclecompare.WithMaxViewpointDelta(20))
+
+	reference := extractRear(t, service, testutil.DefaultSpec(), 1.0)
+	moreLights := testutil.DefaultSpec()
+	moreLights.ExtraLights = 1
+
+	// Square-on captures are compared whatever lights each one shows
+	var mismatch *vehiclecompare.ErrViewpointMismatch
+	if _, err := service.CompareFeatures(reference, extractRear(t, service, moreLights, 1.0)); errors.As(err, &mismatch) {
+		t.Errorf("Expected square-on captures with different light counts to be compared, got %v", err)
+	}
+
+	dir := t.TempDir()
+	referenceImg := testutil.RearVehicle(testutil.DefaultSpec())
+	defer referenceImg.Close()
+	moreLightsImg := testutil.RearVehicle(moreLights)
+	defer moreLightsImg.Close()
+	referencePath := writeTestImage(t, dir, "reference.png", referenceImg)
+	moreLightsPath := writeTestImage(t, dir, "more_lights.png", moreLightsImg)
+	if _, err := service.CompareVehicleImages(referencePath, moreLightsPath); errors.As(err, &mismatch) {
+		t.Errorf("Expected the images to be compared, got %v", err)
+	}
+
+	if _, err := service.CompareFeatures(reference, extractRear(t, service, testutil.DefaultSpec(), 0.7)); !errors.As(err, &mismatch) {
+		t.Errorf("Expected ErrViewpointMismatch for a ~45° yaw, got %v", err)
+	}
+}